@@ -24,6 +24,7 @@ func (r *Runtime) newArrayObject() *arrayObject {
 }
 
 func setArrayValues(a *arrayObject, values []Value) *arrayObject {
+	a.val.runtime.accountMemory(int64(cap(values)) * memValueSize)
 	a.values = values
 	a.length = uint32(len(values))
 	a.objCount = len(values)
@@ -220,8 +221,10 @@ func (r *Runtime) arrayproto_join(call FunctionCall) Value {
 	if l == 0 {
 		return stringEmpty
 	}
+	r.vm.chargeInstructions(int64(l))
 
 	var buf StringBuilder
+	accounted := 0
 
 	element0 := o.self.getIdx(valueInt(0), nil)
 	if element0 != nil && element0 != _undefined && element0 != _null {
@@ -234,7 +237,9 @@ func (r *Runtime) arrayproto_join(call FunctionCall) Value {
 		if element != nil && element != _undefined && element != _null {
 			buf.WriteString(element.toString())
 		}
+		r.accountGrowth(buf.memSize(), &accounted)
 	}
+	r.accountGrowth(buf.memSize(), &accounted)
 
 	return buf.String()
 }
@@ -367,6 +372,7 @@ func (r *Runtime) arrayproto_slice(call FunctionCall) Value {
 	}
 
 	a := arraySpeciesCreate(o, count)
+	r.vm.chargeInstructions(count)
 	if src := r.checkStdArrayObj(o); src != nil {
 		if dst := r.checkStdArrayObjWithProto(a); dst != nil {
 			values := make([]Value, count)
@@ -415,6 +421,7 @@ func (r *Runtime) arrayproto_sort(call FunctionCall) Value {
 			compare: compareFn,
 		}
 
+		r.vm.chargeInstructions(int64(ctx.Len()))
 		sort.Stable(&ctx)
 	} else {
 		length := toLength(o.self.getStr("length", nil))
@@ -431,6 +438,7 @@ func (r *Runtime) arrayproto_sort(call FunctionCall) Value {
 			compare: compareFn,
 		}
 
+		r.vm.chargeInstructions(int64(ctx.Len()))
 		sort.Stable(&ctx)
 		for i := 0; i < len(a); i++ {
 			o.self.setOwnIdx(valueInt(i), a[i], true)
@@ -617,6 +625,7 @@ func (r *Runtime) arrayproto_indexOf(call FunctionCall) Value {
 	if n < 0 {
 		n = max(length+n, 0)
 	}
+	r.vm.chargeInstructions(length - n)
 
 	searchElement := call.Argument(0)
 
@@ -658,6 +667,7 @@ func (r *Runtime) arrayproto_includes(call FunctionCall) Value {
 	if n < 0 {
 		n = max(length+n, 0)
 	}
+	r.vm.chargeInstructions(length - n)
 
 	searchElement := call.Argument(0)
 	if searchElement == _negativeZero {
@@ -703,6 +713,7 @@ func (r *Runtime) arrayproto_lastIndexOf(call FunctionCall) Value {
 			fromIndex += length
 		}
 	}
+	r.vm.chargeInstructions(fromIndex + 1)
 
 	searchElement := call.Argument(0)
 
@@ -987,6 +998,7 @@ func arrayproto_reverse_generic_step(o *Object, lower, upper int64) {
 
 func (r *Runtime) arrayproto_reverse_generic(o *Object, start int64) {
 	l := toLength(o.self.getStr("length", nil))
+	r.vm.chargeInstructions(l - start)
 	middle := l / 2
 	for lower := start; lower != middle; lower++ {
 		arrayproto_reverse_generic_step(o, lower, l-lower-1)
@@ -997,6 +1009,7 @@ func (r *Runtime) arrayproto_reverse(call FunctionCall) Value {
 	o := call.This.ToObject(r)
 	if a := r.checkStdArrayObj(o); a != nil {
 		l := len(a.values)
+		r.vm.chargeInstructions(int64(l))
 		middle := l / 2
 		for lower := 0; lower != middle; lower++ {
 			upper := l - lower - 1
@@ -1112,6 +1125,7 @@ func (r *Runtime) arrayproto_fill(call FunctionCall) Value {
 	}
 	final := relToIdx(relEnd, l)
 	value := call.Argument(0)
+	r.vm.chargeInstructions(final - k)
 	if arr := r.checkStdArrayObj(o); arr != nil {
 		for ; k < final; k++ {
 			arr.values[k] = value
@@ -1368,6 +1382,7 @@ func (r *Runtime) arrayproto_toSorted(call FunctionCall) Value {
 		compare: compareFn,
 	}
 
+	r.vm.chargeInstructions(int64(ctx.Len()))
 	sort.Stable(&ctx)
 	return ar
 }
@@ -1569,6 +1584,178 @@ func (r *Runtime) array_from(call FunctionCall) Value {
 	return arr
 }
 
+// arrayFromAsync holds the state of an Array.fromAsync() call between the awaits.
+type arrayFromAsync struct {
+	r       *Runtime
+	pcap    *promiseCapability
+	a       *Object
+	k       int64
+	mapFn   func(FunctionCall) Value
+	thisArg Value
+
+	iter     *iteratorRecord
+	fromSync bool
+
+	arrayLike *Object
+	length    int64
+}
+
+func (r *Runtime) array_fromAsync(call FunctionCall) Value {
+	s := &arrayFromAsync{
+		r:       r,
+		pcap:    r.newPromiseCapability(r.getPromise()),
+		thisArg: call.Argument(2),
+	}
+	s.pcap.try(func() {
+		s.start(call.This, call.Argument(0), call.Argument(1))
+	})
+	return s.pcap.promise
+}
+
+func (s *arrayFromAsync) start(c, items, mapFn Value) {
+	r := s.r
+	if mapFn != _undefined {
+		s.mapFn = r.toCallable(mapFn)
+	}
+	var ctor func(args []Value, newTarget *Object) *Object
+	if o, ok := c.(*Object); ok {
+		ctor = o.self.assertConstructor()
+	}
+	if usingAsyncIterator := toMethod(r.getV(items, SymAsyncIterator)); usingAsyncIterator != nil {
+		s.iter = r.getIterator(items, usingAsyncIterator)
+	} else if usingSyncIterator := toMethod(r.getV(items, SymIterator)); usingSyncIterator != nil {
+		s.iter = r.getIterator(items, usingSyncIterator)
+		s.fromSync = true
+	}
+	if s.iter != nil {
+		if ctor != nil {
+			s.a = ctor(nil, nil)
+		} else {
+			s.a = r.newArrayValues(nil)
+		}
+		s.iterStep()
+		return
+	}
+	s.arrayLike = items.ToObject(r)
+	s.length = toLength(s.arrayLike.self.getStr("length", nil))
+	if ctor != nil {
+		s.a = ctor([]Value{intToValue(s.length)}, nil)
+	} else {
+		s.a = r.newArrayLength(s.length)
+	}
+	s.arrayLikeStep()
+}
+
+func (s *arrayFromAsync) finish() {
+	s.a.self.setOwnStr("length", intToValue(s.k), true)
+	s.pcap.resolve(s.a)
+}
+
+func (s *arrayFromAsync) iterStep() {
+	r := s.r
+	s.pcap.try(func() {
+		var next Value
+		if s.fromSync {
+			next = r.asyncFromSyncIteratorNext(s.iter)
+		} else {
+			if s.iter.next == nil {
+				panic(r.NewTypeError("iterator.next is missing or not a function"))
+			}
+			next = s.iter.next(FunctionCall{This: s.iter.iterator})
+		}
+		r.await(next, s.onNext, s.pcap.reject)
+	})
+}
+
+func (s *arrayFromAsync) onNext(result Value) {
+	r := s.r
+	s.pcap.try(func() {
+		resultObj, ok := result.(*Object)
+		if !ok {
+			panic(r.NewTypeError("iterator result is not an object"))
+		}
+		if iteratorComplete(resultObj) {
+			s.finish()
+			return
+		}
+		value := iteratorValue(resultObj)
+		if s.mapFn == nil {
+			s.onMapped(value)
+			return
+		}
+		if ex := r.vm.try(func() {
+			mapped := s.mapFn(FunctionCall{This: s.thisArg, Arguments: []Value{value, intToValue(s.k)}})
+			r.await(mapped, s.onMapped, s.closeAndReject)
+		}); ex != nil {
+			s.closeAndReject(ex.val)
+		}
+	})
+}
+
+func (s *arrayFromAsync) onMapped(value Value) {
+	if ex := s.r.vm.try(func() {
+		createDataPropertyOrThrow(s.a, intToValue(s.k), value)
+	}); ex != nil {
+		s.closeAndReject(ex.val)
+		return
+	}
+	s.k++
+	s.iterStep()
+}
+
+// closeAndReject is an equivalent of AsyncIteratorClose() with a throw completion.
+func (s *arrayFromAsync) closeAndReject(reason Value) {
+	r := s.r
+	var innerResult Value
+	if ex := r.vm.try(func() {
+		if s.fromSync {
+			innerResult = r.asyncFromSyncIteratorReturn(s.iter)
+		} else if returnMethod := toMethod(s.iter.iterator.self.getStr("return", nil)); returnMethod != nil {
+			innerResult = returnMethod(FunctionCall{This: s.iter.iterator})
+		}
+		if innerResult != nil {
+			reject := func(Value) {
+				s.pcap.reject(reason)
+			}
+			r.await(innerResult, reject, reject)
+		}
+	}); ex != nil || innerResult == nil {
+		s.pcap.reject(reason)
+	}
+}
+
+func (s *arrayFromAsync) arrayLikeStep() {
+	r := s.r
+	s.pcap.try(func() {
+		if s.k >= s.length {
+			s.finish()
+			return
+		}
+		value := nilSafe(s.arrayLike.self.getIdx(valueInt(s.k), nil))
+		r.await(value, s.onArrayLikeValue, s.pcap.reject)
+	})
+}
+
+func (s *arrayFromAsync) onArrayLikeValue(value Value) {
+	r := s.r
+	s.pcap.try(func() {
+		if s.mapFn == nil {
+			s.onArrayLikeMapped(value)
+			return
+		}
+		mapped := s.mapFn(FunctionCall{This: s.thisArg, Arguments: []Value{value, intToValue(s.k)}})
+		r.await(mapped, s.onArrayLikeMapped, s.pcap.reject)
+	})
+}
+
+func (s *arrayFromAsync) onArrayLikeMapped(value Value) {
+	s.pcap.try(func() {
+		createDataPropertyOrThrow(s.a, intToValue(s.k), value)
+		s.k++
+		s.arrayLikeStep()
+	})
+}
+
 func (r *Runtime) array_isArray(call FunctionCall) Value {
 	if o, ok := call.Argument(0).(*Object); ok {
 		if isArray(o) {
@@ -1661,6 +1848,7 @@ func createArrayProtoTemplate() *objectTemplate {
 	t.putSym(SymIterator, func(r *Runtime) Value { return valueProp(r.getArrayValues(), true, false, true) })
 	t.putSym(SymUnscopables, func(r *Runtime) Value {
 		bl := r.newBaseObject(nil, classObject)
+		bl.setOwnStr("at", valueTrue, true)
 		bl.setOwnStr("copyWithin", valueTrue, true)
 		bl.setOwnStr("entries", valueTrue, true)
 		bl.setOwnStr("fill", valueTrue, true)
@@ -1672,12 +1860,10 @@ func createArrayProtoTemplate() *objectTemplate {
 		bl.setOwnStr("flatMap", valueTrue, true)
 		bl.setOwnStr("includes", valueTrue, true)
 		bl.setOwnStr("keys", valueTrue, true)
-		bl.setOwnStr("values", valueTrue, true)
-		bl.setOwnStr("groupBy", valueTrue, true)
-		bl.setOwnStr("groupByToMap", valueTrue, true)
 		bl.setOwnStr("toReversed", valueTrue, true)
 		bl.setOwnStr("toSorted", valueTrue, true)
 		bl.setOwnStr("toSpliced", valueTrue, true)
+		bl.setOwnStr("values", valueTrue, true)
 
 		return valueProp(bl.val, false, false, true)
 	})
@@ -1718,6 +1904,7 @@ func (r *Runtime) getArray() *Object {
 func (r *Runtime) createArray(val *Object) objectImpl {
 	o := r.newNativeFuncConstructObj(val, r.builtin_newArray, "Array", r.getArrayPrototype(), 1)
 	o._putProp("from", r.newNativeFunc(r.array_from, "from", 1), true, false, true)
+	o._putProp("fromAsync", r.newNativeFunc(r.array_fromAsync, "fromAsync", 1), true, false, true)
 	o._putProp("isArray", r.newNativeFunc(r.array_isArray, "isArray", 1), true, false, true)
 	o._putProp("of", r.newNativeFunc(r.array_of, "of", 0), true, false, true)
 	r.putSpeciesReturnThis(o)
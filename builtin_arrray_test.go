@@ -342,6 +342,16 @@ func TestArrayProto(t *testing.T) {
 	testScriptWithTestLib(SCRIPT, _undefined, t)
 }
 
+func TestArrayUnscopables(t *testing.T) {
+	const SCRIPT = `
+	const unscopables = Array.prototype[Symbol.unscopables];
+	assert.sameValue(Object.getPrototypeOf(unscopables), null);
+	assert(compareArray(Object.keys(unscopables), ["at", "copyWithin", "entries", "fill", "find", "findIndex", "findLast",
+		"findLastIndex", "flat", "flatMap", "includes", "keys", "toReversed", "toSorted", "toSpliced", "values"]));
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
 func TestArrayToSpliced(t *testing.T) {
 	const SCRIPT = `
 	const a = [1, 2, 3];
@@ -365,3 +375,52 @@ func TestArrayToSpliced(t *testing.T) {
 	`
 	testScriptWithTestLib(SCRIPT, _undefined, t)
 }
+
+func TestArrayFromAsync(t *testing.T) {
+	testAsyncFuncWithTestLib(`
+	let res = await Array.fromAsync([1, Promise.resolve(2), 3]);
+	assert(compareArray(res, [1, 2, 3]), "sync iterable");
+
+	res = await Array.fromAsync({length: 2, 0: Promise.resolve(1), 1: 2}, async x => x * 10);
+	assert(compareArray(res, [10, 20]), "array-like");
+
+	const asyncIterable = {
+		[Symbol.asyncIterator]() {
+			let i = 0;
+			return {
+				next() {
+					i++;
+					return Promise.resolve({value: Promise.resolve(i), done: i > 2});
+				},
+			};
+		},
+	};
+	res = await Array.fromAsync(asyncIterable);
+	assert.sameValue(res.length, 2);
+	assert(res[0] instanceof Promise, "values of async iterators are not awaited");
+
+	let closed = false;
+	function* gen() {
+		try {
+			yield 1;
+			yield Promise.reject(new Test262Error());
+		} finally {
+			closed = true;
+		}
+	}
+	try {
+		await Array.fromAsync(gen());
+		throw new Error("should have been rejected");
+	} catch (e) {
+		assert(e instanceof Test262Error);
+	}
+	assert(closed, "sync iterator is closed on rejection");
+
+	try {
+		await Array.fromAsync([], 42);
+		throw new Error("should have been rejected");
+	} catch (e) {
+		assert(e instanceof TypeError);
+	}
+	`, _undefined, t)
+}
@@ -1,24 +1,38 @@
 package goja
 
-import "github.com/dop251/goja/unistring"
+import (
+	"math"
 
-const propNameStack = "stack"
+	"github.com/dop251/goja/unistring"
+)
+
+const (
+	propNameStack = "stack"
+
+	defaultStackTraceLimit = 10
+)
 
 type errorObject struct {
 	baseObject
 	stack          []StackFrame
+	stackLimit     int
 	stackPropAdded bool
 }
 
-func (e *errorObject) formatStack() String {
+type callSiteObject struct {
+	baseObject
+	frame StackFrame
+}
+
+func formatStack(obj *Object, stack []StackFrame) String {
 	var b StringBuilder
-	val := writeErrorString(&b, e.val)
+	val := writeErrorString(&b, obj)
 	if val != nil {
 		b.WriteString(val)
 	}
 	b.WriteRune('\n')
 
-	for _, frame := range e.stack {
+	for _, frame := range stack {
 		b.writeASCII("\tat ")
 		frame.WriteToValueBuilder(&b)
 		b.WriteRune('\n')
@@ -26,15 +40,80 @@ func (e *errorObject) formatStack() String {
 	return b.String()
 }
 
+// prepareStackTrace returns the value of the 'stack' property for obj. If Error.prepareStackTrace is a function
+// it is called with obj and an array of CallSite objects, otherwise the default format is used.
+func (r *Runtime) prepareStackTrace(obj *Object, stack []StackFrame) Value {
+	if errCtor := r.global.Error; errCtor != nil && !r.preparingStackTrace {
+		if prepare, ok := assertCallable(errCtor.self.getStr("prepareStackTrace", nil)); ok {
+			callSites := make([]Value, len(stack))
+			for i := range stack {
+				callSites[i] = r.newCallSite(stack[i])
+			}
+			r.preparingStackTrace = true
+			defer func() {
+				r.preparingStackTrace = false
+			}()
+			return prepare(FunctionCall{
+				This:      errCtor,
+				Arguments: []Value{obj, r.newArrayValues(callSites)},
+			})
+		}
+	}
+	return formatStack(obj, stack)
+}
+
+// stackTraceLimit returns the value of Error.stackTraceLimit. If it is not a number, ok is false which means
+// that no stack trace should be captured.
+func (r *Runtime) stackTraceLimit() (limit int, ok bool) {
+	errCtor := r.global.Error
+	if errCtor == nil {
+		return defaultStackTraceLimit, true
+	}
+	v := errCtor.self.getOwnPropStr("stackTraceLimit")
+	if prop, isProp := v.(*valueProperty); isProp {
+		if prop.accessor {
+			return 0, false
+		}
+		v = prop.value
+	}
+	switch v := v.(type) {
+	case valueInt:
+		if v < 0 {
+			return 0, true
+		}
+		if v > math.MaxInt32 {
+			return math.MaxInt32, true
+		}
+		return int(v), true
+	case valueFloat:
+		f := float64(v)
+		if !(f > 0) {
+			return 0, true
+		}
+		if f > math.MaxInt32 {
+			return math.MaxInt32, true
+		}
+		return int(f), true
+	}
+	return 0, false
+}
+
+func (e *errorObject) limitedStack() []StackFrame {
+	if len(e.stack) > e.stackLimit {
+		return e.stack[:e.stackLimit]
+	}
+	return e.stack
+}
+
 func (e *errorObject) addStackProp() Value {
 	if !e.stackPropAdded {
-		res := e._putProp(propNameStack, e.formatStack(), true, false, true)
+		e.stackPropAdded = true
+		res := e._putProp(propNameStack, e.val.runtime.prepareStackTrace(e.val, e.limitedStack()), true, false, true)
 		if len(e.propNames) > 1 {
 			// reorder property names to ensure 'stack' is the first one
 			copy(e.propNames[1:], e.propNames)
 			e.propNames[0] = propNameStack
 		}
-		e.stackPropAdded = true
 		return res
 	}
 	return nil
@@ -65,8 +144,10 @@ func (e *errorObject) setForeignStr(name unistring.String, val, receiver Value,
 }
 
 func (e *errorObject) deleteStr(name unistring.String, throw bool) bool {
-	if name == propNameStack {
-		e.addStackProp()
+	if name == propNameStack && !e.stackPropAdded {
+		// no need to format the stack only to delete it
+		e.stackPropAdded = true
+		return true
 	}
 	return e.baseObject.deleteStr(name, throw)
 }
@@ -100,8 +181,15 @@ func (e *errorObject) iterateStringKeys() iterNextFunc {
 
 func (e *errorObject) init() {
 	e.baseObject.init()
-	vm := e.val.runtime.vm
+	r := e.val.runtime
+	vm := r.vm
 	e.stack = vm.captureStack(make([]StackFrame, 0, len(vm.callStack)+1), 0)
+	if limit, ok := r.stackTraceLimit(); ok {
+		e.stackLimit = limit
+	} else {
+		// same as V8: no 'stack' property if Error.stackTraceLimit is not a number
+		e.stackPropAdded = true
+	}
 }
 
 func (r *Runtime) newErrorObject(proto *Object, class string) *errorObject {
@@ -201,6 +289,172 @@ func (r *Runtime) error_toString(call FunctionCall) Value {
 	return sb.String()
 }
 
+func (r *Runtime) error_captureStackTrace(call FunctionCall) Value {
+	obj, ok := call.Argument(0).(*Object)
+	if !ok {
+		panic(r.NewTypeError("Invalid argument"))
+	}
+	var skipUntil *Object
+	if fn, ok := call.Argument(1).(*Object); ok {
+		if _, ok := fn.self.assertCallable(); ok {
+			skipUntil = fn
+		}
+	}
+	limit, ok := r.stackTraceLimit()
+	if !ok {
+		return _undefined
+	}
+	// skip the captureStackTrace() frame itself
+	stack := r.vm.captureStackUntil(1, skipUntil, limit)
+
+	if e, ok := obj.self.(*errorObject); ok && e.extensible {
+		if e.stackPropAdded {
+			e.baseObject.deleteStr(propNameStack, true)
+		}
+		e.stack = stack
+		e.stackLimit = limit
+		e.stackPropAdded = false
+		return _undefined
+	}
+
+	var value Value
+	obj.self.defineOwnPropertyStr(propNameStack, PropertyDescriptor{
+		Getter: r.newNativeFunc(func(FunctionCall) Value {
+			if value == nil {
+				value = r.prepareStackTrace(obj, stack)
+				stack = nil
+			}
+			return value
+		}, "", 0),
+		Setter: r.newNativeFunc(func(call FunctionCall) Value {
+			value = call.Argument(0)
+			stack = nil
+			return _undefined
+		}, "", 1),
+		Configurable: FLAG_TRUE,
+	}, true)
+	return _undefined
+}
+
+func (r *Runtime) newCallSite(frame StackFrame) *Object {
+	o := &Object{runtime: r}
+	cs := &callSiteObject{
+		frame: frame,
+	}
+	cs.class = classObject
+	cs.val = o
+	cs.extensible = true
+	cs.prototype = r.getCallSitePrototype()
+	o.self = cs
+	cs.init()
+	return o
+}
+
+func (r *Runtime) toCallSite(v Value, method string) *StackFrame {
+	if o, ok := v.(*Object); ok {
+		if cs, ok := o.self.(*callSiteObject); ok {
+			return &cs.frame
+		}
+	}
+	panic(r.NewTypeError("CallSite method %s expects CallSite as receiver", method))
+}
+
+func (r *Runtime) callSiteProto_getFileName(call FunctionCall) Value {
+	f := r.toCallSite(call.This, "getFileName")
+	if f.prg != nil {
+		if name := f.Position().Filename; name != "" {
+			return newStringValue(name)
+		}
+	}
+	return _undefined
+}
+
+func (r *Runtime) callSiteProto_getLineNumber(call FunctionCall) Value {
+	f := r.toCallSite(call.This, "getLineNumber")
+	if f.prg == nil {
+		return _null
+	}
+	return intToValue(int64(f.Position().Line))
+}
+
+func (r *Runtime) callSiteProto_getColumnNumber(call FunctionCall) Value {
+	f := r.toCallSite(call.This, "getColumnNumber")
+	if f.prg == nil {
+		return _null
+	}
+	return intToValue(int64(f.Position().Column))
+}
+
+func (r *Runtime) callSiteProto_getFunctionName(call FunctionCall) Value {
+	f := r.toCallSite(call.This, "getFunctionName")
+	if f.funcName == "" {
+		return _null
+	}
+	return stringValueFromRaw(f.funcName)
+}
+
+func (r *Runtime) callSiteProto_isNative(call FunctionCall) Value {
+	return r.toBoolean(r.toCallSite(call.This, "isNative").prg == nil)
+}
+
+func (r *Runtime) callSiteProto_isAsync(call FunctionCall) Value {
+	return r.toBoolean(r.toCallSite(call.This, "isAsync").async)
+}
+
+func (r *Runtime) callSiteProto_isToplevel(call FunctionCall) Value {
+	f := r.toCallSite(call.This, "isToplevel")
+	return r.toBoolean(f.prg != nil && f.funcName == "")
+}
+
+func (r *Runtime) callSiteProto_toString(call FunctionCall) Value {
+	var b StringBuilder
+	r.toCallSite(call.This, "toString").WriteToValueBuilder(&b)
+	return b.String()
+}
+
+func (r *Runtime) createCallSiteProto(val *Object) objectImpl {
+	o := newBaseObjectObj(val, r.global.ObjectPrototype, classObject)
+
+	// Information that is not tracked by StackFrame is reported the same way V8 does for strict mode code.
+	constant := func(name unistring.String, v Value) {
+		o._putProp(name, r.newNativeFunc(func(call FunctionCall) Value {
+			r.toCallSite(call.This, name.String())
+			return v
+		}, name, 0), true, false, true)
+	}
+
+	constant("getThis", _undefined)
+	constant("getTypeName", _null)
+	constant("getFunction", _undefined)
+	o._putProp("getFunctionName", r.newNativeFunc(r.callSiteProto_getFunctionName, "getFunctionName", 0), true, false, true)
+	constant("getMethodName", _null)
+	o._putProp("getFileName", r.newNativeFunc(r.callSiteProto_getFileName, "getFileName", 0), true, false, true)
+	o._putProp("getScriptNameOrSourceURL", r.newNativeFunc(r.callSiteProto_getFileName, "getScriptNameOrSourceURL", 0), true, false, true)
+	o._putProp("getLineNumber", r.newNativeFunc(r.callSiteProto_getLineNumber, "getLineNumber", 0), true, false, true)
+	o._putProp("getColumnNumber", r.newNativeFunc(r.callSiteProto_getColumnNumber, "getColumnNumber", 0), true, false, true)
+	constant("getEvalOrigin", _undefined)
+	o._putProp("isToplevel", r.newNativeFunc(r.callSiteProto_isToplevel, "isToplevel", 0), true, false, true)
+	constant("isEval", valueFalse)
+	o._putProp("isNative", r.newNativeFunc(r.callSiteProto_isNative, "isNative", 0), true, false, true)
+	constant("isConstructor", valueFalse)
+	o._putProp("isAsync", r.newNativeFunc(r.callSiteProto_isAsync, "isAsync", 0), true, false, true)
+	constant("isPromiseAll", valueFalse)
+	constant("getPromiseIndex", _null)
+	o._putProp("toString", r.newNativeFunc(r.callSiteProto_toString, "toString", 0), true, false, true)
+
+	return o
+}
+
+func (r *Runtime) getCallSitePrototype() *Object {
+	var o *Object
+	if o = r.global.CallSitePrototype; o == nil {
+		o = &Object{runtime: r}
+		r.global.CallSitePrototype = o
+		o.self = r.createCallSiteProto(o)
+	}
+	return o
+}
+
 func (r *Runtime) createErrorPrototype(name String, ctor *Object) *Object {
 	o := r.newBaseObject(r.getErrorPrototype(), classObject)
 	o._putProp("message", stringEmpty, true, false, true)
@@ -229,6 +483,9 @@ func (r *Runtime) getError() *Object {
 		ret = &Object{runtime: r}
 		r.global.Error = ret
 		r.newNativeFuncConstruct(ret, r.builtin_Error, "Error", r.getErrorPrototype(), 1)
+		o := ret.self
+		o._putProp("captureStackTrace", r.newNativeFunc(r.error_captureStackTrace, "captureStackTrace", 2), true, false, true)
+		o._putProp("stackTraceLimit", intToValue(defaultStackTraceLimit), true, true, true)
 	}
 	return ret
 }
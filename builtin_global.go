@@ -329,6 +329,45 @@ func (r *Runtime) builtin_unescape(call FunctionCall) Value {
 	return asciiString(asciiBuf)
 }
 
+func (r *Runtime) builtin_structuredClone(call FunctionCall) Value {
+	var transfer []*arrayBufferObject
+	if options := call.Argument(1); options != _undefined && options != _null {
+		if list := r.toObject(options).self.getStr("transfer", nil); list != nil && list != _undefined {
+			for _, item := range r.iterableToList(list, nil) {
+				var buf *arrayBufferObject
+				if o, ok := item.(*Object); ok {
+					buf, _ = o.self.(*arrayBufferObject)
+				}
+				if buf == nil {
+					panic(r.NewTypeError("Value not transferable"))
+				}
+				if buf.detached || buf.immutable {
+					panic(r.NewTypeError("ArrayBuffer is not transferable"))
+				}
+				for _, b := range transfer {
+					if b == buf {
+						panic(r.NewTypeError("ArrayBuffer occurs in the transfer array more than once"))
+					}
+				}
+				transfer = append(transfer, buf)
+			}
+		}
+	}
+
+	data := r.serialize(call.Argument(0), transfer)
+
+	transferred := make([]*arrayBufferObject, len(transfer))
+	for i, buf := range transfer {
+		t := r._newArrayBuffer(r.getArrayBufferPrototype(), nil)
+		t.data = buf.data
+		t.maxByteLen = buf.maxByteLen
+		buf.detach()
+		transferred[i] = t
+	}
+
+	return r.deserialize(data, transferred)
+}
+
 func createGlobalObjectTemplate() *objectTemplate {
 	t := newObjectTemplate()
 	t.protoFactory = func(r *Runtime) *Object {
@@ -367,6 +406,7 @@ func createGlobalObjectTemplate() *objectTemplate {
 	t.putStr("Map", func(r *Runtime) Value { return valueProp(r.getMap(), true, false, true) })
 	t.putStr("Set", func(r *Runtime) Value { return valueProp(r.getSet(), true, false, true) })
 	t.putStr("Promise", func(r *Runtime) Value { return valueProp(r.getPromise(), true, false, true) })
+	t.putStr("Iterator", func(r *Runtime) Value { return valueProp(r.getIteratorConstructor(), true, false, true) })
 
 	t.putStr("globalThis", func(r *Runtime) Value { return valueProp(r.globalObject, true, false, true) })
 	t.putStr("NaN", func(r *Runtime) Value { return valueProp(_NaN, false, false, false) })
@@ -383,6 +423,7 @@ func createGlobalObjectTemplate() *objectTemplate {
 	t.putStr("encodeURIComponent", func(r *Runtime) Value { return r.methodProp(r.builtin_encodeURIComponent, "encodeURIComponent", 1) })
 	t.putStr("escape", func(r *Runtime) Value { return r.methodProp(r.builtin_escape, "escape", 1) })
 	t.putStr("unescape", func(r *Runtime) Value { return r.methodProp(r.builtin_unescape, "unescape", 1) })
+	t.putStr("structuredClone", func(r *Runtime) Value { return r.methodProp(r.builtin_structuredClone, "structuredClone", 1) })
 
 	// TODO: Annex B
 
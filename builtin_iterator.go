@@ -0,0 +1,816 @@
+package goja
+
+import (
+	"math"
+)
+
+type iteratorHelperObject struct {
+	baseObject
+	underlying []*iteratorRecord
+	step       func() (Value, bool)
+	state      generatorState
+}
+
+type iteratorWrapObject struct {
+	baseObject
+	iterated *iteratorRecord
+}
+
+func (o *iteratorHelperObject) next() Value {
+	r := o.val.runtime
+	switch o.state {
+	case genStateExecuting:
+		panic(r.NewTypeError("Iterator helper is already running"))
+	case genStateCompleted:
+		return r.createIterResultObject(_undefined, true)
+	}
+	o.state = genStateExecuting
+	completed := true
+	defer func() {
+		if completed {
+			o.state = genStateCompleted
+			o.underlying = nil
+		}
+	}()
+	value, ok := o.step()
+	if !ok {
+		return r.createIterResultObject(_undefined, true)
+	}
+	completed = false
+	o.state = genStateSuspendedYield
+	return r.createIterResultObject(value, false)
+}
+
+func (o *iteratorHelperObject) _return() Value {
+	r := o.val.runtime
+	switch o.state {
+	case genStateExecuting:
+		panic(r.NewTypeError("Iterator helper is already running"))
+	case genStateCompleted:
+		return r.createIterResultObject(_undefined, true)
+	}
+	underlying := o.underlying
+	if o.state == genStateSuspendedStart {
+		o.state = genStateCompleted
+	} else {
+		o.state = genStateExecuting
+		defer func() {
+			o.state = genStateCompleted
+		}()
+	}
+	o.underlying = nil
+	iteratorCloseAll(underlying, nil)
+	return r.createIterResultObject(_undefined, true)
+}
+
+func (r *Runtime) newIteratorHelper(underlying ...*iteratorRecord) *iteratorHelperObject {
+	o := &Object{runtime: r}
+
+	h := &iteratorHelperObject{
+		underlying: underlying,
+		state:      genStateSuspendedStart,
+	}
+	h.class = classObject
+	h.val = o
+	h.extensible = true
+	o.self = h
+	h.prototype = r.getIteratorHelperPrototype()
+	h.init()
+
+	return h
+}
+
+// getIteratorFlattenable is an equivalent of GetIteratorFlattenable(). If iterateStrings is false,
+// all primitive values are rejected, otherwise string primitives are iterated.
+func (r *Runtime) getIteratorFlattenable(obj Value, iterateStrings bool) *iteratorRecord {
+	if _, ok := obj.(*Object); !ok {
+		if _, isStr := obj.(String); !isStr || !iterateStrings {
+			panic(r.NewTypeError("%s is not an object", obj.String()))
+		}
+	}
+	var iter Value
+	if method := toMethod(r.getV(obj, SymIterator)); method != nil {
+		iter = method(FunctionCall{This: obj})
+	} else {
+		iter = obj
+	}
+	iterObj, ok := iter.(*Object)
+	if !ok {
+		panic(r.NewTypeError("Result of the Symbol.iterator method is not an object"))
+	}
+	return r.getIteratorDirect(iterObj)
+}
+
+func (r *Runtime) iteratorThisObj(this Value, method string) *Object {
+	if obj, ok := this.(*Object); ok {
+		return obj
+	}
+	panic(r.NewTypeError("Method Iterator.prototype.%s called on incompatible receiver %s", method, r.objectproto_toString(FunctionCall{This: this})))
+}
+
+// iteratorCallback returns the callable value of arg or closes the iterator and throws a TypeError.
+func (r *Runtime) iteratorCallback(iter *Object, arg Value) func(FunctionCall) Value {
+	if obj, ok := arg.(*Object); ok {
+		if fn, ok := obj.self.assertCallable(); ok {
+			return fn
+		}
+	}
+	(&iteratorRecord{iterator: iter}).closeThrow(r.NewTypeError("%s is not a function", arg.String()))
+	return nil
+}
+
+// iteratorLimit converts arg to a non-negative integer or +Infinity or closes the iterator and throws.
+func (r *Runtime) iteratorLimit(iter *Object, arg Value) float64 {
+	rec := &iteratorRecord{iterator: iter}
+	var limit float64
+	rec.closeOnThrow(func() {
+		limit = arg.ToFloat()
+	})
+	if math.IsNaN(limit) {
+		rec.closeThrow(r.newError(r.getRangeError(), "%s must be a number", arg.String()))
+	}
+	limit = math.Trunc(limit)
+	if limit < 0 {
+		rec.closeThrow(r.newError(r.getRangeError(), "%s must be positive", arg.String()))
+	}
+	return limit
+}
+
+func (r *Runtime) iterator_from(call FunctionCall) Value {
+	iterated := r.getIteratorFlattenable(call.Argument(0), true)
+	if hasInstance(r.getIteratorConstructor(), iterated.iterator) {
+		return iterated.iterator
+	}
+
+	o := &Object{runtime: r}
+	w := &iteratorWrapObject{
+		iterated: iterated,
+	}
+	w.class = classObject
+	w.val = o
+	w.extensible = true
+	o.self = w
+	w.prototype = r.getWrapForValidIteratorPrototype()
+	w.init()
+
+	return o
+}
+
+func (r *Runtime) iterator_concat(call FunctionCall) Value {
+	type iterable struct {
+		openMethod func(FunctionCall) Value
+		obj        *Object
+	}
+	iterables := make([]iterable, 0, len(call.Arguments))
+	for _, item := range call.Arguments {
+		obj, ok := item.(*Object)
+		if !ok {
+			panic(r.NewTypeError("%s is not an object", item.String()))
+		}
+		method := toMethod(obj.self.getSym(SymIterator, nil))
+		if method == nil {
+			panic(r.NewTypeError("%s is not iterable", item.String()))
+		}
+		iterables = append(iterables, iterable{openMethod: method, obj: obj})
+	}
+
+	h := r.newIteratorHelper()
+	var current *iteratorRecord
+	h.step = func() (Value, bool) {
+		for {
+			if current != nil {
+				if value, ok := current.stepValue(); ok {
+					return value, true
+				}
+				current = nil
+				h.underlying = nil
+			}
+			if len(iterables) == 0 {
+				return nil, false
+			}
+			item := iterables[0]
+			iterables = iterables[1:]
+			iter, ok := item.openMethod(FunctionCall{This: item.obj}).(*Object)
+			if !ok {
+				panic(r.NewTypeError("Result of the Symbol.iterator method is not an object"))
+			}
+			current = r.getIteratorDirect(iter)
+			h.underlying = []*iteratorRecord{current}
+		}
+	}
+	return h.val
+}
+
+type iteratorZipMode int
+
+const (
+	iteratorZipShortest iteratorZipMode = iota
+	iteratorZipLongest
+	iteratorZipStrict
+)
+
+// getIteratorZipOptions reads the mode and the padding options of Iterator.zip() and Iterator.zipKeyed().
+// The returned padding is nil unless the mode is "longest".
+func (r *Runtime) getIteratorZipOptions(arg Value) (iteratorZipMode, *Object) {
+	var options *Object
+	switch arg := arg.(type) {
+	case valueUndefined:
+		return iteratorZipShortest, nil
+	case *Object:
+		options = arg
+	default:
+		panic(r.NewTypeError("options must be an object"))
+	}
+	var mode iteratorZipMode
+	switch m := nilSafe(options.self.getStr("mode", nil)); {
+	case m == _undefined, m.StrictEquals(asciiString("shortest")):
+		mode = iteratorZipShortest
+	case m.StrictEquals(asciiString("longest")):
+		mode = iteratorZipLongest
+	case m.StrictEquals(asciiString("strict")):
+		mode = iteratorZipStrict
+	default:
+		panic(r.NewTypeError("mode must be one of 'shortest', 'longest' or 'strict'"))
+	}
+	var padding *Object
+	if mode == iteratorZipLongest {
+		switch p := nilSafe(options.self.getStr("padding", nil)).(type) {
+		case valueUndefined:
+		case *Object:
+			padding = p
+		default:
+			panic(r.NewTypeError("padding must be an object"))
+		}
+	}
+	return mode, padding
+}
+
+func (r *Runtime) iterator_zip(call FunctionCall) Value {
+	iterables, ok := call.Argument(0).(*Object)
+	if !ok {
+		panic(r.NewTypeError("%s is not an object", call.Argument(0).String()))
+	}
+	mode, paddingOption := r.getIteratorZipOptions(call.Argument(1))
+
+	var iters []*iteratorRecord
+	inputIter := r.getIterator(iterables, nil)
+	for {
+		var next Value
+		var ok bool
+		if ex := tryFunc(func() {
+			next, ok = inputIter.stepValue()
+		}); ex != nil {
+			iteratorCloseAll(iters, ex)
+		}
+		if !ok {
+			break
+		}
+		if ex := tryFunc(func() {
+			iters = append(iters, r.getIteratorFlattenable(next, false))
+		}); ex != nil {
+			iteratorCloseAll(append([]*iteratorRecord{inputIter}, iters...), ex)
+		}
+	}
+
+	var padding []Value
+	if mode == iteratorZipLongest {
+		padding = make([]Value, len(iters))
+		for i := range padding {
+			padding[i] = _undefined
+		}
+		if paddingOption != nil {
+			if ex := tryFunc(func() {
+				paddingIter := r.getIterator(paddingOption, nil)
+				for i := range padding {
+					next, ok := paddingIter.stepValue()
+					if !ok {
+						return
+					}
+					padding[i] = next
+				}
+				paddingIter.returnIter()
+			}); ex != nil {
+				iteratorCloseAll(iters, ex)
+			}
+		}
+	}
+
+	return r.iteratorZip(iters, mode, padding, r.newArrayValues)
+}
+
+func (r *Runtime) iterator_zipKeyed(call FunctionCall) Value {
+	iterables, ok := call.Argument(0).(*Object)
+	if !ok {
+		panic(r.NewTypeError("%s is not an object", call.Argument(0).String()))
+	}
+	mode, paddingOption := r.getIteratorZipOptions(call.Argument(1))
+
+	var iters []*iteratorRecord
+	var keys []Value
+	if ex := tryFunc(func() {
+		for _, key := range iterables.self.keys(true, nil) {
+			prop := iterables.getOwnProp(key)
+			if prop == nil {
+				continue
+			}
+			if p, ok := prop.(*valueProperty); ok && !p.enumerable {
+				continue
+			}
+			value := nilSafe(iterables.get(key, nil))
+			if value == _undefined {
+				continue
+			}
+			iters = append(iters, r.getIteratorFlattenable(value, false))
+			keys = append(keys, key)
+		}
+	}); ex != nil {
+		iteratorCloseAll(iters, ex)
+	}
+
+	var padding []Value
+	if mode == iteratorZipLongest {
+		padding = make([]Value, len(iters))
+		for i, key := range keys {
+			if paddingOption != nil {
+				if ex := tryFunc(func() {
+					padding[i] = nilSafe(paddingOption.get(key, nil))
+				}); ex != nil {
+					iteratorCloseAll(iters, ex)
+				}
+			} else {
+				padding[i] = _undefined
+			}
+		}
+	}
+
+	return r.iteratorZip(iters, mode, padding, func(results []Value) *Object {
+		obj := r.newBaseObject(nil, classObject).val
+		for i, key := range keys {
+			createDataPropertyOrThrow(obj, key, results[i])
+		}
+		return obj
+	})
+}
+
+// iteratorZip is an equivalent of IteratorZip().
+func (r *Runtime) iteratorZip(iters []*iteratorRecord, mode iteratorZipMode, padding []Value, finishResults func([]Value) *Object) Value {
+	openIters := make([]*iteratorRecord, len(iters))
+	copy(openIters, iters)
+	iters = append([]*iteratorRecord(nil), iters...)
+
+	h := r.newIteratorHelper(openIters...)
+	removeOpen := func(iter *iteratorRecord) {
+		for i, it := range h.underlying {
+			if it == iter {
+				h.underlying = append(h.underlying[:i:i], h.underlying[i+1:]...)
+				break
+			}
+		}
+	}
+	h.step = func() (Value, bool) {
+		if len(iters) == 0 {
+			return nil, false
+		}
+		results := make([]Value, len(iters))
+		for i, iter := range iters {
+			if iter == nil {
+				results[i] = padding[i]
+				continue
+			}
+			var value Value
+			var ok bool
+			if ex := tryFunc(func() {
+				value, ok = iter.stepValue()
+			}); ex != nil {
+				removeOpen(iter)
+				iteratorCloseAll(h.underlying, ex)
+			}
+			if ok {
+				results[i] = value
+				continue
+			}
+			removeOpen(iter)
+			switch mode {
+			case iteratorZipShortest:
+				iteratorCloseAll(h.underlying, nil)
+				return nil, false
+			case iteratorZipStrict:
+				if i != 0 {
+					iteratorCloseAll(h.underlying, r.NewTypeError("Iterators passed to Iterator.zip() have different lengths"))
+				}
+				for _, iter := range iters[1:] {
+					var done bool
+					if ex := tryFunc(func() {
+						done = iter.stepResult() == nil
+					}); ex != nil {
+						removeOpen(iter)
+						iteratorCloseAll(h.underlying, ex)
+					}
+					if !done {
+						iteratorCloseAll(h.underlying, r.NewTypeError("Iterators passed to Iterator.zip() have different lengths"))
+					}
+					removeOpen(iter)
+				}
+				return nil, false
+			default:
+				if len(h.underlying) == 0 {
+					return nil, false
+				}
+				iters[i] = nil
+				results[i] = padding[i]
+			}
+		}
+		return finishResults(results), true
+	}
+	return h.val
+}
+
+func (r *Runtime) builtin_newIterator(args []Value, newTarget *Object) *Object {
+	if newTarget == nil || newTarget == r.getIteratorConstructor() {
+		panic(r.NewTypeError("Abstract class Iterator not directly constructable"))
+	}
+	return r.newBaseObject(r.getPrototypeFromCtor(newTarget, r.getIteratorConstructor(), r.getIteratorPrototype()), classObject).val
+}
+
+func (r *Runtime) iteratorProto_map(call FunctionCall) Value {
+	o := r.iteratorThisObj(call.This, "map")
+	mapper := r.iteratorCallback(o, call.Argument(0))
+	iterated := r.getIteratorDirect(o)
+	h := r.newIteratorHelper(iterated)
+	var counter int64
+	h.step = func() (Value, bool) {
+		value, ok := iterated.stepValue()
+		if !ok {
+			return nil, false
+		}
+		iterated.closeOnThrow(func() {
+			value = mapper(FunctionCall{This: _undefined, Arguments: []Value{value, intToValue(counter)}})
+		})
+		counter++
+		return value, true
+	}
+	return h.val
+}
+
+func (r *Runtime) iteratorProto_filter(call FunctionCall) Value {
+	o := r.iteratorThisObj(call.This, "filter")
+	predicate := r.iteratorCallback(o, call.Argument(0))
+	iterated := r.getIteratorDirect(o)
+	h := r.newIteratorHelper(iterated)
+	var counter int64
+	h.step = func() (Value, bool) {
+		for {
+			value, ok := iterated.stepValue()
+			if !ok {
+				return nil, false
+			}
+			var selected bool
+			iterated.closeOnThrow(func() {
+				selected = predicate(FunctionCall{This: _undefined, Arguments: []Value{value, intToValue(counter)}}).ToBoolean()
+			})
+			counter++
+			if selected {
+				return value, true
+			}
+		}
+	}
+	return h.val
+}
+
+func (r *Runtime) iteratorProto_take(call FunctionCall) Value {
+	o := r.iteratorThisObj(call.This, "take")
+	remaining := r.iteratorLimit(o, call.Argument(0))
+	iterated := r.getIteratorDirect(o)
+	h := r.newIteratorHelper(iterated)
+	h.step = func() (Value, bool) {
+		if remaining == 0 {
+			iterated.returnIter()
+			return nil, false
+		}
+		if !math.IsInf(remaining, 1) {
+			remaining--
+		}
+		return iterated.stepValue()
+	}
+	return h.val
+}
+
+func (r *Runtime) iteratorProto_drop(call FunctionCall) Value {
+	o := r.iteratorThisObj(call.This, "drop")
+	remaining := r.iteratorLimit(o, call.Argument(0))
+	iterated := r.getIteratorDirect(o)
+	h := r.newIteratorHelper(iterated)
+	h.step = func() (Value, bool) {
+		for remaining > 0 {
+			if !math.IsInf(remaining, 1) {
+				remaining--
+			}
+			if iterated.stepResult() == nil {
+				return nil, false
+			}
+		}
+		return iterated.stepValue()
+	}
+	return h.val
+}
+
+func (r *Runtime) iteratorProto_flatMap(call FunctionCall) Value {
+	o := r.iteratorThisObj(call.This, "flatMap")
+	mapper := r.iteratorCallback(o, call.Argument(0))
+	iterated := r.getIteratorDirect(o)
+	h := r.newIteratorHelper(iterated)
+	var inner *iteratorRecord
+	var counter int64
+	h.step = func() (Value, bool) {
+		for {
+			if inner != nil {
+				var value Value
+				var ok bool
+				iterated.closeOnThrow(func() {
+					value, ok = inner.stepValue()
+				})
+				if ok {
+					return value, true
+				}
+				inner = nil
+				h.underlying = h.underlying[:1]
+			}
+			value, ok := iterated.stepValue()
+			if !ok {
+				return nil, false
+			}
+			iterated.closeOnThrow(func() {
+				mapped := mapper(FunctionCall{This: _undefined, Arguments: []Value{value, intToValue(counter)}})
+				inner = r.getIteratorFlattenable(mapped, false)
+			})
+			h.underlying = append(h.underlying, inner)
+			counter++
+		}
+	}
+	return h.val
+}
+
+func (r *Runtime) iteratorProto_reduce(call FunctionCall) Value {
+	o := r.iteratorThisObj(call.This, "reduce")
+	reducer := r.iteratorCallback(o, call.Argument(0))
+	iterated := r.getIteratorDirect(o)
+	var accumulator Value
+	var counter int64
+	if len(call.Arguments) < 2 {
+		value, ok := iterated.stepValue()
+		if !ok {
+			panic(r.NewTypeError("Reduce of empty iterator with no initial value"))
+		}
+		accumulator = value
+		counter = 1
+	} else {
+		accumulator = call.Argument(1)
+	}
+	for {
+		value, ok := iterated.stepValue()
+		if !ok {
+			return accumulator
+		}
+		iterated.closeOnThrow(func() {
+			accumulator = reducer(FunctionCall{This: _undefined, Arguments: []Value{accumulator, value, intToValue(counter)}})
+		})
+		counter++
+	}
+}
+
+func (r *Runtime) iteratorProto_toArray(call FunctionCall) Value {
+	o := r.iteratorThisObj(call.This, "toArray")
+	iterated := r.getIteratorDirect(o)
+	var values []Value
+	for {
+		value, ok := iterated.stepValue()
+		if !ok {
+			return r.newArrayValues(values)
+		}
+		values = append(values, value)
+	}
+}
+
+func (r *Runtime) iteratorProto_forEach(call FunctionCall) Value {
+	o := r.iteratorThisObj(call.This, "forEach")
+	fn := r.iteratorCallback(o, call.Argument(0))
+	iterated := r.getIteratorDirect(o)
+	for counter := int64(0); ; counter++ {
+		value, ok := iterated.stepValue()
+		if !ok {
+			return _undefined
+		}
+		iterated.closeOnThrow(func() {
+			fn(FunctionCall{This: _undefined, Arguments: []Value{value, intToValue(counter)}})
+		})
+	}
+}
+
+// iteratorFind calls predicate for each value until it returns a value with ToBoolean() equal to expected.
+// In that case the iterator is closed and the value is returned.
+func (r *Runtime) iteratorFind(call FunctionCall, method string, expected bool) (Value, bool) {
+	o := r.iteratorThisObj(call.This, method)
+	predicate := r.iteratorCallback(o, call.Argument(0))
+	iterated := r.getIteratorDirect(o)
+	for counter := int64(0); ; counter++ {
+		value, ok := iterated.stepValue()
+		if !ok {
+			return nil, false
+		}
+		var result bool
+		iterated.closeOnThrow(func() {
+			result = predicate(FunctionCall{This: _undefined, Arguments: []Value{value, intToValue(counter)}}).ToBoolean()
+		})
+		if result == expected {
+			iterated.returnIter()
+			return value, true
+		}
+	}
+}
+
+func (r *Runtime) iteratorProto_some(call FunctionCall) Value {
+	_, found := r.iteratorFind(call, "some", true)
+	return r.toBoolean(found)
+}
+
+func (r *Runtime) iteratorProto_every(call FunctionCall) Value {
+	_, found := r.iteratorFind(call, "every", false)
+	return r.toBoolean(!found)
+}
+
+func (r *Runtime) iteratorProto_find(call FunctionCall) Value {
+	if value, found := r.iteratorFind(call, "find", true); found {
+		return value
+	}
+	return _undefined
+}
+
+func (r *Runtime) iteratorProto_getConstructor(FunctionCall) Value {
+	return r.getIteratorConstructor()
+}
+
+func (r *Runtime) iteratorProto_getToStringTag(FunctionCall) Value {
+	return asciiString(classIterator)
+}
+
+// setterThatIgnoresPrototypeProperties returns a setter function that creates an own property on the receiver
+// unless the receiver is the home object (an equivalent of SetterThatIgnoresPrototypeProperties()).
+func (r *Runtime) setterThatIgnoresPrototypeProperties(home *Object, p Value, name string) func(FunctionCall) Value {
+	return func(call FunctionCall) Value {
+		o, ok := call.This.(*Object)
+		if !ok {
+			panic(r.NewTypeError("Method %s called on incompatible receiver %s", name, r.objectproto_toString(FunctionCall{This: call.This})))
+		}
+		if o == home {
+			panic(r.NewTypeError("Cannot assign to read only property '%s' of %s", p.String(), o.String()))
+		}
+		v := call.Argument(0)
+		if o.getOwnProp(p) == nil {
+			createDataPropertyOrThrow(o, p, v)
+		} else {
+			o.set(p, v, o, true)
+		}
+		return _undefined
+	}
+}
+
+func (r *Runtime) iteratorHelperProto_next(call FunctionCall) Value {
+	thisObj := r.toObject(call.This)
+	if iter, ok := thisObj.self.(*iteratorHelperObject); ok {
+		return iter.next()
+	}
+	panic(r.NewTypeError("Method Iterator Helper.prototype.next called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: thisObj})))
+}
+
+func (r *Runtime) iteratorHelperProto_return(call FunctionCall) Value {
+	thisObj := r.toObject(call.This)
+	if iter, ok := thisObj.self.(*iteratorHelperObject); ok {
+		return iter._return()
+	}
+	panic(r.NewTypeError("Method Iterator Helper.prototype.return called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: thisObj})))
+}
+
+func (r *Runtime) wrapForValidIteratorProto_next(call FunctionCall) Value {
+	thisObj := r.toObject(call.This)
+	if w, ok := thisObj.self.(*iteratorWrapObject); ok {
+		iterated := w.iterated
+		if iterated.next == nil {
+			panic(r.NewTypeError("iterator.next is missing or not a function"))
+		}
+		return iterated.next(FunctionCall{This: iterated.iterator})
+	}
+	panic(r.NewTypeError("Method WrapForValidIteratorPrototype.next called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: thisObj})))
+}
+
+func (r *Runtime) wrapForValidIteratorProto_return(call FunctionCall) Value {
+	thisObj := r.toObject(call.This)
+	if w, ok := thisObj.self.(*iteratorWrapObject); ok {
+		iterator := w.iterated.iterator
+		returnMethod := toMethod(iterator.self.getStr("return", nil))
+		if returnMethod == nil {
+			return r.createIterResultObject(_undefined, true)
+		}
+		return returnMethod(FunctionCall{This: iterator})
+	}
+	panic(r.NewTypeError("Method WrapForValidIteratorPrototype.return called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: thisObj})))
+}
+
+func (r *Runtime) createIterProto(val *Object) objectImpl {
+	o := newBaseObjectObj(val, r.global.ObjectPrototype, classObject)
+
+	o.setOwnStr("constructor", &valueProperty{
+		getterFunc:   r.newNativeFunc(r.iteratorProto_getConstructor, "get constructor", 0),
+		setterFunc:   r.newNativeFunc(r.setterThatIgnoresPrototypeProperties(val, asciiString("constructor"), "set constructor"), "set constructor", 1),
+		accessor:     true,
+		configurable: true,
+	}, true)
+	o._putProp("drop", r.newNativeFunc(r.iteratorProto_drop, "drop", 1), true, false, true)
+	o._putProp("every", r.newNativeFunc(r.iteratorProto_every, "every", 1), true, false, true)
+	o._putProp("filter", r.newNativeFunc(r.iteratorProto_filter, "filter", 1), true, false, true)
+	o._putProp("find", r.newNativeFunc(r.iteratorProto_find, "find", 1), true, false, true)
+	o._putProp("flatMap", r.newNativeFunc(r.iteratorProto_flatMap, "flatMap", 1), true, false, true)
+	o._putProp("forEach", r.newNativeFunc(r.iteratorProto_forEach, "forEach", 1), true, false, true)
+	o._putProp("map", r.newNativeFunc(r.iteratorProto_map, "map", 1), true, false, true)
+	o._putProp("reduce", r.newNativeFunc(r.iteratorProto_reduce, "reduce", 1), true, false, true)
+	o._putProp("some", r.newNativeFunc(r.iteratorProto_some, "some", 1), true, false, true)
+	o._putProp("take", r.newNativeFunc(r.iteratorProto_take, "take", 1), true, false, true)
+	o._putProp("toArray", r.newNativeFunc(r.iteratorProto_toArray, "toArray", 0), true, false, true)
+
+	o._putSym(SymIterator, valueProp(r.newNativeFunc(r.returnThis, "[Symbol.iterator]", 0), true, false, true))
+	o._putSym(SymToStringTag, &valueProperty{
+		getterFunc:   r.newNativeFunc(r.iteratorProto_getToStringTag, "get [Symbol.toStringTag]", 0),
+		setterFunc:   r.newNativeFunc(r.setterThatIgnoresPrototypeProperties(val, SymToStringTag, "set [Symbol.toStringTag]"), "set [Symbol.toStringTag]", 1),
+		accessor:     true,
+		configurable: true,
+	})
+	return o
+}
+
+func (r *Runtime) getIteratorPrototype() *Object {
+	var o *Object
+	if o = r.global.IteratorPrototype; o == nil {
+		o = &Object{runtime: r}
+		r.global.IteratorPrototype = o
+		o.self = r.createIterProto(o)
+	}
+	return o
+}
+
+func (r *Runtime) createIterator(val *Object) objectImpl {
+	o := r.newNativeConstructOnly(val, r.builtin_newIterator, r.getIteratorPrototype(), "Iterator", 0)
+	o._putProp("concat", r.newNativeFunc(r.iterator_concat, "concat", 0), true, false, true)
+	o._putProp("from", r.newNativeFunc(r.iterator_from, "from", 1), true, false, true)
+	o._putProp("zip", r.newNativeFunc(r.iterator_zip, "zip", 1), true, false, true)
+	o._putProp("zipKeyed", r.newNativeFunc(r.iterator_zipKeyed, "zipKeyed", 1), true, false, true)
+
+	return o
+}
+
+func (r *Runtime) getIteratorConstructor() *Object {
+	ret := r.global.Iterator
+	if ret == nil {
+		ret = &Object{runtime: r}
+		r.global.Iterator = ret
+		r.createIterator(ret)
+	}
+	return ret
+}
+
+func (r *Runtime) createIteratorHelperProto(val *Object) objectImpl {
+	o := newBaseObjectObj(val, r.getIteratorPrototype(), classObject)
+
+	o._putProp("next", r.newNativeFunc(r.iteratorHelperProto_next, "next", 0), true, false, true)
+	o._putProp("return", r.newNativeFunc(r.iteratorHelperProto_return, "return", 0), true, false, true)
+	o._putSym(SymToStringTag, valueProp(asciiString(classIteratorHelper), false, false, true))
+
+	return o
+}
+
+func (r *Runtime) getIteratorHelperPrototype() *Object {
+	var o *Object
+	if o = r.global.IteratorHelperPrototype; o == nil {
+		o = &Object{runtime: r}
+		r.global.IteratorHelperPrototype = o
+		o.self = r.createIteratorHelperProto(o)
+	}
+	return o
+}
+
+func (r *Runtime) createWrapForValidIteratorProto(val *Object) objectImpl {
+	o := newBaseObjectObj(val, r.getIteratorPrototype(), classObject)
+
+	o._putProp("next", r.newNativeFunc(r.wrapForValidIteratorProto_next, "next", 0), true, false, true)
+	o._putProp("return", r.newNativeFunc(r.wrapForValidIteratorProto_return, "return", 0), true, false, true)
+
+	return o
+}
+
+func (r *Runtime) getWrapForValidIteratorPrototype() *Object {
+	var o *Object
+	if o = r.global.WrapForValidIteratorPrototype; o == nil {
+		o = &Object{runtime: r}
+		r.global.WrapForValidIteratorPrototype = o
+		o.self = r.createWrapForValidIteratorProto(o)
+	}
+	return o
+}
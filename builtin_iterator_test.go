@@ -0,0 +1,140 @@
+package goja
+
+import (
+	"testing"
+)
+
+func TestIteratorHelpersLazy(t *testing.T) {
+	const SCRIPT = `
+	const log = [];
+	function* gen() {
+		for (let i = 1; i <= 5; i++) {
+			log.push("next " + i);
+			yield i;
+		}
+	}
+	const it = gen().map(x => x * 10).filter(x => x !== 20);
+	assert.sameValue(log.length, 0, "no values pulled before next()");
+	assert.sameValue(it.next().value, 10);
+	assert.sameValue(it.next().value, 30);
+	assert(compareArray(log, ["next 1", "next 2", "next 3"]), log.join());
+	assert(compareArray(it.toArray(), [40, 50]));
+	assert(compareArray(gen().drop(3).toArray(), [4, 5]));
+	assert(compareArray(gen().flatMap(x => [x, -x]).take(3).toArray(), [1, -1, 2]));
+	assert.sameValue(gen().reduce((acc, x) => acc + x), 15);
+	assert.sameValue(gen().find(x => x > 3), 4);
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestIteratorHelperReturn(t *testing.T) {
+	const SCRIPT = `
+	let closed = 0;
+	const underlying = {
+		__proto__: Iterator.prototype,
+		next() { return {value: 1, done: false}; },
+		return() { closed++; return {}; },
+	};
+	const helper = underlying.map(x => x);
+	helper.return();
+	assert.sameValue(closed, 1, "closed in suspended-start state");
+	helper.return();
+	assert.sameValue(closed, 1, "not closed twice");
+	assert.sameValue(helper.next().done, true);
+
+	const taken = underlying.take(1);
+	assert.sameValue(taken.next().value, 1);
+	assert.sameValue(taken.next().done, true);
+	assert.sameValue(closed, 2, "take() closes the iterator when the limit is reached");
+
+	assert.throws(TypeError, () => underlying.map(null));
+	assert.sameValue(closed, 3, "argument validation failure closes the iterator");
+	assert.throws(RangeError, () => underlying.drop(-1));
+	assert.sameValue(closed, 4);
+
+	assert.throws(Test262Error, () => underlying.forEach(() => { throw new Test262Error(); }));
+	assert.sameValue(closed, 5, "abrupt callback completion closes the iterator");
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestIteratorFrom(t *testing.T) {
+	const SCRIPT = `
+	let i = 0;
+	const plain = {next() { return {value: i++, done: i > 3}; }};
+	const wrapped = Iterator.from(plain);
+	assert(wrapped !== plain);
+	assert(wrapped instanceof Iterator);
+	assert(compareArray(wrapped.toArray(), [0, 1, 2]));
+
+	const gen = (function*() {})();
+	assert.sameValue(Iterator.from(gen), gen, "instances of Iterator are returned as is");
+	assert(compareArray(Iterator.from("ab").toArray(), ["a", "b"]));
+	assert.throws(TypeError, () => Iterator.from(1));
+
+	assert.throws(TypeError, () => new Iterator());
+	assert.throws(TypeError, () => Iterator());
+	class MyIterator extends Iterator {}
+	assert.sameValue(Object.getPrototypeOf(new MyIterator()), MyIterator.prototype);
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestIteratorConcat(t *testing.T) {
+	const SCRIPT = `
+	const log = [];
+	function* gen(name, n) {
+		try {
+			for (let i = 0; i < n; i++) {
+				yield name + i;
+			}
+		} finally {
+			log.push(name);
+		}
+	}
+	assert(compareArray(Iterator.concat([1, 2], new Set([3]), gen("a", 2)).toArray(), [1, 2, 3, "a0", "a1"]));
+	assert(compareArray(log, ["a"]));
+
+	const it = Iterator.concat(gen("b", 2), gen("c", 2));
+	it.next();
+	it.next();
+	it.next();
+	it.return();
+	assert(compareArray(log, ["a", "b", "c"]), log.join());
+
+	assert.throws(TypeError, () => Iterator.concat("abc"));
+	assert.throws(TypeError, () => Iterator.concat({}));
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestIteratorZip(t *testing.T) {
+	const SCRIPT = `
+	const log = [];
+	function* gen(name, n) {
+		try {
+			for (let i = 0; i < n; i++) {
+				yield name + i;
+			}
+		} finally {
+			log.push(name);
+		}
+	}
+	let res = Iterator.zip([gen("a", 2), gen("b", 3)]).toArray();
+	assert.sameValue(JSON.stringify(res), '[["a0","b0"],["a1","b1"]]');
+	assert(compareArray(log, ["a", "b"]), "shortest closes the remaining iterators");
+
+	res = Iterator.zip([gen("a", 2), gen("b", 3)], {mode: "longest", padding: ["x"]}).toArray();
+	assert.sameValue(JSON.stringify(res), '[["a0","b0"],["a1","b1"],["x","b2"]]');
+
+	assert.throws(TypeError, () => Iterator.zip([gen("a", 2), gen("b", 3)], {mode: "strict"}).toArray());
+	assert.sameValue(Iterator.zip([[1], [2]], {mode: "strict"}).toArray().length, 1);
+	assert.throws(TypeError, () => Iterator.zip([], {mode: "all"}));
+	assert.throws(TypeError, () => Iterator.zip(["ab"]));
+
+	res = Iterator.zipKeyed({a: [1, 2], b: [3]}, {mode: "longest", padding: {b: 0}}).toArray();
+	assert.sameValue(JSON.stringify(res), '[{"a":1,"b":3},{"a":2,"b":0}]');
+	assert.sameValue(Object.getPrototypeOf(res[0]), null);
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
@@ -17,10 +17,46 @@ import (
 
 const hex = "0123456789abcdef"
 
+type _builtinJSON_parseContext struct {
+	r   *Runtime
+	d   *json.Decoder
+	src string
+
+	// whether to build parse records needed to provide source text to the reviver
+	trackSource bool
+	offset      int64
+}
+
+// jsonParseRecord is a snapshot of a parsed JSON value. For primitive values it holds the source text,
+// for arrays and objects it holds the records of their elements or properties.
+type jsonParseRecord struct {
+	value    Value
+	source   string
+	elements []*jsonParseRecord
+	entries  map[unistring.String]*jsonParseRecord
+}
+
+type jsonRawObject struct {
+	baseObject
+}
+
 func (r *Runtime) builtinJSON_parse(call FunctionCall) Value {
-	d := json.NewDecoder(strings.NewReader(call.Argument(0).toString().String()))
+	src := call.Argument(0).toString().String()
+	r.vm.chargeInstructions(int64(len(src)))
+	var reviver func(FunctionCall) Value
+
+	if arg1 := call.Argument(1); arg1 != _undefined {
+		reviver, _ = arg1.ToObject(r).self.assertCallable()
+	}
+
+	ctx := _builtinJSON_parseContext{
+		r:           r,
+		d:           json.NewDecoder(strings.NewReader(src)),
+		src:         src,
+		trackSource: reviver != nil,
+	}
 
-	value, err := r.builtinJSON_decodeValue(d)
+	value, record, err := ctx.decodeValue()
 	if errors.Is(err, io.EOF) {
 		panic(r.newError(r.getSyntaxError(), "Unexpected end of JSON input (%v)", err.Error()))
 	}
@@ -28,79 +64,103 @@ func (r *Runtime) builtinJSON_parse(call FunctionCall) Value {
 		panic(r.newError(r.getSyntaxError(), "%s", err.Error()))
 	}
 
-	if tok, err := d.Token(); err != io.EOF {
+	if tok, err := ctx.d.Token(); err != io.EOF {
 		panic(r.newError(r.getSyntaxError(), "Unexpected token at the end: %v", tok))
 	}
 
-	var reviver func(FunctionCall) Value
-
-	if arg1 := call.Argument(1); arg1 != _undefined {
-		reviver, _ = arg1.ToObject(r).self.assertCallable()
-	}
-
 	if reviver != nil {
 		root := r.NewObject()
 		createDataPropertyOrThrow(root, stringEmpty, value)
-		return r.builtinJSON_reviveWalk(reviver, root, stringEmpty)
+		return r.builtinJSON_reviveWalk(reviver, root, stringEmpty, record)
 	}
 
 	return value
 }
 
-func (r *Runtime) builtinJSON_decodeToken(d *json.Decoder, tok json.Token) (Value, error) {
+func (ctx *_builtinJSON_parseContext) token() (json.Token, error) {
+	tok, err := ctx.d.Token()
+	if ctx.trackSource {
+		ctx.offset = ctx.d.InputOffset()
+	}
+	return tok, err
+}
+
+// source returns the source text of the last token that started after the offset prevOffset.
+func (ctx *_builtinJSON_parseContext) source(prevOffset int64) string {
+	return strings.TrimLeft(ctx.src[prevOffset:ctx.offset], " \t\n\r,:")
+}
+
+func (ctx *_builtinJSON_parseContext) decodeToken(tok json.Token, prevOffset int64) (Value, *jsonParseRecord, error) {
+	var value Value
 	switch tok := tok.(type) {
 	case json.Delim:
 		switch tok {
 		case '{':
-			return r.builtinJSON_decodeObject(d)
+			return ctx.decodeObject()
 		case '[':
-			return r.builtinJSON_decodeArray(d)
+			return ctx.decodeArray()
 		}
 	case nil:
-		return _null, nil
+		value = _null
 	case string:
-		return newStringValue(tok), nil
+		value = newStringValue(tok)
 	case float64:
-		return floatToValue(tok), nil
+		value = floatToValue(tok)
 	case bool:
 		if tok {
-			return valueTrue, nil
+			value = valueTrue
+		} else {
+			value = valueFalse
 		}
-		return valueFalse, nil
 	}
-	return nil, fmt.Errorf("Unexpected token (%T): %v", tok, tok)
+	if value == nil {
+		return nil, nil, fmt.Errorf("Unexpected token (%T): %v", tok, tok)
+	}
+	if ctx.trackSource {
+		return value, &jsonParseRecord{value: value, source: ctx.source(prevOffset)}, nil
+	}
+	return value, nil, nil
 }
 
-func (r *Runtime) builtinJSON_decodeValue(d *json.Decoder) (Value, error) {
-	tok, err := d.Token()
+func (ctx *_builtinJSON_parseContext) decodeValue() (Value, *jsonParseRecord, error) {
+	prevOffset := ctx.offset
+	tok, err := ctx.token()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return r.builtinJSON_decodeToken(d, tok)
+	return ctx.decodeToken(tok, prevOffset)
 }
 
-func (r *Runtime) builtinJSON_decodeObject(d *json.Decoder) (*Object, error) {
-	object := r.NewObject()
+func (ctx *_builtinJSON_parseContext) decodeObject() (*Object, *jsonParseRecord, error) {
+	object := ctx.r.NewObject()
+	var record *jsonParseRecord
+	if ctx.trackSource {
+		record = &jsonParseRecord{value: object, entries: make(map[unistring.String]*jsonParseRecord)}
+	}
 	for {
-		key, end, err := r.builtinJSON_decodeObjectKey(d)
+		key, end, err := ctx.decodeObjectKey()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if end {
 			break
 		}
-		value, err := r.builtinJSON_decodeValue(d)
+		value, valueRecord, err := ctx.decodeValue()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		object.self._putProp(unistring.NewFromString(key), value, true, true, true)
+		name := unistring.NewFromString(key)
+		object.self._putProp(name, value, true, true, true)
+		if record != nil {
+			record.entries[name] = valueRecord
+		}
 	}
-	return object, nil
+	return object, record, nil
 }
 
-func (r *Runtime) builtinJSON_decodeObjectKey(d *json.Decoder) (string, bool, error) {
-	tok, err := d.Token()
+func (ctx *_builtinJSON_parseContext) decodeObjectKey() (string, bool, error) {
+	tok, err := ctx.token()
 	if err != nil {
 		return "", false, err
 	}
@@ -116,36 +176,60 @@ func (r *Runtime) builtinJSON_decodeObjectKey(d *json.Decoder) (string, bool, er
 	return "", false, fmt.Errorf("Unexpected token (%T): %v", tok, tok)
 }
 
-func (r *Runtime) builtinJSON_decodeArray(d *json.Decoder) (*Object, error) {
+func (ctx *_builtinJSON_parseContext) decodeArray() (*Object, *jsonParseRecord, error) {
 	var arrayValue []Value
+	var elements []*jsonParseRecord
 	for {
-		tok, err := d.Token()
+		prevOffset := ctx.offset
+		tok, err := ctx.token()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if delim, ok := tok.(json.Delim); ok {
 			if delim == ']' {
 				break
 			}
 		}
-		value, err := r.builtinJSON_decodeToken(d, tok)
+		value, record, err := ctx.decodeToken(tok, prevOffset)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		arrayValue = append(arrayValue, value)
+		if ctx.trackSource {
+			elements = append(elements, record)
+		}
+	}
+	array := ctx.r.newArrayValues(arrayValue)
+	if ctx.trackSource {
+		return array, &jsonParseRecord{value: array, elements: elements}, nil
 	}
-	return r.newArrayValues(arrayValue), nil
+	return array, nil, nil
 }
 
-func (r *Runtime) builtinJSON_reviveWalk(reviver func(FunctionCall) Value, holder *Object, name Value) Value {
+func (r *Runtime) builtinJSON_reviveWalk(reviver func(FunctionCall) Value, holder *Object, name Value, record *jsonParseRecord) Value {
 	value := nilSafe(holder.get(name, nil))
 
+	context := r.NewObject()
+	if record != nil && value.SameAs(record.value) {
+		if _, isObject := value.(*Object); !isObject {
+			// the value has not been modified by the reviver, so the source text still applies
+			context.self._putProp("source", newStringValue(record.source), true, true, true)
+		}
+	}
+
 	if object, ok := value.(*Object); ok {
+		if record != nil && !object.SameAs(record.value) {
+			record = nil
+		}
 		if isArray(object) {
 			length := toLength(object.self.getStr("length", nil))
 			for index := int64(0); index < length; index++ {
 				name := asciiString(strconv.FormatInt(index, 10))
-				value := r.builtinJSON_reviveWalk(reviver, object, name)
+				var elemRecord *jsonParseRecord
+				if record != nil && index < int64(len(record.elements)) {
+					elemRecord = record.elements[index]
+				}
+				value := r.builtinJSON_reviveWalk(reviver, object, name, elemRecord)
 				if value == _undefined {
 					object.delete(name, false)
 				} else {
@@ -154,7 +238,11 @@ func (r *Runtime) builtinJSON_reviveWalk(reviver func(FunctionCall) Value, holde
 			}
 		} else {
 			for _, name := range object.self.stringKeys(false, nil) {
-				value := r.builtinJSON_reviveWalk(reviver, object, name)
+				var propRecord *jsonParseRecord
+				if record != nil {
+					propRecord = record.entries[name.string()]
+				}
+				value := r.builtinJSON_reviveWalk(reviver, object, name, propRecord)
 				if value == _undefined {
 					object.self.deleteStr(name.string(), false)
 				} else {
@@ -165,10 +253,58 @@ func (r *Runtime) builtinJSON_reviveWalk(reviver func(FunctionCall) Value, holde
 	}
 	return reviver(FunctionCall{
 		This:      holder,
-		Arguments: []Value{name, value},
+		Arguments: []Value{name, value, context},
 	})
 }
 
+func (r *Runtime) builtinJSON_rawJSON(call FunctionCall) Value {
+	jsonString := call.Argument(0).toString()
+	src := jsonString.String()
+	if src == "" {
+		panic(r.newError(r.getSyntaxError(), "Invalid value for JSON.rawJSON"))
+	}
+	switch src[0] {
+	case '\t', '\n', '\r', ' ':
+		panic(r.newError(r.getSyntaxError(), "Invalid value for JSON.rawJSON"))
+	}
+	switch src[len(src)-1] {
+	case '\t', '\n', '\r', ' ':
+		panic(r.newError(r.getSyntaxError(), "Invalid value for JSON.rawJSON"))
+	}
+
+	d := json.NewDecoder(strings.NewReader(src))
+	tok, err := d.Token()
+	if err != nil {
+		panic(r.newError(r.getSyntaxError(), "%s", err.Error()))
+	}
+	if _, ok := tok.(json.Delim); ok {
+		panic(r.newError(r.getSyntaxError(), "JSON.rawJSON cannot create objects or arrays"))
+	}
+	if tok, err := d.Token(); err != io.EOF {
+		panic(r.newError(r.getSyntaxError(), "Unexpected token at the end: %v", tok))
+	}
+
+	o := &Object{runtime: r}
+	raw := &jsonRawObject{}
+	raw.class = classObject
+	raw.val = o
+	raw.extensible = true
+	o.self = raw
+	raw.init()
+	raw._putProp("rawJSON", jsonString, false, true, false)
+	raw.extensible = false
+	return o
+}
+
+func (r *Runtime) builtinJSON_isRawJSON(call FunctionCall) Value {
+	if o, ok := call.Argument(0).(*Object); ok {
+		if _, ok := o.self.(*jsonRawObject); ok {
+			return valueTrue
+		}
+	}
+	return valueFalse
+}
+
 type _builtinJSON_stringifyContext struct {
 	r                *Runtime
 	stack            []*Object
@@ -177,6 +313,9 @@ type _builtinJSON_stringifyContext struct {
 	gap, indent      string
 	buf              bytes.Buffer
 	allAscii         bool
+
+	// the part of buf that has been accounted against the memory limit
+	accounted int
 }
 
 func (r *Runtime) builtinJSON_stringify(call FunctionCall) Value {
@@ -261,6 +400,8 @@ func (r *Runtime) builtinJSON_stringify(call FunctionCall) Value {
 	}
 
 	if ctx.do(call.Argument(0)) {
+		r.accountGrowth(ctx.buf.Len(), &ctx.accounted)
+		r.vm.chargeInstructions(int64(ctx.buf.Len()))
 		if ctx.allAscii {
 			return asciiString(ctx.buf.String())
 		} else {
@@ -279,6 +420,7 @@ func (ctx *_builtinJSON_stringifyContext) do(v Value) bool {
 }
 
 func (ctx *_builtinJSON_stringifyContext) str(key Value, holder *Object) bool {
+	ctx.r.accountGrowth(ctx.buf.Len(), &ctx.accounted)
 	value := nilSafe(holder.get(key, nil))
 
 	switch value.(type) {
@@ -302,6 +444,15 @@ func (ctx *_builtinJSON_stringifyContext) str(key Value, holder *Object) bool {
 
 	if o, ok := value.(*Object); ok {
 		switch o1 := o.self.(type) {
+		case *jsonRawObject:
+			switch raw := nilSafe(o1.getStr("rawJSON", nil)).toString().(type) {
+			case asciiString:
+				ctx.buf.WriteString(string(raw))
+			default:
+				ctx.buf.WriteString(raw.String())
+				ctx.allAscii = false
+			}
+			return true
 		case *primitiveValueObject:
 			switch pValue := o1.pValue.(type) {
 			case valueInt, valueFloat:
@@ -536,6 +687,8 @@ func (r *Runtime) getJSON() *Object {
 		r.global.JSON = ret
 		JSON._putProp("parse", r.newNativeFunc(r.builtinJSON_parse, "parse", 2), true, false, true)
 		JSON._putProp("stringify", r.newNativeFunc(r.builtinJSON_stringify, "stringify", 3), true, false, true)
+		JSON._putProp("rawJSON", r.newNativeFunc(r.builtinJSON_rawJSON, "rawJSON", 1), true, false, true)
+		JSON._putProp("isRawJSON", r.newNativeFunc(r.builtinJSON_isRawJSON, "isRawJSON", 1), true, false, true)
 		JSON._putSym(SymToStringTag, valueProp(asciiString(classJSON), false, false, true))
 	}
 	return ret
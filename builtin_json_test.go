@@ -79,6 +79,62 @@ func TestJSONParseReviver(t *testing.T) {
 	testScript(SCRIPT, intToValue(10), t)
 }
 
+func TestJSONParseReviverSource(t *testing.T) {
+	const SCRIPT = `
+	const sources = [];
+	const res = JSON.parse(' {"id": 12345678901234567890, "a": [1.10, "x\\u0041", true, null, []], "o": {}} ', function(key, value, context) {
+		sources.push(key + "=" + context.source);
+		if (key === "id") {
+			return BigInt(context.source);
+		}
+		return value;
+	});
+	assert.sameValue(res.id, 12345678901234567890n);
+	assert(compareArray(sources, [
+		"id=12345678901234567890",
+		"0=1.10",
+		'1="x\\u0041"',
+		"2=true",
+		"3=null",
+		"4=undefined",
+		"a=undefined",
+		"o=undefined",
+		"=undefined",
+	]), sources.join());
+
+	const modified = [];
+	JSON.parse('[1, 2]', function(key, value, context) {
+		if (key === "0") {
+			this[1] = 3;
+		}
+		modified.push(context.source);
+		return value;
+	});
+	assert(compareArray(modified, ["1", undefined, undefined]), String(modified));
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestJSONRawJSON(t *testing.T) {
+	const SCRIPT = `
+	const raw = JSON.rawJSON("12345678901234567890");
+	assert(JSON.isRawJSON(raw), "isRawJSON");
+	assert(!JSON.isRawJSON({rawJSON: "1"}), "plain object");
+	assert(!JSON.isRawJSON(1), "primitive");
+	assert.sameValue(Object.getPrototypeOf(raw), null, "prototype");
+	assert(Object.isFrozen(raw), "frozen");
+	assert.sameValue(raw.rawJSON, "12345678901234567890");
+
+	assert.sameValue(JSON.stringify({id: raw, s: JSON.rawJSON('"é"')}), '{"id":12345678901234567890,"s":"é"}');
+	assert.sameValue(JSON.stringify([1n].map(v => JSON.rawJSON(String(v)))), "[1]");
+
+	for (const bad of ["", " 1", "1 ", "\t1", "{}", "[]", "1 2", "abc"]) {
+		assert.throws(SyntaxError, () => JSON.rawJSON(bad), JSON.stringify(bad));
+	}
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
 func TestQuoteMalformedSurrogatePair(t *testing.T) {
 	testScript(`JSON.stringify("\uD800")`, asciiString(`"\ud800"`), t)
 }
@@ -242,3 +242,28 @@ func BenchmarkMapDeleteJS(b *testing.B) {
 		}
 	}
 }
+
+func TestMapGroupBy(t *testing.T) {
+	const SCRIPT = `
+	const key = {};
+	const m = Map.groupBy([-0, 0, 1, key, key], x => x);
+	assert(m instanceof Map);
+	assert(compareArray([...m.keys()], [0, 1, key]));
+	assert.sameValue(1 / [...m.keys()][0], Infinity, "-0 is normalised");
+	assert(compareArray(m.get(0), [-0, 0]));
+	assert.sameValue(m.get(key).length, 2);
+
+	let closed = false;
+	const iterable = {
+		[Symbol.iterator]() {
+			return {
+				next() { return {value: 1, done: false}; },
+				return() { closed = true; return {}; },
+			};
+		},
+	};
+	assert.throws(Test262Error, () => Map.groupBy(iterable, () => { throw new Test262Error(); }));
+	assert(closed, "iterator is closed on abrupt completion");
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
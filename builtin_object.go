@@ -584,6 +584,48 @@ func (r *Runtime) object_fromEntries(call FunctionCall) Value {
 	return result
 }
 
+// groupBy is an equivalent of GroupBy(). If propertyKeys is true, the keys are converted to property keys,
+// otherwise they are canonicalised as Map keys. Groups are returned in the order of their first appearance.
+func (r *Runtime) groupBy(items, callback Value, propertyKeys bool) (keys []Value, groups [][]Value) {
+	r.checkObjectCoercible(items)
+	callbackFn := r.toCallable(callback)
+	index := r.newOrderedMap()
+	iter := r.getIterator(items, nil)
+	for k := int64(0); ; k++ {
+		value, ok := iter.stepValue()
+		if !ok {
+			break
+		}
+		var key Value
+		iter.closeOnThrow(func() {
+			key = callbackFn(FunctionCall{This: _undefined, Arguments: []Value{value, intToValue(k)}})
+			if propertyKeys {
+				key = toPropertyKey(key)
+			} else if key == _negativeZero {
+				key = intToValue(0)
+			}
+		})
+		if idx := index.get(key); idx != nil {
+			i := idx.ToInteger()
+			groups[i] = append(groups[i], value)
+		} else {
+			index.set(key, intToValue(int64(len(groups))))
+			keys = append(keys, key)
+			groups = append(groups, []Value{value})
+		}
+	}
+	return
+}
+
+func (r *Runtime) object_groupBy(call FunctionCall) Value {
+	keys, groups := r.groupBy(call.Argument(0), call.Argument(1), true)
+	result := r.newBaseObject(nil, classObject).val
+	for i, key := range keys {
+		createDataPropertyOrThrow(result, key, r.newArrayValues(groups[i]))
+	}
+	return result
+}
+
 func (r *Runtime) object_hasOwn(call FunctionCall) Value {
 	o := call.Argument(0)
 	obj := o.ToObject(r)
@@ -634,6 +676,7 @@ func createObjectTemplate() *objectTemplate {
 	t.putStr("setPrototypeOf", func(r *Runtime) Value { return r.methodProp(r.object_setPrototypeOf, "setPrototypeOf", 2) })
 	t.putStr("values", func(r *Runtime) Value { return r.methodProp(r.object_values, "values", 1) })
 	t.putStr("fromEntries", func(r *Runtime) Value { return r.methodProp(r.object_fromEntries, "fromEntries", 1) })
+	t.putStr("groupBy", func(r *Runtime) Value { return r.methodProp(r.object_groupBy, "groupBy", 2) })
 	t.putStr("hasOwn", func(r *Runtime) Value { return r.methodProp(r.object_hasOwn, "hasOwn", 2) })
 
 	return t
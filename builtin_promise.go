@@ -342,9 +342,11 @@ func (r *Runtime) promiseProto_catch(call FunctionCall) Value {
 
 func (r *Runtime) promiseResolve(c *Object, x Value) *Object {
 	if obj, ok := x.(*Object); ok {
-		xConstructor := nilSafe(obj.self.getStr("constructor", nil))
-		if xConstructor.SameAs(c) {
-			return obj
+		if _, isPromise := obj.self.(*Promise); isPromise {
+			xConstructor := nilSafe(obj.self.getStr("constructor", nil))
+			if xConstructor.SameAs(c) {
+				return obj
+			}
 		}
 	}
 	pcap := r.newPromiseCapability(c)
@@ -352,6 +354,83 @@ func (r *Runtime) promiseResolve(c *Object, x Value) *Object {
 	return pcap.promise
 }
 
+// await calls onFulfilled or onRejected from a promise job once v is settled, the same way as the Await()
+// operation resumes an async function. It panics if v cannot be resolved to a promise.
+func (r *Runtime) await(v Value, onFulfilled, onRejected func(Value)) {
+	promise := r.promiseResolve(r.getPromise(), v)
+	promise.self.(*Promise).addReactions(&promiseReaction{
+		typ: promiseReactionFulfill,
+		handler: &jobCallback{callback: func(call FunctionCall) Value {
+			onFulfilled(call.Argument(0))
+			return _undefined
+		}},
+	}, &promiseReaction{
+		typ: promiseReactionReject,
+		handler: &jobCallback{callback: func(call FunctionCall) Value {
+			onRejected(call.Argument(0))
+			return _undefined
+		}},
+	})
+}
+
+// asyncFromSyncIteratorNext is an equivalent of %AsyncFromSyncIteratorPrototype%.next().
+func (r *Runtime) asyncFromSyncIteratorNext(syncIter *iteratorRecord) *Object {
+	pcap := r.newPromiseCapability(r.getPromise())
+	pcap.try(func() {
+		if syncIter.next == nil {
+			panic(r.NewTypeError("iterator.next is missing or not a function"))
+		}
+		result := r.toObject(syncIter.next(FunctionCall{This: syncIter.iterator}))
+		r.asyncFromSyncIteratorContinuation(result, pcap, syncIter, true)
+	})
+	return pcap.promise
+}
+
+// asyncFromSyncIteratorReturn is an equivalent of %AsyncFromSyncIteratorPrototype%.return().
+func (r *Runtime) asyncFromSyncIteratorReturn(syncIter *iteratorRecord) *Object {
+	pcap := r.newPromiseCapability(r.getPromise())
+	pcap.try(func() {
+		iter := syncIter.iterator
+		returnMethod := toMethod(iter.self.getStr("return", nil))
+		if returnMethod == nil {
+			pcap.resolve(r.createIterResultObject(_undefined, true))
+			return
+		}
+		result, ok := returnMethod(FunctionCall{This: iter}).(*Object)
+		if !ok {
+			panic(r.NewTypeError("iterator result is not an object"))
+		}
+		r.asyncFromSyncIteratorContinuation(result, pcap, syncIter, false)
+	})
+	return pcap.promise
+}
+
+func (r *Runtime) asyncFromSyncIteratorContinuation(result *Object, pcap *promiseCapability, syncIter *iteratorRecord, closeOnRejection bool) {
+	done := iteratorComplete(result)
+	value := iteratorValue(result)
+	closeOnRejection = closeOnRejection && !done
+	var valueWrapper *Object
+	if ex := r.vm.try(func() {
+		valueWrapper = r.promiseResolve(r.getPromise(), value)
+	}); ex != nil {
+		if closeOnRejection {
+			syncIter.closeThrow(ex)
+		}
+		panic(ex)
+	}
+	onFulfilled := r.newNativeFunc(func(call FunctionCall) Value {
+		return r.createIterResultObject(call.Argument(0), done)
+	}, "", 1)
+	var onRejected Value = _undefined
+	if closeOnRejection {
+		onRejected = r.newNativeFunc(func(call FunctionCall) Value {
+			syncIter.closeThrow(call.Argument(0))
+			return nil
+		}, "", 1)
+	}
+	r.performPromiseThen(valueWrapper.self.(*Promise), onFulfilled, onRejected, pcap)
+}
+
 func (r *Runtime) promiseProto_finally(call FunctionCall) Value {
 	promise := r.toObject(call.This)
 	c := r.speciesConstructorObj(promise, r.getPromise())
@@ -538,6 +617,27 @@ func (r *Runtime) promise_race(call FunctionCall) Value {
 	return pcap.promise
 }
 
+func (r *Runtime) promise_try(call FunctionCall) Value {
+	pcap := r.newPromiseCapability(r.toObject(call.This))
+	pcap.try(func() {
+		var args []Value
+		if len(call.Arguments) > 1 {
+			args = call.Arguments[1:]
+		}
+		pcap.resolve(r.toCallable(call.Argument(0))(FunctionCall{This: _undefined, Arguments: args}))
+	})
+	return pcap.promise
+}
+
+func (r *Runtime) promise_withResolvers(call FunctionCall) Value {
+	pcap := r.newPromiseCapability(r.toObject(call.This))
+	obj := r.NewObject()
+	createDataPropertyOrThrow(obj, asciiString("promise"), pcap.promise)
+	createDataPropertyOrThrow(obj, asciiString("resolve"), pcap.resolveObj)
+	createDataPropertyOrThrow(obj, asciiString("reject"), pcap.rejectObj)
+	return obj
+}
+
 func (r *Runtime) promise_reject(call FunctionCall) Value {
 	pcap := r.newPromiseCapability(r.toObject(call.This))
 	pcap.reject(call.Argument(0))
@@ -570,6 +670,8 @@ func (r *Runtime) createPromise(val *Object) objectImpl {
 	o._putProp("race", r.newNativeFunc(r.promise_race, "race", 1), true, false, true)
 	o._putProp("reject", r.newNativeFunc(r.promise_reject, "reject", 1), true, false, true)
 	o._putProp("resolve", r.newNativeFunc(r.promise_resolve, "resolve", 1), true, false, true)
+	o._putProp("try", r.newNativeFunc(r.promise_try, "try", 1), true, false, true)
+	o._putProp("withResolvers", r.newNativeFunc(r.promise_withResolvers, "withResolvers", 0), true, false, true)
 
 	r.putSpeciesReturnThis(o)
 
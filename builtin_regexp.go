@@ -773,6 +773,7 @@ func (r *Runtime) regexpproto_stdMatcher(call FunctionCall) Value {
 	}
 	if rx.pattern.global {
 		rx.setOwnStr("lastIndex", intToValue(0), true)
+		r.vm.chargeInstructions(int64(s.Length()))
 		res := rx.pattern.findAllSubmatchIndex(s, 0, -1, rx.pattern.sticky)
 		if len(res) == 0 {
 			return _null
@@ -1039,6 +1040,7 @@ func (r *Runtime) regexpproto_stdSplitter(call FunctionCall) Value {
 	lastIndex := 0
 	found := 0
 
+	r.vm.chargeInstructions(int64(targetLength))
 	result := pattern.findAllSubmatchIndex(s, 0, -1, false)
 	if targetLength == 0 {
 		if result == nil {
@@ -1116,6 +1118,7 @@ func (r *Runtime) regexpproto_stdReplacerGeneric(rxObj *Object, s, replaceStr St
 	lengthS := s.Length()
 	nextSourcePosition := 0
 	var resultBuf StringBuilder
+	accounted := 0
 	for _, result := range results {
 		obj := r.toObject(result)
 		nCaptures := max(toLength(obj.self.getStr("length", nil))-1, 0)
@@ -1161,10 +1164,12 @@ func (r *Runtime) regexpproto_stdReplacerGeneric(rxObj *Object, s, replaceStr St
 				nextSourcePosition = position + matchLength
 			}
 		}
+		r.accountGrowth(resultBuf.memSize(), &accounted)
 	}
 	if nextSourcePosition < lengthS {
 		resultBuf.WriteString(s.Substring(nextSourcePosition, lengthS))
 	}
+	r.accountGrowth(resultBuf.memSize(), &accounted)
 	return resultBuf.String()
 }
 
@@ -1238,6 +1243,7 @@ func (r *Runtime) regexpproto_stdReplacer(call FunctionCall) Value {
 	} else {
 		index = rx.getLastIndex()
 	}
+	r.vm.chargeInstructions(int64(s.Length()) - index)
 	found := rx.pattern.findAllSubmatchIndex(s, toIntStrict(index), find, rx.pattern.sticky)
 	if rx.pattern.global || rx.pattern.sticky {
 		var newLastIndex int64
@@ -1247,7 +1253,7 @@ func (r *Runtime) regexpproto_stdReplacer(call FunctionCall) Value {
 		rx.setOwnStr("lastIndex", intToValue(newLastIndex), true)
 	}
 
-	return stringReplace(s, found, replaceStr, rcall)
+	return r.stringReplace(s, found, replaceStr, rcall)
 }
 
 func (r *Runtime) regExpStringIteratorProto_next(call FunctionCall) Value {
@@ -2,7 +2,10 @@ package goja
 
 import (
 	"fmt"
+	"math"
 	"reflect"
+
+	"github.com/dop251/goja/unistring"
 )
 
 var setExportType = reflectTypeArray
@@ -42,7 +45,7 @@ func (o *setIterObject) next() Value {
 
 func (so *setObject) init() {
 	so.baseObject.init()
-	so.m = newOrderedMap(so.val.runtime.getHash())
+	so.m = so.val.runtime.newOrderedMap()
 }
 
 func (so *setObject) exportType() reflect.Type {
@@ -192,6 +195,233 @@ func (r *Runtime) setProto_values(call FunctionCall) Value {
 	return r.createSetIterator(call.This, iterationKindValue)
 }
 
+// setRecord is an equivalent of the Set Record returned by GetSetRecord().
+type setRecord struct {
+	set  *Object
+	size float64
+	has  func(FunctionCall) Value
+	keys func(FunctionCall) Value
+}
+
+func (r *Runtime) getSetRecord(v Value) *setRecord {
+	obj, ok := v.(*Object)
+	if !ok {
+		panic(r.NewTypeError("%s is not an object", v.String()))
+	}
+	size := nilSafe(obj.self.getStr("size", nil)).ToNumber().ToFloat()
+	if math.IsNaN(size) {
+		panic(r.NewTypeError("The 'size' property must be a number"))
+	}
+	size = math.Trunc(size)
+	if size < 0 {
+		panic(r.newError(r.getRangeError(), "The 'size' property must be non-negative"))
+	}
+	has, ok := r.getSetRecordMethod(obj, "has")
+	if !ok {
+		panic(r.NewTypeError("The 'has' property must be a function"))
+	}
+	keys, ok := r.getSetRecordMethod(obj, "keys")
+	if !ok {
+		panic(r.NewTypeError("The 'keys' property must be a function"))
+	}
+	return &setRecord{
+		set:  obj,
+		size: size,
+		has:  has,
+		keys: keys,
+	}
+}
+
+func (r *Runtime) getSetRecordMethod(obj *Object, name unistring.String) (func(FunctionCall) Value, bool) {
+	if m, ok := obj.self.getStr(name, nil).(*Object); ok {
+		return m.self.assertCallable()
+	}
+	return nil, false
+}
+
+func (sr *setRecord) contains(v Value) bool {
+	return sr.has(FunctionCall{This: sr.set, Arguments: []Value{v}}).ToBoolean()
+}
+
+func (sr *setRecord) iterateKeys() *iteratorRecord {
+	r := sr.set.runtime
+	iter, ok := sr.keys(FunctionCall{This: sr.set}).(*Object)
+	if !ok {
+		panic(r.NewTypeError("The result of the 'keys' method is not an object"))
+	}
+	return r.getIteratorDirect(iter)
+}
+
+func (r *Runtime) checkSetThis(this Value, method string) *setObject {
+	thisObj := r.toObject(this)
+	so, ok := thisObj.self.(*setObject)
+	if !ok {
+		panic(r.NewTypeError("Method Set.prototype.%s called on incompatible receiver %s", method, r.objectproto_toString(FunctionCall{This: thisObj})))
+	}
+	return so
+}
+
+func (r *Runtime) setProto_union(call FunctionCall) Value {
+	so := r.checkSetThis(call.This, "union")
+	other := r.getSetRecord(call.Argument(0))
+	keysIter := other.iterateKeys()
+	m := so.m.clone()
+	for {
+		next, ok := keysIter.stepValue()
+		if !ok {
+			break
+		}
+		m.set(next, nil)
+	}
+	return r.newSetFromMap(m)
+}
+
+func (r *Runtime) setProto_intersection(call FunctionCall) Value {
+	so := r.checkSetThis(call.This, "intersection")
+	other := r.getSetRecord(call.Argument(0))
+	m := r.newOrderedMap()
+	if float64(so.m.size) <= other.size {
+		iter := so.m.newIter()
+		for entry := iter.next(); entry != nil; entry = iter.next() {
+			key := entry.key
+			if other.contains(key) {
+				m.set(key, nil)
+			}
+		}
+	} else {
+		keysIter := other.iterateKeys()
+		for {
+			next, ok := keysIter.stepValue()
+			if !ok {
+				break
+			}
+			if so.m.has(next) {
+				m.set(next, nil)
+			}
+		}
+	}
+	return r.newSetFromMap(m)
+}
+
+func (r *Runtime) setProto_difference(call FunctionCall) Value {
+	so := r.checkSetThis(call.This, "difference")
+	other := r.getSetRecord(call.Argument(0))
+	m := so.m.clone()
+	if float64(so.m.size) <= other.size {
+		iter := m.newIter()
+		for entry := iter.next(); entry != nil; entry = iter.next() {
+			key := entry.key
+			if other.contains(key) {
+				m.remove(key)
+			}
+		}
+	} else {
+		keysIter := other.iterateKeys()
+		for {
+			next, ok := keysIter.stepValue()
+			if !ok {
+				break
+			}
+			m.remove(next)
+		}
+	}
+	return r.newSetFromMap(m)
+}
+
+func (r *Runtime) setProto_symmetricDifference(call FunctionCall) Value {
+	so := r.checkSetThis(call.This, "symmetricDifference")
+	other := r.getSetRecord(call.Argument(0))
+	keysIter := other.iterateKeys()
+	m := so.m.clone()
+	for {
+		next, ok := keysIter.stepValue()
+		if !ok {
+			break
+		}
+		if so.m.has(next) {
+			m.remove(next)
+		} else {
+			m.set(next, nil)
+		}
+	}
+	return r.newSetFromMap(m)
+}
+
+func (r *Runtime) setProto_isSubsetOf(call FunctionCall) Value {
+	so := r.checkSetThis(call.This, "isSubsetOf")
+	other := r.getSetRecord(call.Argument(0))
+	if float64(so.m.size) > other.size {
+		return valueFalse
+	}
+	iter := so.m.newIter()
+	for entry := iter.next(); entry != nil; entry = iter.next() {
+		if !other.contains(entry.key) {
+			return valueFalse
+		}
+	}
+	return valueTrue
+}
+
+func (r *Runtime) setProto_isSupersetOf(call FunctionCall) Value {
+	so := r.checkSetThis(call.This, "isSupersetOf")
+	other := r.getSetRecord(call.Argument(0))
+	if float64(so.m.size) < other.size {
+		return valueFalse
+	}
+	keysIter := other.iterateKeys()
+	for {
+		next, ok := keysIter.stepValue()
+		if !ok {
+			return valueTrue
+		}
+		if !so.m.has(next) {
+			keysIter.returnIter()
+			return valueFalse
+		}
+	}
+}
+
+func (r *Runtime) setProto_isDisjointFrom(call FunctionCall) Value {
+	so := r.checkSetThis(call.This, "isDisjointFrom")
+	other := r.getSetRecord(call.Argument(0))
+	if float64(so.m.size) <= other.size {
+		iter := so.m.newIter()
+		for entry := iter.next(); entry != nil; entry = iter.next() {
+			if other.contains(entry.key) {
+				return valueFalse
+			}
+		}
+	} else {
+		keysIter := other.iterateKeys()
+		for {
+			next, ok := keysIter.stepValue()
+			if !ok {
+				break
+			}
+			if so.m.has(next) {
+				keysIter.returnIter()
+				return valueFalse
+			}
+		}
+	}
+	return valueTrue
+}
+
+func (r *Runtime) newSetFromMap(m *orderedMap) *Object {
+	o := &Object{runtime: r}
+
+	so := &setObject{}
+	so.class = classObject
+	so.val = o
+	so.extensible = true
+	o.self = so
+	so.prototype = r.getSetPrototype()
+	so.baseObject.init()
+	so.m = m
+
+	return o
+}
+
 func (r *Runtime) builtin_newSet(args []Value, newTarget *Object) *Object {
 	if newTarget == nil {
 		panic(r.needNew("Set"))
@@ -282,6 +512,13 @@ func (r *Runtime) createSetProto(val *Object) objectImpl {
 	o._putProp("delete", r.newNativeFunc(r.setProto_delete, "delete", 1), true, false, true)
 	o._putProp("forEach", r.newNativeFunc(r.setProto_forEach, "forEach", 1), true, false, true)
 	o._putProp("has", r.newNativeFunc(r.setProto_has, "has", 1), true, false, true)
+	o._putProp("union", r.newNativeFunc(r.setProto_union, "union", 1), true, false, true)
+	o._putProp("intersection", r.newNativeFunc(r.setProto_intersection, "intersection", 1), true, false, true)
+	o._putProp("difference", r.newNativeFunc(r.setProto_difference, "difference", 1), true, false, true)
+	o._putProp("symmetricDifference", r.newNativeFunc(r.setProto_symmetricDifference, "symmetricDifference", 1), true, false, true)
+	o._putProp("isSubsetOf", r.newNativeFunc(r.setProto_isSubsetOf, "isSubsetOf", 1), true, false, true)
+	o._putProp("isSupersetOf", r.newNativeFunc(r.setProto_isSupersetOf, "isSupersetOf", 1), true, false, true)
+	o._putProp("isDisjointFrom", r.newNativeFunc(r.setProto_isDisjointFrom, "isDisjointFrom", 1), true, false, true)
 	o.setOwnStr("size", &valueProperty{
 		getterFunc:   r.newNativeFunc(r.setProto_getSize, "get size", 0),
 		accessor:     true,
@@ -178,3 +178,60 @@ func TestSetGetAdderGetIteratorOrder(t *testing.T) {
 	`
 	testScript(SCRIPT, valueTrue, t)
 }
+
+func TestSetMethods(t *testing.T) {
+	const SCRIPT = `
+	const a = new Set([1, 2, 3, 4]);
+	const b = new Set([5, 4, 3]);
+	assert(compareArray([...a.union(b)], [1, 2, 3, 4, 5]), "union");
+	assert(compareArray([...a.intersection(b)], [4, 3]), "intersection iterates the smaller set");
+	assert(compareArray([...a.intersection(new Set([3, 4, 5, 6, 7]))], [3, 4]), "intersection");
+	assert(compareArray([...a.difference(b)], [1, 2]), "difference");
+	assert(compareArray([...a.symmetricDifference(b)], [1, 2, 5]), "symmetricDifference");
+	assert(new Set([3, 4]).isSubsetOf(a), "isSubsetOf");
+	assert(!a.isSubsetOf(b), "!isSubsetOf");
+	assert(a.isSupersetOf(new Set([1, 2])), "isSupersetOf");
+	assert(a.isDisjointFrom(new Set([7, 8])), "isDisjointFrom");
+	assert(!a.isDisjointFrom(b), "!isDisjointFrom");
+
+	// set-like arguments
+	const setLike = {
+		size: 2,
+		has(v) { return v === 1 || v === 10; },
+		keys() { return [1, 10][Symbol.iterator](); },
+	};
+	assert(compareArray([...a.union(setLike)], [1, 2, 3, 4, 10]), "union with set-like");
+	assert(compareArray([...a.intersection(setLike)], [1]), "intersection with set-like");
+	assert(compareArray([...new Map([[1, 2], [3, 4]]).keys()], [...a.intersection(new Map([[1, 2], [3, 4]]))]), "Map is set-like");
+
+	assert.throws(TypeError, () => a.union([1, 2]), "arrays are not set-like");
+	assert.throws(RangeError, () => a.union({size: -1, has() {}, keys() {}}));
+	assert.throws(TypeError, () => a.union({size: 1, has: 1, keys() {}}));
+	assert.throws(TypeError, () => Set.prototype.union.call({}, a));
+	assert.sameValue(Object.getPrototypeOf(a.union(b)), Set.prototype);
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestSetMethodsCloseIterator(t *testing.T) {
+	const SCRIPT = `
+	let closed = false;
+	const setLike = {
+		size: 1,
+		has() { throw new Error("should not be called"); },
+		keys() {
+			let i = 0;
+			return {
+				next() { return {value: i++, done: false}; },
+				return() { closed = true; return {}; },
+			};
+		},
+	};
+	assert(!new Set([0, 1]).isSupersetOf(setLike));
+	assert(closed, "isSupersetOf closes the keys iterator");
+	closed = false;
+	assert(!new Set([0, 1]).isDisjointFrom(setLike));
+	assert(closed, "isDisjointFrom closes the keys iterator");
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
@@ -253,6 +253,7 @@ func (r *Runtime) stringproto_concat(call FunctionCall) Value {
 	}
 
 	if allAscii {
+		r.accountMemory(int64(totalLen))
 		var buf strings.Builder
 		buf.Grow(totalLen)
 		for _, s := range strs {
@@ -260,6 +261,7 @@ func (r *Runtime) stringproto_concat(call FunctionCall) Value {
 		}
 		return asciiString(buf.String())
 	} else {
+		r.accountMemory(int64(totalLen) * 2)
 		buf := make([]uint16, totalLen+1)
 		buf[0] = unistring.BOM
 		pos := 1
@@ -323,6 +325,7 @@ func (r *Runtime) stringproto_includes(call FunctionCall) Value {
 		pos = 0
 	}
 	start := toIntStrict(min(max(pos, 0), int64(s.Length())))
+	r.vm.chargeInstructions(int64(s.Length() - start))
 	if s.index(searchStr, start) != -1 {
 		return valueTrue
 	}
@@ -344,6 +347,7 @@ func (r *Runtime) stringproto_indexOf(call FunctionCall) Value {
 		}
 	}
 
+	r.vm.chargeInstructions(int64(value.Length()) - pos)
 	return intToValue(int64(value.index(target, toIntStrict(pos))))
 }
 
@@ -368,6 +372,7 @@ func (r *Runtime) stringproto_lastIndexOf(call FunctionCall) Value {
 		}
 	}
 
+	r.vm.chargeInstructions(pos)
 	return intToValue(int64(value.lastIndex(target, toIntStrict(pos))))
 }
 
@@ -504,6 +509,8 @@ func (r *Runtime) _stringPad(call FunctionCall, start bool) Value {
 	remaining := toIntStrict(maxLength - stringLength)
 	if fillerUnicode == nil && strUnicode == nil {
 		fl := fillerAscii.Length()
+		r.accountMemory(maxLength)
+		r.vm.chargeInstructions(maxLength)
 		var sb strings.Builder
 		sb.Grow(toIntStrict(maxLength))
 		if !start {
@@ -521,6 +528,8 @@ func (r *Runtime) _stringPad(call FunctionCall, start bool) Value {
 		}
 		return asciiString(sb.String())
 	}
+	r.accountMemory(maxLength * 2)
+	r.vm.chargeInstructions(maxLength)
 	var sb unicodeStringBuilder
 	sb.Grow(toIntStrict(maxLength))
 	if !start {
@@ -564,8 +573,10 @@ func (r *Runtime) stringproto_repeat(call FunctionCall) Value {
 		return stringEmpty
 	}
 	num := toIntStrict(numInt)
+	r.vm.chargeInstructions(int64(s.Length()) * int64(num))
 	a, u := devirtualizeString(s)
 	if u == nil {
+		r.accountMemory(int64(len(a)) * int64(num))
 		var sb strings.Builder
 		sb.Grow(len(a) * num)
 		for i := 0; i < num; i++ {
@@ -574,6 +585,7 @@ func (r *Runtime) stringproto_repeat(call FunctionCall) Value {
 		return asciiString(sb.String())
 	}
 
+	r.accountMemory(int64(u.Length()) * int64(num) * 2)
 	var sb unicodeStringBuilder
 	sb.Grow(u.Length() * num)
 	for i := 0; i < num; i++ {
@@ -593,7 +605,7 @@ func getReplaceValue(replaceValue Value) (str String, rcall func(FunctionCall) V
 	return
 }
 
-func stringReplace(s String, found [][]int, newstring String, rcall func(FunctionCall) Value) Value {
+func (r *Runtime) stringReplace(s String, found [][]int, newstring String, rcall func(FunctionCall) Value) Value {
 	if len(found) == 0 {
 		return s
 	}
@@ -601,6 +613,7 @@ func stringReplace(s String, found [][]int, newstring String, rcall func(Functio
 	a, u := devirtualizeString(s)
 
 	var buf StringBuilder
+	accounted := 0
 
 	lastIndex := 0
 	lengthS := s.Length()
@@ -630,6 +643,7 @@ func stringReplace(s String, found [][]int, newstring String, rcall func(Functio
 				Arguments: argumentList,
 			}).toString()
 			buf.WriteString(replacement)
+			r.accountGrowth(buf.memSize(), &accounted)
 			lastIndex = item[1]
 		}
 	} else {
@@ -647,6 +661,7 @@ func stringReplace(s String, found [][]int, newstring String, rcall func(Functio
 				}
 				return stringEmpty
 			}, newstring, &buf)
+			r.accountGrowth(buf.memSize(), &accounted)
 			lastIndex = item[1]
 		}
 	}
@@ -654,6 +669,7 @@ func stringReplace(s String, found [][]int, newstring String, rcall func(Functio
 	if lastIndex != lengthS {
 		buf.WriteString(s.Substring(lastIndex, lengthS))
 	}
+	r.accountGrowth(buf.memSize(), &accounted)
 
 	return buf.String()
 }
@@ -674,13 +690,14 @@ func (r *Runtime) stringproto_replace(call FunctionCall) Value {
 	s := call.This.toString()
 	var found [][]int
 	searchStr := searchValue.toString()
+	r.vm.chargeInstructions(int64(s.Length()))
 	pos := s.index(searchStr, 0)
 	if pos != -1 {
 		found = append(found, []int{pos, pos + searchStr.Length()})
 	}
 
 	str, rcall := getReplaceValue(replaceValue)
-	return stringReplace(s, found, str, rcall)
+	return r.stringReplace(s, found, str, rcall)
 }
 
 func (r *Runtime) stringproto_replaceAll(call FunctionCall) Value {
@@ -708,6 +725,7 @@ func (r *Runtime) stringproto_replaceAll(call FunctionCall) Value {
 	searchStr := searchValue.toString()
 	searchLength := searchStr.Length()
 	advanceBy := toIntStrict(max(1, int64(searchLength)))
+	r.vm.chargeInstructions(int64(s.Length()))
 
 	pos := s.index(searchStr, 0)
 	for pos != -1 {
@@ -716,7 +734,7 @@ func (r *Runtime) stringproto_replaceAll(call FunctionCall) Value {
 	}
 
 	str, rcall := getReplaceValue(replaceValue)
-	return stringReplace(s, found, str, rcall)
+	return r.stringReplace(s, found, str, rcall)
 }
 
 func (r *Runtime) stringproto_search(call FunctionCall) Value {
@@ -828,6 +846,7 @@ func (r *Runtime) stringproto_split(call FunctionCall) Value {
 		splitLimit = limit + 1
 	}
 
+	r.vm.chargeInstructions(int64(len(str)))
 	// TODO handle invalid UTF-16
 	split := strings.SplitN(str, separator, splitLimit)
 
@@ -1,6 +1,7 @@
 package goja
 
 import (
+	"encoding/base64"
 	"fmt"
 	"math"
 	"sort"
@@ -11,64 +12,88 @@ import (
 	"github.com/dop251/goja/unistring"
 )
 
+// typedArraySortCtx sorts the elements of a typed array in place, it is only used without a comparison function.
 type typedArraySortCtx struct {
-	ta           *typedArrayObject
-	compare      func(FunctionCall) Value
-	needValidate bool
-	detached     bool
+	ta     *typedArrayObject
+	length int
 }
 
 func (ctx *typedArraySortCtx) Len() int {
-	return ctx.ta.length
+	return ctx.length
 }
 
-func (ctx *typedArraySortCtx) checkDetached() {
-	if !ctx.detached && ctx.needValidate {
-		ctx.detached = !ctx.ta.viewedArrayBuf.ensureNotDetached(false)
-		ctx.needValidate = false
-	}
+func (ctx *typedArraySortCtx) Less(i, j int) bool {
+	offset := ctx.ta.offset
+	return ctx.ta.typedArray.less(offset+i, offset+j)
 }
 
-func (ctx *typedArraySortCtx) Less(i, j int) bool {
-	ctx.checkDetached()
-	if ctx.detached {
-		return false
-	}
+func (ctx *typedArraySortCtx) Swap(i, j int) {
 	offset := ctx.ta.offset
-	if ctx.compare != nil {
-		x := ctx.ta.typedArray.get(offset + i)
-		y := ctx.ta.typedArray.get(offset + j)
-		res := ctx.compare(FunctionCall{
-			This:      _undefined,
-			Arguments: []Value{x, y},
-		}).ToNumber()
-		ctx.needValidate = true
-		if i, ok := res.(valueInt); ok {
-			return i < 0
-		}
-		f := res.ToFloat()
-		if f < 0 {
-			return true
-		}
-		if f > 0 {
-			return false
-		}
-		if math.Signbit(f) {
-			return true
-		}
+	ctx.ta.typedArray.swap(offset+i, offset+j)
+}
+
+// typedArrayValuesSortCtx sorts the values read from a typed array using a comparison function. The values are
+// sorted separately because the comparison function may detach or shrink the buffer.
+type typedArrayValuesSortCtx struct {
+	values  []Value
+	compare func(FunctionCall) Value
+}
+
+func (ctx *typedArrayValuesSortCtx) Len() int {
+	return len(ctx.values)
+}
+
+func (ctx *typedArrayValuesSortCtx) Less(i, j int) bool {
+	res := ctx.compare(FunctionCall{
+		This:      _undefined,
+		Arguments: []Value{ctx.values[i], ctx.values[j]},
+	}).ToNumber()
+	if i, ok := res.(valueInt); ok {
+		return i < 0
+	}
+	f := res.ToFloat()
+	if f < 0 {
+		return true
+	}
+	if f > 0 {
 		return false
 	}
+	if math.Signbit(f) {
+		return true
+	}
+	return false
+}
 
-	return ctx.ta.typedArray.less(offset+i, offset+j)
+func (ctx *typedArrayValuesSortCtx) Swap(i, j int) {
+	ctx.values[i], ctx.values[j] = ctx.values[j], ctx.values[i]
 }
 
-func (ctx *typedArraySortCtx) Swap(i, j int) {
-	ctx.checkDetached()
-	if ctx.detached {
+// typedArraySort sorts the first length elements of the typed array. If compare is not nil, the elements are
+// copied, sorted and written back to the indices that are still valid once the sorting is done (as per the
+// SortIndexedProperties() steps).
+func (r *Runtime) typedArraySort(ta *typedArrayObject, length int, compare func(FunctionCall) Value) {
+	r.vm.chargeInstructions(int64(length))
+	if compare == nil {
+		sort.Stable(&typedArraySortCtx{
+			ta:     ta,
+			length: length,
+		})
 		return
 	}
-	offset := ctx.ta.offset
-	ctx.ta.typedArray.swap(offset+i, offset+j)
+	values := make([]Value, length)
+	for i := range values {
+		values[i] = ta.typedArray.get(ta.offset + i)
+	}
+	sort.Stable(&typedArrayValuesSortCtx{
+		values:  values,
+		compare: compare,
+	})
+	for i, v := range values {
+		if !ta.isValidIntegerIndex(i) {
+			break
+		}
+		ta.typedArray.set(ta.offset+i, v)
+	}
 }
 
 func allocByteSlice(size int) (b []byte) {
@@ -84,14 +109,38 @@ func allocByteSlice(size int) (b []byte) {
 	return
 }
 
+// allocBuffer allocates the data of an ArrayBuffer, accounting it against the memory limit. Zeroing the memory
+// is charged as one instruction per byte.
+func (r *Runtime) allocBuffer(size int) []byte {
+	if size > 0 {
+		r.accountMemory(int64(size))
+		r.vm.chargeInstructions(int64(size))
+	}
+	return allocByteSlice(size)
+}
+
 func (r *Runtime) builtin_newArrayBuffer(args []Value, newTarget *Object) *Object {
 	if newTarget == nil {
 		panic(r.needNew("ArrayBuffer"))
 	}
-	b := r._newArrayBuffer(r.getPrototypeFromCtor(newTarget, r.getArrayBuffer(), r.getArrayBufferPrototype()), nil)
+	var byteLen int
 	if len(args) > 0 {
-		b.data = allocByteSlice(r.toIndex(args[0]))
+		byteLen = r.toIndex(args[0])
 	}
+	maxByteLen := -1
+	if len(args) > 1 {
+		if opts, ok := args[1].(*Object); ok {
+			if m := nilSafe(opts.self.getStr("maxByteLength", nil)); m != _undefined {
+				maxByteLen = r.toIndex(m)
+				if byteLen > maxByteLen {
+					panic(r.newError(r.getRangeError(), "byteLength %d exceeds maxByteLength %d", byteLen, maxByteLen))
+				}
+			}
+		}
+	}
+	b := r._newArrayBuffer(r.getPrototypeFromCtor(newTarget, r.getArrayBuffer(), r.getArrayBufferPrototype()), nil)
+	b.data = r.allocBuffer(byteLen)
+	b.maxByteLen = maxByteLen
 	return b.val
 }
 
@@ -109,6 +158,7 @@ func (r *Runtime) arrayBufferProto_getByteLength(call FunctionCall) Value {
 func (r *Runtime) arrayBufferProto_slice(call FunctionCall) Value {
 	o := r.toObject(call.This)
 	if b, ok := o.self.(*arrayBufferObject); ok {
+		b.ensureNotDetached(true)
 		l := int64(len(b.data))
 		start := relToIdx(call.Argument(0).ToInteger(), l)
 		var stop int64
@@ -130,7 +180,11 @@ func (r *Runtime) arrayBufferProto_slice(call FunctionCall) Value {
 					panic(r.NewTypeError("Species constructor returned an ArrayBuffer that is too small: %d", len(ab.data)))
 				}
 				ab.ensureNotDetached(true)
-				copy(ab.data, b.data[start:stop])
+				ab.ensureMutable()
+				b.ensureNotDetached(true)
+				if curLen := int64(len(b.data)); start < curLen {
+					copy(ab.data, b.data[start:min(stop, curLen)])
+				}
 			}
 			return ret
 		}
@@ -139,6 +193,136 @@ func (r *Runtime) arrayBufferProto_slice(call FunctionCall) Value {
 	panic(r.NewTypeError("Object is not ArrayBuffer: %s", o))
 }
 
+func (r *Runtime) arrayBufferProto_getMaxByteLength(call FunctionCall) Value {
+	o := r.toObject(call.This)
+	if b, ok := o.self.(*arrayBufferObject); ok {
+		if b.detached {
+			return intToValue(0)
+		}
+		if b.maxByteLen >= 0 {
+			return intToValue(int64(b.maxByteLen))
+		}
+		return intToValue(int64(len(b.data)))
+	}
+	panic(r.NewTypeError("Object is not ArrayBuffer: %s", o))
+}
+
+func (r *Runtime) arrayBufferProto_getResizable(call FunctionCall) Value {
+	o := r.toObject(call.This)
+	if b, ok := o.self.(*arrayBufferObject); ok {
+		return r.toBoolean(b.maxByteLen >= 0)
+	}
+	panic(r.NewTypeError("Object is not ArrayBuffer: %s", o))
+}
+
+func (r *Runtime) arrayBufferProto_resize(call FunctionCall) Value {
+	o := r.toObject(call.This)
+	if b, ok := o.self.(*arrayBufferObject); ok && b.maxByteLen >= 0 && !b.immutable {
+		newLen := r.toIndex(call.Argument(0))
+		b.ensureNotDetached(true)
+		if newLen > b.maxByteLen {
+			panic(r.newError(r.getRangeError(), "New length %d exceeds maxByteLength %d", newLen, b.maxByteLen))
+		}
+		b.resize(newLen)
+		return _undefined
+	}
+	panic(r.NewTypeError("Method ArrayBuffer.prototype.resize called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: call.This})))
+}
+
+func (r *Runtime) arrayBufferProto_getDetached(call FunctionCall) Value {
+	o := r.toObject(call.This)
+	if b, ok := o.self.(*arrayBufferObject); ok {
+		return r.toBoolean(b.detached)
+	}
+	panic(r.NewTypeError("Object is not ArrayBuffer: %s", o))
+}
+
+// arrayBufferCopyAndDetach moves the contents of the buffer into a new one and detaches the original.
+// The underlying slice is re-used if it has enough capacity.
+func (r *Runtime) arrayBufferCopyAndDetach(call FunctionCall, method string, preserveResizability, immutable bool) Value {
+	b, ok := r.toObject(call.This).self.(*arrayBufferObject)
+	if !ok {
+		panic(r.NewTypeError("Method ArrayBuffer.prototype.%s called on incompatible receiver %s", method, r.objectproto_toString(FunctionCall{This: call.This})))
+	}
+	var newLen int
+	if arg := call.Argument(0); arg != _undefined {
+		newLen = r.toIndex(arg)
+	} else {
+		newLen = len(b.data)
+	}
+	b.ensureNotDetached(true)
+	b.ensureMutable()
+	maxByteLen := -1
+	if preserveResizability {
+		maxByteLen = b.maxByteLen
+	}
+	if maxByteLen >= 0 && newLen > maxByteLen {
+		panic(r.newError(r.getRangeError(), "New length %d exceeds maxByteLength %d", newLen, maxByteLen))
+	}
+	ret := r._newArrayBuffer(r.getArrayBufferPrototype(), nil)
+	ret.maxByteLen = maxByteLen
+	ret.immutable = immutable
+	if oldLen := len(b.data); newLen <= cap(b.data) {
+		ret.data = b.data[:newLen]
+		if newLen > oldLen {
+			clear(ret.data[oldLen:])
+		}
+	} else {
+		ret.data = r.allocBuffer(newLen)
+		copy(ret.data, b.data)
+	}
+	b.detach()
+	return ret.val
+}
+
+func (r *Runtime) arrayBufferProto_transfer(call FunctionCall) Value {
+	return r.arrayBufferCopyAndDetach(call, "transfer", true, false)
+}
+
+func (r *Runtime) arrayBufferProto_transferToFixedLength(call FunctionCall) Value {
+	return r.arrayBufferCopyAndDetach(call, "transferToFixedLength", false, false)
+}
+
+func (r *Runtime) arrayBufferProto_transferToImmutable(call FunctionCall) Value {
+	return r.arrayBufferCopyAndDetach(call, "transferToImmutable", false, true)
+}
+
+func (r *Runtime) arrayBufferProto_getImmutable(call FunctionCall) Value {
+	o := r.toObject(call.This)
+	if b, ok := o.self.(*arrayBufferObject); ok {
+		return r.toBoolean(b.immutable)
+	}
+	panic(r.NewTypeError("Object is not ArrayBuffer: %s", o))
+}
+
+func (r *Runtime) arrayBufferProto_sliceToImmutable(call FunctionCall) Value {
+	o := r.toObject(call.This)
+	if b, ok := o.self.(*arrayBufferObject); ok {
+		b.ensureNotDetached(true)
+		l := int64(len(b.data))
+		start := relToIdx(call.Argument(0).ToInteger(), l)
+		var stop int64
+		if arg := call.Argument(1); arg != _undefined {
+			stop = arg.ToInteger()
+		} else {
+			stop = l
+		}
+		stop = relToIdx(stop, l)
+		b.ensureNotDetached(true)
+		if stop > int64(len(b.data)) {
+			panic(r.newError(r.getRangeError(), "ArrayBuffer has been shrunk"))
+		}
+		ret := r._newArrayBuffer(r.getArrayBufferPrototype(), nil)
+		ret.immutable = true
+		if stop > start {
+			ret.data = r.allocBuffer(int(stop - start))
+			copy(ret.data, b.data[start:stop])
+		}
+		return ret.val
+	}
+	panic(r.NewTypeError("Object is not ArrayBuffer: %s", o))
+}
+
 func (r *Runtime) arrayBuffer_isView(call FunctionCall) Value {
 	if o, ok := call.Argument(0).(*Object); ok {
 		if _, ok := o.self.(*dataViewObject); ok {
@@ -170,14 +354,15 @@ func (r *Runtime) newDataView(args []Value, newTarget *Object) *Object {
 	}
 	var byteOffset, byteLen int
 	if len(args) > 1 {
-		offsetArg := nilSafe(args[1])
-		byteOffset = r.toIndex(offsetArg)
-		buffer.ensureNotDetached(true)
-		if byteOffset > len(buffer.data) {
-			panic(r.newError(r.getRangeError(), "Start offset %s is outside the bounds of the buffer", offsetArg.String()))
-		}
+		byteOffset = r.toIndex(nilSafe(args[1]))
 	}
-	if len(args) > 2 && args[2] != nil && args[2] != _undefined {
+	buffer.ensureNotDetached(true)
+	if byteOffset > len(buffer.data) {
+		panic(r.newError(r.getRangeError(), "Start offset %d is outside the bounds of the buffer", byteOffset))
+	}
+	hasLength := len(args) > 2 && args[2] != nil && args[2] != _undefined
+	lengthTracking := !hasLength && buffer.maxByteLen >= 0
+	if hasLength {
 		byteLen = r.toIndex(args[2])
 		if byteOffset+byteLen > len(buffer.data) {
 			panic(r.newError(r.getRangeError(), "Invalid DataView length %d", byteLen))
@@ -190,7 +375,7 @@ func (r *Runtime) newDataView(args []Value, newTarget *Object) *Object {
 	if byteOffset > len(buffer.data) {
 		panic(r.newError(r.getRangeError(), "Start offset %d is outside the bounds of the buffer", byteOffset))
 	}
-	if byteOffset+byteLen > len(buffer.data) {
+	if hasLength && byteOffset+byteLen > len(buffer.data) {
 		panic(r.newError(r.getRangeError(), "Invalid DataView length %d", byteLen))
 	}
 	o := &Object{runtime: r}
@@ -204,6 +389,7 @@ func (r *Runtime) newDataView(args []Value, newTarget *Object) *Object {
 		viewedArrayBuf: buffer,
 		byteOffset:     byteOffset,
 		byteLen:        byteLen,
+		lengthTracking: lengthTracking,
 	}
 	o.self = b
 	b.init()
@@ -219,20 +405,26 @@ func (r *Runtime) dataViewProto_getBuffer(call FunctionCall) Value {
 
 func (r *Runtime) dataViewProto_getByteLen(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
-		dv.viewedArrayBuf.ensureNotDetached(true)
-		return intToValue(int64(dv.byteLen))
+		return intToValue(int64(dv.validate()))
 	}
 	panic(r.NewTypeError("Method get DataView.prototype.byteLength called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: call.This})))
 }
 
 func (r *Runtime) dataViewProto_getByteOffset(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
-		dv.viewedArrayBuf.ensureNotDetached(true)
+		dv.validate()
 		return intToValue(int64(dv.byteOffset))
 	}
 	panic(r.NewTypeError("Method get DataView.prototype.byteOffset called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: call.This})))
 }
 
+func (r *Runtime) dataViewProto_getFloat16(call FunctionCall) Value {
+	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
+		return floatToValue(dv.viewedArrayBuf.getFloat16(dv.getIdxAndByteOrder(r.toIndex(call.Argument(0)), call.Argument(1), 2)))
+	}
+	panic(r.NewTypeError("Method DataView.prototype.getFloat16 called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: call.This})))
+}
+
 func (r *Runtime) dataViewProto_getFloat32(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
 		return floatToValue(float64(dv.viewedArrayBuf.getFloat32(dv.getIdxAndByteOrder(r.toIndex(call.Argument(0)), call.Argument(1), 4))))
@@ -305,11 +497,22 @@ func (r *Runtime) dataViewProto_getBigUint64(call FunctionCall) Value {
 	panic(r.NewTypeError("Method DataView.prototype.getBigUint64 called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: call.This})))
 }
 
+func (r *Runtime) dataViewProto_setFloat16(call FunctionCall) Value {
+	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
+		idxVal := r.toIndex(call.Argument(0))
+		val := call.Argument(1).ToFloat()
+		idx, bo := dv.setIdxAndByteOrder(idxVal, call.Argument(2), 2)
+		dv.viewedArrayBuf.setFloat16(idx, val, bo)
+		return _undefined
+	}
+	panic(r.NewTypeError("Method DataView.prototype.setFloat16 called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: call.This})))
+}
+
 func (r *Runtime) dataViewProto_setFloat32(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
 		idxVal := r.toIndex(call.Argument(0))
 		val := toFloat32(call.Argument(1))
-		idx, bo := dv.getIdxAndByteOrder(idxVal, call.Argument(2), 4)
+		idx, bo := dv.setIdxAndByteOrder(idxVal, call.Argument(2), 4)
 		dv.viewedArrayBuf.setFloat32(idx, val, bo)
 		return _undefined
 	}
@@ -320,7 +523,7 @@ func (r *Runtime) dataViewProto_setFloat64(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
 		idxVal := r.toIndex(call.Argument(0))
 		val := call.Argument(1).ToFloat()
-		idx, bo := dv.getIdxAndByteOrder(idxVal, call.Argument(2), 8)
+		idx, bo := dv.setIdxAndByteOrder(idxVal, call.Argument(2), 8)
 		dv.viewedArrayBuf.setFloat64(idx, val, bo)
 		return _undefined
 	}
@@ -331,7 +534,7 @@ func (r *Runtime) dataViewProto_setInt8(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
 		idxVal := r.toIndex(call.Argument(0))
 		val := toInt8(call.Argument(1))
-		idx, _ := dv.getIdxAndByteOrder(idxVal, call.Argument(2), 1)
+		idx, _ := dv.setIdxAndByteOrder(idxVal, call.Argument(2), 1)
 		dv.viewedArrayBuf.setInt8(idx, val)
 		return _undefined
 	}
@@ -342,7 +545,7 @@ func (r *Runtime) dataViewProto_setInt16(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
 		idxVal := r.toIndex(call.Argument(0))
 		val := toInt16(call.Argument(1))
-		idx, bo := dv.getIdxAndByteOrder(idxVal, call.Argument(2), 2)
+		idx, bo := dv.setIdxAndByteOrder(idxVal, call.Argument(2), 2)
 		dv.viewedArrayBuf.setInt16(idx, val, bo)
 		return _undefined
 	}
@@ -353,7 +556,7 @@ func (r *Runtime) dataViewProto_setInt32(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
 		idxVal := r.toIndex(call.Argument(0))
 		val := toInt32(call.Argument(1))
-		idx, bo := dv.getIdxAndByteOrder(idxVal, call.Argument(2), 4)
+		idx, bo := dv.setIdxAndByteOrder(idxVal, call.Argument(2), 4)
 		dv.viewedArrayBuf.setInt32(idx, val, bo)
 		return _undefined
 	}
@@ -364,7 +567,7 @@ func (r *Runtime) dataViewProto_setUint8(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
 		idxVal := r.toIndex(call.Argument(0))
 		val := toUint8(call.Argument(1))
-		idx, _ := dv.getIdxAndByteOrder(idxVal, call.Argument(2), 1)
+		idx, _ := dv.setIdxAndByteOrder(idxVal, call.Argument(2), 1)
 		dv.viewedArrayBuf.setUint8(idx, val)
 		return _undefined
 	}
@@ -375,7 +578,7 @@ func (r *Runtime) dataViewProto_setUint16(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
 		idxVal := r.toIndex(call.Argument(0))
 		val := toUint16(call.Argument(1))
-		idx, bo := dv.getIdxAndByteOrder(idxVal, call.Argument(2), 2)
+		idx, bo := dv.setIdxAndByteOrder(idxVal, call.Argument(2), 2)
 		dv.viewedArrayBuf.setUint16(idx, val, bo)
 		return _undefined
 	}
@@ -386,7 +589,7 @@ func (r *Runtime) dataViewProto_setUint32(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
 		idxVal := r.toIndex(call.Argument(0))
 		val := toUint32(call.Argument(1))
-		idx, bo := dv.getIdxAndByteOrder(idxVal, call.Argument(2), 4)
+		idx, bo := dv.setIdxAndByteOrder(idxVal, call.Argument(2), 4)
 		dv.viewedArrayBuf.setUint32(idx, val, bo)
 		return _undefined
 	}
@@ -397,7 +600,7 @@ func (r *Runtime) dataViewProto_setBigInt64(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
 		idxVal := r.toIndex(call.Argument(0))
 		val := toBigInt64(call.Argument(1))
-		idx, bo := dv.getIdxAndByteOrder(idxVal, call.Argument(2), 8)
+		idx, bo := dv.setIdxAndByteOrder(idxVal, call.Argument(2), 8)
 		dv.viewedArrayBuf.setBigInt64(idx, val, bo)
 		return _undefined
 	}
@@ -408,7 +611,7 @@ func (r *Runtime) dataViewProto_setBigUint64(call FunctionCall) Value {
 	if dv, ok := r.toObject(call.This).self.(*dataViewObject); ok {
 		idxVal := r.toIndex(call.Argument(0))
 		val := toBigUint64(call.Argument(1))
-		idx, bo := dv.getIdxAndByteOrder(idxVal, call.Argument(2), 8)
+		idx, bo := dv.setIdxAndByteOrder(idxVal, call.Argument(2), 8)
 		dv.viewedArrayBuf.setBigUint64(idx, val, bo)
 		return _undefined
 	}
@@ -424,27 +627,21 @@ func (r *Runtime) typedArrayProto_getBuffer(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_getByteLen(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		if ta.viewedArrayBuf.data == nil {
-			return _positiveZero
-		}
-		return intToValue(int64(ta.length) * int64(ta.elemSize))
+		return intToValue(int64(ta.getLength()) * int64(ta.elemSize))
 	}
 	panic(r.NewTypeError("Method get TypedArray.prototype.byteLength called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: call.This})))
 }
 
 func (r *Runtime) typedArrayProto_getLength(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		if ta.viewedArrayBuf.data == nil {
-			return _positiveZero
-		}
-		return intToValue(int64(ta.length))
+		return intToValue(int64(ta.getLength()))
 	}
 	panic(r.NewTypeError("Method get TypedArray.prototype.length called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: call.This})))
 }
 
 func (r *Runtime) typedArrayProto_getByteOffset(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		if ta.viewedArrayBuf.data == nil {
+		if ta.isOutOfBounds() {
 			return _positiveZero
 		}
 		return intToValue(int64(ta.offset) * int64(ta.elemSize))
@@ -454,8 +651,7 @@ func (r *Runtime) typedArrayProto_getByteOffset(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_copyWithin(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
-		l := int64(ta.length)
+		l := int64(ta.validateWritable())
 		var relEnd int64
 		to := toIntStrict(relToIdx(call.Argument(0).ToInteger(), l))
 		from := toIntStrict(relToIdx(call.Argument(1).ToInteger(), l))
@@ -465,12 +661,17 @@ func (r *Runtime) typedArrayProto_copyWithin(call FunctionCall) Value {
 			relEnd = l
 		}
 		final := toIntStrict(relToIdx(relEnd, l))
-		data := ta.viewedArrayBuf.data
-		offset := ta.offset
-		elemSize := ta.elemSize
-		if final > from {
-			ta.viewedArrayBuf.ensureNotDetached(true)
-			copy(data[(offset+to)*elemSize:], data[(offset+from)*elemSize:(offset+final)*elemSize])
+		if count := min(int64(final-from), l-int64(to)); count > 0 {
+			// the buffer may have been shrunk by the argument conversions
+			length := ta.validateWritable()
+			count := toIntStrict(min(count, int64(length)-max(int64(from), int64(to))))
+			if count > 0 {
+				r.vm.chargeInstructions(int64(count))
+				data := ta.viewedArrayBuf.data
+				offset := ta.offset
+				elemSize := ta.elemSize
+				copy(data[(offset+to)*elemSize:(offset+to+count)*elemSize], data[(offset+from)*elemSize:(offset+from+count)*elemSize])
+			}
 		}
 		return call.This
 	}
@@ -479,7 +680,7 @@ func (r *Runtime) typedArrayProto_copyWithin(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_entries(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		ta.validate()
 		return r.createArrayIterator(ta.val, iterationKindKeyValue)
 	}
 	panic(r.NewTypeError("Method TypedArray.prototype.entries called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: call.This})))
@@ -487,13 +688,13 @@ func (r *Runtime) typedArrayProto_entries(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_every(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		callbackFn := r.toCallable(call.Argument(0))
 		fc := FunctionCall{
 			This:      call.Argument(1),
 			Arguments: []Value{nil, nil, call.This},
 		}
-		for k := 0; k < ta.length; k++ {
+		for k := 0; k < length; k++ {
 			if ta.isValidIntegerIndex(k) {
 				fc.Arguments[0] = ta.typedArray.get(ta.offset + k)
 			} else {
@@ -512,8 +713,7 @@ func (r *Runtime) typedArrayProto_every(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_fill(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
-		l := int64(ta.length)
+		l := int64(ta.validateWritable())
 		k := toIntStrict(relToIdx(call.Argument(1).ToInteger(), l))
 		var relEnd int64
 		if endArg := call.Argument(2); endArg != _undefined {
@@ -523,7 +723,10 @@ func (r *Runtime) typedArrayProto_fill(call FunctionCall) Value {
 		}
 		final := toIntStrict(relToIdx(relEnd, l))
 		value := ta.typedArray.toRaw(call.Argument(0))
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		if l := ta.validateWritable(); l < final {
+			final = l
+		}
+		r.vm.chargeInstructions(int64(final - k))
 		for ; k < final; k++ {
 			ta.typedArray.setRaw(ta.offset+k, value)
 		}
@@ -535,16 +738,16 @@ func (r *Runtime) typedArrayProto_fill(call FunctionCall) Value {
 func (r *Runtime) typedArrayProto_filter(call FunctionCall) Value {
 	o := r.toObject(call.This)
 	if ta, ok := o.self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		callbackFn := r.toCallable(call.Argument(0))
 		fc := FunctionCall{
 			This:      call.Argument(1),
 			Arguments: []Value{nil, nil, call.This},
 		}
-		buf := make([]byte, 0, ta.length*ta.elemSize)
+		buf := make([]byte, 0, length*ta.elemSize)
 		captured := 0
 		rawVal := make([]byte, ta.elemSize)
-		for k := 0; k < ta.length; k++ {
+		for k := 0; k < length; k++ {
 			if ta.isValidIntegerIndex(k) {
 				fc.Arguments[0] = ta.typedArray.get(ta.offset + k)
 				i := (ta.offset + k) * ta.elemSize
@@ -581,13 +784,13 @@ func (r *Runtime) typedArrayProto_filter(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_find(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		predicate := r.toCallable(call.Argument(0))
 		fc := FunctionCall{
 			This:      call.Argument(1),
 			Arguments: []Value{nil, nil, call.This},
 		}
-		for k := 0; k < ta.length; k++ {
+		for k := 0; k < length; k++ {
 			var val Value
 			if ta.isValidIntegerIndex(k) {
 				val = ta.typedArray.get(ta.offset + k)
@@ -605,13 +808,13 @@ func (r *Runtime) typedArrayProto_find(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_findIndex(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		predicate := r.toCallable(call.Argument(0))
 		fc := FunctionCall{
 			This:      call.Argument(1),
 			Arguments: []Value{nil, nil, call.This},
 		}
-		for k := 0; k < ta.length; k++ {
+		for k := 0; k < length; k++ {
 			if ta.isValidIntegerIndex(k) {
 				fc.Arguments[0] = ta.typedArray.get(ta.offset + k)
 			} else {
@@ -629,13 +832,13 @@ func (r *Runtime) typedArrayProto_findIndex(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_findLast(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		predicate := r.toCallable(call.Argument(0))
 		fc := FunctionCall{
 			This:      call.Argument(1),
 			Arguments: []Value{nil, nil, call.This},
 		}
-		for k := ta.length - 1; k >= 0; k-- {
+		for k := length - 1; k >= 0; k-- {
 			var val Value
 			if ta.isValidIntegerIndex(k) {
 				val = ta.typedArray.get(ta.offset + k)
@@ -653,13 +856,13 @@ func (r *Runtime) typedArrayProto_findLast(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_findLastIndex(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		predicate := r.toCallable(call.Argument(0))
 		fc := FunctionCall{
 			This:      call.Argument(1),
 			Arguments: []Value{nil, nil, call.This},
 		}
-		for k := ta.length - 1; k >= 0; k-- {
+		for k := length - 1; k >= 0; k-- {
 			if ta.isValidIntegerIndex(k) {
 				fc.Arguments[0] = ta.typedArray.get(ta.offset + k)
 			} else {
@@ -677,13 +880,13 @@ func (r *Runtime) typedArrayProto_findLastIndex(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_forEach(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		callbackFn := r.toCallable(call.Argument(0))
 		fc := FunctionCall{
 			This:      call.Argument(1),
 			Arguments: []Value{nil, nil, call.This},
 		}
-		for k := 0; k < ta.length; k++ {
+		for k := 0; k < length; k++ {
 			var val Value
 			if ta.isValidIntegerIndex(k) {
 				val = ta.typedArray.get(ta.offset + k)
@@ -699,8 +902,7 @@ func (r *Runtime) typedArrayProto_forEach(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_includes(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
-		length := int64(ta.length)
+		length := int64(ta.validate())
 		if length == 0 {
 			return valueFalse
 		}
@@ -719,15 +921,15 @@ func (r *Runtime) typedArrayProto_includes(call FunctionCall) Value {
 			searchElement = _positiveZero
 		}
 		startIdx := toIntStrict(n)
-		if !ta.viewedArrayBuf.ensureNotDetached(false) {
-			if searchElement == _undefined && startIdx < ta.length {
-				return valueTrue
-			}
-			return valueFalse
+		// the buffer may have been shrunk or detached by ToInteger(), the missing elements are undefined
+		curLength := ta.getLength()
+		if int64(curLength) < length && searchElement == _undefined {
+			return valueTrue
 		}
 		if ta.typedArray.typeMatch(searchElement) {
+			r.vm.chargeInstructions(int64(curLength - startIdx))
 			se := ta.typedArray.toRaw(searchElement)
-			for k := startIdx; k < ta.length; k++ {
+			for k := startIdx; k < curLength; k++ {
 				if ta.typedArray.getRaw(ta.offset+k) == se {
 					return valueTrue
 				}
@@ -740,16 +942,15 @@ func (r *Runtime) typedArrayProto_includes(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_at(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := int64(ta.validate())
 		idx := call.Argument(0).ToInteger()
-		length := int64(ta.length)
 		if idx < 0 {
 			idx = length + idx
 		}
 		if idx >= length || idx < 0 {
 			return _undefined
 		}
-		if ta.viewedArrayBuf.ensureNotDetached(false) {
+		if ta.isValidIntegerIndex(int(idx)) {
 			return ta.typedArray.get(ta.offset + int(idx))
 		}
 		return _undefined
@@ -759,8 +960,7 @@ func (r *Runtime) typedArrayProto_at(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_indexOf(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
-		length := int64(ta.length)
+		length := int64(ta.validate())
 		if length == 0 {
 			return intToValue(-1)
 		}
@@ -774,14 +974,15 @@ func (r *Runtime) typedArrayProto_indexOf(call FunctionCall) Value {
 			n = max(length+n, 0)
 		}
 
-		if ta.viewedArrayBuf.ensureNotDetached(false) {
+		if curLength := ta.getLength(); curLength > 0 {
 			searchElement := call.Argument(0)
 			if searchElement == _negativeZero {
 				searchElement = _positiveZero
 			}
 			if !IsNaN(searchElement) && ta.typedArray.typeMatch(searchElement) {
+				r.vm.chargeInstructions(int64(curLength) - n)
 				se := ta.typedArray.toRaw(searchElement)
-				for k := toIntStrict(n); k < ta.length; k++ {
+				for k := toIntStrict(n); k < curLength; k++ {
 					if ta.typedArray.getRaw(ta.offset+k) == se {
 						return intToValue(int64(k))
 					}
@@ -795,7 +996,7 @@ func (r *Runtime) typedArrayProto_indexOf(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_join(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		s := call.Argument(0)
 		var sep String
 		if s != _undefined {
@@ -803,10 +1004,11 @@ func (r *Runtime) typedArrayProto_join(call FunctionCall) Value {
 		} else {
 			sep = asciiString(",")
 		}
-		l := ta.length
+		l := length
 		if l == 0 {
 			return stringEmpty
 		}
+		r.vm.chargeInstructions(int64(l))
 
 		var buf StringBuilder
 
@@ -835,7 +1037,7 @@ func (r *Runtime) typedArrayProto_join(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_keys(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		ta.validate()
 		return r.createArrayIterator(ta.val, iterationKindKey)
 	}
 	panic(r.NewTypeError("Method TypedArray.prototype.keys called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: call.This})))
@@ -843,8 +1045,7 @@ func (r *Runtime) typedArrayProto_keys(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_lastIndexOf(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
-		length := int64(ta.length)
+		length := int64(ta.validate())
 		if length == 0 {
 			return intToValue(-1)
 		}
@@ -865,14 +1066,15 @@ func (r *Runtime) typedArrayProto_lastIndexOf(call FunctionCall) Value {
 			}
 		}
 
-		if ta.viewedArrayBuf.ensureNotDetached(false) {
+		if curLength := int64(ta.getLength()); curLength > 0 {
 			searchElement := call.Argument(0)
 			if searchElement == _negativeZero {
 				searchElement = _positiveZero
 			}
 			if !IsNaN(searchElement) && ta.typedArray.typeMatch(searchElement) {
+				r.vm.chargeInstructions(min(fromIndex, curLength-1) + 1)
 				se := ta.typedArray.toRaw(searchElement)
-				for k := toIntStrict(fromIndex); k >= 0; k-- {
+				for k := toIntStrict(min(fromIndex, curLength-1)); k >= 0; k-- {
 					if ta.typedArray.getRaw(ta.offset+k) == se {
 						return intToValue(int64(k))
 					}
@@ -887,14 +1089,14 @@ func (r *Runtime) typedArrayProto_lastIndexOf(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_map(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		callbackFn := r.toCallable(call.Argument(0))
 		fc := FunctionCall{
 			This:      call.Argument(1),
 			Arguments: []Value{nil, nil, call.This},
 		}
-		dst := r.typedArraySpeciesCreate(ta, []Value{intToValue(int64(ta.length))})
-		for i := 0; i < ta.length; i++ {
+		dst := r.typedArraySpeciesCreate(ta, []Value{intToValue(int64(length))})
+		for i := 0; i < length; i++ {
 			if ta.isValidIntegerIndex(i) {
 				fc.Arguments[0] = ta.typedArray.get(ta.offset + i)
 			} else {
@@ -910,7 +1112,7 @@ func (r *Runtime) typedArrayProto_map(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_reduce(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		callbackFn := r.toCallable(call.Argument(0))
 		fc := FunctionCall{
 			This:      _undefined,
@@ -920,7 +1122,7 @@ func (r *Runtime) typedArrayProto_reduce(call FunctionCall) Value {
 		if len(call.Arguments) >= 2 {
 			fc.Arguments[0] = call.Argument(1)
 		} else {
-			if ta.length > 0 {
+			if length > 0 {
 				fc.Arguments[0] = ta.typedArray.get(ta.offset + 0)
 				k = 1
 			}
@@ -928,7 +1130,7 @@ func (r *Runtime) typedArrayProto_reduce(call FunctionCall) Value {
 		if fc.Arguments[0] == nil {
 			panic(r.NewTypeError("Reduce of empty array with no initial value"))
 		}
-		for ; k < ta.length; k++ {
+		for ; k < length; k++ {
 			if ta.isValidIntegerIndex(k) {
 				fc.Arguments[1] = ta.typedArray.get(ta.offset + k)
 			} else {
@@ -945,13 +1147,13 @@ func (r *Runtime) typedArrayProto_reduce(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_reduceRight(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		callbackFn := r.toCallable(call.Argument(0))
 		fc := FunctionCall{
 			This:      _undefined,
 			Arguments: []Value{nil, nil, nil, call.This},
 		}
-		k := ta.length - 1
+		k := length - 1
 		if len(call.Arguments) >= 2 {
 			fc.Arguments[0] = call.Argument(1)
 		} else {
@@ -980,8 +1182,9 @@ func (r *Runtime) typedArrayProto_reduceRight(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_reverse(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
-		l := ta.length
+		length := ta.validateWritable()
+		l := length
+		r.vm.chargeInstructions(int64(l))
 		middle := l / 2
 		for lower := 0; lower != middle; lower++ {
 			upper := l - lower - 1
@@ -1000,14 +1203,13 @@ func (r *Runtime) typedArrayProto_set(call FunctionCall) Value {
 		if targetOffset < 0 {
 			panic(r.newError(r.getRangeError(), "offset should be >= 0"))
 		}
-		ta.viewedArrayBuf.ensureNotDetached(true)
-		targetLen := ta.length
+		targetLen := ta.validateWritable()
 		if src, ok := srcObj.self.(*typedArrayObject); ok {
-			src.viewedArrayBuf.ensureNotDetached(true)
-			srcLen := src.length
+			srcLen := src.validate()
 			if x := srcLen + targetOffset; x < 0 || x > targetLen {
 				panic(r.newError(r.getRangeError(), "Source is too large"))
 			}
+			r.vm.chargeInstructions(int64(srcLen))
 			if src.defaultCtor == ta.defaultCtor {
 				copy(ta.viewedArrayBuf.data[(ta.offset+targetOffset)*ta.elemSize:],
 					src.viewedArrayBuf.data[src.offset*src.elemSize:(src.offset+srcLen)*src.elemSize])
@@ -1053,11 +1255,11 @@ func (r *Runtime) typedArrayProto_set(call FunctionCall) Value {
 				}
 			}
 		} else {
-			targetLen := ta.length
 			srcLen := toIntStrict(toLength(srcObj.self.getStr("length", nil)))
 			if x := srcLen + targetOffset; x < 0 || x > targetLen {
 				panic(r.newError(r.getRangeError(), "Source is too large"))
 			}
+			r.vm.chargeInstructions(int64(srcLen))
 			for i := 0; i < srcLen; i++ {
 				val := nilSafe(srcObj.self.getIdx(valueInt(i), nil))
 				if ta.isValidIntegerIndex(targetOffset + i) {
@@ -1072,8 +1274,7 @@ func (r *Runtime) typedArrayProto_set(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_slice(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
-		length := int64(ta.length)
+		length := int64(ta.validate())
 		start := toIntStrict(relToIdx(call.Argument(0).ToInteger(), length))
 		var e int64
 		if endArg := call.Argument(1); endArg != _undefined {
@@ -1088,17 +1289,23 @@ func (r *Runtime) typedArrayProto_slice(call FunctionCall) Value {
 			count = 0
 		}
 		dst := r.typedArraySpeciesCreate(ta, []Value{intToValue(int64(count))})
-		if dst.defaultCtor == ta.defaultCtor {
-			if count > 0 {
-				ta.viewedArrayBuf.ensureNotDetached(true)
-				offset := ta.offset
-				elemSize := ta.elemSize
-				copy(dst.viewedArrayBuf.data, ta.viewedArrayBuf.data[(offset+start)*elemSize:(offset+start+count)*elemSize])
+		if count > 0 {
+			// the species constructor may have shrunk the buffer
+			if l := ta.validate(); l < end {
+				end = l
 			}
-		} else {
-			for i := 0; i < count; i++ {
-				ta.viewedArrayBuf.ensureNotDetached(true)
-				dst.typedArray.set(i, ta.typedArray.get(ta.offset+start+i))
+			count = end - start
+			r.vm.chargeInstructions(int64(count))
+			if dst.defaultCtor == ta.defaultCtor {
+				if count > 0 {
+					offset := ta.offset
+					elemSize := ta.elemSize
+					copy(dst.viewedArrayBuf.data[dst.offset*elemSize:], ta.viewedArrayBuf.data[(offset+start)*elemSize:(offset+end)*elemSize])
+				}
+			} else {
+				for i := 0; i < count; i++ {
+					dst._putIdx(i, ta._getIdx(start+i))
+				}
 			}
 		}
 		return dst.val
@@ -1108,13 +1315,13 @@ func (r *Runtime) typedArrayProto_slice(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_some(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		callbackFn := r.toCallable(call.Argument(0))
 		fc := FunctionCall{
 			This:      call.Argument(1),
 			Arguments: []Value{nil, nil, call.This},
 		}
-		for k := 0; k < ta.length; k++ {
+		for k := 0; k < length; k++ {
 			if ta.isValidIntegerIndex(k) {
 				fc.Arguments[0] = ta.typedArray.get(ta.offset + k)
 			} else {
@@ -1132,19 +1339,13 @@ func (r *Runtime) typedArrayProto_some(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_sort(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
 		var compareFn func(FunctionCall) Value
 
 		if arg := call.Argument(0); arg != _undefined {
 			compareFn = r.toCallable(arg)
 		}
 
-		ctx := typedArraySortCtx{
-			ta:      ta,
-			compare: compareFn,
-		}
-
-		sort.Stable(&ctx)
+		r.typedArraySort(ta, ta.validateWritable(), compareFn)
 		return call.This
 	}
 	panic(r.NewTypeError("Method TypedArray.prototype.sort called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: call.This})))
@@ -1152,10 +1353,15 @@ func (r *Runtime) typedArrayProto_sort(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_subarray(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		l := int64(ta.length)
+		l := int64(ta.getLength())
 		beginIdx := relToIdx(call.Argument(0).ToInteger(), l)
+		beginByteOffset := intToValue((int64(ta.offset) + beginIdx) * int64(ta.elemSize))
+		endArg := call.Argument(1)
+		if ta.lengthTracking && endArg == _undefined {
+			return r.typedArraySpeciesCreate(ta, []Value{ta.viewedArrayBuf.val, beginByteOffset}).val
+		}
 		var relEnd int64
-		if endArg := call.Argument(1); endArg != _undefined {
+		if endArg != _undefined {
 			relEnd = endArg.ToInteger()
 		} else {
 			relEnd = l
@@ -1163,7 +1369,7 @@ func (r *Runtime) typedArrayProto_subarray(call FunctionCall) Value {
 		endIdx := relToIdx(relEnd, l)
 		newLen := max(endIdx-beginIdx, 0)
 		return r.typedArraySpeciesCreate(ta, []Value{ta.viewedArrayBuf.val,
-			intToValue((int64(ta.offset) + beginIdx) * int64(ta.elemSize)),
+			beginByteOffset,
 			intToValue(newLen),
 		}).val
 	}
@@ -1172,15 +1378,13 @@ func (r *Runtime) typedArrayProto_subarray(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_toLocaleString(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		length := ta.length
+		length := ta.validate()
 		var buf StringBuilder
 		for i := 0; i < length; i++ {
-			ta.viewedArrayBuf.ensureNotDetached(true)
 			if i > 0 {
 				buf.WriteRune(',')
 			}
-			item := ta.typedArray.get(ta.offset + i)
-			r.writeItemLocaleString(item, &buf)
+			r.writeItemLocaleString(ta._getIdx(i), &buf)
 		}
 		return buf.String()
 	}
@@ -1189,7 +1393,7 @@ func (r *Runtime) typedArrayProto_toLocaleString(call FunctionCall) Value {
 
 func (r *Runtime) typedArrayProto_values(call FunctionCall) Value {
 	if ta, ok := r.toObject(call.This).self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		ta.validate()
 		return r.createArrayIterator(ta.val, iterationKindValue)
 	}
 	panic(r.NewTypeError("Method TypedArray.prototype.values called on incompatible receiver %s", r.objectproto_toString(FunctionCall{This: call.This})))
@@ -1211,18 +1415,14 @@ func (r *Runtime) typedArrayProto_with(call FunctionCall) Value {
 	if !ok {
 		panic(r.NewTypeError("%s is not a valid TypedArray", r.objectproto_toString(FunctionCall{This: call.This})))
 	}
-	ta.viewedArrayBuf.ensureNotDetached(true)
-	length := ta.length
+	length := ta.validate()
 	relativeIndex := call.Argument(0).ToInteger()
 	var actualIndex int
 
 	if relativeIndex >= 0 {
-		actualIndex = toIntStrict(relativeIndex)
+		actualIndex = toIntClamp(relativeIndex)
 	} else {
-		actualIndex = toIntStrict(int64(length) + relativeIndex)
-	}
-	if !ta.isValidIntegerIndex(actualIndex) {
-		panic(r.newError(r.getRangeError(), "Invalid typed array index"))
+		actualIndex = toIntClamp(int64(length) + relativeIndex)
 	}
 
 	var numericValue Value
@@ -1233,15 +1433,19 @@ func (r *Runtime) typedArrayProto_with(call FunctionCall) Value {
 		numericValue = call.Argument(1).ToNumber()
 	}
 
+	if !ta.isValidIntegerIndex(actualIndex) {
+		panic(r.newError(r.getRangeError(), "Invalid typed array index"))
+	}
+
 	a := r.typedArrayCreate(ta.defaultCtor, intToValue(int64(length)))
 	for k := 0; k < length; k++ {
 		var fromValue Value
 		if k == actualIndex {
 			fromValue = numericValue
 		} else {
-			fromValue = ta.typedArray.get(ta.offset + k)
+			fromValue = nilSafe(ta._getIdx(k))
 		}
-		a.typedArray.set(ta.offset+k, fromValue)
+		a._putIdx(k, fromValue)
 	}
 	return a.val
 }
@@ -1252,15 +1456,14 @@ func (r *Runtime) typedArrayProto_toReversed(call FunctionCall) Value {
 	if !ok {
 		panic(r.NewTypeError("%s is not a valid TypedArray", r.objectproto_toString(FunctionCall{This: call.This})))
 	}
-	ta.viewedArrayBuf.ensureNotDetached(true)
-	length := ta.length
+	length := ta.validate()
 
 	a := r.typedArrayCreate(ta.defaultCtor, intToValue(int64(length)))
 
 	for k := 0; k < length; k++ {
 		from := length - k - 1
 		fromValue := ta.typedArray.get(ta.offset + from)
-		a.typedArray.set(ta.offset+k, fromValue)
+		a.typedArray.set(a.offset+k, fromValue)
 	}
 
 	return a.val
@@ -1272,7 +1475,7 @@ func (r *Runtime) typedArrayProto_toSorted(call FunctionCall) Value {
 	if !ok {
 		panic(r.NewTypeError("%s is not a valid TypedArray", r.objectproto_toString(FunctionCall{This: call.This})))
 	}
-	ta.viewedArrayBuf.ensureNotDetached(true)
+	length := ta.validate()
 
 	var compareFn func(FunctionCall) Value
 	arg := call.Argument(0)
@@ -1285,17 +1488,10 @@ func (r *Runtime) typedArrayProto_toSorted(call FunctionCall) Value {
 		}
 	}
 
-	length := ta.length
-
 	a := r.typedArrayCreate(ta.defaultCtor, intToValue(int64(length)))
-	copy(a.viewedArrayBuf.data, ta.viewedArrayBuf.data)
+	copy(a.viewedArrayBuf.data[a.offset*a.elemSize:], ta.viewedArrayBuf.data[ta.offset*ta.elemSize:(ta.offset+length)*ta.elemSize])
 
-	ctx := typedArraySortCtx{
-		ta:      a,
-		compare: compareFn,
-	}
-
-	sort.Stable(&ctx)
+	r.typedArraySort(a, length, compareFn)
 
 	return a.val
 }
@@ -1365,7 +1561,7 @@ func (r *Runtime) allocateTypedArray(newTarget *Object, length int, taCtor typed
 	buf := r._newArrayBuffer(r.getArrayBufferPrototype(), nil)
 	ta := taCtor(buf, 0, length, r.getPrototypeFromCtor(newTarget, nil, proto))
 	if length > 0 {
-		buf.data = allocByteSlice(length * ta.elemSize)
+		buf.data = r.allocBuffer(length * ta.elemSize)
 	}
 	return ta
 }
@@ -1377,10 +1573,12 @@ func (r *Runtime) typedArraySpeciesCreate(ta *typedArrayObject, args []Value) *t
 func (r *Runtime) typedArrayCreate(ctor *Object, args ...Value) *typedArrayObject {
 	o := r.toConstructor(ctor)(args, ctor)
 	if ta, ok := o.self.(*typedArrayObject); ok {
-		ta.viewedArrayBuf.ensureNotDetached(true)
+		length := ta.validate()
 		if len(args) == 1 {
 			if l, ok := args[0].(valueInt); ok {
-				if ta.length < int(l) {
+				// the array is created to be written into
+				ta.viewedArrayBuf.ensureMutable()
+				if length < int(l) {
 					panic(r.NewTypeError("Derived TypedArray constructor created an array which was too small"))
 				}
 			}
@@ -1455,6 +1653,12 @@ func (r *Runtime) _newTypedArrayFromArrayBuffer(ab *arrayBufferObject, args []Va
 		if byteOffset+length*ta.elemSize > len(ab.data) {
 			panic(r.newError(r.getRangeError(), "Invalid typed array length: %d", length))
 		}
+	} else if ab.maxByteLen >= 0 {
+		ab.ensureNotDetached(true)
+		if byteOffset > len(ab.data) {
+			panic(r.newError(r.getRangeError(), "Start offset %d is outside the bounds of the buffer", byteOffset))
+		}
+		ta.lengthTracking = true
 	} else {
 		ab.ensureNotDetached(true)
 		if len(ab.data)%ta.elemSize != 0 {
@@ -1481,14 +1685,12 @@ func checkTypedArrayMixBigInt(src, dst *Object) {
 
 func (r *Runtime) _newTypedArrayFromTypedArray(src *typedArrayObject, newTarget *Object, taCtor typedArrayObjectCtor, proto *Object) *Object {
 	dst := r.allocateTypedArray(newTarget, 0, taCtor, proto)
-	src.viewedArrayBuf.ensureNotDetached(true)
-	l := src.length
+	l := src.validate()
 
-	dst.viewedArrayBuf.data = allocByteSlice(toIntStrict(int64(l) * int64(dst.elemSize)))
-	src.viewedArrayBuf.ensureNotDetached(true)
+	dst.viewedArrayBuf.data = r.allocBuffer(toIntStrict(int64(l) * int64(dst.elemSize)))
 	if src.defaultCtor == dst.defaultCtor {
 		copy(dst.viewedArrayBuf.data, src.viewedArrayBuf.data[src.offset*src.elemSize:])
-		dst.length = src.length
+		dst.length = l
 		return dst.val
 	} else {
 		checkTypedArrayMixBigInt(src.defaultCtor, newTarget)
@@ -1553,6 +1755,10 @@ func (r *Runtime) newInt32Array(args []Value, newTarget, proto *Object) *Object
 	return r._newTypedArray(args, newTarget, r.newInt32ArrayObject, proto)
 }
 
+func (r *Runtime) newFloat16Array(args []Value, newTarget, proto *Object) *Object {
+	return r._newTypedArray(args, newTarget, r.newFloat16ArrayObject, proto)
+}
+
 func (r *Runtime) newFloat32Array(args []Value, newTarget, proto *Object) *Object {
 	return r._newTypedArray(args, newTarget, r.newFloat32ArrayObject, proto)
 }
@@ -1569,6 +1775,150 @@ func (r *Runtime) newBigUint64Array(args []Value, newTarget, proto *Object) *Obj
 	return r._newTypedArray(args, newTarget, r.newBigUint64ArrayObject, proto)
 }
 
+func (r *Runtime) toUint8Array(v Value, method string) *typedArrayObject {
+	if obj, ok := v.(*Object); ok {
+		if ta, ok := obj.self.(*typedArrayObject); ok {
+			if _, ok := ta.typedArray.(*uint8Array); ok {
+				return ta
+			}
+		}
+	}
+	panic(r.NewTypeError("Method Uint8Array.prototype.%s called on incompatible receiver %s", method, r.objectproto_toString(FunctionCall{This: v})))
+}
+
+func (r *Runtime) base64OptionsObject(v Value) *Object {
+	switch v := v.(type) {
+	case valueUndefined:
+		return nil
+	case *Object:
+		return v
+	}
+	panic(r.NewTypeError("options must be an object"))
+}
+
+// base64Alphabet returns true if the "alphabet" option is "base64url".
+func (r *Runtime) base64Alphabet(opts *Object) bool {
+	if opts == nil {
+		return false
+	}
+	switch a := nilSafe(opts.self.getStr("alphabet", nil)); {
+	case a == _undefined, a.StrictEquals(asciiString("base64")):
+		return false
+	case a.StrictEquals(asciiString("base64url")):
+		return true
+	}
+	panic(r.NewTypeError("alphabet must be either 'base64' or 'base64url'"))
+}
+
+func (r *Runtime) base64LastChunkHandling(opts *Object) base64LastChunkHandling {
+	if opts == nil {
+		return base64LastChunkLoose
+	}
+	switch h := nilSafe(opts.self.getStr("lastChunkHandling", nil)); {
+	case h == _undefined, h.StrictEquals(asciiString("loose")):
+		return base64LastChunkLoose
+	case h.StrictEquals(asciiString("strict")):
+		return base64LastChunkStrict
+	case h.StrictEquals(asciiString("stop-before-partial")):
+		return base64LastChunkStopBeforePartial
+	}
+	panic(r.NewTypeError("lastChunkHandling must be one of 'loose', 'strict' or 'stop-before-partial'"))
+}
+
+func (r *Runtime) toBase64Input(v Value) String {
+	if s, ok := v.(String); ok {
+		return s
+	}
+	panic(r.NewTypeError("Argument must be a string"))
+}
+
+func (r *Runtime) newUint8ArrayFromBytes(data []byte) *Object {
+	ta := r.allocateTypedArray(r.getUint8Array(), len(data), r.newUint8ArrayObject, nil)
+	copy(ta.viewedArrayBuf.data, data)
+	return ta.val
+}
+
+// setUint8ArrayBytes writes the bytes decoded by setFromBase64() or setFromHex() and returns
+// the result object or throws a SyntaxError if decoding has failed.
+func (r *Runtime) setUint8ArrayBytes(ta *typedArrayObject, read int, data []byte, errMsg string) Value {
+	copy(ta.viewedArrayBuf.data[ta.offset:], data)
+	if errMsg != "" {
+		panic(r.newError(r.getSyntaxError(), "%s", errMsg))
+	}
+	res := r.NewObject()
+	res.self._putProp("read", intToValue(int64(read)), true, true, true)
+	res.self._putProp("written", intToValue(int64(len(data))), true, true, true)
+	return res
+}
+
+func (r *Runtime) uint8Array_fromBase64(call FunctionCall) Value {
+	s := r.toBase64Input(call.Argument(0))
+	opts := r.base64OptionsObject(call.Argument(1))
+	url := r.base64Alphabet(opts)
+	lastChunkHandling := r.base64LastChunkHandling(opts)
+	_, data, errMsg := fromBase64(s, url, lastChunkHandling, maxInt)
+	if errMsg != "" {
+		panic(r.newError(r.getSyntaxError(), "%s", errMsg))
+	}
+	return r.newUint8ArrayFromBytes(data)
+}
+
+func (r *Runtime) uint8Array_fromHex(call FunctionCall) Value {
+	s := r.toBase64Input(call.Argument(0))
+	_, data, errMsg := fromHex(s, maxInt)
+	if errMsg != "" {
+		panic(r.newError(r.getSyntaxError(), "%s", errMsg))
+	}
+	return r.newUint8ArrayFromBytes(data)
+}
+
+func (r *Runtime) uint8ArrayProto_setFromBase64(call FunctionCall) Value {
+	ta := r.toUint8Array(call.This, "setFromBase64")
+	s := r.toBase64Input(call.Argument(0))
+	opts := r.base64OptionsObject(call.Argument(1))
+	url := r.base64Alphabet(opts)
+	lastChunkHandling := r.base64LastChunkHandling(opts)
+	read, data, errMsg := fromBase64(s, url, lastChunkHandling, ta.validateWritable())
+	return r.setUint8ArrayBytes(ta, read, data, errMsg)
+}
+
+func (r *Runtime) uint8ArrayProto_setFromHex(call FunctionCall) Value {
+	ta := r.toUint8Array(call.This, "setFromHex")
+	s := r.toBase64Input(call.Argument(0))
+	read, data, errMsg := fromHex(s, ta.validateWritable())
+	return r.setUint8ArrayBytes(ta, read, data, errMsg)
+}
+
+func (r *Runtime) uint8ArrayProto_toBase64(call FunctionCall) Value {
+	ta := r.toUint8Array(call.This, "toBase64")
+	opts := r.base64OptionsObject(call.Argument(0))
+	url := r.base64Alphabet(opts)
+	var omitPadding bool
+	if opts != nil {
+		omitPadding = nilSafe(opts.self.getStr("omitPadding", nil)).ToBoolean()
+	}
+	length := ta.validate()
+	enc := base64.StdEncoding
+	if url {
+		enc = base64.URLEncoding
+	}
+	if omitPadding {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+	return asciiString(enc.EncodeToString(ta.viewedArrayBuf.data[ta.offset : ta.offset+length]))
+}
+
+func (r *Runtime) uint8ArrayProto_toHex(call FunctionCall) Value {
+	ta := r.toUint8Array(call.This, "toHex")
+	length := ta.validate()
+	data := ta.viewedArrayBuf.data[ta.offset : ta.offset+length]
+	res := make([]byte, 0, len(data)*2)
+	for _, b := range data {
+		res = append(res, hex[b>>4], hex[b&0xF])
+	}
+	return asciiString(res)
+}
+
 func (r *Runtime) createArrayBufferProto(val *Object) objectImpl {
 	b := newBaseObjectObj(val, r.global.ObjectPrototype, classObject)
 	byteLengthProp := &valueProperty{
@@ -1578,7 +1928,32 @@ func (r *Runtime) createArrayBufferProto(val *Object) objectImpl {
 	}
 	b._put("byteLength", byteLengthProp)
 	b._putProp("constructor", r.getArrayBuffer(), true, false, true)
+	b._put("detached", &valueProperty{
+		accessor:     true,
+		configurable: true,
+		getterFunc:   r.newNativeFunc(r.arrayBufferProto_getDetached, "get detached", 0),
+	})
+	b._put("immutable", &valueProperty{
+		accessor:     true,
+		configurable: true,
+		getterFunc:   r.newNativeFunc(r.arrayBufferProto_getImmutable, "get immutable", 0),
+	})
+	b._put("maxByteLength", &valueProperty{
+		accessor:     true,
+		configurable: true,
+		getterFunc:   r.newNativeFunc(r.arrayBufferProto_getMaxByteLength, "get maxByteLength", 0),
+	})
+	b._put("resizable", &valueProperty{
+		accessor:     true,
+		configurable: true,
+		getterFunc:   r.newNativeFunc(r.arrayBufferProto_getResizable, "get resizable", 0),
+	})
+	b._putProp("resize", r.newNativeFunc(r.arrayBufferProto_resize, "resize", 1), true, false, true)
 	b._putProp("slice", r.newNativeFunc(r.arrayBufferProto_slice, "slice", 2), true, false, true)
+	b._putProp("transfer", r.newNativeFunc(r.arrayBufferProto_transfer, "transfer", 0), true, false, true)
+	b._putProp("transferToFixedLength", r.newNativeFunc(r.arrayBufferProto_transferToFixedLength, "transferToFixedLength", 0), true, false, true)
+	b._putProp("transferToImmutable", r.newNativeFunc(r.arrayBufferProto_transferToImmutable, "transferToImmutable", 0), true, false, true)
+	b._putProp("sliceToImmutable", r.newNativeFunc(r.arrayBufferProto_sliceToImmutable, "sliceToImmutable", 2), true, false, true)
 	b._putSym(SymToStringTag, valueProp(asciiString("ArrayBuffer"), false, false, true))
 	return b
 }
@@ -1615,7 +1990,7 @@ func (r *Runtime) getTypedArray() *Object {
 	return ret
 }
 
-func (r *Runtime) createTypedArrayCtor(val *Object, ctor func(args []Value, newTarget, proto *Object) *Object, name unistring.String, bytesPerElement int) {
+func (r *Runtime) createTypedArrayCtor(val *Object, ctor func(args []Value, newTarget, proto *Object) *Object, name unistring.String, bytesPerElement int) (*nativeFuncObject, *baseObject) {
 	p := r.newBaseObject(r.getTypedArrayPrototype(), classObject)
 	o := r.newNativeConstructOnly(val, func(args []Value, newTarget *Object) *Object {
 		return ctor(args, newTarget, p.val)
@@ -1627,6 +2002,7 @@ func (r *Runtime) createTypedArrayCtor(val *Object, ctor func(args []Value, newT
 	bpe := intToValue(int64(bytesPerElement))
 	o._putProp("BYTES_PER_ELEMENT", bpe, false, false, false)
 	p._putProp("BYTES_PER_ELEMENT", bpe, false, false, false)
+	return o, p
 }
 
 func addTypedArrays(t *objectTemplate) {
@@ -1639,6 +2015,7 @@ func addTypedArrays(t *objectTemplate) {
 	t.putStr("Int16Array", func(r *Runtime) Value { return valueProp(r.getInt16Array(), true, false, true) })
 	t.putStr("Uint32Array", func(r *Runtime) Value { return valueProp(r.getUint32Array(), true, false, true) })
 	t.putStr("Int32Array", func(r *Runtime) Value { return valueProp(r.getInt32Array(), true, false, true) })
+	t.putStr("Float16Array", func(r *Runtime) Value { return valueProp(r.getFloat16Array(), true, false, true) })
 	t.putStr("Float32Array", func(r *Runtime) Value { return valueProp(r.getFloat32Array(), true, false, true) })
 	t.putStr("Float64Array", func(r *Runtime) Value { return valueProp(r.getFloat64Array(), true, false, true) })
 	t.putStr("BigInt64Array", func(r *Runtime) Value { return valueProp(r.getBigInt64Array(), true, false, true) })
@@ -1761,7 +2138,13 @@ func (r *Runtime) getUint8Array() *Object {
 	if ret == nil {
 		ret = &Object{runtime: r}
 		r.global.Uint8Array = ret
-		r.createTypedArrayCtor(ret, r.newUint8Array, "Uint8Array", 1)
+		o, p := r.createTypedArrayCtor(ret, r.newUint8Array, "Uint8Array", 1)
+		o._putProp("fromBase64", r.newNativeFunc(r.uint8Array_fromBase64, "fromBase64", 1), true, false, true)
+		o._putProp("fromHex", r.newNativeFunc(r.uint8Array_fromHex, "fromHex", 1), true, false, true)
+		p._putProp("setFromBase64", r.newNativeFunc(r.uint8ArrayProto_setFromBase64, "setFromBase64", 1), true, false, true)
+		p._putProp("setFromHex", r.newNativeFunc(r.uint8ArrayProto_setFromHex, "setFromHex", 1), true, false, true)
+		p._putProp("toBase64", r.newNativeFunc(r.uint8ArrayProto_toBase64, "toBase64", 0), true, false, true)
+		p._putProp("toHex", r.newNativeFunc(r.uint8ArrayProto_toHex, "toHex", 0), true, false, true)
 	}
 	return ret
 }
@@ -1826,6 +2209,16 @@ func (r *Runtime) getInt32Array() *Object {
 	return ret
 }
 
+func (r *Runtime) getFloat16Array() *Object {
+	ret := r.global.Float16Array
+	if ret == nil {
+		ret = &Object{runtime: r}
+		r.global.Float16Array = ret
+		r.createTypedArrayCtor(ret, r.newFloat16Array, "Float16Array", 2)
+	}
+	return ret
+}
+
 func (r *Runtime) getFloat32Array() *Object {
 	ret := r.global.Float32Array
 	if ret == nil {
@@ -1896,6 +2289,7 @@ func createDataViewProtoTemplate() *objectTemplate {
 
 	t.putStr("constructor", func(r *Runtime) Value { return valueProp(r.getDataView(), true, false, true) })
 
+	t.putStr("getFloat16", func(r *Runtime) Value { return r.methodProp(r.dataViewProto_getFloat16, "getFloat16", 1) })
 	t.putStr("getFloat32", func(r *Runtime) Value { return r.methodProp(r.dataViewProto_getFloat32, "getFloat32", 1) })
 	t.putStr("getFloat64", func(r *Runtime) Value { return r.methodProp(r.dataViewProto_getFloat64, "getFloat64", 1) })
 	t.putStr("getInt8", func(r *Runtime) Value { return r.methodProp(r.dataViewProto_getInt8, "getInt8", 1) })
@@ -1906,6 +2300,7 @@ func createDataViewProtoTemplate() *objectTemplate {
 	t.putStr("getUint32", func(r *Runtime) Value { return r.methodProp(r.dataViewProto_getUint32, "getUint32", 1) })
 	t.putStr("getBigInt64", func(r *Runtime) Value { return r.methodProp(r.dataViewProto_getBigInt64, "getBigInt64", 1) })
 	t.putStr("getBigUint64", func(r *Runtime) Value { return r.methodProp(r.dataViewProto_getBigUint64, "getBigUint64", 1) })
+	t.putStr("setFloat16", func(r *Runtime) Value { return r.methodProp(r.dataViewProto_setFloat16, "setFloat16", 2) })
 	t.putStr("setFloat32", func(r *Runtime) Value { return r.methodProp(r.dataViewProto_setFloat32, "setFloat32", 2) })
 	t.putStr("setFloat64", func(r *Runtime) Value { return r.methodProp(r.dataViewProto_setFloat64, "setFloat64", 2) })
 	t.putStr("setInt8", func(r *Runtime) Value { return r.methodProp(r.dataViewProto_setInt8, "setInt8", 2) })
@@ -383,3 +383,351 @@ func TestTypedArraySubarraySet(t *testing.T) {
 
 	testScript(SCRIPT, _undefined, t)
 }
+
+func TestFloat16Array(t *testing.T) {
+	const SCRIPT = `
+	const a = new Float16Array([1.337, 65504, 65520, 5.960464477539063e-8, 2.9802322387695312e-8, -0, NaN, 2049, 2051]);
+	assert.sameValue(Float16Array.BYTES_PER_ELEMENT, 2);
+	assert.sameValue(a[0], 1.3369140625);
+	assert.sameValue(a[1], 65504);
+	assert.sameValue(a[2], Infinity);
+	assert.sameValue(a[3], 5.960464477539063e-8, "smallest subnormal");
+	assert.sameValue(a[4], 0, "ties to even");
+	assert.sameValue(1 / a[5], -Infinity);
+	assert.sameValue(a[6], NaN);
+	assert.sameValue(a[7], 2048, "ties to even");
+	assert.sameValue(a[8], 2052, "ties to even");
+
+	assert.sameValue(Math.f16round(5.5), 5.5);
+	assert.sameValue(Math.f16round(5.05), 5.05078125);
+	assert.sameValue(Math.f16round(1.00048828125), 1, "ties to even");
+	assert.sameValue(Math.f16round(1 + 2 ** -11 + 2 ** -40), 1 + 2 ** -10, "no double rounding through float32");
+	assert.sameValue(Math.f16round(-1e10), -Infinity);
+
+	const dv = new DataView(new ArrayBuffer(4));
+	dv.setFloat16(1, 1.5);
+	assert.sameValue(dv.getUint16(1), 0x3E00);
+	assert.sameValue(dv.getFloat16(1), 1.5);
+	dv.setFloat16(0, -2, true);
+	assert.sameValue(dv.getUint16(0, true), 0xC000);
+	assert.sameValue(dv.getFloat16(0, true), -2);
+	assert.throws(RangeError, () => dv.getFloat16(3));
+
+	const sorted = new Float16Array([3, NaN, -0, 0, -1]).sort();
+	assert.sameValue(sorted.join(), "-1,0,0,3,NaN");
+	assert.sameValue(1 / sorted[1], -Infinity);
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestFloat16ArrayExport(t *testing.T) {
+	vm := New()
+	v, err := vm.RunString(`new Float16Array([1, -2, 0.5])`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp, ok := v.Export().([]uint16)
+	if !ok {
+		t.Fatalf("unexpected export type: %T", v.Export())
+	}
+	if len(exp) != 3 || exp[0] != 0x3C00 || exp[1] != 0xC000 || exp[2] != 0x3800 {
+		t.Fatalf("unexpected value: %x", exp)
+	}
+}
+
+func TestUint8ArrayBase64(t *testing.T) {
+	const SCRIPT = `
+	const bytes = new Uint8Array([251, 255, 191, 0, 1]);
+	assert.sameValue(bytes.toBase64(), "+/+/AAE=");
+	assert.sameValue(bytes.toBase64({alphabet: "base64url", omitPadding: true}), "-_-_AAE");
+	assert.sameValue(bytes.toHex(), "fbffbf0001");
+	assert.sameValue(bytes.subarray(1, 3).toBase64(), "/78=");
+
+	assert(compareArray(Uint8Array.fromBase64(" +/+/ AAE= "), [251, 255, 191, 0, 1]));
+	assert(compareArray(Uint8Array.fromBase64("-_-_AAE", {alphabet: "base64url"}), [251, 255, 191, 0, 1]));
+	assert(compareArray(Uint8Array.fromHex("FBffbf0001"), [251, 255, 191, 0, 1]));
+	assert(compareArray(Uint8Array.fromBase64("ZXhhZg"), [101, 120, 97, 102]), "loose allows missing padding");
+
+	assert.throws(SyntaxError, () => Uint8Array.fromBase64("ZXhhZg", {lastChunkHandling: "strict"}));
+	assert.throws(SyntaxError, () => Uint8Array.fromBase64("ZXhhZh==", {lastChunkHandling: "strict"}), "non-zero padding bits");
+	assert(compareArray(Uint8Array.fromBase64("ZXhhZh==", {lastChunkHandling: "loose"}), [101, 120, 97, 102]));
+	assert(compareArray(Uint8Array.fromBase64("ZXhhZg", {lastChunkHandling: "stop-before-partial"}), [101, 120, 97]));
+	assert.throws(SyntaxError, () => Uint8Array.fromBase64("-_", {alphabet: "base64"}));
+	assert.throws(SyntaxError, () => Uint8Array.fromBase64("Z"));
+	assert.throws(SyntaxError, () => Uint8Array.fromHex("abc"));
+	assert.throws(SyntaxError, () => Uint8Array.fromHex("zz"));
+	assert.throws(TypeError, () => Uint8Array.fromBase64(1));
+	assert.throws(TypeError, () => Uint8Array.fromBase64("", {alphabet: "other"}));
+	assert.throws(TypeError, () => Uint8Array.fromBase64("", {lastChunkHandling: "other"}));
+	assert.throws(TypeError, () => Uint8Array.prototype.toHex.call(new Uint8ClampedArray(1)));
+
+	const target = new Uint8Array(4);
+	let res = target.setFromBase64("ZXhhZg==");
+	assert.sameValue(res.read, 8);
+	assert.sameValue(res.written, 4);
+	assert(compareArray(target, [101, 120, 97, 102]));
+
+	const small = new Uint8Array(2);
+	res = small.setFromBase64("ZXhhZg==");
+	assert.sameValue(res.read, 0, "a partial chunk is not consumed");
+	assert.sameValue(res.written, 0);
+	res = new Uint8Array(3).setFromBase64("ZXhhZg==");
+	assert.sameValue(res.read, 4);
+	assert.sameValue(res.written, 3);
+
+	res = small.setFromHex("0102030405");
+	assert.sameValue(res.read, 4);
+	assert.sameValue(res.written, 2);
+	assert(compareArray(small, [1, 2]));
+
+	const partial = new Uint8Array(4);
+	assert.throws(SyntaxError, () => partial.setFromHex("aabb!!"));
+	assert(compareArray(partial, [0xaa, 0xbb, 0, 0]), "bytes decoded before the error are written");
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestResizableArrayBuffer(t *testing.T) {
+	const SCRIPT = `
+	const rab = new ArrayBuffer(4, {maxByteLength: 16});
+	assert.sameValue(rab.resizable, true);
+	assert.sameValue(rab.maxByteLength, 16);
+	assert.sameValue(new ArrayBuffer(4).resizable, false);
+	assert.sameValue(new ArrayBuffer(4).maxByteLength, 4);
+	assert.throws(RangeError, () => new ArrayBuffer(8, {maxByteLength: 4}));
+	assert.throws(TypeError, () => new ArrayBuffer(4).resize(2));
+
+	const tracking = new Uint16Array(rab);
+	const fixed = new Uint8Array(rab, 1, 2);
+	const dv = new DataView(rab, 2);
+	tracking[1] = 0x1234;
+	assert.sameValue(tracking.length, 2);
+
+	rab.resize(10);
+	assert.sameValue(rab.byteLength, 10);
+	assert.sameValue(tracking.length, 5);
+	assert.sameValue(tracking[1], 0x1234, "data is preserved");
+	assert.sameValue(tracking[4], 0, "new bytes are zeroed");
+	assert.sameValue(dv.byteLength, 8);
+	assert.sameValue(fixed.length, 2);
+	assert.throws(RangeError, () => rab.resize(17));
+
+	rab.resize(2);
+	assert.sameValue(tracking.length, 1);
+	assert.sameValue(dv.byteLength, 0);
+	assert.sameValue(fixed.length, 0, "out of bounds");
+	assert.sameValue(fixed.byteOffset, 0);
+	assert.sameValue(fixed[0], undefined);
+	assert.throws(TypeError, () => fixed.fill(0));
+	assert.throws(TypeError, () => [...fixed]);
+
+	rab.resize(1);
+	assert.sameValue(tracking.length, 0);
+	assert.throws(TypeError, () => dv.byteLength, "offset is out of bounds");
+
+	rab.resize(6);
+	assert.sameValue(fixed.length, 2, "back in bounds");
+	assert.sameValue(fixed[1], 0, "shrunk bytes are zeroed when the buffer grows");
+	assert(compareArray(tracking.subarray(1), [0, 0]));
+	const sub = tracking.subarray(1);
+	rab.resize(8);
+	assert.sameValue(sub.length, 3, "subarray() without end tracks the length");
+
+	const ta = new Uint8Array(rab);
+	ta.set([1, 2, 3, 4, 5, 6, 7, 8]);
+	const res = ta.map((x, i) => {
+		if (i === 0) {
+			rab.resize(4);
+		}
+		return x;
+	});
+	assert(compareArray(res, [1, 2, 3, 4, 0, 0, 0, 0]));
+	assert.sameValue(ta.slice(1, 3).join(), "2,3");
+	assert.sameValue(ta.includes(undefined), false);
+	assert.sameValue(ta.includes(undefined, {valueOf() { rab.resize(2); return 0; }}), true);
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestTypedArraySortShrink(t *testing.T) {
+	const SCRIPT = `
+	const rab = new ArrayBuffer(8, {maxByteLength: 8});
+	const ta = new Uint8Array(rab);
+	ta.set([8, 7, 6, 5, 4, 3, 2, 1]);
+	let max = 0;
+	ta.sort((a, b) => {
+		if (rab.byteLength === 8) {
+			rab.resize(3);
+		}
+		max = Math.max(max, a, b);
+		return a - b;
+	});
+	assert.sameValue(max, 8, "the comparison function gets the values read before the sorting");
+	assert.sameValue(ta.join(), "1,2,3", "only the indices in bounds are written");
+	rab.resize(8);
+	assert.sameValue(ta.join(), "1,2,3,0,0,0,0,0");
+
+	const detached = new Uint8Array([3, 2, 1]);
+	detached.sort((a, b) => {
+		if (detached.length > 0) {
+			detached.buffer.transfer();
+		}
+		return a - b;
+	});
+	assert.sameValue(detached.length, 0);
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestResizableArrayBufferBytes(t *testing.T) {
+	vm := New()
+	v, err := vm.RunString(`
+	var rab = new ArrayBuffer(2, {maxByteLength: 1024});
+	new Uint8Array(rab).set([1, 2]);
+	rab;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab := v.Export().(ArrayBuffer)
+	if !bytes.Equal(ab.Bytes(), []byte{1, 2}) {
+		t.Fatal(ab.Bytes())
+	}
+	_, err = vm.RunString(`
+	rab.resize(4);
+	new Uint8Array(rab)[3] = 4;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ab.Bytes(), []byte{1, 2, 0, 4}) {
+		t.Fatal(ab.Bytes())
+	}
+}
+
+func TestArrayBufferTransfer(t *testing.T) {
+	const SCRIPT = `
+	const buf = new ArrayBuffer(4, {maxByteLength: 8});
+	new Uint8Array(buf).set([1, 2, 3, 4]);
+	const view = new Uint8Array(buf);
+	const moved = buf.transfer();
+	assert.sameValue(buf.detached, true);
+	assert.sameValue(buf.byteLength, 0);
+	assert.sameValue(view.length, 0);
+	assert.sameValue(moved.detached, false);
+	assert.sameValue(moved.resizable, true);
+	assert.sameValue(moved.maxByteLength, 8);
+	assert(compareArray(new Uint8Array(moved), [1, 2, 3, 4]));
+	assert.throws(TypeError, () => buf.transfer());
+
+	const grown = moved.transfer(6);
+	assert(compareArray(new Uint8Array(grown), [1, 2, 3, 4, 0, 0]));
+	assert.throws(RangeError, () => grown.transfer(9));
+	assert.sameValue(grown.detached, false, "not detached when the length is invalid");
+
+	const fixed = grown.transferToFixedLength(2);
+	assert.sameValue(fixed.resizable, false);
+	assert(compareArray(new Uint8Array(fixed), [1, 2]));
+	const bigger = fixed.transferToFixedLength(3);
+	assert(compareArray(new Uint8Array(bigger), [1, 2, 0]));
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestArrayBufferTransferZeroCopy(t *testing.T) {
+	vm := New()
+	data := []byte{1, 2, 3, 4}
+	vm.Set("buf", vm.NewArrayBuffer(data))
+	v, err := vm.RunString(`buf.transfer(2)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := v.Export().(ArrayBuffer).Bytes()
+	if len(moved) != 2 || &moved[0] != &data[0] {
+		t.Fatal("the backing slice was not re-used")
+	}
+	if vm.Get("buf").Export().(ArrayBuffer).Detached() != true {
+		t.Fatal("source is not detached")
+	}
+}
+
+func TestImmutableArrayBuffer(t *testing.T) {
+	const SCRIPT = `
+	const src = new ArrayBuffer(4);
+	new Uint8Array(src).set([1, 2, 3, 4]);
+	const buf = src.transferToImmutable();
+	assert.sameValue(src.detached, true);
+	assert.sameValue(buf.immutable, true);
+	assert.sameValue(buf.resizable, false);
+	assert.sameValue(new ArrayBuffer(1).immutable, false);
+
+	const ta = new Uint8Array(buf);
+	assert(compareArray(ta, [1, 2, 3, 4]));
+	ta[0] = 10;
+	assert.sameValue(ta[0], 1, "sloppy mode assignment is ignored");
+	assert.throws(TypeError, () => { "use strict"; ta[0] = 10; });
+	const desc = Object.getOwnPropertyDescriptor(ta, "0");
+	assert.sameValue(desc.writable, false);
+	assert.sameValue(desc.configurable, false);
+	assert.throws(TypeError, () => Object.defineProperty(ta, "0", {value: 5}));
+	Object.defineProperty(ta, "0", {value: 1});
+	Object.freeze(ta);
+	assert(Object.isFrozen(ta));
+
+	assert.throws(TypeError, () => ta.fill(0));
+	assert.throws(TypeError, () => ta.set([0]));
+	assert.throws(TypeError, () => ta.reverse());
+	assert.throws(TypeError, () => ta.sort());
+	assert.throws(TypeError, () => ta.copyWithin(0, 1));
+	assert.throws(TypeError, () => ta.setFromHex("00"));
+	assert.throws(TypeError, () => new DataView(buf).setUint8(0, 1));
+	assert.throws(TypeError, () => buf.transfer());
+	assert.throws(TypeError, () => buf.resize(1));
+	assert.sameValue(buf.detached, false);
+
+	assert(compareArray(ta.map(x => x * 2), [2, 4, 6, 8]), "derived arrays are mutable");
+	assert(compareArray(ta.toReversed(), [4, 3, 2, 1]));
+	assert.sameValue(ta.subarray(1).buffer, buf);
+	assert.sameValue(new DataView(buf).getUint8(3), 4);
+	assert.sameValue(new Uint8Array(buf.slice(1)).join(), "2,3,4");
+
+	const sliced = buf.sliceToImmutable(1, 3);
+	assert.sameValue(sliced.immutable, true);
+	assert(compareArray(new Uint8Array(sliced), [2, 3]));
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestNewImmutableArrayBuffer(t *testing.T) {
+	vm := New()
+	data := []byte{1, 2, 3}
+	ab := vm.NewImmutableArrayBuffer(data)
+	vm.Set("buf", ab)
+	_, err := vm.RunString(`
+	"use strict";
+	const ta = new Uint8Array(buf);
+	if (ta[2] !== 3) {
+		throw new Error("unexpected value: " + ta[2]);
+	}
+	try {
+		ta[0] = 0;
+		throw new Error("assignment did not throw");
+	} catch (e) {
+		if (!(e instanceof TypeError)) {
+			throw e;
+		}
+	}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 1 {
+		t.Fatal("data was modified")
+	}
+	if ab.Detach() {
+		t.Fatal("immutable buffer was detached")
+	}
+	if !ab.Immutable() {
+		t.Fatal("Immutable() returned false")
+	}
+}
@@ -73,6 +73,7 @@ type Program struct {
 	funcName unistring.String
 	src      *file.File
 	srcMap   []srcMapItem
+	strict   bool
 }
 
 type compiler struct {
@@ -87,6 +88,12 @@ type compiler struct {
 	evalVM *vm // VM used to evaluate constant expressions
 	ctxVM  *vm // VM in which an eval() code is compiled
 
+	// compiling for a debugger: all bindings are placed in named stashes, and every statement gets a source map entry
+	debug bool
+
+	// the instrumentation data if the code coverage is being collected
+	coverage *coverageScript
+
 	codeScratchpad []instruction
 
 	stringCache map[unistring.String]Value
@@ -367,10 +374,11 @@ func (c *compiler) newScope() {
 		strict = c.scope.strict
 	}
 	c.scope = &scope{
-		c:      c,
-		prg:    c.p,
-		outer:  c.scope,
-		strict: strict,
+		c:         c,
+		prg:       c.p,
+		outer:     c.scope,
+		strict:    strict,
+		dynLookup: c.debug,
 	}
 }
 
@@ -921,6 +929,7 @@ func (c *compiler) compile(in *ast.Program, strict, inGlobal bool, evalVm *vm) {
 		strict = c.isStrict(in.Body) != nil
 	}
 	scope.strict = strict
+	c.p.strict = strict
 	ownVarScope := eval && strict
 	ownLexScope := !inGlobal || eval
 	if ownVarScope {
@@ -124,6 +124,7 @@ type compiledFunctionLiteral struct {
 	homeObjOffset   uint32
 	typ             funcType
 	isExpr          bool
+	cov             int // the coverage function index, see compiler.coverageFunction()
 
 	isAsync, isGenerator bool
 }
@@ -160,21 +161,25 @@ type compiledUnaryExpr struct {
 type compiledConditionalExpr struct {
 	baseCompiledExpr
 	test, consequent, alternate compiledExpr
+	cov                         int
 }
 
 type compiledLogicalOr struct {
 	baseCompiledExpr
 	left, right compiledExpr
+	cov         int
 }
 
 type compiledCoalesce struct {
 	baseCompiledExpr
 	left, right compiledExpr
+	cov         int
 }
 
 type compiledLogicalAnd struct {
 	baseCompiledExpr
 	left, right compiledExpr
+	cov         int
 }
 
 type compiledBinaryExpr struct {
@@ -1355,6 +1360,7 @@ func (e *compiledFunctionLiteral) compile() (prg *Program, name unistring.String
 	if !s.strict {
 		s.strict = e.strict != nil
 	}
+	e.c.p.strict = s.strict
 
 	hasPatterns := false
 	hasInits := false
@@ -1533,6 +1539,7 @@ func (e *compiledFunctionLiteral) compile() (prg *Program, name unistring.String
 		}
 	}
 
+	e.c.coverFunctionEntry(e.cov, name)
 	e.c.compileFunctions(funcs)
 	if e.isGenerator {
 		e.c.emit(yieldEmpty)
@@ -1761,6 +1768,7 @@ func (c *compiler) compileFunctionLiteral(v *ast.FunctionLiteral, isExpr bool) *
 		strict:          strictBody,
 		isAsync:         v.Async,
 		isGenerator:     v.Generator,
+		cov:             c.coverageFunction(v, v.Name),
 	}
 	r.init(c, v.Idx0())
 	return r
@@ -2157,6 +2165,7 @@ func (e *compiledClassLiteral) compileFieldsAndStaticBlocks(elements []clsElemen
 		src:      savedPrg.src,
 		funcName: funcName,
 		code:     e.c.newCode(2, 16),
+		strict:   true,
 	}
 
 	e.c.newScope()
@@ -2261,6 +2270,7 @@ func (c *compiler) compileArrowFunctionLiteral(v *ast.ArrowFunctionLiteral) *com
 	case *ast.ExpressionBody:
 		body = []ast.Statement{
 			&ast.ReturnStatement{
+				Return:   b.Expression.Idx0(),
 				Argument: b.Expression,
 			},
 		}
@@ -2276,6 +2286,7 @@ func (c *compiler) compileArrowFunctionLiteral(v *ast.ArrowFunctionLiteral) *com
 		typ:             funcArrow,
 		strict:          strictBody,
 		isAsync:         v.Async,
+		cov:             c.coverageFunction(v, nil),
 	}
 	r.init(c, v.Idx0())
 	return r
@@ -2448,7 +2459,11 @@ func (c *compiler) evalConst(expr compiledExpr) (Value, *Exception) {
 		createdPrg = true
 	}
 	savedPc := len(c.p.code)
+	// the code is run now rather than by the program, it must not be counted
+	savedCoverage := c.coverage
+	c.coverage = nil
 	expr.emitGetter(true)
+	c.coverage = savedCoverage
 	c.evalVM.pc = savedPc
 	ex := c.evalVM.runTry()
 	if createdPrg {
@@ -2549,10 +2564,12 @@ func (e *compiledConditionalExpr) emitGetter(putOnStack bool) {
 	e.test.emitGetter(true)
 	j := len(e.c.p.code)
 	e.c.emit(nil)
+	e.c.emitBranchHit(e.cov, 0)
 	e.consequent.emitGetter(putOnStack)
 	j1 := len(e.c.p.code)
 	e.c.emit(nil)
 	e.c.p.code[j] = jneP(len(e.c.p.code) - j)
+	e.c.emitBranchHit(e.cov, 1)
 	e.alternate.emitGetter(putOnStack)
 	e.c.p.code[j1] = jump(len(e.c.p.code) - j1)
 }
@@ -2562,6 +2579,7 @@ func (c *compiler) compileConditionalExpression(v *ast.ConditionalExpression) co
 		test:       c.compileExpression(v.Test),
 		consequent: c.compileExpression(v.Consequent),
 		alternate:  c.compileExpression(v.Alternate),
+		cov:        c.coverageBranch("cond-expr", v, v.Consequent, v.Alternate),
 	}
 	r.init(c, v.Idx0())
 	return r
@@ -2583,9 +2601,11 @@ func (e *compiledLogicalOr) constant() bool {
 }
 
 func (e *compiledLogicalOr) emitGetter(putOnStack bool) {
+	e.c.emitBranchHit(e.cov, 0)
 	if e.left.constant() {
 		if v, ex := e.c.evalConst(e.left); ex == nil {
 			if !v.ToBoolean() {
+				e.c.emitBranchHit(e.cov, 1)
 				e.c.emitExpr(e.right, putOnStack)
 			} else {
 				if putOnStack {
@@ -2601,6 +2621,7 @@ func (e *compiledLogicalOr) emitGetter(putOnStack bool) {
 	j := len(e.c.p.code)
 	e.addSrcMap()
 	e.c.emit(nil)
+	e.c.emitBranchHit(e.cov, 1)
 	e.c.emitExpr(e.right, true)
 	e.c.p.code[j] = jeq(len(e.c.p.code) - j)
 	if !putOnStack {
@@ -2624,9 +2645,11 @@ func (e *compiledCoalesce) constant() bool {
 }
 
 func (e *compiledCoalesce) emitGetter(putOnStack bool) {
+	e.c.emitBranchHit(e.cov, 0)
 	if e.left.constant() {
 		if v, ex := e.c.evalConst(e.left); ex == nil {
 			if v == _undefined || v == _null {
+				e.c.emitBranchHit(e.cov, 1)
 				e.c.emitExpr(e.right, putOnStack)
 			} else {
 				if putOnStack {
@@ -2642,6 +2665,7 @@ func (e *compiledCoalesce) emitGetter(putOnStack bool) {
 	j := len(e.c.p.code)
 	e.addSrcMap()
 	e.c.emit(nil)
+	e.c.emitBranchHit(e.cov, 1)
 	e.c.emitExpr(e.right, true)
 	e.c.p.code[j] = jcoalesc(len(e.c.p.code) - j)
 	if !putOnStack {
@@ -2667,11 +2691,13 @@ func (e *compiledLogicalAnd) constant() bool {
 
 func (e *compiledLogicalAnd) emitGetter(putOnStack bool) {
 	var j int
+	e.c.emitBranchHit(e.cov, 0)
 	if e.left.constant() {
 		if v, ex := e.c.evalConst(e.left); ex == nil {
 			if !v.ToBoolean() {
 				e.c.emitLiteralValue(v)
 			} else {
+				e.c.emitBranchHit(e.cov, 1)
 				e.c.emitExpr(e.right, putOnStack)
 			}
 		} else {
@@ -2683,6 +2709,7 @@ func (e *compiledLogicalAnd) emitGetter(putOnStack bool) {
 	j = len(e.c.p.code)
 	e.addSrcMap()
 	e.c.emit(nil)
+	e.c.emitBranchHit(e.cov, 1)
 	e.c.emitExpr(e.right, true)
 	e.c.p.code[j] = jne(len(e.c.p.code) - j)
 	if !putOnStack {
@@ -2758,11 +2785,11 @@ func (c *compiler) compileBinaryExpression(v *ast.BinaryExpression) compiledExpr
 
 	switch v.Operator {
 	case token.LOGICAL_OR:
-		return c.compileLogicalOr(v.Left, v.Right, v.Idx0())
+		return c.compileLogicalOr(v.Left, v.Right, v.Idx0(), c.coverageBranch("binary-expr", v, v.Left, v.Right))
 	case token.COALESCE:
-		return c.compileCoalesce(v.Left, v.Right, v.Idx0())
+		return c.compileCoalesce(v.Left, v.Right, v.Idx0(), c.coverageBranch("binary-expr", v, v.Left, v.Right))
 	case token.LOGICAL_AND:
-		return c.compileLogicalAnd(v.Left, v.Right, v.Idx0())
+		return c.compileLogicalAnd(v.Left, v.Right, v.Idx0(), c.coverageBranch("binary-expr", v, v.Left, v.Right))
 	}
 
 	if id, ok := v.Left.(*ast.PrivateIdentifier); ok {
@@ -2806,28 +2833,31 @@ func (c *compiler) compilePrivateIn(id *ast.PrivateIdentifier, right ast.Express
 	return r
 }
 
-func (c *compiler) compileLogicalOr(left, right ast.Expression, idx file.Idx) compiledExpr {
+func (c *compiler) compileLogicalOr(left, right ast.Expression, idx file.Idx, cov int) compiledExpr {
 	r := &compiledLogicalOr{
 		left:  c.compileExpression(left),
 		right: c.compileExpression(right),
+		cov:   cov,
 	}
 	r.init(c, idx)
 	return r
 }
 
-func (c *compiler) compileCoalesce(left, right ast.Expression, idx file.Idx) compiledExpr {
+func (c *compiler) compileCoalesce(left, right ast.Expression, idx file.Idx, cov int) compiledExpr {
 	r := &compiledCoalesce{
 		left:  c.compileExpression(left),
 		right: c.compileExpression(right),
+		cov:   cov,
 	}
 	r.init(c, idx)
 	return r
 }
 
-func (c *compiler) compileLogicalAnd(left, right ast.Expression, idx file.Idx) compiledExpr {
+func (c *compiler) compileLogicalAnd(left, right ast.Expression, idx file.Idx, cov int) compiledExpr {
 	r := &compiledLogicalAnd{
 		left:  c.compileExpression(left),
 		right: c.compileExpression(right),
+		cov:   cov,
 	}
 	r.init(c, idx)
 	return r
@@ -8,6 +8,20 @@ import (
 )
 
 func (c *compiler) compileStatement(v ast.Statement, needResult bool) {
+	if c.debug {
+		switch v.(type) {
+		case *ast.BlockStatement, *ast.EmptyStatement, *ast.FunctionDeclaration:
+		default:
+			c.addSrcMap(v)
+		}
+	}
+	if c.coverage != nil {
+		switch v.(type) {
+		case *ast.BlockStatement, *ast.EmptyStatement, *ast.FunctionDeclaration:
+		default:
+			c.coverStatement(v)
+		}
+	}
 
 	switch v := v.(type) {
 	case *ast.BlockStatement:
@@ -52,6 +66,8 @@ func (c *compiler) compileStatement(v ast.Statement, needResult bool) {
 	case *ast.WithStatement:
 		c.compileWithStatement(v, needResult)
 	case *ast.DebuggerStatement:
+		c.addSrcMap(v)
+		c.emit(debuggerStmt)
 	default:
 		c.assert(false, int(v.Idx0())-1, "Unknown statement type: %T", v)
 		panic("unreachable")
@@ -679,6 +695,13 @@ func (c *compiler) compileIfBodyDummy(s ast.Statement) {
 
 func (c *compiler) compileIfStatement(v *ast.IfStatement, needResult bool) {
 	test := c.compileExpression(v.Test)
+	// without the else branch its location is the whole statement
+	var cov int
+	if v.Alternate != nil {
+		cov = c.coverageBranch("if", v, v.Consequent, v.Alternate)
+	} else {
+		cov = c.coverageBranch("if", v, v.Consequent, v)
+	}
 	if needResult {
 		c.emit(clearResult)
 	}
@@ -690,12 +713,14 @@ func (c *compiler) compileIfStatement(v *ast.IfStatement, needResult bool) {
 			return
 		}
 		if r.ToBoolean() {
+			c.emitBranchHit(cov, 0)
 			c.compileIfBody(v.Consequent, needResult)
 			if v.Alternate != nil {
 				c.compileIfBodyDummy(v.Alternate)
 			}
 		} else {
 			c.compileIfBodyDummy(v.Consequent)
+			c.emitBranchHit(cov, 1)
 			if v.Alternate != nil {
 				c.compileIfBody(v.Alternate, needResult)
 			} else {
@@ -709,18 +734,25 @@ func (c *compiler) compileIfStatement(v *ast.IfStatement, needResult bool) {
 	test.emitGetter(true)
 	jmp := len(c.p.code)
 	c.emit(nil)
+	c.emitBranchHit(cov, 0)
 	c.compileIfBody(v.Consequent, needResult)
 	if v.Alternate != nil {
 		jmp1 := len(c.p.code)
 		c.emit(nil)
 		c.p.code[jmp] = jneP(len(c.p.code) - jmp)
+		c.emitBranchHit(cov, 1)
 		c.compileIfBody(v.Alternate, needResult)
 		c.p.code[jmp1] = jump(len(c.p.code) - jmp1)
 	} else {
-		if needResult {
-			c.emit(jump(2))
+		if needResult || cov >= 0 {
+			jmp1 := len(c.p.code)
+			c.emit(nil)
 			c.p.code[jmp] = jneP(len(c.p.code) - jmp)
-			c.emit(clearResult)
+			if needResult {
+				c.emit(clearResult)
+			}
+			c.emitBranchHit(cov, 1)
+			c.p.code[jmp1] = jump(len(c.p.code) - jmp1)
 		} else {
 			c.p.code[jmp] = jneP(len(c.p.code) - jmp)
 		}
@@ -1069,6 +1101,12 @@ func (c *compiler) compileSwitchStatement(v *ast.SwitchStatement, needResult boo
 		c.emit(clearResult)
 	}
 
+	cases := make([]ast.Node, len(v.Body))
+	for i, s := range v.Body {
+		cases[i] = s
+	}
+	cov := c.coverageBranch("switch", v, cases...)
+
 	jumps := make([]int, len(v.Body))
 
 	for i, s := range v.Body {
@@ -1108,6 +1146,7 @@ func (c *compiler) compileSwitchStatement(v *ast.SwitchStatement, needResult boo
 		if s.Test != nil || i != 0 {
 			c.p.code[jumps[i]] = jump(len(c.p.code) - jumps[i])
 		}
+		c.emitBranchHit(cov, i)
 		c.compileStatements(s.Consequent, needResult)
 	}
 
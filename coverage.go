@@ -0,0 +1,574 @@
+package goja
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja/unistring"
+)
+
+// Coverage collects code coverage: how many times each statement, function and branch of the code has been executed.
+// The code is instrumented when it is compiled, either by a Runtime the Coverage is set on (see Runtime.SetCoverage)
+// or by Coverage.Compile(). A Coverage can be shared by multiple Runtimes, including the ones running concurrently,
+// the data for the same file is merged.
+//
+// The collected data can be written in the LCOV format (WriteLCOV) or as an Istanbul coverage JSON (WriteIstanbul),
+// which are understood by most coverage reporting tools. The positions are mapped through the source maps, so that
+// the report refers to the original sources.
+//
+// The branches recorded are the arms of if statements, conditional (?:) expressions, logical (&&, ||, ??)
+// expressions and switch statements.
+type Coverage struct {
+	mu      sync.Mutex
+	scripts []*coverageScript
+}
+
+// coverageRange is a range of offsets in the compiled source, the end is exclusive.
+type coverageRange struct {
+	start, end int
+}
+
+type coverageStatement struct {
+	loc     coverageRange
+	counter int
+}
+
+type coverageFunction struct {
+	name      unistring.String
+	decl, loc coverageRange
+	counter   int
+}
+
+type coverageBranch struct {
+	typ       string
+	loc       coverageRange
+	locations []coverageRange
+	// the counter of the first location, the others follow
+	counter int
+}
+
+type coverageNodeKey struct {
+	node ast.Node
+	kind byte
+}
+
+const (
+	coverageKindStatement byte = iota
+	coverageKindFunction
+	coverageKindBranch
+)
+
+// coverageScript holds the instrumentation data of a compiled script.
+type coverageScript struct {
+	src        *file.File
+	statements []coverageStatement
+	functions  []coverageFunction
+	branches   []coverageBranch
+	counters   []uint64
+
+	// the nodes already registered, only used during the compilation
+	nodes map[coverageNodeKey]int
+}
+
+// NewCoverage creates an empty Coverage.
+func NewCoverage() *Coverage {
+	return &Coverage{}
+}
+
+// SetCoverage sets the Coverage that records the execution of the scripts compiled by the Runtime after the call,
+// which are only the ones run by RunScript. Pass nil to stop instrumenting the code.
+//
+// Whether the code is instrumented is decided when it is compiled, so the following is not covered:
+//   - the code without a name, such as the one run by RunString;
+//   - the code compiled by eval() and the Function constructor;
+//   - the Programs created by Compile(), CompileAST() and UnmarshalProgram() (even when run by RunProgram in this
+//     Runtime), use Coverage.Compile() and Coverage.CompileAST() instead;
+//   - the scripts compiled before the call.
+func (r *Runtime) SetCoverage(cov *Coverage) {
+	r.coverage = cov
+}
+
+// Compile is like the Compile function, but the resulting Program records its execution into the Coverage.
+// Such a Program cannot be marshalled (see Program.MarshalBinary).
+func (c *Coverage) Compile(name, src string, strict bool) (*Program, error) {
+	return compile(name, src, strict, true, nil, false, c)
+}
+
+// CompileAST is like the CompileAST function, but the resulting Program records its execution into the Coverage.
+func (c *Coverage) CompileAST(prg *ast.Program, strict bool) (*Program, error) {
+	return compileAST(prg, strict, true, nil, false, c)
+}
+
+func (c *Coverage) addScript(s *coverageScript) {
+	s.nodes = nil
+	c.mu.Lock()
+	c.scripts = append(c.scripts, s)
+	c.mu.Unlock()
+}
+
+func newCoverageScript(src *file.File) *coverageScript {
+	return &coverageScript{
+		src:   src,
+		nodes: make(map[coverageNodeKey]int),
+	}
+}
+
+func (s *coverageScript) newCounters(n int) int {
+	idx := len(s.counters)
+	for ; n > 0; n-- {
+		s.counters = append(s.counters, 0)
+	}
+	return idx
+}
+
+func coverageRangeOf(node ast.Node) coverageRange {
+	start := int(node.Idx0()) - 1
+	var end int
+	if c, ok := node.(*ast.CaseStatement); ok && len(c.Consequent) == 0 {
+		// Idx1() is not defined for a case without statements
+		if c.Test != nil {
+			end = int(c.Test.Idx1())
+		} else {
+			end = start + len("default")
+		}
+	} else {
+		end = int(node.Idx1()) - 1
+	}
+	if end < start {
+		end = start
+	}
+	return coverageRange{start: start, end: end}
+}
+
+// coverStatement registers the statement and emits its counter.
+func (c *compiler) coverStatement(v ast.Statement) {
+	s := c.coverage
+	key := coverageNodeKey{node: v, kind: coverageKindStatement}
+	counter, exists := s.nodes[key]
+	if !exists {
+		counter = s.newCounters(1)
+		s.nodes[key] = counter
+		s.statements = append(s.statements, coverageStatement{
+			loc:     coverageRangeOf(v),
+			counter: counter,
+		})
+	}
+	c.emitCoverageHit(counter)
+}
+
+// coverageFunction registers the function and returns its index or -1 if the coverage is not being collected.
+func (c *compiler) coverageFunction(v ast.Node, name *ast.Identifier) int {
+	s := c.coverage
+	if s == nil {
+		return -1
+	}
+	key := coverageNodeKey{node: v, kind: coverageKindFunction}
+	if idx, exists := s.nodes[key]; exists {
+		return idx
+	}
+	f := coverageFunction{
+		loc:     coverageRangeOf(v),
+		counter: s.newCounters(1),
+	}
+	if name != nil {
+		f.name = name.Name
+		f.decl = coverageRangeOf(name)
+	} else {
+		f.decl = f.loc
+	}
+	idx := len(s.functions)
+	s.functions = append(s.functions, f)
+	s.nodes[key] = idx
+	return idx
+}
+
+// coverFunctionEntry emits the counter of the function registered by coverageFunction(). The name is used if the
+// function literal does not have one (e.g. it is assigned to a variable).
+func (c *compiler) coverFunctionEntry(idx int, name unistring.String) {
+	if idx < 0 || c.coverage == nil {
+		return
+	}
+	f := &c.coverage.functions[idx]
+	if f.name == "" {
+		f.name = name
+	}
+	c.emitCoverageHit(f.counter)
+}
+
+// coverageBranch registers a branch with the given locations and returns the counter of the first location (the
+// others follow), or -1 if the coverage is not being collected.
+func (c *compiler) coverageBranch(typ string, v ast.Node, locations ...ast.Node) int {
+	s := c.coverage
+	if s == nil {
+		return -1
+	}
+	key := coverageNodeKey{node: v, kind: coverageKindBranch}
+	if counter, exists := s.nodes[key]; exists {
+		return counter
+	}
+	b := coverageBranch{
+		typ:       typ,
+		loc:       coverageRangeOf(v),
+		locations: make([]coverageRange, len(locations)),
+		counter:   s.newCounters(len(locations)),
+	}
+	for i, l := range locations {
+		b.locations[i] = coverageRangeOf(l)
+	}
+	s.branches = append(s.branches, b)
+	s.nodes[key] = b.counter
+	return b.counter
+}
+
+// emitBranchHit emits the counter of the i-th location of the branch registered by coverageBranch().
+func (c *compiler) emitBranchHit(branch, i int) {
+	if branch >= 0 {
+		c.emitCoverageHit(branch + i)
+	}
+}
+
+func (c *compiler) emitCoverageHit(counter int) {
+	if counter < 0 || c.coverage == nil {
+		return
+	}
+	c.emit(coverageHit{script: c.coverage, counter: counter})
+}
+
+// coverageLocation is a range of source positions (after applying the source maps), the end is exclusive.
+type coverageLocation struct {
+	start, end file.Position
+}
+
+func (l coverageLocation) less(other coverageLocation) bool {
+	if l.start.Line != other.start.Line {
+		return l.start.Line < other.start.Line
+	}
+	if l.start.Column != other.start.Column {
+		return l.start.Column < other.start.Column
+	}
+	if l.end.Line != other.end.Line {
+		return l.end.Line < other.end.Line
+	}
+	return l.end.Column < other.end.Column
+}
+
+func (s *coverageScript) location(r coverageRange) coverageLocation {
+	start := s.src.Position(r.start)
+	end := s.src.Position(r.end)
+	if end.Filename != start.Filename || end.Line < start.Line || end.Line == start.Line && end.Column < start.Column {
+		end = start
+	}
+	return coverageLocation{start: start, end: end}
+}
+
+func (s *coverageScript) count(counter int) uint64 {
+	return atomic.LoadUint64(&s.counters[counter])
+}
+
+type coverageStatementReport struct {
+	loc   coverageLocation
+	count uint64
+}
+
+type coverageFunctionReport struct {
+	name      string
+	decl, loc coverageLocation
+	count     uint64
+}
+
+type coverageBranchReport struct {
+	typ       string
+	loc       coverageLocation
+	locations []coverageLocation
+	counts    []uint64
+}
+
+type coverageBranchKey struct {
+	typ string
+	loc coverageLocation
+}
+
+// coverageFileReport is the coverage of a source file, merged from all scripts that refer to it.
+type coverageFileReport struct {
+	path       string
+	statements []*coverageStatementReport
+	functions  []*coverageFunctionReport
+	branches   []*coverageBranchReport
+
+	statementsByLoc map[coverageLocation]*coverageStatementReport
+	functionsByLoc  map[coverageLocation]*coverageFunctionReport
+	branchesByKey   map[coverageBranchKey]*coverageBranchReport
+}
+
+func (f *coverageFileReport) addStatement(loc coverageLocation, count uint64) {
+	if st := f.statementsByLoc[loc]; st != nil {
+		st.count += count
+		return
+	}
+	st := &coverageStatementReport{loc: loc, count: count}
+	f.statementsByLoc[loc] = st
+	f.statements = append(f.statements, st)
+}
+
+func (f *coverageFileReport) addFunction(name string, decl, loc coverageLocation, count uint64) {
+	if fn := f.functionsByLoc[loc]; fn != nil {
+		fn.count += count
+		return
+	}
+	fn := &coverageFunctionReport{name: name, decl: decl, loc: loc, count: count}
+	f.functionsByLoc[loc] = fn
+	f.functions = append(f.functions, fn)
+}
+
+func (f *coverageFileReport) addBranch(typ string, loc coverageLocation, locations []coverageLocation, counts []uint64) {
+	key := coverageBranchKey{typ: typ, loc: loc}
+	if b := f.branchesByKey[key]; b != nil && len(b.counts) == len(counts) {
+		for i, c := range counts {
+			b.counts[i] += c
+		}
+		return
+	}
+	b := &coverageBranchReport{typ: typ, loc: loc, locations: locations, counts: counts}
+	f.branchesByKey[key] = b
+	f.branches = append(f.branches, b)
+}
+
+// report merges the data of all scripts by the source file (after applying the source maps) and returns the files
+// sorted by the path.
+func (c *Coverage) report() []*coverageFileReport {
+	c.mu.Lock()
+	scripts := c.scripts
+	c.mu.Unlock()
+
+	files := make(map[string]*coverageFileReport)
+	getFile := func(path string) *coverageFileReport {
+		f := files[path]
+		if f == nil {
+			f = &coverageFileReport{
+				path:            path,
+				statementsByLoc: make(map[coverageLocation]*coverageStatementReport),
+				functionsByLoc:  make(map[coverageLocation]*coverageFunctionReport),
+				branchesByKey:   make(map[coverageBranchKey]*coverageBranchReport),
+			}
+			files[path] = f
+		}
+		return f
+	}
+
+	for _, s := range scripts {
+		for _, st := range s.statements {
+			loc := s.location(st.loc)
+			getFile(loc.start.Filename).addStatement(loc, s.count(st.counter))
+		}
+		for _, fn := range s.functions {
+			loc := s.location(fn.loc)
+			decl := s.location(fn.decl)
+			if decl.start.Filename != loc.start.Filename {
+				decl = loc
+			}
+			getFile(loc.start.Filename).addFunction(fn.name.String(), decl, loc, s.count(fn.counter))
+		}
+		for _, b := range s.branches {
+			loc := s.location(b.loc)
+			locations := make([]coverageLocation, len(b.locations))
+			counts := make([]uint64, len(b.locations))
+			for i, l := range b.locations {
+				locations[i] = s.location(l)
+				counts[i] = s.count(b.counter + i)
+			}
+			getFile(loc.start.Filename).addBranch(b.typ, loc, locations, counts)
+		}
+	}
+
+	res := make([]*coverageFileReport, 0, len(files))
+	for _, f := range files {
+		sort.SliceStable(f.statements, func(i, j int) bool {
+			return f.statements[i].loc.less(f.statements[j].loc)
+		})
+		sort.SliceStable(f.functions, func(i, j int) bool {
+			return f.functions[i].loc.less(f.functions[j].loc)
+		})
+		sort.SliceStable(f.branches, func(i, j int) bool {
+			return f.branches[i].loc.less(f.branches[j].loc)
+		})
+		n := 0
+		for _, fn := range f.functions {
+			if fn.name == "" {
+				fn.name = "(anonymous_" + strconv.Itoa(n) + ")"
+				n++
+			}
+		}
+		res = append(res, f)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].path < res[j].path
+	})
+	return res
+}
+
+// WriteLCOV writes the collected data in the LCOV tracefile format. It can be called while the code is running,
+// in which case the counters are read without stopping it.
+func (c *Coverage) WriteLCOV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range c.report() {
+		fmt.Fprintf(bw, "TN:\nSF:%s\n", f.path)
+
+		hit := 0
+		for _, fn := range f.functions {
+			fmt.Fprintf(bw, "FN:%d,%s\n", fn.loc.start.Line, fn.name)
+		}
+		for _, fn := range f.functions {
+			fmt.Fprintf(bw, "FNDA:%d,%s\n", fn.count, fn.name)
+			if fn.count > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "FNF:%d\nFNH:%d\n", len(f.functions), hit)
+
+		total, hit := 0, 0
+		for i, b := range f.branches {
+			var sum uint64
+			for _, count := range b.counts {
+				sum += count
+			}
+			for j, count := range b.counts {
+				total++
+				if sum == 0 {
+					// the branch has not been reached
+					fmt.Fprintf(bw, "BRDA:%d,%d,%d,-\n", b.loc.start.Line, i, j)
+					continue
+				}
+				fmt.Fprintf(bw, "BRDA:%d,%d,%d,%d\n", b.loc.start.Line, i, j, count)
+				if count > 0 {
+					hit++
+				}
+			}
+		}
+		fmt.Fprintf(bw, "BRF:%d\nBRH:%d\n", total, hit)
+
+		// a line is attributed the maximum count of the statements that start on it
+		var lines []int
+		lineCounts := make(map[int]uint64)
+		for _, st := range f.statements {
+			line := st.loc.start.Line
+			count, exists := lineCounts[line]
+			if !exists {
+				lines = append(lines, line)
+			}
+			if !exists || st.count > count {
+				lineCounts[line] = st.count
+			}
+		}
+		sort.Ints(lines)
+		hit = 0
+		for _, line := range lines {
+			count := lineCounts[line]
+			fmt.Fprintf(bw, "DA:%d,%d\n", line, count)
+			if count > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit)
+	}
+	return bw.Flush()
+}
+
+type istanbulPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type istanbulLocation struct {
+	Start istanbulPosition `json:"start"`
+	End   istanbulPosition `json:"end"`
+}
+
+type istanbulFunction struct {
+	Name string           `json:"name"`
+	Decl istanbulLocation `json:"decl"`
+	Loc  istanbulLocation `json:"loc"`
+	Line int              `json:"line"`
+}
+
+type istanbulBranch struct {
+	Loc       istanbulLocation   `json:"loc"`
+	Type      string             `json:"type"`
+	Locations []istanbulLocation `json:"locations"`
+	Line      int                `json:"line"`
+}
+
+type istanbulFileCoverage struct {
+	Path         string                      `json:"path"`
+	StatementMap map[string]istanbulLocation `json:"statementMap"`
+	FnMap        map[string]istanbulFunction `json:"fnMap"`
+	BranchMap    map[string]istanbulBranch   `json:"branchMap"`
+	S            map[string]uint64           `json:"s"`
+	F            map[string]uint64           `json:"f"`
+	B            map[string][]uint64         `json:"b"`
+}
+
+func (l coverageLocation) istanbul() istanbulLocation {
+	// Istanbul columns are 0-based
+	return istanbulLocation{
+		Start: istanbulPosition{Line: l.start.Line, Column: l.start.Column - 1},
+		End:   istanbulPosition{Line: l.end.Line, Column: l.end.Column - 1},
+	}
+}
+
+// WriteIstanbul writes the collected data as an Istanbul coverage JSON (the format of coverage-final.json produced
+// by nyc and Jest), a map of the file paths to their coverage. See WriteLCOV regarding the running code.
+func (c *Coverage) WriteIstanbul(w io.Writer) error {
+	res := make(map[string]*istanbulFileCoverage)
+	for _, f := range c.report() {
+		fc := &istanbulFileCoverage{
+			Path:         f.path,
+			StatementMap: make(map[string]istanbulLocation, len(f.statements)),
+			FnMap:        make(map[string]istanbulFunction, len(f.functions)),
+			BranchMap:    make(map[string]istanbulBranch, len(f.branches)),
+			S:            make(map[string]uint64, len(f.statements)),
+			F:            make(map[string]uint64, len(f.functions)),
+			B:            make(map[string][]uint64, len(f.branches)),
+		}
+		for i, st := range f.statements {
+			id := strconv.Itoa(i)
+			fc.StatementMap[id] = st.loc.istanbul()
+			fc.S[id] = st.count
+		}
+		for i, fn := range f.functions {
+			id := strconv.Itoa(i)
+			fc.FnMap[id] = istanbulFunction{
+				Name: fn.name,
+				Decl: fn.decl.istanbul(),
+				Loc:  fn.loc.istanbul(),
+				Line: fn.loc.start.Line,
+			}
+			fc.F[id] = fn.count
+		}
+		for i, b := range f.branches {
+			id := strconv.Itoa(i)
+			locations := make([]istanbulLocation, len(b.locations))
+			for j, l := range b.locations {
+				locations[j] = l.istanbul()
+			}
+			fc.BranchMap[id] = istanbulBranch{
+				Loc:       b.loc.istanbul(),
+				Type:      b.typ,
+				Locations: locations,
+				Line:      b.loc.start.Line,
+			}
+			fc.B[id] = b.counts
+		}
+		res[f.path] = fc
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(res)
+}
@@ -0,0 +1,210 @@
+package goja
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja/parser"
+)
+
+func TestCoverageLCOV(t *testing.T) {
+	const SCRIPT = `function f(a) {
+	if (a > 1) {
+		return a || 0;
+	}
+	return a ? 1 : 2;
+}
+var g = x => x * 2;
+switch (f(2)) {
+case 1:
+case 2:
+	g(1);
+	break;
+default:
+	f(0);
+}
+f(0);
+`
+	cov := NewCoverage()
+	r := New()
+	r.SetCoverage(cov)
+	if _, err := r.RunScript("test.js", SCRIPT); err != nil {
+		t.Fatal(err)
+	}
+	// neither eval() code nor code without a name is instrumented
+	if _, err := r.RunString("eval('f(3)')"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := cov.WriteLCOV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	const expected = `TN:
+SF:test.js
+FN:1,f
+FN:7,g
+FNDA:3,f
+FNDA:1,g
+FNF:2
+FNH:2
+BRDA:2,0,0,2
+BRDA:2,0,1,1
+BRDA:3,1,0,2
+BRDA:3,1,1,0
+BRDA:5,2,0,0
+BRDA:5,2,1,1
+BRDA:8,3,0,0
+BRDA:8,3,1,1
+BRDA:8,3,2,0
+BRF:9
+BRH:5
+DA:2,3
+DA:3,2
+DA:5,1
+DA:7,1
+DA:8,1
+DA:11,1
+DA:12,1
+DA:14,0
+DA:16,1
+LF:9
+LH:8
+end_of_record
+`
+	if res := buf.String(); res != expected {
+		t.Fatalf("unexpected result:\n%s", res)
+	}
+}
+
+func TestCoverageNotInstrumented(t *testing.T) {
+	cov := NewCoverage()
+	r := New()
+	if _, err := r.RunScript("before.js", "var x = 1;"); err != nil {
+		t.Fatal(err)
+	}
+	r.SetCoverage(cov)
+
+	if _, err := r.RunString("x++"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RunString("eval('x++'); new Function('x++')()"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RunProgram(MustCompile("compiled.js", "x++", false)); err != nil {
+		t.Fatal(err)
+	}
+	ast, err := Parse("ast.js", "x++")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prg, err := CompileAST(ast, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RunProgram(prg); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := cov.WriteLCOV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if res := buf.String(); res != "" {
+		t.Fatalf("unexpected result:\n%s", res)
+	}
+
+	if _, err := r.RunScript("after.js", "x++"); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := cov.WriteLCOV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if res := buf.String(); res != "TN:\nSF:after.js\nFNF:0\nFNH:0\nBRF:0\nBRH:0\nDA:1,1\nLF:1\nLH:1\nend_of_record\n" {
+		t.Fatalf("unexpected result:\n%s", res)
+	}
+}
+
+func TestCoverageIstanbul(t *testing.T) {
+	const SCRIPT = `var o = {
+	get x() { return this.y ?? 42; }
+};
+function unused() {
+	return 1;
+}
+o.x;
+`
+	cov := NewCoverage()
+	prg, err := cov.Compile("test.js", SCRIPT, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the data of the same file is merged
+	for i := 0; i < 2; i++ {
+		if _, err := New().RunProgram(prg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := New()
+	r.SetCoverage(cov)
+	if _, err := r.RunScript("test.js", SCRIPT); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := cov.WriteIstanbul(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var res map[string]*istanbulFileCoverage
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	fc := res["test.js"]
+	if len(res) != 1 || fc == nil || fc.Path != "test.js" {
+		t.Fatalf("unexpected result: %s", buf.String())
+	}
+	if len(fc.FnMap) != 2 || fc.FnMap["0"].Name != "x" || fc.FnMap["1"].Name != "unused" || fc.F["0"] != 3 || fc.F["1"] != 0 {
+		t.Fatalf("unexpected functions: %v, %v", fc.FnMap, fc.F)
+	}
+	if fc.FnMap["1"].Loc != (istanbulLocation{Start: istanbulPosition{Line: 4, Column: 0}, End: istanbulPosition{Line: 6, Column: 1}}) {
+		t.Fatalf("unexpected function location: %v", fc.FnMap["1"].Loc)
+	}
+	if len(fc.S) != 4 || fc.S["0"] != 3 || fc.S["1"] != 3 || fc.S["2"] != 0 || fc.S["3"] != 3 {
+		t.Fatalf("unexpected statements: %v", fc.S)
+	}
+	if b := fc.BranchMap["0"]; b.Type != "binary-expr" || b.Line != 2 || len(b.Locations) != 2 ||
+		b.Locations[1] != (istanbulLocation{Start: istanbulPosition{Line: 2, Column: 28}, End: istanbulPosition{Line: 2, Column: 30}}) {
+		t.Fatalf("unexpected branch: %v", b)
+	}
+	if counts := fc.B["0"]; len(counts) != 2 || counts[0] != 3 || counts[1] != 3 {
+		t.Fatalf("unexpected branch counts: %v", counts)
+	}
+}
+
+func TestCoverageSourceMap(t *testing.T) {
+	// the generated lines map to the lines 3 and 4 of orig.js
+	const SCRIPT = `var a = 1;
+a++;
+//# sourceMappingURL=gen.js.map
+`
+	const MAP = `{"version":3,"sources":["orig.js"],"names":[],"mappings":"AAEA;AACA"}`
+	cov := NewCoverage()
+	r := New()
+	r.SetParserOptions(parser.WithSourceMapLoader(func(path string) ([]byte, error) {
+		return []byte(MAP), nil
+	}))
+	r.SetCoverage(cov)
+	if _, err := r.RunScript("gen.js", SCRIPT); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := cov.WriteLCOV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if res := buf.String(); !strings.Contains(res, "SF:orig.js\n") || !strings.Contains(res, "DA:3,1\nDA:4,1\n") {
+		t.Fatalf("unexpected result:\n%s", res)
+	}
+}
@@ -0,0 +1,515 @@
+package goja
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja/unistring"
+)
+
+// PauseReason describes why the execution has been paused.
+type PauseReason int
+
+const (
+	PauseBreakpoint        PauseReason = iota // a breakpoint has been hit
+	PauseDebuggerStatement                    // a 'debugger' statement has been executed
+	PauseException                            // an exception has been thrown (see Debugger.SetPauseOnExceptions)
+	PauseStep                                 // a step requested by the previous DebugAction has completed
+	PauseRequested                            // Debugger.Pause() has been called
+)
+
+func (r PauseReason) String() string {
+	switch r {
+	case PauseBreakpoint:
+		return "breakpoint"
+	case PauseDebuggerStatement:
+		return "debugger statement"
+	case PauseException:
+		return "exception"
+	case PauseStep:
+		return "step"
+	case PauseRequested:
+		return "requested"
+	}
+	return "unknown"
+}
+
+// DebugAction tells the debugger how to proceed after a pause.
+type DebugAction int
+
+const (
+	DebugContinue DebugAction = iota // run until the next breakpoint, 'debugger' statement or exception
+	DebugStepInto                    // pause at the next statement, entering function calls
+	DebugStepOver                    // pause at the next statement in the current function, or when it returns
+	DebugStepOut                     // pause when the current function returns
+)
+
+// DebugHandler is called synchronously, on the goroutine running the Runtime, every time the execution is paused.
+// The execution resumes when it returns. The DebugPause and everything obtained from it can only be used until then.
+type DebugHandler func(*DebugPause) DebugAction
+
+// Debugger allows to set breakpoints, step through the code and inspect the call stack and the variables of a
+// Runtime. It is created by Runtime.AttachDebugger().
+//
+// While a Debugger is attached, the code is compiled so that all variables (including the ones that would otherwise
+// live on the stack) are accessible by name, and every statement is a potential step location. This makes the code
+// slower, and it only applies to the code compiled by the Runtime after the Debugger has been attached (i.e. it does
+// not apply to Programs created with Compile()). Breakpoints and stepping work in such code as well, but some of
+// the variables may not be visible and the granularity of the stepping is coarser.
+//
+// The breakpoints, the pause on exceptions flag and Pause() can be used from any goroutine, including while the Runtime
+// is running. SetScriptHandler must be called from the DebugHandler or when the Runtime is not running.
+type Debugger struct {
+	r             *Runtime
+	handler       DebugHandler
+	scriptHandler func(filename, source string)
+
+	bpMu              sync.Mutex
+	breakpoints       map[debugBreakpointKey]*Breakpoint
+	pauseOnExceptions uint32
+	pauseRequested    uint32
+
+	// set while the handler is running, the code evaluated by the handler is not debugged
+	suspended bool
+
+	action    DebugAction
+	stepDepth int
+
+	// last visited location for each call stack depth
+	locations     []debugLocation
+	lastException *Exception
+}
+
+type debugBreakpointKey struct {
+	filename string
+	line     int
+}
+
+type debugLocation struct {
+	prg      *Program
+	sb, pc   int
+	filename string
+	line     int
+}
+
+// Breakpoint is a location at which the execution pauses.
+type Breakpoint struct {
+	filename string
+	line     int
+}
+
+// Filename returns the name of the file the breakpoint is set in.
+func (b *Breakpoint) Filename() string {
+	return b.filename
+}
+
+// Line returns the line number (1-based) the breakpoint is set at.
+func (b *Breakpoint) Line() int {
+	return b.line
+}
+
+// DebugPause describes the state of a paused Runtime.
+type DebugPause struct {
+	d          *Debugger
+	reason     PauseReason
+	breakpoint *Breakpoint
+	exception  *Exception
+	frames     []*DebugFrame
+	done       bool
+}
+
+// DebugFrame is a call stack frame of a paused Runtime.
+type DebugFrame struct {
+	p         *DebugPause
+	prg       *Program
+	pc, sb    int
+	args      int
+	stash     *stash
+	privEnv   *privateEnv
+	newTarget Value
+}
+
+// DebugScopeType is the type of DebugScope.
+type DebugScopeType int
+
+const (
+	DebugScopeBlock   DebugScopeType = iota // a block ({...}, a loop body, a catch clause, etc.)
+	DebugScopeLocal                         // the top-level scope of the function the frame belongs to
+	DebugScopeClosure                       // the top-level scope of an enclosing function
+	DebugScopeWith                          // a 'with' statement object
+	DebugScopeScript                        // the global lexical scope (top-level 'let', 'const' and 'class')
+	DebugScopeGlobal                        // the global object
+)
+
+func (t DebugScopeType) String() string {
+	switch t {
+	case DebugScopeBlock:
+		return "block"
+	case DebugScopeLocal:
+		return "local"
+	case DebugScopeClosure:
+		return "closure"
+	case DebugScopeWith:
+		return "with"
+	case DebugScopeScript:
+		return "script"
+	case DebugScopeGlobal:
+		return "global"
+	}
+	return "unknown"
+}
+
+// DebugScope is a variable scope of a DebugFrame.
+type DebugScope struct {
+	typ   DebugScopeType
+	stash *stash
+	obj   *Object
+}
+
+var errDebugPauseEnded = errors.New("goja: the debugger is no longer paused")
+
+// AttachDebugger attaches a new Debugger to the Runtime replacing the existing one, if any. The handler is called
+// every time the execution is paused. See Debugger for the details.
+func (r *Runtime) AttachDebugger(handler DebugHandler) *Debugger {
+	d := &Debugger{
+		r:           r,
+		handler:     handler,
+		breakpoints: make(map[debugBreakpointKey]*Breakpoint),
+	}
+	r.vm.debugger = d
+	return d
+}
+
+// DetachDebugger detaches the current Debugger (if any). It can be called from the DebugHandler.
+func (r *Runtime) DetachDebugger() {
+	r.vm.debugger = nil
+}
+
+// SetBreakpoint sets a breakpoint at the given line (1-based) of the given file. If source maps are used, the
+// filename and the line refer to the original source. If there is already a breakpoint at this location,
+// it is returned.
+func (d *Debugger) SetBreakpoint(filename string, line int) *Breakpoint {
+	key := debugBreakpointKey{filename: filename, line: line}
+	d.bpMu.Lock()
+	defer d.bpMu.Unlock()
+	if b := d.breakpoints[key]; b != nil {
+		return b
+	}
+	b := &Breakpoint{filename: filename, line: line}
+	d.breakpoints[key] = b
+	return b
+}
+
+// RemoveBreakpoint removes the breakpoint.
+func (d *Debugger) RemoveBreakpoint(b *Breakpoint) {
+	key := debugBreakpointKey{filename: b.filename, line: b.line}
+	d.bpMu.Lock()
+	if d.breakpoints[key] == b {
+		delete(d.breakpoints, key)
+	}
+	d.bpMu.Unlock()
+}
+
+// Breakpoints returns all breakpoints sorted by the filename and the line.
+func (d *Debugger) Breakpoints() []*Breakpoint {
+	d.bpMu.Lock()
+	res := make([]*Breakpoint, 0, len(d.breakpoints))
+	for _, b := range d.breakpoints {
+		res = append(res, b)
+	}
+	d.bpMu.Unlock()
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].filename != res[j].filename {
+			return res[i].filename < res[j].filename
+		}
+		return res[i].line < res[j].line
+	})
+	return res
+}
+
+// SetPauseOnExceptions sets whether the execution should pause when an exception is thrown (whether it is caught or not).
+func (d *Debugger) SetPauseOnExceptions(pause bool) {
+	var v uint32
+	if pause {
+		v = 1
+	}
+	atomic.StoreUint32(&d.pauseOnExceptions, v)
+}
+
+// Pause requests the execution to pause at the next statement.
+func (d *Debugger) Pause() {
+	atomic.StoreUint32(&d.pauseRequested, 1)
+}
+
+// SetScriptHandler sets a function that is called every time the Runtime compiles a script (i.e. in RunString and
+// RunScript, but not in eval()) while the Debugger is attached. It is called on the goroutine running the Runtime,
+// before the script starts. nil removes the handler.
+func (d *Debugger) SetScriptHandler(handler func(filename, source string)) {
+	d.scriptHandler = handler
+}
+
+func (d *Debugger) check(vm *vm) {
+	depth := len(vm.callStack)
+	if d.action != DebugContinue && depth < d.stepDepth {
+		// the function has returned
+		d.pause(vm, PauseStep, nil, nil)
+		return
+	}
+	prg, pc := vm.prg, vm.pc
+	newLine := false
+	pos, isStepPoint := debugStepPoint(prg, pc)
+	if isStepPoint {
+		for len(d.locations) <= depth {
+			d.locations = append(d.locations, debugLocation{})
+		}
+		d.locations = d.locations[:depth+1]
+		loc := &d.locations[depth]
+		newLine = loc.prg != prg || loc.sb != vm.sb || loc.filename != pos.Filename || loc.line != pos.Line || pc <= loc.pc
+		*loc = debugLocation{prg: prg, sb: vm.sb, pc: pc, filename: pos.Filename, line: pos.Line}
+	}
+	if _, ok := prg.code[pc].(_debuggerStmt); ok {
+		d.pause(vm, PauseDebuggerStatement, nil, nil)
+		return
+	}
+	if !newLine {
+		return
+	}
+	if atomic.CompareAndSwapUint32(&d.pauseRequested, 1, 0) {
+		d.pause(vm, PauseRequested, nil, nil)
+		return
+	}
+	d.bpMu.Lock()
+	b := d.breakpoints[debugBreakpointKey{filename: pos.Filename, line: pos.Line}]
+	d.bpMu.Unlock()
+	if b != nil {
+		d.pause(vm, PauseBreakpoint, b, nil)
+		return
+	}
+	switch d.action {
+	case DebugStepInto:
+		d.pause(vm, PauseStep, nil, nil)
+	case DebugStepOver:
+		if depth <= d.stepDepth {
+			d.pause(vm, PauseStep, nil, nil)
+		}
+	}
+}
+
+// debugStepPoint returns the source position of pc if it starts a source map entry. The entry pointing to the
+// function itself, which starts the function prologue, is skipped.
+func debugStepPoint(prg *Program, pc int) (file.Position, bool) {
+	if prg.src == nil {
+		return file.Position{}, false
+	}
+	if pc == 0 {
+		switch prg.code[0].(type) {
+		case *enterFunc, *enterFunc1, *enterFuncStashless:
+			return file.Position{}, false
+		}
+	}
+	i := sort.Search(len(prg.srcMap), func(idx int) bool {
+		return prg.srcMap[idx].pc > pc
+	}) - 1
+	if i < 0 || prg.srcMap[i].pc != pc {
+		return file.Position{}, false
+	}
+	return prg.src.Position(prg.srcMap[i].srcPos), true
+}
+
+func (d *Debugger) exceptionThrown(vm *vm, ex *Exception) {
+	// the same exception is handled again every time it crosses a native function boundary
+	if atomic.LoadUint32(&d.pauseOnExceptions) == 0 || d.suspended || ex == d.lastException {
+		return
+	}
+	d.lastException = ex
+	d.pause(vm, PauseException, nil, ex)
+}
+
+func (d *Debugger) pause(vm *vm, reason PauseReason, b *Breakpoint, ex *Exception) {
+	p := &DebugPause{
+		d:          d,
+		reason:     reason,
+		breakpoint: b,
+		exception:  ex,
+	}
+	p.frames = p.captureFrames(vm)
+	d.suspended = true
+	defer func() {
+		p.done = true
+		d.suspended = false
+	}()
+	d.action = d.handler(p)
+	d.stepDepth = len(vm.callStack)
+}
+
+func (p *DebugPause) captureFrames(vm *vm) []*DebugFrame {
+	var frames []*DebugFrame
+	if vm.prg != nil {
+		frames = append(frames, &DebugFrame{p: p, prg: vm.prg, pc: vm.pc, sb: vm.sb, args: vm.args,
+			stash: vm.stash, privEnv: vm.privEnv, newTarget: vm.newTarget})
+	}
+	for i := len(vm.callStack) - 1; i >= 0; i-- {
+		ctx := &vm.callStack[i]
+		if ctx.prg != nil {
+			frames = append(frames, &DebugFrame{p: p, prg: ctx.prg, pc: ctx.pc, sb: ctx.sb, args: ctx.args,
+				stash: ctx.stash, privEnv: ctx.privEnv, newTarget: ctx.newTarget})
+		}
+	}
+	return frames
+}
+
+// Reason returns the reason of the pause.
+func (p *DebugPause) Reason() PauseReason {
+	return p.reason
+}
+
+// Breakpoint returns the breakpoint that has been hit or nil if the reason is not PauseBreakpoint.
+func (p *DebugPause) Breakpoint() *Breakpoint {
+	return p.breakpoint
+}
+
+// Exception returns the exception that has been thrown or nil if the reason is not PauseException.
+func (p *DebugPause) Exception() *Exception {
+	return p.exception
+}
+
+// Frames returns the JavaScript frames of the call stack, the innermost first. Native functions are omitted.
+func (p *DebugPause) Frames() []*DebugFrame {
+	return p.frames
+}
+
+// Position returns the current position in the frame.
+func (f *DebugFrame) Position() file.Position {
+	if f.prg.src == nil {
+		return file.Position{}
+	}
+	return f.prg.src.Position(f.prg.sourceOffset(f.pc))
+}
+
+// FuncName returns the name of the function the frame belongs to or an empty string for the top-level code
+// and anonymous functions.
+func (f *DebugFrame) FuncName() string {
+	return f.prg.funcName.String()
+}
+
+// This returns the value of 'this' in the frame or nil if it's not available (e.g. in a derived class constructor
+// before super() has been called).
+func (f *DebugFrame) This() Value {
+	v, err := f.Eval("this")
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// Scopes returns the variable scopes of the frame, the innermost first. The last two scopes are always the
+// DebugScopeScript and the DebugScopeGlobal.
+func (f *DebugFrame) Scopes() []*DebugScope {
+	r := f.p.d.r
+	var scopes []*DebugScope
+	seenFunc := false
+	for s := f.stash; s != nil; s = s.outer {
+		if s == &r.global.stash {
+			scopes = append(scopes, &DebugScope{typ: DebugScopeScript, stash: s}, &DebugScope{typ: DebugScopeGlobal, obj: r.globalObject})
+			break
+		}
+		scope := &DebugScope{stash: s}
+		switch {
+		case s.obj != nil:
+			scope.typ, scope.obj = DebugScopeWith, s.obj
+		case s.isVariable() && !seenFunc:
+			scope.typ = DebugScopeLocal
+			seenFunc = true
+		case s.isVariable():
+			scope.typ = DebugScopeClosure
+		default:
+			scope.typ = DebugScopeBlock
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes
+}
+
+// Eval evaluates the expression in the context of the frame, as if it were passed to a direct eval() call made
+// at the current position. The expression is not debugged, i.e. it does not pause on breakpoints or exceptions.
+// It can only be called from the DebugHandler.
+func (f *DebugFrame) Eval(src string) (v Value, err error) {
+	if f.p.done {
+		return nil, errDebugPauseEnded
+	}
+	r := f.p.d.r
+	vm := r.vm
+	var saved context
+	vm.saveCtx(&saved)
+	sp := vm.sp
+	vm.prg, vm.stash, vm.privEnv, vm.newTarget, vm.pc, vm.sb, vm.args =
+		f.prg, f.stash, f.privEnv, f.newTarget, f.pc, f.sb, f.args
+	ex := vm.try(func() {
+		v = r.eval(newStringValue(src), true, f.prg.strict)
+	})
+	vm.restoreCtx(&saved)
+	vm.sp = sp
+	if ex != nil {
+		return nil, ex
+	}
+	return v, nil
+}
+
+// Type returns the type of the scope.
+func (s *DebugScope) Type() DebugScopeType {
+	return s.typ
+}
+
+// Object returns the object for the DebugScopeWith and DebugScopeGlobal scopes, nil otherwise.
+func (s *DebugScope) Object() *Object {
+	return s.obj
+}
+
+// Names returns the names of the variables in the scope in the order of declaration. For object scopes these are
+// the object's own enumerable string keys. Variables of the code compiled without a debugger attached may be missing.
+func (s *DebugScope) Names() []string {
+	if s.obj != nil {
+		return s.obj.Keys()
+	}
+	type entry struct {
+		name unistring.String
+		idx  uint32
+	}
+	entries := make([]entry, 0, len(s.stash.names))
+	for name, idx := range s.stash.names {
+		if name != thisBindingName {
+			entries = append(entries, entry{name: name, idx: idx &^ maskTyp})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].idx < entries[j].idx
+	})
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.name.String()
+	}
+	return names
+}
+
+// Get returns the value of the variable and whether it exists in the scope. The value is nil if the variable is
+// in its temporal dead zone (i.e. a 'let', 'const' or 'class' binding that has not been initialised yet).
+func (s *DebugScope) Get(name string) (Value, bool) {
+	if s.obj != nil {
+		if !s.obj.self.hasPropertyStr(unistring.NewFromString(name)) {
+			return nil, false
+		}
+		return s.obj.Get(name), true
+	}
+	idx, exists := s.stash.names[unistring.NewFromString(name)]
+	if !exists {
+		return nil, false
+	}
+	v := s.stash.values[idx&^maskTyp]
+	if v == nil && idx&maskVar != 0 {
+		v = _undefined
+	}
+	return v, true
+}
@@ -0,0 +1,230 @@
+package goja
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDebuggerBreakpoint(t *testing.T) {
+	const SCRIPT = `
+function f(a) {
+	let b = a * 2;
+	{
+		const c = b + 1;
+		return c;
+	}
+}
+let x = 1;
+f(x);
+`
+	r := New()
+	var lines []int
+	var values []int64
+	d := r.AttachDebugger(func(p *DebugPause) DebugAction {
+		if p.Reason() != PauseBreakpoint {
+			t.Fatalf("unexpected reason: %v", p.Reason())
+		}
+		top := p.Frames()[0]
+		lines = append(lines, top.Position().Line)
+		if top.FuncName() != "f" {
+			t.Fatalf("unexpected function: %q", top.FuncName())
+		}
+		v, err := top.Eval("a + b + c")
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, v.ToInteger())
+
+		scopes := top.Scopes()
+		var types []DebugScopeType
+		for _, s := range scopes {
+			types = append(types, s.Type())
+		}
+		if !reflect.DeepEqual(types, []DebugScopeType{DebugScopeBlock, DebugScopeLocal, DebugScopeScript, DebugScopeGlobal}) {
+			t.Fatalf("unexpected scopes: %v", types)
+		}
+		if v, ok := scopes[0].Get("c"); !ok || v.ToInteger() != 3 {
+			t.Fatalf("c: %v", v)
+		}
+		if names := scopes[1].Names(); !reflect.DeepEqual(names, []string{"a", "b", "arguments"}) {
+			t.Fatalf("unexpected names: %v", names)
+		}
+		if v, ok := scopes[2].Get("x"); !ok || v.ToInteger() != 1 {
+			t.Fatalf("x: %v", v)
+		}
+		if _, ok := scopes[3].Get("f"); !ok {
+			t.Fatal("f is not found in the global scope")
+		}
+		if len(p.Frames()) != 2 {
+			t.Fatalf("unexpected number of frames: %d", len(p.Frames()))
+		}
+		return DebugContinue
+	})
+	b := d.SetBreakpoint("test.js", 6)
+	if _, err := r.RunScript("test.js", SCRIPT); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RunString("f(x)"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, []int{6, 6}) || !reflect.DeepEqual(values, []int64{6, 6}) {
+		t.Fatalf("lines: %v, values: %v", lines, values)
+	}
+
+	d.RemoveBreakpoint(b)
+	if len(d.Breakpoints()) != 0 {
+		t.Fatal("breakpoint is not removed")
+	}
+	if _, err := r.RunString("f(x)"); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Fatal("removed breakpoint has been hit")
+	}
+}
+
+func TestDebuggerStepping(t *testing.T) {
+	const SCRIPT = `debugger;
+function g() {
+	return 1;
+}
+function f() {
+	let a = g();
+	return a + 1;
+}
+f();
+var done = true;
+`
+	actions := []DebugAction{DebugStepOver, DebugStepInto, DebugStepInto, DebugStepOut, DebugStepOver, DebugStepOut, DebugStepOver, DebugContinue}
+	var lines []int
+	r := New()
+	r.AttachDebugger(func(p *DebugPause) DebugAction {
+		lines = append(lines, p.Frames()[0].Position().Line)
+		a := actions[0]
+		actions = actions[1:]
+		return a
+	})
+	if _, err := r.RunScript("test.js", SCRIPT); err != nil {
+		t.Fatal(err)
+	}
+	// debugger; -> f(); -> let a = g(); -> return 1; -> (out of g) let a = g(); -> return a + 1; -> (out of f) f(); -> var done = true;
+	expected := []int{1, 9, 6, 3, 6, 7, 9, 10}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("unexpected lines: %v, expected: %v", lines, expected)
+	}
+}
+
+func TestDebuggerClosureAndException(t *testing.T) {
+	const SCRIPT = `
+function outer() {
+	let captured = "outer";
+	return function inner(p) {
+		throw new Error(captured + p);
+	};
+}
+try {
+	outer()("!");
+} catch (e) {
+	e.message;
+}
+`
+	r := New()
+	paused := 0
+	d := r.AttachDebugger(func(p *DebugPause) DebugAction {
+		paused++
+		if p.Reason() != PauseException {
+			t.Fatalf("unexpected reason: %v", p.Reason())
+		}
+		if msg := p.Exception().Value().ToObject(r).Get("message").String(); msg != "outer!" {
+			t.Fatalf("unexpected exception: %s", msg)
+		}
+		top := p.Frames()[0]
+		if top.FuncName() != "inner" || top.Position().Line != 5 {
+			t.Fatalf("unexpected frame: %s at %v", top.FuncName(), top.Position())
+		}
+		v, err := top.Eval("captured + p")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.String() != "outer!" {
+			t.Fatalf("unexpected eval result: %v", v)
+		}
+		if _, err := top.Eval("undefinedVariable"); err == nil {
+			t.Fatal("expected an error")
+		}
+		return DebugContinue
+	})
+	d.SetPauseOnExceptions(true)
+	v, err := r.RunString(SCRIPT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "outer!" {
+		t.Fatalf("unexpected result: %v", v)
+	}
+	if paused != 1 {
+		t.Fatalf("paused %d times", paused)
+	}
+
+	r.DetachDebugger()
+	if _, err := r.RunString(SCRIPT); err != nil {
+		t.Fatal(err)
+	}
+	if paused != 1 {
+		t.Fatal("paused after detaching")
+	}
+}
+
+func TestDebuggerEvalStrict(t *testing.T) {
+	const SCRIPT = `
+function sloppy() {
+	return 1;
+}
+function strict() {
+	"use strict";
+	return 2;
+}
+class C {
+	m() {
+		return 3;
+	}
+}
+sloppy();
+strict();
+new C().m();
+`
+	r := New()
+	results := make(map[string]string)
+	d := r.AttachDebugger(func(p *DebugPause) DebugAction {
+		top := p.Frames()[0]
+		var res []string
+		for _, src := range []string{
+			"(function() { return this === undefined; })()",
+			"delete Object.prototype",
+			"with ({}) {}",
+		} {
+			if v, err := top.Eval(src); err != nil {
+				res = append(res, "error")
+			} else {
+				res = append(res, v.String())
+			}
+		}
+		results[top.FuncName()] = strings.Join(res, ",")
+		return DebugContinue
+	})
+	d.SetBreakpoint("test.js", 3)
+	d.SetBreakpoint("test.js", 7)
+	d.SetBreakpoint("test.js", 11)
+	if _, err := r.RunScript("test.js", SCRIPT); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"sloppy": "false,false,undefined",
+		"strict": "true,error,error",
+		"m":      "true,error,error",
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("unexpected results: %v", results)
+	}
+}
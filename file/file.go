@@ -108,6 +108,7 @@ type File struct {
 	src               string
 	base              int // This will always be 1 or greater
 	sourceMap         *sourcemap.Consumer
+	sourceMapData     []byte
 	lineOffsets       []int
 	lastScannedOffset int
 }
@@ -134,6 +135,25 @@ func (fl *File) Base() int {
 
 func (fl *File) SetSourceMap(m *sourcemap.Consumer) {
 	fl.sourceMap = m
+	fl.sourceMapData = nil
+}
+
+// SetSourceMapData parses the source map and attaches it to the File. Unlike SetSourceMap, the raw data is retained
+// and can be retrieved with SourceMapData (it is used to preserve the source map when a compiled program is
+// serialised).
+func (fl *File) SetSourceMapData(data []byte) error {
+	m, err := sourcemap.Parse(fl.name, data)
+	if err != nil {
+		return err
+	}
+	fl.sourceMap = m
+	fl.sourceMapData = data
+	return nil
+}
+
+// SourceMapData returns the source map set by SetSourceMapData, or nil.
+func (fl *File) SourceMapData() []byte {
+	return fl.sourceMapData
 }
 
 func (fl *File) Position(offset int) Position {
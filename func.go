@@ -134,6 +134,9 @@ type wrappedFuncObject struct {
 type boundFuncObject struct {
 	nativeFuncObject
 	wrapped *Object
+
+	// the arguments passed to bind(), including thisArg
+	boundArgs []Value
 }
 
 type generatorState uint8
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	crand "crypto/rand"
 	"encoding/binary"
 	"flag"
@@ -8,18 +9,27 @@ import (
 	"io"
 	"log"
 	"math/rand"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"runtime/debug"
 	"runtime/pprof"
 	"time"
 
 	"github.com/dop251/goja"
+	"github.com/dop251/goja/inspector"
 	"github.com/dop251/goja_nodejs/console"
 	"github.com/dop251/goja_nodejs/require"
 )
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
-var timelimit = flag.Int("timelimit", 0, "max time to run (in seconds)")
+var timelimit = flag.Int("timelimit", 0, "max time to run (in seconds), with -inspect it starts when the debugger "+
+	"has attached and includes the time spent paused")
+var inspect = flag.String("inspect", "", "serve the Chrome DevTools Protocol at this address (e.g. 127.0.0.1:9229), "+
+	"wait for a debugger to attach and pause at the first statement")
+var coverage = flag.String("coverage", "", "write code coverage to file, in the Istanbul JSON format if the name ends "+
+	"with .json, in the LCOV format otherwise")
 
 func readSource(filename string) ([]byte, error) {
 	if filename == "" || filename == "-" {
@@ -49,7 +59,45 @@ func newRandSource() goja.RandSource {
 	return rand.New(rand.NewSource(seed)).Float64
 }
 
-func run() error {
+func startInspector(vm *goja.Runtime, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	insp := inspector.New(vm)
+	go http.Serve(ln, insp)
+	fmt.Fprintf(os.Stderr, "Debugger listening on %s\n", insp.WebSocketURL(ln.Addr().String()))
+	fmt.Fprintln(os.Stderr, "Waiting for the debugger to attach...")
+	insp.WaitForDebugger()
+	insp.Break()
+	return nil
+}
+
+func writeCoverage(cov *goja.Coverage, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(filename) == ".json" {
+		err = cov.WriteIstanbul(f)
+	} else {
+		err = cov.WriteLCOV(f)
+	}
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// runContext returns the context the script runs with, which has a deadline if -timelimit is set.
+func runContext() (context.Context, context.CancelFunc) {
+	if *timelimit > 0 {
+		return context.WithTimeout(context.Background(), time.Duration(*timelimit)*time.Second)
+	}
+	return context.WithCancel(context.Background())
+}
+
+func run() (err error) {
 	filename := flag.Arg(0)
 	src, err := readSource(filename)
 	if err != nil {
@@ -66,6 +114,19 @@ func run() error {
 	new(require.Registry).Enable(vm)
 	console.Enable(vm)
 
+	compile := goja.Compile
+	if *coverage != "" {
+		cov := goja.NewCoverage()
+		vm.SetCoverage(cov)
+		compile = cov.Compile
+		defer func() {
+			// written even if the script has failed
+			if err1 := writeCoverage(cov, *coverage); err == nil {
+				err = err1
+			}
+		}()
+	}
+
 	vm.Set("load", func(call goja.FunctionCall) goja.Value {
 		return load(vm, call)
 	})
@@ -78,19 +139,27 @@ func run() error {
 		return string(b), nil
 	})
 
-	if *timelimit > 0 {
-		time.AfterFunc(time.Duration(*timelimit)*time.Second, func() {
-			vm.Interrupt("timeout")
-		})
+	if *inspect != "" {
+		if err := startInspector(vm, *inspect); err != nil {
+			return err
+		}
+		// created after the debugger has attached, so that waiting for it does not count against the limit
+		ctx, cancel := runContext()
+		defer cancel()
+		// the code is compiled by the Runtime so that all variables are visible to the debugger
+		_, err = vm.RunScriptContext(ctx, filename, string(src))
+		return err
 	}
 
 	//log.Println("Compiling...")
-	prg, err := goja.Compile(filename, string(src), false)
+	prg, err := compile(filename, string(src), false)
 	if err != nil {
 		return err
 	}
 	//log.Println("Running...")
-	_, err = vm.RunProgram(prg)
+	ctx, cancel := runContext()
+	defer cancel()
+	_, err = vm.RunProgramContext(ctx, prg)
 	//log.Println("Finished.")
 	return err
 }
@@ -0,0 +1,337 @@
+// Package inspector implements a Chrome DevTools Protocol (CDP) server for goja. It allows Chrome DevTools,
+// VS Code and other CDP clients to attach to a Runtime to debug it (breakpoints, stepping, call stack and variable
+// inspection, console evaluation) and to take CPU profiles.
+//
+// The Debugger, Runtime and Profiler domains are supported to the extent needed by the DevTools front-end.
+// Only one client can be attached at a time.
+//
+// Usage:
+//
+//	r := goja.New()
+//	insp := inspector.New(r)
+//	ln, err := net.Listen("tcp", "127.0.0.1:9229")
+//	if err != nil { /* ... */ }
+//	go http.Serve(ln, insp)
+//	log.Printf("Debugger listening on %s", insp.WebSocketURL(ln.Addr().String()))
+//	insp.WaitForDebugger()
+//	_, err = r.RunScript("main.js", src)
+//
+// In Chrome, open chrome://inspect and add the address under "Discover network targets". In VS Code use an "attach"
+// launch configuration of type "node" with the same address.
+//
+// The inspector attaches a goja.Debugger to the Runtime, so the limitations described there apply: the code must be
+// compiled by the Runtime (RunScript, RunString) after the inspector has been created, otherwise some of the
+// variables are not visible. Scripts that use source maps are reported with their generated source.
+//
+// Commands that need to run JavaScript (e.g. evaluating an expression in the console) are executed when the Runtime
+// is paused or, if it is running, at the next statement. If the Runtime is idle they are delayed until it runs some code,
+// and if too many of them are pending, the new ones fail with an error.
+//
+// The inspector does not authenticate the clients, anyone who can connect to it can run arbitrary code in the Runtime,
+// so it should only listen on the loopback interface. To protect against DNS rebinding attacks, the requests are
+// rejected unless their Host header is "localhost" or an IP address, and the WebSocket connections are rejected if
+// they come from a web page (i.e. have an Origin header other than devtools://).
+package inspector
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dop251/goja"
+)
+
+// task is a command that must be executed on the goroutine running the Runtime. p is nil if the Runtime is not paused.
+// If resume is true the pause ends with the returned action.
+type task func(p *pauseState) (action goja.DebugAction, resume bool)
+
+type script struct {
+	id     string
+	url    string
+	source string
+}
+
+// Inspector serves the Chrome DevTools Protocol for a Runtime. It implements http.Handler, serving both the
+// discovery endpoints (/json, /json/list and /json/version) and the WebSocket connections.
+type Inspector struct {
+	r  *goja.Runtime
+	d  *goja.Debugger
+	id string
+
+	tasks chan task
+
+	// set when the pause has been requested by the client rather than to run the pending tasks
+	breakRequested uint32
+
+	// the action of the last pause, accessed only on the Runtime goroutine
+	action goja.DebugAction
+	// set while the Runtime evaluates code on behalf of the client, accessed only on the Runtime goroutine
+	evaluating bool
+
+	mu           sync.Mutex
+	session      *session
+	scripts      []*script
+	scriptsByURL map[string]*script
+	scriptsByID  map[string]*script
+
+	waitOnce sync.Once
+	waitCh   chan struct{}
+}
+
+// New creates an Inspector for the Runtime and attaches a debugger to it (see goja.Runtime.AttachDebugger).
+func New(r *goja.Runtime) *Inspector {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	i := &Inspector{
+		r:            r,
+		id:           hex.EncodeToString(id[:]),
+		tasks:        make(chan task, maxPendingTasks),
+		scriptsByURL: make(map[string]*script),
+		scriptsByID:  make(map[string]*script),
+		waitCh:       make(chan struct{}),
+	}
+	i.d = r.AttachDebugger(i.onPause)
+	i.d.SetScriptHandler(i.onScript)
+	return i
+}
+
+// WebSocketURL returns the URL the clients should connect to, given the address the Inspector is served at.
+func (i *Inspector) WebSocketURL(host string) string {
+	return "ws://" + host + "/" + i.id
+}
+
+// WaitForDebugger blocks until a client attaches and signals that it is ready (by sending
+// Runtime.runIfWaitingForDebugger), so that the breakpoints it has set apply from the start.
+func (i *Inspector) WaitForDebugger() {
+	<-i.waitCh
+}
+
+// Break requests the execution to pause at the next statement, as if the client has requested it.
+// It is safe to call from any goroutine.
+func (i *Inspector) Break() {
+	atomic.StoreUint32(&i.breakRequested, 1)
+	i.d.Pause()
+}
+
+// Close disconnects the client (if any) and detaches the debugger from the Runtime. It must not be called
+// while the Runtime is running.
+func (i *Inspector) Close() {
+	i.mu.Lock()
+	s := i.session
+	i.mu.Unlock()
+	if s != nil {
+		s.ws.Close()
+	}
+	i.r.DetachDebugger()
+}
+
+// isAllowedHost returns true if the value of the Host header is "localhost" or an IP address, with an optional port.
+// Any other name could have been resolved to a loopback address by a malicious DNS server.
+func isAllowedHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	} else if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	return strings.EqualFold(host, "localhost") || net.ParseIP(host) != nil
+}
+
+// isAllowedOrigin returns true if the WebSocket connection does not come from a web page. The browsers always
+// send the Origin header, the DevTools front-end sends devtools://devtools and the other clients do not send it.
+func isAllowedOrigin(origin string) bool {
+	return origin == "" || strings.HasPrefix(origin, "devtools://")
+}
+
+func (i *Inspector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !isAllowedHost(req.Host) {
+		http.Error(w, "Host header is not allowed: "+req.Host, http.StatusForbidden)
+		return
+	}
+	switch req.URL.Path {
+	case "/json", "/json/list":
+		i.writeJSON(w, []interface{}{i.target(req.Host)})
+	case "/json/version":
+		i.writeJSON(w, map[string]string{
+			"Browser":          "goja",
+			"Protocol-Version": "1.3",
+		})
+	case "/" + i.id:
+		if origin := req.Header.Get("Origin"); !isAllowedOrigin(origin) {
+			http.Error(w, "Origin is not allowed: "+origin, http.StatusForbidden)
+			return
+		}
+		i.mu.Lock()
+		busy := i.session != nil
+		i.mu.Unlock()
+		if busy {
+			http.Error(w, "A debugger is already attached", http.StatusConflict)
+			return
+		}
+		ws, err := wsUpgrade(w, req)
+		if err != nil {
+			return
+		}
+		i.serve(ws)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (i *Inspector) target(host string) map[string]string {
+	ws := host + "/" + i.id
+	return map[string]string{
+		"description":          "goja instance",
+		"devtoolsFrontendUrl":  "devtools://devtools/bundled/js_app.html?experiments=true&v8only=true&ws=" + ws,
+		"id":                   i.id,
+		"title":                "goja",
+		"type":                 "node",
+		"url":                  "file://",
+		"webSocketDebuggerUrl": "ws://" + ws,
+	}
+}
+
+func (i *Inspector) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
+
+func (i *Inspector) serve(ws *wsConn) {
+	s := newSession(i, ws)
+	i.mu.Lock()
+	if i.session != nil {
+		i.mu.Unlock()
+		ws.Close()
+		return
+	}
+	i.session = s
+	i.mu.Unlock()
+
+	s.run()
+
+	i.mu.Lock()
+	i.session = nil
+	i.mu.Unlock()
+	close(s.done)
+	s.cleanup()
+	ws.Close()
+}
+
+func (i *Inspector) currentSession() *session {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.session
+}
+
+// maxPendingTasks is the number of tasks that can be waiting for the Runtime, the commands that would exceed it
+// are rejected (the Runtime does not run the tasks while it is idle, so waiting for a free slot could block forever).
+const maxPendingTasks = 64
+
+// enqueue schedules a task to be run on the Runtime goroutine. It returns false if there are too many pending tasks.
+func (i *Inspector) enqueue(t task) bool {
+	select {
+	case i.tasks <- t:
+	default:
+		return false
+	}
+	i.d.Pause()
+	return true
+}
+
+// onScript is called on the Runtime goroutine when a script is compiled.
+func (i *Inspector) onScript(name, source string) {
+	if i.evaluating {
+		return
+	}
+	i.mu.Lock()
+	sc := i.addScript(name, source)
+	s := i.session
+	i.mu.Unlock()
+	if s != nil {
+		s.scriptParsed(sc)
+	}
+}
+
+// addScript registers a script, replacing the one with the same url. Must be called with i.mu held.
+func (i *Inspector) addScript(url, source string) *script {
+	if sc := i.scriptsByURL[url]; sc != nil && sc.source == source {
+		return sc
+	}
+	sc := &script{
+		id:     strconv.Itoa(len(i.scripts) + 1),
+		url:    url,
+		source: source,
+	}
+	i.scripts = append(i.scripts, sc)
+	i.scriptsByURL[url] = sc
+	i.scriptsByID[sc.id] = sc
+	return sc
+}
+
+// scriptForURL returns the script with the given url registering an empty one if it is not known (which happens
+// for the code compiled before the inspector was created and for the sources mapped by source maps).
+func (i *Inspector) scriptForURL(s *session, url string) *script {
+	i.mu.Lock()
+	sc := i.scriptsByURL[url]
+	if sc == nil {
+		sc = i.addScript(url, "")
+		i.mu.Unlock()
+		s.scriptParsed(sc)
+		return sc
+	}
+	i.mu.Unlock()
+	return sc
+}
+
+func (i *Inspector) runPending(p *pauseState) {
+	for {
+		select {
+		case t := <-i.tasks:
+			t(p)
+		default:
+			return
+		}
+	}
+}
+
+// onPause is the goja.DebugHandler, it runs on the Runtime goroutine.
+func (i *Inspector) onPause(p *goja.DebugPause) goja.DebugAction {
+	s := i.currentSession()
+	if s == nil || !s.isDebuggerEnabled() {
+		i.runPending(nil)
+		i.action = goja.DebugContinue
+		return i.action
+	}
+	if p.Reason() == goja.PauseRequested && atomic.SwapUint32(&i.breakRequested, 0) == 0 {
+		// the pause has been requested to run the pending tasks
+		i.runPending(nil)
+		return i.action
+	}
+
+	ps := s.newPauseState(p)
+	s.sendEvent("Debugger.paused", ps.pausedEvent())
+	for {
+		select {
+		case t := <-i.tasks:
+			if action, resume := t(ps); resume {
+				i.action = action
+				s.sendEvent("Debugger.resumed", struct{}{})
+				return action
+			}
+		case <-s.done:
+			i.action = goja.DebugContinue
+			return i.action
+		}
+	}
+}
+
+func urlToFilename(url string) string {
+	return strings.TrimPrefix(url, "file://")
+}
@@ -0,0 +1,366 @@
+package inspector
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+type testClient struct {
+	t       *testing.T
+	ws      *wsConn
+	nextID  int64
+	msgs    chan map[string]interface{}
+	pending []map[string]interface{}
+}
+
+const testWsKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+// wsHandshake sends the opening handshake with the additional header lines and returns the response.
+func wsHandshake(t *testing.T, wsURL, header string) (*http.Response, net.Conn, *bufio.Reader) {
+	addr := strings.TrimPrefix(wsURL, "ws://")
+	host, path, _ := strings.Cut(addr, "/")
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Write([]byte("GET /" + path + " HTTP/1.1\r\nHost: " + host + "\r\nUpgrade: websocket\r\n" +
+		"Connection: Upgrade\r\nSec-WebSocket-Key: " + testWsKey + "\r\nSec-WebSocket-Version: 13\r\n" + header + "\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, conn, br
+}
+
+func dialTestClient(t *testing.T, wsURL string) *testClient {
+	resp, conn, br := wsHandshake(t, wsURL, "")
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(testWsKey) {
+		t.Fatalf("unexpected handshake response: %v", resp)
+	}
+	c := &testClient{
+		t:    t,
+		ws:   &wsConn{conn: conn, r: br, mask: true},
+		msgs: make(chan map[string]interface{}, 100),
+	}
+	go func() {
+		defer close(c.msgs)
+		for {
+			b, err := c.ws.readMessage()
+			if err != nil {
+				return
+			}
+			var msg map[string]interface{}
+			if err := json.Unmarshal(b, &msg); err != nil {
+				t.Error(err)
+				return
+			}
+			c.msgs <- msg
+		}
+	}()
+	return c
+}
+
+// next returns the first message (including the ones received earlier) that matches.
+func (c *testClient) next(match func(map[string]interface{}) bool) map[string]interface{} {
+	c.t.Helper()
+	for i, msg := range c.pending {
+		if match(msg) {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return msg
+		}
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg, ok := <-c.msgs:
+			if !ok {
+				c.t.Fatal("connection closed")
+			}
+			if match(msg) {
+				return msg
+			}
+			c.pending = append(c.pending, msg)
+		case <-timeout:
+			c.t.Fatal("timeout")
+		}
+	}
+}
+
+func (c *testClient) call(method string, params interface{}) map[string]interface{} {
+	c.t.Helper()
+	c.nextID++
+	id := c.nextID
+	b, _ := json.Marshal(map[string]interface{}{"id": id, "method": method, "params": params})
+	if err := c.ws.writeMessage(b); err != nil {
+		c.t.Fatal(err)
+	}
+	resp := c.next(func(msg map[string]interface{}) bool {
+		return msg["id"] == float64(id)
+	})
+	if e := resp["error"]; e != nil {
+		c.t.Fatalf("%s: %v", method, e)
+	}
+	res, _ := resp["result"].(map[string]interface{})
+	return res
+}
+
+func (c *testClient) waitEvent(method string) map[string]interface{} {
+	c.t.Helper()
+	ev := c.next(func(msg map[string]interface{}) bool {
+		return msg["method"] == method
+	})
+	params, _ := ev["params"].(map[string]interface{})
+	return params
+}
+
+// get returns the value at the path of nested objects and arrays.
+func get(v interface{}, path ...interface{}) interface{} {
+	for _, p := range path {
+		switch p := p.(type) {
+		case string:
+			m, _ := v.(map[string]interface{})
+			v = m[p]
+		case int:
+			a, _ := v.([]interface{})
+			if p >= len(a) {
+				return nil
+			}
+			v = a[p]
+		}
+	}
+	return v
+}
+
+func TestInspector(t *testing.T) {
+	const SCRIPT = `function f(a) {
+	var b = a + 1;
+	return b;
+}
+f(1);
+`
+	r := goja.New()
+	insp := New(r)
+	defer insp.Close()
+	srv := httptest.NewServer(insp)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/json/list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var targets []map[string]string
+	err = json.NewDecoder(resp.Body).Decode(&targets)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wsURL := insp.WebSocketURL(strings.TrimPrefix(srv.URL, "http://"))
+	if len(targets) != 1 || targets[0]["webSocketDebuggerUrl"] != wsURL {
+		t.Fatalf("unexpected targets: %v", targets)
+	}
+
+	c := dialTestClient(t, wsURL)
+	defer c.ws.Close()
+	c.call("Runtime.enable", nil)
+	c.waitEvent("Runtime.executionContextCreated")
+	c.call("Debugger.enable", nil)
+	bp := c.call("Debugger.setBreakpointByUrl", map[string]interface{}{"url": "test.js", "lineNumber": 2})
+	c.call("Runtime.runIfWaitingForDebugger", nil)
+
+	done := make(chan error, 1)
+	go func() {
+		insp.WaitForDebugger()
+		_, err := r.RunScript("test.js", SCRIPT)
+		done <- err
+	}()
+
+	sc := c.waitEvent("Debugger.scriptParsed")
+	if sc["url"] != "test.js" {
+		t.Fatalf("unexpected script: %v", sc)
+	}
+	src := c.call("Debugger.getScriptSource", map[string]interface{}{"scriptId": sc["scriptId"]})
+	if src["scriptSource"] != SCRIPT {
+		t.Fatalf("unexpected source: %v", src)
+	}
+
+	paused := c.waitEvent("Debugger.paused")
+	if get(paused, "hitBreakpoints", 0) != bp["breakpointId"] {
+		t.Fatalf("unexpected hitBreakpoints: %v", paused["hitBreakpoints"])
+	}
+	if get(paused, "callFrames", 0, "location", "lineNumber") != float64(2) ||
+		get(paused, "callFrames", 0, "functionName") != "f" {
+		t.Fatalf("unexpected call frame: %v", get(paused, "callFrames", 0))
+	}
+
+	res := c.call("Debugger.evaluateOnCallFrame", map[string]interface{}{"callFrameId": "0", "expression": "a * 10 + b"})
+	if get(res, "result", "value") != float64(12) {
+		t.Fatalf("unexpected result: %v", res)
+	}
+	res = c.call("Debugger.evaluateOnCallFrame", map[string]interface{}{"callFrameId": "0", "expression": "nonexistent"})
+	if get(res, "exceptionDetails") == nil {
+		t.Fatalf("expected an exception: %v", res)
+	}
+	res = c.call("Runtime.evaluate", map[string]interface{}{"expression": "({x: [1, 2]})"})
+	objID := get(res, "result", "objectId")
+	if objID == nil {
+		t.Fatalf("expected an object: %v", res)
+	}
+	res = c.call("Runtime.getProperties", map[string]interface{}{"objectId": objID, "ownProperties": true})
+	if get(res, "result", 0, "name") != "x" || get(res, "result", 0, "value", "subtype") != "array" {
+		t.Fatalf("unexpected properties: %v", res)
+	}
+
+	scopeID := get(paused, "callFrames", 0, "scopeChain", 0, "object", "objectId")
+	if get(paused, "callFrames", 0, "scopeChain", 0, "type") != "local" {
+		t.Fatalf("unexpected scope chain: %v", get(paused, "callFrames", 0, "scopeChain"))
+	}
+	res = c.call("Runtime.getProperties", map[string]interface{}{"objectId": scopeID})
+	vars := make(map[string]interface{})
+	for _, p := range res["result"].([]interface{}) {
+		vars[get(p, "name").(string)] = get(p, "value", "value")
+	}
+	if vars["a"] != float64(1) || vars["b"] != float64(2) {
+		t.Fatalf("unexpected variables: %v", res)
+	}
+
+	c.call("Debugger.stepOver", nil)
+	c.waitEvent("Debugger.resumed")
+	paused = c.waitEvent("Debugger.paused")
+	if get(paused, "callFrames", 0, "location", "lineNumber") != float64(4) {
+		t.Fatalf("unexpected location after step: %v", get(paused, "callFrames", 0, "location"))
+	}
+	c.call("Debugger.resume", nil)
+	c.waitEvent("Debugger.resumed")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	c.call("Debugger.removeBreakpoint", map[string]interface{}{"breakpointId": bp["breakpointId"]})
+	c.call("Profiler.enable", nil)
+	c.call("Profiler.setSamplingInterval", map[string]interface{}{"interval": 1000})
+	c.call("Profiler.start", nil)
+	if _, err := r.RunString(`var end = Date.now() + 50; while (Date.now() < end) {}`); err != nil {
+		t.Fatal(err)
+	}
+	prof := c.call("Profiler.stop", nil)
+	if get(prof, "profile", "nodes", 0, "callFrame", "functionName") != "(root)" {
+		t.Fatalf("unexpected profile: %v", prof)
+	}
+
+	resp, err = http.Get(srv.URL + "/" + insp.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("second client: unexpected status %d", resp.StatusCode)
+	}
+}
+
+func TestInspectorHostOrigin(t *testing.T) {
+	insp := New(goja.New())
+	defer insp.Close()
+	srv := httptest.NewServer(insp)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		host string
+		ok   bool
+	}{
+		{"localhost", true},
+		{"LOCALHOST:9229", true},
+		{"127.0.0.1:9229", true},
+		{"[::1]:9229", true},
+		{"[::1]", true},
+		{"192.168.0.1", true},
+		{"evil.example.com", false},
+		{"evil.example.com:9229", false},
+		{"localhost.evil.example.com:9229", false},
+	} {
+		req, err := http.NewRequest("GET", srv.URL+"/json/list", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = tc.host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if ok := resp.StatusCode == http.StatusOK; ok != tc.ok {
+			t.Fatalf("%s: unexpected status %d", tc.host, resp.StatusCode)
+		}
+	}
+
+	wsURL := insp.WebSocketURL(strings.TrimPrefix(srv.URL, "http://"))
+	resp, conn, _ := wsHandshake(t, wsURL, "Origin: http://evil.example.com\r\n")
+	conn.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("web page origin: unexpected status %d", resp.StatusCode)
+	}
+	resp, conn, _ = wsHandshake(t, wsURL, "Origin: devtools://devtools\r\n")
+	conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("devtools origin: unexpected status %d", resp.StatusCode)
+	}
+}
+
+func TestInspectorPendingLimit(t *testing.T) {
+	r := goja.New()
+	insp := New(r)
+	defer insp.Close()
+	srv := httptest.NewServer(insp)
+	defer srv.Close()
+
+	c := dialTestClient(t, insp.WebSocketURL(strings.TrimPrefix(srv.URL, "http://")))
+	defer c.ws.Close()
+
+	// the Runtime is idle, so the evaluations stay pending
+	const n = maxPendingTasks + 10
+	for id := 1; id <= n; id++ {
+		b, _ := json.Marshal(map[string]interface{}{"id": id, "method": "Runtime.evaluate", "params": map[string]interface{}{"expression": "1"}})
+		if err := c.ws.writeMessage(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.nextID = n
+	for id := maxPendingTasks + 1; id <= n; id++ {
+		resp := c.next(func(msg map[string]interface{}) bool {
+			return msg["id"] == float64(id)
+		})
+		if get(resp, "error", "code") != float64(errCodeServerError) {
+			t.Fatalf("%d: expected an error: %v", id, resp)
+		}
+	}
+	// the session is not blocked
+	c.call("Runtime.getIsolateId", nil)
+
+	if _, err := r.RunString("0"); err != nil {
+		t.Fatal(err)
+	}
+	for id := 1; id <= maxPendingTasks; id++ {
+		resp := c.next(func(msg map[string]interface{}) bool {
+			return msg["id"] == float64(id)
+		})
+		if get(resp, "result", "result", "value") != float64(1) {
+			t.Fatalf("%d: unexpected response: %v", id, resp)
+		}
+	}
+}
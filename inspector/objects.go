@@ -0,0 +1,442 @@
+package inspector
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/dop251/goja"
+)
+
+type remoteObject struct {
+	Type                string          `json:"type"`
+	Subtype             string          `json:"subtype,omitempty"`
+	ClassName           string          `json:"className,omitempty"`
+	Value               json.RawMessage `json:"value,omitempty"`
+	UnserializableValue string          `json:"unserializableValue,omitempty"`
+	Description         string          `json:"description,omitempty"`
+	ObjectID            string          `json:"objectId,omitempty"`
+}
+
+type propertyDescriptor struct {
+	Name         string        `json:"name"`
+	Value        *remoteObject `json:"value,omitempty"`
+	Writable     bool          `json:"writable"`
+	Configurable bool          `json:"configurable"`
+	Enumerable   bool          `json:"enumerable"`
+	IsOwn        bool          `json:"isOwn"`
+}
+
+type internalPropertyDescriptor struct {
+	Name  string        `json:"name"`
+	Value *remoteObject `json:"value"`
+}
+
+type callFrame struct {
+	CallFrameID  string        `json:"callFrameId"`
+	FunctionName string        `json:"functionName"`
+	Location     location      `json:"location"`
+	URL          string        `json:"url"`
+	ScopeChain   []scope       `json:"scopeChain"`
+	This         *remoteObject `json:"this"`
+}
+
+type scope struct {
+	Type   string        `json:"type"`
+	Object *remoteObject `json:"object"`
+}
+
+// pauseState holds the state of the current pause, it's only accessed on the Runtime goroutine.
+type pauseState struct {
+	s      *session
+	p      *goja.DebugPause
+	frames []*goja.DebugFrame
+}
+
+func (s *session) newPauseState(p *goja.DebugPause) *pauseState {
+	return &pauseState{
+		s:      s,
+		p:      p,
+		frames: p.Frames(),
+	}
+}
+
+func (ps *pauseState) pausedEvent() interface{} {
+	s := ps.s
+	frames := make([]callFrame, 0, len(ps.frames))
+	for idx, f := range ps.frames {
+		pos := f.Position()
+		sc := s.i.scriptForURL(s, pos.Filename)
+		frame := callFrame{
+			CallFrameID:  strconv.Itoa(idx),
+			FunctionName: f.FuncName(),
+			Location: location{
+				ScriptID:     sc.id,
+				LineNumber:   pos.Line - 1,
+				ColumnNumber: pos.Column - 1,
+			},
+			URL:  sc.url,
+			This: s.remoteObject(f.This()),
+		}
+		for _, sc := range f.Scopes() {
+			frame.ScopeChain = append(frame.ScopeChain, scope{
+				Type: sc.Type().String(),
+				Object: &remoteObject{
+					Type:        "object",
+					ClassName:   "Object",
+					Description: "Object",
+					ObjectID:    s.register(sc),
+				},
+			})
+		}
+		frames = append(frames, frame)
+	}
+
+	params := map[string]interface{}{
+		"callFrames": frames,
+		"reason":     "other",
+	}
+	switch ps.p.Reason() {
+	case goja.PauseBreakpoint:
+		ps.s.i.mu.Lock()
+		params["hitBreakpoints"] = s.breakpointIDs(ps.p.Breakpoint())
+		ps.s.i.mu.Unlock()
+	case goja.PauseException:
+		params["reason"] = "exception"
+		params["data"] = s.remoteObject(ps.p.Exception().Value())
+	case goja.PauseDebuggerStatement:
+		params["reason"] = "debugCommand"
+	}
+	return params
+}
+
+func (ps *pauseState) frame(id string) *goja.DebugFrame {
+	idx, err := strconv.Atoi(id)
+	if err != nil || idx < 0 || idx >= len(ps.frames) {
+		return nil
+	}
+	return ps.frames[idx]
+}
+
+// register returns an id for a value (a goja.Value or a *goja.DebugScope) which the client can use to refer to it.
+func (s *session) register(v interface{}) string {
+	s.nextObject++
+	id := strconv.Itoa(s.nextObject)
+	s.objects[id] = v
+	return id
+}
+
+func (s *session) remoteObject(v goja.Value) *remoteObject {
+	if v == nil || goja.IsUndefined(v) {
+		return &remoteObject{Type: "undefined"}
+	}
+	if goja.IsNull(v) {
+		return &remoteObject{Type: "object", Subtype: "null", Value: json.RawMessage("null")}
+	}
+	switch v := v.(type) {
+	case *goja.Object:
+		return s.objectRemoteObject(v)
+	case *goja.Symbol:
+		return &remoteObject{Type: "symbol", Description: v.String(), ObjectID: s.register(v)}
+	}
+	switch e := v.Export().(type) {
+	case bool:
+		return &remoteObject{Type: "boolean", Value: marshal(e), Description: v.String()}
+	case int64:
+		return &remoteObject{Type: "number", Value: marshal(e), Description: v.String()}
+	case float64:
+		o := &remoteObject{Type: "number", Description: v.String()}
+		if math.IsNaN(e) || math.IsInf(e, 0) || e == 0 && math.Signbit(e) {
+			if e == 0 {
+				o.Description = "-0"
+			}
+			o.UnserializableValue = o.Description
+		} else {
+			o.Value = marshal(e)
+		}
+		return o
+	case *big.Int:
+		return &remoteObject{Type: "bigint", UnserializableValue: e.String() + "n", Description: e.String() + "n"}
+	default:
+		return &remoteObject{Type: "string", Value: marshal(v.String())}
+	}
+}
+
+func (s *session) objectRemoteObject(o *goja.Object) *remoteObject {
+	r := s.i.r
+	ro := &remoteObject{
+		Type:        "object",
+		ClassName:   o.ClassName(),
+		Description: o.ClassName(),
+		ObjectID:    s.register(o),
+	}
+	str := func() string {
+		var res string
+		if r.Try(func() {
+			res = o.String()
+		}) != nil {
+			return ro.ClassName
+		}
+		return res
+	}
+	if _, ok := goja.AssertFunction(o); ok {
+		ro.Type = "function"
+		ro.ClassName = "Function"
+		ro.Description = str()
+		return ro
+	}
+	switch ro.ClassName {
+	case "Array":
+		ro.Subtype = "array"
+		ro.Description = "Array(" + strconv.FormatInt(o.Get("length").ToInteger(), 10) + ")"
+	case "RegExp":
+		ro.Subtype = "regexp"
+		ro.Description = str()
+	case "Date":
+		ro.Subtype = "date"
+		ro.Description = str()
+	case "Error":
+		ro.Subtype = "error"
+		if stack := o.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+			ro.Description = stack.String()
+		} else {
+			ro.Description = str()
+		}
+	case "Map":
+		ro.Subtype = "map"
+	case "Set":
+		ro.Subtype = "set"
+	case "WeakMap":
+		ro.Subtype = "weakmap"
+	case "WeakSet":
+		ro.Subtype = "weakset"
+	case "Promise":
+		ro.Subtype = "promise"
+	}
+	return ro
+}
+
+func marshal(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return b
+}
+
+// evalResult converts the result of an evaluation to the format shared by Runtime.evaluate, Runtime.callFunctionOn
+// and Debugger.evaluateOnCallFrame.
+func (s *session) evalResult(v goja.Value, err error, byValue bool) interface{} {
+	if err != nil {
+		var val goja.Value
+		var ex *goja.Exception
+		if errors.As(err, &ex) {
+			val = ex.Value()
+		} else {
+			val = s.i.r.ToValue(err.Error())
+		}
+		ro := s.remoteObject(val)
+		return map[string]interface{}{
+			"result": ro,
+			"exceptionDetails": map[string]interface{}{
+				"exceptionId":  1,
+				"text":         "Uncaught",
+				"lineNumber":   0,
+				"columnNumber": 0,
+				"exception":    ro,
+			},
+		}
+	}
+	if o, ok := v.(*goja.Object); ok && byValue {
+		return map[string]interface{}{"result": &remoteObject{Type: "object", Value: marshal(o.Export())}}
+	}
+	return map[string]interface{}{"result": s.remoteObject(v)}
+}
+
+// runEval runs f while making sure the scripts compiled by it are not reported to the client.
+func (s *session) runEval(f func() (goja.Value, error)) (goja.Value, error) {
+	s.i.evaluating = true
+	defer func() {
+		s.i.evaluating = false
+	}()
+	return f()
+}
+
+func (s *session) evaluateOnCallFrame(req *request) {
+	var params struct {
+		CallFrameID   string `json:"callFrameId"`
+		Expression    string `json:"expression"`
+		ReturnByValue bool   `json:"returnByValue"`
+	}
+	if !s.parseParams(req, &params) {
+		return
+	}
+	s.enqueue(req, func(p *pauseState) (goja.DebugAction, bool) {
+		var f *goja.DebugFrame
+		if p != nil {
+			f = p.frame(params.CallFrameID)
+		}
+		if f == nil {
+			s.respondError(req.ID, errCodeServerError, "Could not find call frame with given id")
+			return 0, false
+		}
+		v, err := s.runEval(func() (goja.Value, error) {
+			return f.Eval(params.Expression)
+		})
+		s.respond(req.ID, s.evalResult(v, err, params.ReturnByValue))
+		return 0, false
+	})
+}
+
+func (s *session) evaluate(req *request) {
+	var params struct {
+		Expression    string `json:"expression"`
+		ReturnByValue bool   `json:"returnByValue"`
+	}
+	if !s.parseParams(req, &params) {
+		return
+	}
+	s.enqueue(req, func(*pauseState) (goja.DebugAction, bool) {
+		v, err := s.runEval(func() (goja.Value, error) {
+			return s.i.r.RunString(params.Expression)
+		})
+		s.respond(req.ID, s.evalResult(v, err, params.ReturnByValue))
+		return 0, false
+	})
+}
+
+func (s *session) callFunctionOn(req *request) {
+	var params struct {
+		FunctionDeclaration string `json:"functionDeclaration"`
+		ObjectID            string `json:"objectId"`
+		Arguments           []struct {
+			Value               json.RawMessage `json:"value"`
+			UnserializableValue string          `json:"unserializableValue"`
+			ObjectID            string          `json:"objectId"`
+		} `json:"arguments"`
+		ReturnByValue bool `json:"returnByValue"`
+	}
+	if !s.parseParams(req, &params) {
+		return
+	}
+	s.enqueue(req, func(*pauseState) (goja.DebugAction, bool) {
+		r := s.i.r
+		this := goja.Undefined()
+		if params.ObjectID != "" {
+			v, ok := s.objects[params.ObjectID].(goja.Value)
+			if !ok {
+				s.respondError(req.ID, errCodeServerError, "Could not find object with given id")
+				return 0, false
+			}
+			this = v
+		}
+		args := make([]goja.Value, len(params.Arguments))
+		for idx, a := range params.Arguments {
+			switch {
+			case a.ObjectID != "":
+				v, _ := s.objects[a.ObjectID].(goja.Value)
+				args[idx] = v
+			case a.UnserializableValue != "":
+				args[idx], _ = s.runEval(func() (goja.Value, error) {
+					return r.RunString(a.UnserializableValue)
+				})
+			case len(a.Value) > 0:
+				var v interface{}
+				_ = json.Unmarshal(a.Value, &v)
+				args[idx] = r.ToValue(v)
+			default:
+				args[idx] = goja.Undefined()
+			}
+		}
+		v, err := s.runEval(func() (goja.Value, error) {
+			fn, err := r.RunString("(" + params.FunctionDeclaration + "\n)")
+			if err != nil {
+				return nil, err
+			}
+			call, ok := goja.AssertFunction(fn)
+			if !ok {
+				return nil, errors.New("the function declaration is not a function")
+			}
+			return call(this, args...)
+		})
+		s.respond(req.ID, s.evalResult(v, err, params.ReturnByValue))
+		return 0, false
+	})
+}
+
+func (s *session) getProperties(req *request) {
+	var params struct {
+		ObjectID               string `json:"objectId"`
+		OwnProperties          bool   `json:"ownProperties"`
+		AccessorPropertiesOnly bool   `json:"accessorPropertiesOnly"`
+	}
+	if !s.parseParams(req, &params) {
+		return
+	}
+	s.enqueue(req, func(*pauseState) (goja.DebugAction, bool) {
+		result := []propertyDescriptor{}
+		var internal []internalPropertyDescriptor
+		switch obj := s.objects[params.ObjectID].(type) {
+		case *goja.DebugScope:
+			if params.AccessorPropertiesOnly {
+				break
+			}
+			for _, name := range obj.Names() {
+				v, _ := obj.Get(name)
+				result = append(result, propertyDescriptor{
+					Name:         name,
+					Value:        s.remoteObject(v),
+					Writable:     true,
+					Configurable: true,
+					Enumerable:   true,
+					IsOwn:        true,
+				})
+			}
+		case *goja.Object:
+			if params.AccessorPropertiesOnly {
+				break
+			}
+			r := s.i.r
+			var names []string
+			if ex := r.Try(func() {
+				names = obj.GetOwnPropertyNames()
+			}); ex != nil {
+				s.respond(req.ID, s.evalResult(nil, ex, false))
+				return 0, false
+			}
+			for _, name := range names {
+				var v goja.Value
+				if ex := r.Try(func() {
+					v = obj.Get(name)
+				}); ex != nil {
+					v = ex.Value()
+				}
+				result = append(result, propertyDescriptor{
+					Name:         name,
+					Value:        s.remoteObject(v),
+					Writable:     true,
+					Configurable: true,
+					Enumerable:   true,
+					IsOwn:        true,
+				})
+			}
+			if proto := obj.Prototype(); proto != nil {
+				internal = append(internal, internalPropertyDescriptor{
+					Name:  "[[Prototype]]",
+					Value: s.remoteObject(proto),
+				})
+			}
+		case nil:
+			s.respondError(req.ID, errCodeServerError, "Could not find object with given id")
+			return 0, false
+		}
+		res := map[string]interface{}{"result": result}
+		if len(internal) > 0 {
+			res["internalProperties"] = internal
+		}
+		s.respond(req.ID, res)
+		return 0, false
+	})
+}
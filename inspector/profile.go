@@ -0,0 +1,115 @@
+package inspector
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// The CPU profile format of the Profiler domain.
+
+type profileCallFrame struct {
+	FunctionName string `json:"functionName"`
+	ScriptID     string `json:"scriptId"`
+	URL          string `json:"url"`
+	LineNumber   int    `json:"lineNumber"`
+	ColumnNumber int    `json:"columnNumber"`
+}
+
+type profileNode struct {
+	ID        int              `json:"id"`
+	CallFrame profileCallFrame `json:"callFrame"`
+	HitCount  int64            `json:"hitCount"`
+	Children  []int            `json:"children,omitempty"`
+
+	childByFrame map[profileCallFrame]*profileNode
+}
+
+type cdpProfile struct {
+	Nodes      []*profileNode `json:"nodes"`
+	StartTime  int64          `json:"startTime"`
+	EndTime    int64          `json:"endTime"`
+	Samples    []int          `json:"samples"`
+	TimeDeltas []int64        `json:"timeDeltas"`
+}
+
+// cdpProfile converts a profile produced by Runtime.StartProfile() (in the pprof format) to the CDP format.
+// Since the pprof profile only holds the number of samples per stack, the samples are spread evenly over time.
+func (s *session) cdpProfile(data []byte, start, end time.Time) (*cdpProfile, error) {
+	p, err := profile.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	root := &profileNode{
+		ID: 1,
+		CallFrame: profileCallFrame{
+			FunctionName: "(root)",
+			ScriptID:     "0",
+			LineNumber:   -1,
+			ColumnNumber: -1,
+		},
+	}
+	res := &cdpProfile{
+		Nodes:      []*profileNode{root},
+		StartTime:  start.UnixMicro(),
+		Samples:    []int{},
+		TimeDeltas: []int64{},
+	}
+	interval := p.Period / 1000
+	for _, sample := range p.Sample {
+		node := root
+		// the locations (and the lines within them) are ordered from the leaf to the root
+		for i := len(sample.Location) - 1; i >= 0; i-- {
+			lines := sample.Location[i].Line
+			for j := len(lines) - 1; j >= 0; j-- {
+				node = res.child(s, node, lines[j])
+			}
+		}
+		count := sample.Value[0]
+		node.HitCount += count
+		for ; count > 0; count-- {
+			res.Samples = append(res.Samples, node.ID)
+			res.TimeDeltas = append(res.TimeDeltas, interval)
+		}
+	}
+	res.EndTime = res.StartTime + int64(len(res.Samples))*interval
+	if e := end.UnixMicro(); res.EndTime < e {
+		res.EndTime = e
+	}
+	return res, nil
+}
+
+func (res *cdpProfile) child(s *session, parent *profileNode, line profile.Line) *profileNode {
+	url := line.Function.Filename
+	frame := profileCallFrame{
+		FunctionName: line.Function.Name,
+		ScriptID:     s.scriptIDForURL(url),
+		URL:          url,
+		LineNumber:   int(line.Line) - 1,
+		ColumnNumber: -1,
+	}
+	if n := parent.childByFrame[frame]; n != nil {
+		return n
+	}
+	n := &profileNode{
+		ID:        len(res.Nodes) + 1,
+		CallFrame: frame,
+	}
+	if parent.childByFrame == nil {
+		parent.childByFrame = make(map[profileCallFrame]*profileNode)
+	}
+	parent.childByFrame[frame] = n
+	parent.Children = append(parent.Children, n.ID)
+	res.Nodes = append(res.Nodes, n)
+	return n
+}
+
+func (s *session) scriptIDForURL(url string) string {
+	s.i.mu.Lock()
+	defer s.i.mu.Unlock()
+	if sc := s.i.scriptsByURL[url]; sc != nil {
+		return sc.id
+	}
+	return "0"
+}
@@ -0,0 +1,476 @@
+package inspector
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+type request struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type response struct {
+	ID     int64        `json:"id"`
+	Result interface{}  `json:"result,omitempty"`
+	Error  *responseErr `json:"error,omitempty"`
+}
+
+type responseErr struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type event struct {
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+const (
+	errCodeInvalidParams  = -32602
+	errCodeMethodNotFound = -32601
+	errCodeServerError    = -32000
+)
+
+// noOpMethods are acknowledged without doing anything, either because there is nothing to do or because the
+// functionality is not supported, but the clients expect them to succeed.
+var noOpMethods = map[string]bool{
+	"Runtime.disable":                            true,
+	"Runtime.discardConsoleEntries":              true,
+	"Runtime.setAsyncCallStackDepth":             true,
+	"Runtime.addBinding":                         true,
+	"Runtime.setCustomObjectFormatterEnabled":    true,
+	"Debugger.setAsyncCallStackDepth":            true,
+	"Debugger.setBlackboxPatterns":               true,
+	"Debugger.setBlackboxExecutionContexts":      true,
+	"Debugger.setBreakpointsActive":              true,
+	"Debugger.setSkipAllPauses":                  true,
+	"Debugger.setInstrumentationBreakpoint":      true,
+	"Debugger.removeInstrumentationBreakpoint":   true,
+	"Profiler.enable":                            true,
+	"Profiler.disable":                           true,
+	"HeapProfiler.enable":                        true,
+	"HeapProfiler.disable":                       true,
+	"HeapProfiler.collectGarbage":                true,
+	"NodeRuntime.enable":                         true,
+	"NodeRuntime.notifyWhenWaitingForDisconnect": true,
+	"NodeWorker.enable":                          true,
+}
+
+// session is a connection of a client.
+type session struct {
+	i    *Inspector
+	ws   *wsConn
+	done chan struct{}
+
+	// the fields below are protected by i.mu
+	debuggerEnabled bool
+	breakpoints     map[string][]*goja.Breakpoint
+	nextBreakpoint  int
+
+	// accessed only on the reader goroutine
+	profiling    bool
+	profBuf      bytes.Buffer
+	profStart    time.Time
+	profInterval time.Duration
+
+	// accessed only on the Runtime goroutine
+	objects    map[string]interface{}
+	nextObject int
+
+	sendMu sync.Mutex
+}
+
+func newSession(i *Inspector, ws *wsConn) *session {
+	return &session{
+		i:           i,
+		ws:          ws,
+		done:        make(chan struct{}),
+		breakpoints: make(map[string][]*goja.Breakpoint),
+		objects:     make(map[string]interface{}),
+	}
+}
+
+func (s *session) send(v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	_ = s.ws.writeMessage(b)
+}
+
+func (s *session) sendEvent(method string, params interface{}) {
+	s.send(event{Method: method, Params: params})
+}
+
+func (s *session) respond(id int64, result interface{}) {
+	if result == nil {
+		result = struct{}{}
+	}
+	s.send(response{ID: id, Result: result})
+}
+
+func (s *session) respondError(id int64, code int, msg string) {
+	s.send(response{ID: id, Error: &responseErr{Code: code, Message: msg}})
+}
+
+func (s *session) isDebuggerEnabled() bool {
+	s.i.mu.Lock()
+	defer s.i.mu.Unlock()
+	return s.debuggerEnabled
+}
+
+func (s *session) run() {
+	for {
+		msg, err := s.ws.readMessage()
+		if err != nil {
+			return
+		}
+		var req request
+		if err := json.Unmarshal(msg, &req); err != nil {
+			continue
+		}
+		s.dispatch(&req)
+	}
+}
+
+// cleanup undoes the changes the client has made to the state of the Runtime.
+func (s *session) cleanup() {
+	s.i.mu.Lock()
+	for _, list := range s.breakpoints {
+		for _, b := range list {
+			s.i.d.RemoveBreakpoint(b)
+		}
+	}
+	s.breakpoints = nil
+	s.i.mu.Unlock()
+	s.i.d.SetPauseOnExceptions(false)
+	if s.profiling {
+		_ = s.i.r.StopProfile()
+	}
+}
+
+func (s *session) scriptParsed(sc *script) {
+	s.i.mu.Lock()
+	enabled := s.debuggerEnabled
+	s.i.mu.Unlock()
+	if enabled {
+		s.sendEvent("Debugger.scriptParsed", scriptParsedEvent(sc))
+	}
+}
+
+func scriptParsedEvent(sc *script) interface{} {
+	lines := strings.Count(sc.source, "\n")
+	lastLine := sc.source[strings.LastIndexByte(sc.source, '\n')+1:]
+	return map[string]interface{}{
+		"scriptId":           sc.id,
+		"url":                sc.url,
+		"startLine":          0,
+		"startColumn":        0,
+		"endLine":            lines,
+		"endColumn":          len(lastLine),
+		"executionContextId": 1,
+		"hash":               "",
+		"isModule":           false,
+		"length":             len(sc.source),
+	}
+}
+
+func (s *session) dispatch(req *request) {
+	switch req.Method {
+	case "Runtime.enable":
+		s.respond(req.ID, nil)
+		s.sendEvent("Runtime.executionContextCreated", map[string]interface{}{
+			"context": map[string]interface{}{
+				"id":     1,
+				"origin": "",
+				"name":   "goja",
+				"auxData": map[string]interface{}{
+					"isDefault": true,
+				},
+			},
+		})
+	case "Runtime.runIfWaitingForDebugger":
+		s.respond(req.ID, nil)
+		s.i.waitOnce.Do(func() {
+			close(s.i.waitCh)
+		})
+	case "Debugger.enable":
+		s.i.mu.Lock()
+		s.debuggerEnabled = true
+		scripts := append([]*script(nil), s.i.scripts...)
+		s.i.mu.Unlock()
+		s.respond(req.ID, map[string]string{"debuggerId": s.i.id})
+		for _, sc := range scripts {
+			s.sendEvent("Debugger.scriptParsed", scriptParsedEvent(sc))
+		}
+	case "Debugger.disable":
+		s.i.mu.Lock()
+		s.debuggerEnabled = false
+		s.i.mu.Unlock()
+		s.respond(req.ID, nil)
+	case "Debugger.setPauseOnExceptions":
+		var params struct {
+			State string `json:"state"`
+		}
+		if !s.parseParams(req, &params) {
+			return
+		}
+		s.i.d.SetPauseOnExceptions(params.State != "" && params.State != "none")
+		s.respond(req.ID, nil)
+	case "Debugger.setBreakpointByUrl":
+		s.setBreakpointByURL(req)
+	case "Debugger.setBreakpoint":
+		s.setBreakpoint(req)
+	case "Debugger.removeBreakpoint":
+		var params struct {
+			BreakpointID string `json:"breakpointId"`
+		}
+		if !s.parseParams(req, &params) {
+			return
+		}
+		s.removeBreakpoint(params.BreakpointID)
+		s.respond(req.ID, nil)
+	case "Debugger.getPossibleBreakpoints":
+		s.respond(req.ID, map[string]interface{}{"locations": []interface{}{}})
+	case "Debugger.getScriptSource":
+		var params struct {
+			ScriptID string `json:"scriptId"`
+		}
+		if !s.parseParams(req, &params) {
+			return
+		}
+		s.i.mu.Lock()
+		sc := s.i.scriptsByID[params.ScriptID]
+		s.i.mu.Unlock()
+		if sc == nil {
+			s.respondError(req.ID, errCodeServerError, "No script for id: "+params.ScriptID)
+			return
+		}
+		s.respond(req.ID, map[string]string{"scriptSource": sc.source})
+	case "Debugger.pause":
+		s.i.Break()
+		s.respond(req.ID, nil)
+	case "Debugger.resume":
+		s.resume(req, goja.DebugContinue)
+	case "Debugger.stepOver":
+		s.resume(req, goja.DebugStepOver)
+	case "Debugger.stepInto":
+		s.resume(req, goja.DebugStepInto)
+	case "Debugger.stepOut":
+		s.resume(req, goja.DebugStepOut)
+	case "Debugger.evaluateOnCallFrame":
+		s.evaluateOnCallFrame(req)
+	case "Runtime.evaluate":
+		s.evaluate(req)
+	case "Runtime.getProperties":
+		s.getProperties(req)
+	case "Runtime.callFunctionOn":
+		s.callFunctionOn(req)
+	case "Runtime.releaseObject":
+		var params struct {
+			ObjectID string `json:"objectId"`
+		}
+		if !s.parseParams(req, &params) {
+			return
+		}
+		s.enqueue(req, func(*pauseState) (goja.DebugAction, bool) {
+			delete(s.objects, params.ObjectID)
+			s.respond(req.ID, nil)
+			return 0, false
+		})
+	case "Runtime.releaseObjectGroup":
+		s.enqueue(req, func(*pauseState) (goja.DebugAction, bool) {
+			s.objects = make(map[string]interface{})
+			s.respond(req.ID, nil)
+			return 0, false
+		})
+	case "Runtime.getIsolateId":
+		s.respond(req.ID, map[string]string{"id": s.i.id})
+	case "Runtime.getHeapUsage":
+		s.respond(req.ID, map[string]int{"usedSize": 0, "totalSize": 0})
+	case "Profiler.setSamplingInterval":
+		var params struct {
+			Interval int64 `json:"interval"` // in microseconds
+		}
+		if !s.parseParams(req, &params) {
+			return
+		}
+		s.profInterval = time.Duration(params.Interval) * time.Microsecond
+		s.respond(req.ID, nil)
+	case "Profiler.start":
+		if s.profiling {
+			s.respond(req.ID, nil)
+			return
+		}
+		s.profBuf.Reset()
+		if err := s.i.r.StartProfile(&s.profBuf, &goja.ProfileOptions{Interval: s.profInterval}); err != nil {
+			s.respondError(req.ID, errCodeServerError, err.Error())
+			return
+		}
+		s.profiling = true
+		s.profStart = time.Now()
+		s.respond(req.ID, nil)
+	case "Profiler.stop":
+		if !s.profiling {
+			s.respondError(req.ID, errCodeServerError, "Profiler is not started")
+			return
+		}
+		err := s.i.r.StopProfile()
+		s.profiling = false
+		if err != nil {
+			s.respondError(req.ID, errCodeServerError, err.Error())
+			return
+		}
+		prof, err := s.cdpProfile(s.profBuf.Bytes(), s.profStart, time.Now())
+		if err != nil {
+			s.respondError(req.ID, errCodeServerError, err.Error())
+			return
+		}
+		s.respond(req.ID, map[string]interface{}{"profile": prof})
+	default:
+		if noOpMethods[req.Method] {
+			s.respond(req.ID, nil)
+			return
+		}
+		s.respondError(req.ID, errCodeMethodNotFound, "'"+req.Method+"' wasn't found")
+	}
+}
+
+func (s *session) parseParams(req *request, params interface{}) bool {
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, params); err != nil {
+			s.respondError(req.ID, errCodeInvalidParams, "Invalid parameters: "+err.Error())
+			return false
+		}
+	}
+	return true
+}
+
+type location struct {
+	ScriptID     string `json:"scriptId"`
+	LineNumber   int    `json:"lineNumber"`
+	ColumnNumber int    `json:"columnNumber"`
+}
+
+// addBreakpoint must be called with i.mu held.
+func (s *session) addBreakpoint(id, filename string, line int) []location {
+	s.breakpoints[id] = append(s.breakpoints[id], s.i.d.SetBreakpoint(filename, line+1))
+	if sc := s.i.scriptsByURL[filename]; sc != nil {
+		return []location{{ScriptID: sc.id, LineNumber: line}}
+	}
+	return nil
+}
+
+// newBreakpointID must be called with i.mu held.
+func (s *session) newBreakpointID() string {
+	s.nextBreakpoint++
+	return strconv.Itoa(s.nextBreakpoint)
+}
+
+func (s *session) setBreakpointByURL(req *request) {
+	var params struct {
+		LineNumber int    `json:"lineNumber"`
+		URL        string `json:"url"`
+		URLRegex   string `json:"urlRegex"`
+	}
+	if !s.parseParams(req, &params) {
+		return
+	}
+	var re *regexp.Regexp
+	if params.URLRegex != "" {
+		var err error
+		if re, err = regexp.Compile(params.URLRegex); err != nil {
+			s.respondError(req.ID, errCodeInvalidParams, "Invalid urlRegex: "+err.Error())
+			return
+		}
+	}
+	s.i.mu.Lock()
+	defer s.i.mu.Unlock()
+	id := s.newBreakpointID()
+	locs := []location{}
+	if re != nil {
+		// only the scripts that are already known can be matched
+		for url := range s.i.scriptsByURL {
+			if re.MatchString(url) {
+				locs = append(locs, s.addBreakpoint(id, url, params.LineNumber)...)
+			}
+		}
+	} else {
+		filename := params.URL
+		if s.i.scriptsByURL[filename] == nil {
+			filename = urlToFilename(filename)
+		}
+		locs = append(locs, s.addBreakpoint(id, filename, params.LineNumber)...)
+	}
+	s.respond(req.ID, map[string]interface{}{"breakpointId": id, "locations": locs})
+}
+
+func (s *session) setBreakpoint(req *request) {
+	var params struct {
+		Location location `json:"location"`
+	}
+	if !s.parseParams(req, &params) {
+		return
+	}
+	s.i.mu.Lock()
+	defer s.i.mu.Unlock()
+	sc := s.i.scriptsByID[params.Location.ScriptID]
+	if sc == nil {
+		s.respondError(req.ID, errCodeServerError, "No script for id: "+params.Location.ScriptID)
+		return
+	}
+	id := s.newBreakpointID()
+	locs := s.addBreakpoint(id, sc.url, params.Location.LineNumber)
+	s.respond(req.ID, map[string]interface{}{"breakpointId": id, "actualLocation": locs[0]})
+}
+
+func (s *session) removeBreakpoint(id string) {
+	s.i.mu.Lock()
+	defer s.i.mu.Unlock()
+	removed := s.breakpoints[id]
+	delete(s.breakpoints, id)
+	for _, b := range removed {
+		if len(s.breakpointIDs(b)) == 0 {
+			s.i.d.RemoveBreakpoint(b)
+		}
+	}
+}
+
+// breakpointIDs returns the ids of the client's breakpoints that resolve to b. Must be called with i.mu held.
+func (s *session) breakpointIDs(b *goja.Breakpoint) []string {
+	ids := []string{}
+	for id, list := range s.breakpoints {
+		for _, b1 := range list {
+			if b1 == b {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// enqueue schedules a task that handles the request, responding with an error if it cannot be scheduled.
+func (s *session) enqueue(req *request, t task) {
+	if !s.i.enqueue(t) {
+		s.respondError(req.ID, errCodeServerError, "Too many pending commands")
+	}
+}
+
+func (s *session) resume(req *request, action goja.DebugAction) {
+	s.enqueue(req, func(p *pauseState) (goja.DebugAction, bool) {
+		s.respond(req.ID, nil)
+		return action, p != nil
+	})
+}
@@ -16,6 +16,9 @@ type orderedMap struct {
 	hashTable           map[uint64]*mapEntry
 	iterFirst, iterLast *mapEntry
 	size                int
+
+	// the Runtime the memory used by the entries is accounted against, nil if it's not accounted
+	runtime *Runtime
 }
 
 type orderedMapIter struct {
@@ -55,6 +58,9 @@ func (m *orderedMap) set(key, value Value) {
 		}
 		m.iterLast = entry
 		m.size++
+		if m.runtime != nil {
+			m.runtime.accountMemory(memMapEntrySize)
+		}
 	}
 }
 
@@ -147,6 +153,14 @@ func newOrderedMap(h *maphash.Hash) *orderedMap {
 	}
 }
 
+// newOrderedMap creates an orderedMap for a Map or a Set (or a similar structure) created by the JavaScript code,
+// its entries are accounted against the memory limit.
+func (r *Runtime) newOrderedMap() *orderedMap {
+	m := newOrderedMap(r.getHash())
+	m.runtime = r
+	return m
+}
+
 func (m *orderedMap) newIter() *orderedMapIter {
 	iter := &orderedMapIter{
 		m: m,
@@ -154,6 +168,15 @@ func (m *orderedMap) newIter() *orderedMapIter {
 	return iter
 }
 
+func (m *orderedMap) clone() *orderedMap {
+	c := newOrderedMap(m.hash)
+	c.runtime = m.runtime
+	for item := m.iterFirst; item != nil; item = item.iterNext {
+		c.set(item.key, item.value)
+	}
+	return c
+}
+
 func (m *orderedMap) clear() {
 	for item := m.iterFirst; item != nil; item = item.iterNext {
 		item.key = nil
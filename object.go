@@ -34,6 +34,8 @@ const (
 	classSetIterator          = "Set Iterator"
 	classStringIterator       = "String Iterator"
 	classRegExpStringIterator = "RegExp String Iterator"
+	classIterator             = "Iterator"
+	classIteratorHelper       = "Iterator Helper"
 
 	classGenerator         = "Generator"
 	classGeneratorFunction = "GeneratorFunction"
@@ -272,6 +274,9 @@ func (f ConstructorCall) Argument(idx int) Value {
 }
 
 func (o *baseObject) init() {
+	if o.val != nil {
+		o.val.runtime.accountMemory(memObjectSize)
+	}
 	o.values = make(map[unistring.String]Value)
 }
 
@@ -487,6 +492,7 @@ func (o *baseObject) setOwnStr(name unistring.String, val Value, throw bool) boo
 			o.val.runtime.typeErrorResult(throw, "Cannot add property %s, object is not extensible", name)
 			return false
 		} else {
+			o.val.runtime.accountMemory(memPropertySize)
 			o.values[name] = val
 			names := copyNamesIfNeeded(o.propNames, 1)
 			o.propNames = append(names, name)
@@ -756,6 +762,9 @@ Reject:
 func (o *baseObject) defineOwnPropertyStr(name unistring.String, descr PropertyDescriptor, throw bool) bool {
 	existingVal := o.values[name]
 	if v, ok := o._defineOwnProperty(name, existingVal, descr, throw); ok {
+		if existingVal == nil {
+			o.val.runtime.accountMemory(memPropertySize)
+		}
 		o.values[name] = v
 		if existingVal == nil {
 			names := copyNamesIfNeeded(o.propNames, 1)
@@ -3,6 +3,7 @@ package goja
 import (
 	"fmt"
 	"reflect"
+	"sort"
 
 	"github.com/dop251/goja/unistring"
 )
@@ -249,22 +250,52 @@ func (i *gomapReflectPropIter) next() (propIterItem, iterNextFunc) {
 	return propIterItem{}, nil
 }
 
+func (o *objectGoMapReflect) mapKeys() []reflect.Value {
+	keys := o.fieldsValue.MapKeys()
+	if o.val.runtime.deterministic {
+		strs := make([]string, len(keys))
+		for i, key := range keys {
+			strs[i] = o.keyToString(key).String()
+		}
+		sort.Sort(&reflectMapKeys{keys: keys, strs: strs})
+	}
+	return keys
+}
+
 func (o *objectGoMapReflect) iterateStringKeys() iterNextFunc {
 	return (&gomapReflectPropIter{
 		o:    o,
-		keys: o.fieldsValue.MapKeys(),
+		keys: o.mapKeys(),
 	}).next
 }
 
 func (o *objectGoMapReflect) stringKeys(_ bool, accum []Value) []Value {
 	// all own keys are enumerable
-	for _, key := range o.fieldsValue.MapKeys() {
+	for _, key := range o.mapKeys() {
 		accum = append(accum, o.keyToString(key))
 	}
 
 	return accum
 }
 
+type reflectMapKeys struct {
+	keys []reflect.Value
+	strs []string
+}
+
+func (k *reflectMapKeys) Len() int {
+	return len(k.keys)
+}
+
+func (k *reflectMapKeys) Less(i, j int) bool {
+	return k.strs[i] < k.strs[j]
+}
+
+func (k *reflectMapKeys) Swap(i, j int) {
+	k.keys[i], k.keys[j] = k.keys[j], k.keys[i]
+	k.strs[i], k.strs[j] = k.strs[j], k.strs[i]
+}
+
 func (*objectGoMapReflect) keyToString(key reflect.Value) String {
 	kind := key.Kind()
 
@@ -138,6 +138,20 @@ func TestObjectAssign(t *testing.T) {
 	testScriptWithTestLib(SCRIPT, _undefined, t)
 }
 
+func TestObjectGroupBy(t *testing.T) {
+	const SCRIPT = `
+	const groups = Object.groupBy(new Set([1, 2, 3, 4, 5]), x => x % 2 ? "odd" : "even");
+	assert.sameValue(Object.getPrototypeOf(groups), null);
+	assert(compareArray(Object.keys(groups), ["odd", "even"]));
+	assert(compareArray(groups.odd, [1, 3, 5]));
+	assert(compareArray(groups.even, [2, 4]));
+	assert(compareArray(Object.keys(Object.groupBy("aab", c => c)), ["a", "b"]));
+	assert.throws(TypeError, () => Object.groupBy(null, x => x));
+	assert.throws(TypeError, () => Object.groupBy([], null));
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
 func TestExportCircular(t *testing.T) {
 	vm := New()
 	o := vm.NewObject()
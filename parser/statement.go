@@ -9,7 +9,6 @@ import (
 	"github.com/dop251/goja/ast"
 	"github.com/dop251/goja/file"
 	"github.com/dop251/goja/token"
-	"github.com/go-sourcemap/sourcemap"
 )
 
 func (self *_parser) parseBlockStatement() *ast.BlockStatement {
@@ -851,9 +850,10 @@ func (self *_parser) parseWhileStatement() ast.Statement {
 }
 
 func (self *_parser) parseIfStatement() ast.Statement {
-	self.expect(token.IF)
+	idx := self.expect(token.IF)
 	self.expect(token.LEFT_PARENTHESIS)
 	node := &ast.IfStatement{
+		If:   idx,
 		Test: self.parseExpression(),
 	}
 	self.expect(token.RIGHT_PARENTHESIS)
@@ -889,7 +889,7 @@ func (self *_parser) parseProgram() *ast.Program {
 		DeclarationList: self.scope.declarationList,
 		File:            self.file,
 	}
-	self.file.SetSourceMap(self.parseSourceMap())
+	self.parseSourceMap()
 	return prg
 }
 
@@ -912,9 +912,9 @@ func extractSourceMapLine(str string) string {
 	return ""
 }
 
-func (self *_parser) parseSourceMap() *sourcemap.Consumer {
+func (self *_parser) parseSourceMap() {
 	if self.opts.disableSourceMaps {
-		return nil
+		return
 	}
 	if smLine := extractSourceMapLine(self.str); smLine != "" {
 		urlIndex := strings.Index(smLine, "=")
@@ -942,19 +942,16 @@ func (self *_parser) parseSourceMap() *sourcemap.Consumer {
 
 		if err != nil {
 			self.error(file.Idx(0), "Could not load source map: %v", err)
-			return nil
+			return
 		}
 		if data == nil {
-			return nil
+			return
 		}
 
-		if sm, err := sourcemap.Parse(self.file.Name(), data); err == nil {
-			return sm
-		} else {
+		if err := self.file.SetSourceMapData(data); err != nil {
 			self.error(file.Idx(0), "Could not parse source map: %v", err)
 		}
 	}
-	return nil
 }
 
 func (self *_parser) parseBreakStatement() ast.Statement {
@@ -0,0 +1,77 @@
+package goja
+
+import (
+	"sync"
+)
+
+// RuntimePool is a pool of Runtimes that can be used to avoid the cost of creating a new Runtime for every
+// script execution. Runtimes returned to the pool are Reset and their settings are restored to the defaults of
+// a new Runtime (see Put), so neither the state nor the settings are carried over between the uses.
+//
+// The Runtimes can be prepared before they are handed out, which is useful to define the globals the scripts
+// rely on, in one of two ways:
+//
+// A warm-up Program (see NewRuntimePool) is run every time a Runtime is handed out, including the re-used ones,
+// because Reset discards its effects. Its cost is that of running the Program, which is low for code that
+// mostly defines functions and objects.
+//
+// A Snapshot (see NewRuntimePoolFromSnapshot) is restored into every Runtime returned to the pool by Put,
+// so the re-used Runtimes are handed out as they are. Restoring copies the objects the preparation has created
+// or modified, which costs about as much as creating them: for the hundred functions of BenchmarkRuntimePool
+// it takes about 0.1ms, compared to 0.18ms to run the warm-up. It pays off when the preparation does other
+// work as well, such as computing tables: the bootstrap code of BenchmarkSnapshot takes about 7.5ms to run
+// and 0.36ms to restore.
+//
+// RuntimePool is safe for concurrent use. The Runtimes themselves are not.
+type RuntimePool struct {
+	pool     sync.Pool
+	warmUp   *Program
+	snapshot *Snapshot
+}
+
+// NewRuntimePool creates a new RuntimePool. warmUp may be nil.
+func NewRuntimePool(warmUp *Program) *RuntimePool {
+	return &RuntimePool{
+		warmUp: warmUp,
+	}
+}
+
+// NewRuntimePoolFromSnapshot creates a new RuntimePool which hands out Runtimes with a copy of the heap
+// captured by the Snapshot.
+func NewRuntimePoolFromSnapshot(snapshot *Snapshot) *RuntimePool {
+	return &RuntimePool{
+		snapshot: snapshot,
+	}
+}
+
+// Get returns a Runtime from the pool creating a new one if the pool is empty. If the warm-up Program fails,
+// the error is returned and the Runtime is discarded.
+func (p *RuntimePool) Get() (*Runtime, error) {
+	r, _ := p.pool.Get().(*Runtime)
+	if r == nil {
+		if p.snapshot != nil {
+			return p.snapshot.NewRuntime(), nil
+		}
+		r = New()
+	}
+	if p.warmUp != nil {
+		if _, err := r.RunProgram(p.warmUp); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Put resets the Runtime and returns it to the pool. All the settings are restored to the defaults of a new
+// Runtime: the debugger is detached, the profiling is stopped (and the profile is written), the coverage, the limits,
+// the trackers, the field name mapper, the parser options and the maximum call stack size are removed, and
+// the time and random sources (including the ones set by SetDeterministic) are restored. The Runtime must not be
+// running and must not be used by the caller afterwards.
+func (p *RuntimePool) Put(r *Runtime) {
+	r.Reset()
+	r.resetSettings()
+	if p.snapshot != nil {
+		p.snapshot.restore(r)
+	}
+	p.pool.Put(r)
+}
@@ -0,0 +1,197 @@
+package goja
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRuntimePool(t *testing.T) {
+	p := NewRuntimePool(MustCompile("warmup.js", `var calls = 0; function inc() { return ++calls; }`, false))
+	for i := 0; i < 3; i++ {
+		r, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := r.RunString(`
+		if (typeof leaked !== "undefined" || "leaked" in Object.prototype) {
+			throw new Error("state leaked");
+		}
+		var leaked = 1;
+		Object.prototype.leaked = 1;
+		inc(); inc()
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.ToInteger() != 2 {
+			t.Fatalf("%d: unexpected result: %v", i, v)
+		}
+		p.Put(r)
+	}
+	p = NewRuntimePool(MustCompile("warmup.js", `throw new Error("failed")`, false))
+	if _, err := p.Get(); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestRuntimePoolFromSnapshot(t *testing.T) {
+	r := New()
+	_, err := r.RunString(`
+	const table = [];
+	for (let i = 0; i < 10; i++) {
+		table.push(i * i);
+	}
+	var calls = 0;
+	function inc() { return ++calls; }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := r.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewRuntimePoolFromSnapshot(snap)
+	for i := 0; i < 3; i++ {
+		r, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := r.RunString(`
+		if (typeof leaked !== "undefined" || "leaked" in Object.prototype) {
+			throw new Error("state leaked");
+		}
+		var leaked = 1;
+		Object.prototype.leaked = 1;
+		table.push(0);
+		inc(); inc() + table[9] + table.length
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.ToInteger() != 2+81+11 {
+			t.Fatalf("%d: unexpected result: %v", i, v)
+		}
+		p.Put(r)
+	}
+}
+
+func TestRuntimePoolIsolation(t *testing.T) {
+	const SCRIPT = `
+	function rec(n) {
+		return n === 0 ? 0 : rec(n - 1);
+	}
+	rec(100);
+	for (let i = 0; i < 10000; i++) {}
+	[Math.random(), Date.now(), typeof s.Field]
+	`
+	type S struct {
+		Field int
+	}
+	p := NewRuntimePool(nil)
+	cov := NewCoverage()
+	var pauses int
+	var prof bytes.Buffer
+
+	r := New()
+	r.SetDeterministic(DeterministicOptions{Seed: 1})
+	first := r.ToValue(r.rand()).String()
+
+	r, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetCoverage(cov)
+	r.AttachDebugger(func(*DebugPause) DebugAction {
+		pauses++
+		return DebugContinue
+	}).SetBreakpoint("test.js", 5)
+	r.SetDeterministic(DeterministicOptions{Seed: 1})
+	r.SetMaxCallStackSize(1000)
+	r.SetInstructionLimit(1000000)
+	r.SetMemoryLimit(1 << 20)
+	r.SetFieldNameMapper(UncapFieldNameMapper())
+	r.SetPromiseRejectionTracker(func(*Promise, PromiseRejectionOperation) {})
+	if err := r.StartProfile(&prof, nil); err != nil {
+		t.Fatal(err)
+	}
+	r.Set("s", S{})
+	v, err := r.RunScript("test.js", SCRIPT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := v.String(); res != first+",0,undefined" || pauses != 1 {
+		t.Fatalf("unexpected result: %s, %d pauses", res, pauses)
+	}
+	var lcov bytes.Buffer
+	if err := cov.WriteLCOV(&lcov); err != nil {
+		t.Fatal(err)
+	}
+	p.Put(r)
+	if prof.Len() == 0 {
+		t.Fatal("the profile has not been written")
+	}
+
+	r, err = p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Set("s", S{})
+	v, err = r.RunScript("test.js", SCRIPT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := strings.Split(v.String(), ",")
+	if res[0] == first || res[1] == "0" || res[2] != "number" {
+		t.Fatalf("the settings have been carried over: %v", v)
+	}
+	if pauses != 1 || r.InstructionsExecuted() != 0 || r.MemoryUsage() != 0 || r.promiseRejectionTracker != nil {
+		t.Fatal("the debugger or the limits have been carried over")
+	}
+	var lcov1 bytes.Buffer
+	if err := cov.WriteLCOV(&lcov1); err != nil {
+		t.Fatal(err)
+	}
+	if lcov1.String() != lcov.String() {
+		t.Fatal("the coverage has been carried over")
+	}
+	p.Put(r)
+}
+
+func BenchmarkRuntimePool(b *testing.B) {
+	warmUp := MustCompile("warmup.js", `
+	var lib = {};
+	for (let i = 0; i < 100; i++) {
+		lib["f" + i] = function(x) { return x + i; };
+	}
+	`, false)
+	b.Run("warmUp", func(b *testing.B) {
+		benchmarkRuntimePool(b, NewRuntimePool(warmUp))
+	})
+	b.Run("snapshot", func(b *testing.B) {
+		r := New()
+		if _, err := r.RunProgram(warmUp); err != nil {
+			b.Fatal(err)
+		}
+		snap, err := r.Snapshot()
+		if err != nil {
+			b.Fatal(err)
+		}
+		benchmarkRuntimePool(b, NewRuntimePoolFromSnapshot(snap))
+	})
+}
+
+func benchmarkRuntimePool(b *testing.B, p *RuntimePool) {
+	prg := MustCompile("test.js", `lib.f1(1)`, false)
+	for i := 0; i < b.N; i++ {
+		r, err := p.Get()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := r.RunProgram(prg); err != nil {
+			b.Fatal(err)
+		}
+		p.Put(r)
+	}
+}
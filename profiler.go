@@ -3,7 +3,9 @@ package goja
 import (
 	"errors"
 	"io"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -30,11 +32,21 @@ type _globalProfiler struct {
 
 var globalProfiler _globalProfiler
 
+// _localProfiler is the profiler of a Runtime, see Runtime.StartProfile.
+type _localProfiler struct {
+	mu sync.Mutex
+	p  *profiler
+	w  io.Writer
+
+	enabled int32
+}
+
 type profTracker struct {
 	req, finished int32
 	start, stop   time.Time
 	numFrames     int
 	frames        [profMaxStackDepth]StackFrame
+	labels        *profLabels
 }
 
 type profiler struct {
@@ -42,6 +54,26 @@ type profiler struct {
 	trackers []*profTracker
 	buf      *profBuffer
 	running  bool
+
+	// the sampling interval (profInterval if not set) and the labels attached to all samples
+	interval time.Duration
+	labels   map[string]string
+}
+
+// profLabels is an immutable set of labels attached to the samples, see Runtime.DoWithProfileLabels.
+type profLabels struct {
+	labels map[string]string
+	// the canonical representation used to group the samples
+	key string
+}
+
+// ProfileOptions configures a profile started by Runtime.StartProfile.
+type ProfileOptions struct {
+	// Interval is the sampling interval, 10ms if not set.
+	Interval time.Duration
+	// Labels are attached to all samples of the profile (in addition to the ones set by
+	// Runtime.DoWithProfileLabels), for example to tell the Runtimes apart when their profiles are merged.
+	Labels map[string]string
 }
 
 type profFunc struct {
@@ -51,14 +83,62 @@ type profFunc struct {
 
 type profSampleNode struct {
 	loc      *profile.Location
-	sample   *profile.Sample
+	samples  map[string]*profile.Sample // by the key of the labels
 	parent   *profSampleNode
 	children map[*profile.Location]*profSampleNode
 }
 
 type profBuffer struct {
-	funcs map[*Program]*profFunc
-	root  profSampleNode
+	funcs    map[*Program]*profFunc
+	root     profSampleNode
+	interval time.Duration
+	labels   map[string]string
+}
+
+func (l *profLabels) with(labels map[string]string) *profLabels {
+	merged := make(map[string]string, len(labels))
+	if l != nil {
+		for k, v := range l.labels {
+			merged[k] = v
+		}
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(strconv.Quote(k))
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(merged[k]))
+		sb.WriteByte(',')
+	}
+	return &profLabels{
+		labels: merged,
+		key:    sb.String(),
+	}
+}
+
+// sampleLabels returns the labels of a sample, the ones set by Runtime.DoWithProfileLabels take precedence over
+// the ones of the profile.
+func (pb *profBuffer) sampleLabels(l *profLabels) map[string][]string {
+	if len(pb.labels) == 0 && l == nil {
+		return nil
+	}
+	res := make(map[string][]string)
+	for k, v := range pb.labels {
+		res[k] = []string{v}
+	}
+	if l != nil {
+		for k, v := range l.labels {
+			res[k] = []string{v}
+		}
+	}
+	return res
 }
 
 func (pb *profBuffer) addSample(pt *profTracker) {
@@ -98,7 +178,11 @@ func (pb *profBuffer) addSample(pt *profTracker) {
 			n = nn
 		}
 	}
-	smpl := n.sample
+	var key string
+	if pt.labels != nil {
+		key = pt.labels.key
+	}
+	smpl := n.samples[key]
 	if smpl == nil {
 		locs := make([]*profile.Location, 0, len(sampleFrames))
 		for n1 := n; n1.loc != nil; n1 = n1.parent {
@@ -107,8 +191,12 @@ func (pb *profBuffer) addSample(pt *profTracker) {
 		smpl = &profile.Sample{
 			Location: locs,
 			Value:    make([]int64, 2),
+			Label:    pb.sampleLabels(pt.labels),
 		}
-		n.sample = smpl
+		if n.samples == nil {
+			n.samples = make(map[string]*profile.Sample, 1)
+		}
+		n.samples[key] = smpl
 	}
 	smpl.Value[0]++
 	smpl.Value[1] += int64(pt.stop.Sub(pt.start))
@@ -121,7 +209,7 @@ func (pb *profBuffer) profile() *profile.Profile {
 		{Type: "cpu", Unit: "nanoseconds"},
 	}
 	pr.PeriodType = pr.SampleType[1]
-	pr.Period = int64(profInterval)
+	pr.Period = int64(pb.interval)
 	mapping := &profile.Mapping{
 		ID:   1,
 		File: "[ECMAScript code]",
@@ -172,16 +260,23 @@ func (pb *profBuffer) profile() *profile.Profile {
 }
 
 func (pb *profBuffer) addSamples(p *profile.Profile, n *profSampleNode) {
-	if n.sample != nil {
-		p.Sample = append(p.Sample, n.sample)
+	for _, smpl := range n.samples {
+		p.Sample = append(p.Sample, smpl)
 	}
 	for _, child := range n.children {
 		pb.addSamples(p, child)
 	}
 }
 
+func (p *profiler) period() time.Duration {
+	if p.interval > 0 {
+		return p.interval
+	}
+	return profInterval
+}
+
 func (p *profiler) run() {
-	ticker := time.NewTicker(profInterval)
+	ticker := time.NewTicker(p.period())
 	counter := 0
 
 	for ts := range ticker.C {
@@ -249,7 +344,10 @@ func (p *profiler) start() error {
 		p.mu.Unlock()
 		return errors.New("profiler is already active")
 	}
-	p.buf = new(profBuffer)
+	p.buf = &profBuffer{
+		interval: p.period(),
+		labels:   p.labels,
+	}
 	p.mu.Unlock()
 	return nil
 }
@@ -323,7 +421,9 @@ If there are multiple functions with the same name, their names get a '.N' suffi
 because otherwise the graph view merges them together (even if they are in different mappings). This includes
 "<anonymous>" functions.
 
-The sampling period is set to 10ms.
+The samples are annotated with the labels set by Runtime.DoWithProfileLabels.
+
+The sampling period is set to 10ms. To profile a single Runtime or to use a different period see Runtime.StartProfile.
 
 It returns an error if profiling is already active.
 */
@@ -348,3 +448,86 @@ func StopProfile() {
 	}
 	globalProfiler.w = nil
 }
+
+/*
+StartProfile enables execution time profiling for the Runtime. The profile is written to w by StopProfile. It has the same
+format and semantics as the one produced by the StartProfile function, but only the code run by this Runtime is sampled.
+It can be used at the same time as the global profile, and the profiles of different Runtimes are independent.
+
+The sampling interval and the labels attached to all samples can be set in opts, which may be nil.
+
+It can be called from any goroutine, including while the Runtime is running, in which case the sampling starts shortly
+after the call. It returns an error if the Runtime is already being profiled.
+*/
+func (r *Runtime) StartProfile(w io.Writer, opts *ProfileOptions) error {
+	p := &profiler{}
+	if opts != nil {
+		p.interval = opts.Interval
+		if len(opts.Labels) > 0 {
+			p.labels = make(map[string]string, len(opts.Labels))
+			for k, v := range opts.Labels {
+				p.labels[k] = v
+			}
+		}
+	}
+	lp := &r.localProfiler
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	if lp.p != nil {
+		return errors.New("profiler is already active")
+	}
+	_ = p.start()
+	lp.p, lp.w = p, w
+	atomic.StoreInt32(&lp.enabled, 1)
+	return nil
+}
+
+/*
+StopProfile stops the profile started by Runtime.StartProfile, if any, and writes it. It can be called from any goroutine.
+*/
+func (r *Runtime) StopProfile() error {
+	lp := &r.localProfiler
+	lp.mu.Lock()
+	atomic.StoreInt32(&lp.enabled, 0)
+	p, w := lp.p, lp.w
+	lp.p, lp.w = nil, nil
+	lp.mu.Unlock()
+	if p == nil {
+		return nil
+	}
+	if pr := p.stop(); pr != nil && w != nil {
+		return pr.Write(w)
+	}
+	return nil
+}
+
+func (lp *_localProfiler) registerVm() *profTracker {
+	lp.mu.Lock()
+	p := lp.p
+	lp.mu.Unlock()
+	if p == nil {
+		return nil
+	}
+	return p.registerVm()
+}
+
+/*
+DoWithProfileLabels calls f with the labels added to the current ones (replacing the ones with the same keys). The samples
+taken while f runs, by both the global and the Runtime's profiler, are annotated with these labels. This allows to
+attribute the execution time to, for example, a tenant when the Runtimes are shared:
+
+	r.DoWithProfileLabels(map[string]string{"tenant": tenantID}, func() {
+		res, err = handler(goja.Undefined(), req)
+	})
+
+The labels are not propagated to the code that is run later as a result of f (such as the promise reactions).
+*/
+func (r *Runtime) DoWithProfileLabels(labels map[string]string, f func()) {
+	vm := r.vm
+	saved := vm.profLabels
+	vm.profLabels = saved.with(labels)
+	defer func() {
+		vm.profLabels = saved
+	}()
+	f()
+}
@@ -1,9 +1,13 @@
 package goja
 
 import (
+	"bytes"
+	"runtime"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/google/pprof/profile"
 )
 
 func TestProfiler(t *testing.T) {
@@ -100,3 +104,96 @@ func TestProfiler1(t *testing.T) {
 		t.Fatal("No samples were recorded")
 	}
 }
+
+func TestRuntimeProfiler(t *testing.T) {
+	vm := New()
+	// let the sampling goroutine run even if there is only one CPU
+	vm.Set("yield", runtime.Gosched)
+	if _, err := vm.RunScript("test.js", `
+	function busy(ms) {
+		var end = Date.now() + ms;
+		while (Date.now() < end) {
+			yield();
+		}
+	}
+	`); err != nil {
+		t.Fatal(err)
+	}
+	busy, ok := AssertFunction(vm.Get("busy"))
+	if !ok {
+		t.Fatal("busy is not a function")
+	}
+
+	var buf bytes.Buffer
+	err := vm.StartProfile(&buf, &ProfileOptions{
+		Interval: time.Millisecond,
+		Labels:   map[string]string{"runtime": "test"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.StartProfile(&buf, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := busy(nil, vm.ToValue(30)); err != nil {
+		t.Fatal(err)
+	}
+	vm.DoWithProfileLabels(map[string]string{"tenant": "a"}, func() {
+		vm.DoWithProfileLabels(map[string]string{"user": "b"}, func() {
+			_, err = busy(nil, vm.ToValue(30))
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.StopProfile(); err != nil {
+		t.Fatal(err)
+	}
+
+	pr, err := profile.Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Period != int64(time.Millisecond) {
+		t.Fatalf("unexpected period: %d", pr.Period)
+	}
+	var plain, labelled int64
+	for _, s := range pr.Sample {
+		if s.Label["runtime"][0] != "test" {
+			t.Fatalf("unexpected labels: %v", s.Label)
+		}
+		if len(s.Label["tenant"]) == 0 {
+			plain += s.Value[0]
+			continue
+		}
+		if s.Label["tenant"][0] != "a" || s.Label["user"][0] != "b" {
+			t.Fatalf("unexpected labels: %v", s.Label)
+		}
+		labelled += s.Value[0]
+	}
+	if plain == 0 || labelled == 0 {
+		t.Fatalf("expected both plain and labelled samples, got %d and %d", plain, labelled)
+	}
+
+	// the profile can be started while the Runtime is running
+	buf.Reset()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		if err := vm.StartProfile(&buf, &ProfileOptions{Interval: time.Millisecond}); err != nil {
+			panic(err)
+		}
+	}()
+	if _, err := busy(nil, vm.ToValue(50)); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.StopProfile(); err != nil {
+		t.Fatal(err)
+	}
+	pr, err = profile.Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pr.Sample) == 0 {
+		t.Fatal("No samples were recorded")
+	}
+}
@@ -0,0 +1,1033 @@
+package goja
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"unsafe"
+
+	"github.com/dop251/goja/file"
+)
+
+// The marshalled Program starts with a magic string, a format version byte, a fingerprint of the instruction set
+// (the instruction types and their fields), the length of the payload and its SHA-256 checksum, which is verified
+// before decoding to detect corrupted data. Instructions are encoded as an index in programInstructionTypes followed
+// by their fields, which are encoded recursively based on their kind. Pointers are numbered in the order they are first
+// encountered, subsequent occurrences are encoded as references, which preserves shared objects (such as the source
+// file) and pointer identity where it matters.
+const (
+	programMagic         = "goja"
+	programFormatVersion = 3
+	// the magic, the version, the fingerprint, the payload length and the checksum
+	programHeaderSize = len(programMagic) + 1 + 8 + 8 + sha256.Size
+
+	// a tag for constants in addition to the serTag* ones
+	programTagValueProperty byte = 0xff
+)
+
+var (
+	errInvalidProgramData  = errors.New("goja: invalid program data")
+	errIncompatibleProgram = errors.New("goja: program data was produced by an incompatible version")
+)
+
+func instructionType[T instruction]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// programInstructionTypes lists all instruction types. Changes to this list or to any of the types are detected
+// by the fingerprint, so that data produced by a different version is rejected.
+var programInstructionTypes = [...]reflect.Type{
+	instructionType[loadVal](),
+	instructionType[_loadUndef](),
+	instructionType[_loadNil](),
+	instructionType[_saveResult](),
+	instructionType[_loadResult](),
+	instructionType[_clearResult](),
+	instructionType[_loadGlobalObject](),
+	instructionType[loadStack](),
+	instructionType[loadStack1](),
+	instructionType[loadStackLex](),
+	instructionType[loadStack1Lex](),
+	instructionType[_loadCallee](),
+	instructionType[storeStack](),
+	instructionType[storeStack1](),
+	instructionType[storeStackLex](),
+	instructionType[storeStack1Lex](),
+	instructionType[initStack](),
+	instructionType[initStackP](),
+	instructionType[initStack1](),
+	instructionType[initStack1P](),
+	instructionType[storeStackP](),
+	instructionType[storeStack1P](),
+	instructionType[storeStackLexP](),
+	instructionType[storeStack1LexP](),
+	instructionType[_toNumber](),
+	instructionType[_add](),
+	instructionType[_sub](),
+	instructionType[_mul](),
+	instructionType[_exp](),
+	instructionType[_div](),
+	instructionType[_mod](),
+	instructionType[_neg](),
+	instructionType[_plus](),
+	instructionType[_inc](),
+	instructionType[_dec](),
+	instructionType[_and](),
+	instructionType[_or](),
+	instructionType[_xor](),
+	instructionType[_bnot](),
+	instructionType[_sal](),
+	instructionType[_sar](),
+	instructionType[_shr](),
+	instructionType[jump](),
+	instructionType[_toPropertyKey](),
+	instructionType[_toString](),
+	instructionType[_getElemRef](),
+	instructionType[_getElemRefRecv](),
+	instructionType[_getElemRefStrict](),
+	instructionType[_getElemRefRecvStrict](),
+	instructionType[_setElem](),
+	instructionType[_setElem1](),
+	instructionType[_setElem1Named](),
+	instructionType[*defineMethod](),
+	instructionType[_setElemP](),
+	instructionType[_setElemStrict](),
+	instructionType[_setElemRecv](),
+	instructionType[_setElemRecvStrict](),
+	instructionType[_setElemStrictP](),
+	instructionType[_setElemRecvP](),
+	instructionType[_setElemRecvStrictP](),
+	instructionType[_deleteElem](),
+	instructionType[_deleteElemStrict](),
+	instructionType[deleteProp](),
+	instructionType[deletePropStrict](),
+	instructionType[getPropRef](),
+	instructionType[getPropRefRecv](),
+	instructionType[getPropRefStrict](),
+	instructionType[getPropRefRecvStrict](),
+	instructionType[setProp](),
+	instructionType[setPropP](),
+	instructionType[setPropStrict](),
+	instructionType[setPropRecv](),
+	instructionType[setPropRecvStrict](),
+	instructionType[setPropRecvP](),
+	instructionType[setPropRecvStrictP](),
+	instructionType[setPropStrictP](),
+	instructionType[putProp](),
+	instructionType[definePropKeyed](),
+	instructionType[defineProp](),
+	instructionType[*defineMethodKeyed](),
+	instructionType[_setProto](),
+	instructionType[*defineGetterKeyed](),
+	instructionType[*defineSetterKeyed](),
+	instructionType[*defineGetter](),
+	instructionType[*defineSetter](),
+	instructionType[getProp](),
+	instructionType[getPropRecv](),
+	instructionType[getPropRecvCallee](),
+	instructionType[getPropCallee](),
+	instructionType[_getElem](),
+	instructionType[_getElemRecv](),
+	instructionType[_getKey](),
+	instructionType[_getElemCallee](),
+	instructionType[_getElemRecvCallee](),
+	instructionType[_dup](),
+	instructionType[dupN](),
+	instructionType[rdupN](),
+	instructionType[dupLast](),
+	instructionType[_newObject](),
+	instructionType[newArray](),
+	instructionType[_pushArrayItem](),
+	instructionType[_pushArraySpread](),
+	instructionType[_pushSpread](),
+	instructionType[_newArrayFromIter](),
+	instructionType[*newRegexp](),
+	instructionType[storeStash](),
+	instructionType[storeStashP](),
+	instructionType[storeStashLex](),
+	instructionType[storeStashLexP](),
+	instructionType[initStash](),
+	instructionType[initStashP](),
+	instructionType[initGlobalP](),
+	instructionType[initGlobal](),
+	instructionType[resolveVar1](),
+	instructionType[deleteVar](),
+	instructionType[deleteGlobal](),
+	instructionType[resolveVar1Strict](),
+	instructionType[setGlobal](),
+	instructionType[setGlobalStrict](),
+	instructionType[loadStash](),
+	instructionType[loadStashLex](),
+	instructionType[*loadMixed](),
+	instructionType[*loadMixedLex](),
+	instructionType[*loadMixedStack](),
+	instructionType[*loadMixedStack1](),
+	instructionType[*loadMixedStackLex](),
+	instructionType[*loadMixedStack1Lex](),
+	instructionType[*resolveMixed](),
+	instructionType[*resolveMixedStack](),
+	instructionType[*resolveMixedStack1](),
+	instructionType[_getValue](),
+	instructionType[_putValue](),
+	instructionType[_popRef](),
+	instructionType[_putValueP](),
+	instructionType[_initValueP](),
+	instructionType[loadDynamic](),
+	instructionType[loadDynamicRef](),
+	instructionType[loadDynamicCallee](),
+	instructionType[_pop](),
+	instructionType[callEval](),
+	instructionType[callEvalStrict](),
+	instructionType[_callEvalVariadic](),
+	instructionType[_callEvalVariadicStrict](),
+	instructionType[_boxThis](),
+	instructionType[_startVariadic](),
+	instructionType[_callVariadic](),
+	instructionType[_endVariadic](),
+	instructionType[call](),
+	instructionType[*enterBlock](),
+	instructionType[*enterCatchBlock](),
+	instructionType[*leaveBlock](),
+	instructionType[*enterFunc](),
+	instructionType[*enterFunc1](),
+	instructionType[*enterFuncBody](),
+	instructionType[_ret](),
+	instructionType[cret](),
+	instructionType[*enterFuncStashless](),
+	instructionType[*newFunc](),
+	instructionType[*newAsyncFunc](),
+	instructionType[*newGeneratorFunc](),
+	instructionType[*newMethod](),
+	instructionType[*newAsyncMethod](),
+	instructionType[*newGeneratorMethod](),
+	instructionType[*newArrowFunc](),
+	instructionType[*newAsyncArrowFunc](),
+	instructionType[*bindVars](),
+	instructionType[*bindGlobal](),
+	instructionType[jneP](),
+	instructionType[jeqP](),
+	instructionType[jeq](),
+	instructionType[jne](),
+	instructionType[jdef](),
+	instructionType[jdefP](),
+	instructionType[jopt](),
+	instructionType[joptc](),
+	instructionType[joptdel](),
+	instructionType[joptdelc](),
+	instructionType[joptdelP](),
+	instructionType[joptdelcP](),
+	instructionType[jcoalesc](),
+	instructionType[jcoalescP](),
+	instructionType[_not](),
+	instructionType[_op_lt](),
+	instructionType[_op_lte](),
+	instructionType[_op_gt](),
+	instructionType[_op_gte](),
+	instructionType[_op_eq](),
+	instructionType[_op_neq](),
+	instructionType[_op_strict_eq](),
+	instructionType[_op_strict_neq](),
+	instructionType[_op_instanceof](),
+	instructionType[_op_in](),
+	instructionType[try](),
+	instructionType[leaveTry](),
+	instructionType[enterFinally](),
+	instructionType[leaveFinally](),
+	instructionType[_throw](),
+	instructionType[_newVariadic](),
+	instructionType[_new](),
+	instructionType[superCall](),
+	instructionType[_superCallVariadic](),
+	instructionType[_loadNewTarget](),
+	instructionType[_typeof](),
+	instructionType[createArgsMapped](),
+	instructionType[createArgsUnmapped](),
+	instructionType[_enterWith](),
+	instructionType[_leaveWith](),
+	instructionType[_enumerate](),
+	instructionType[enumNext](),
+	instructionType[_enumGet](),
+	instructionType[_enumPop](),
+	instructionType[_enumPopClose](),
+	instructionType[_iterateP](),
+	instructionType[_iterate](),
+	instructionType[iterNext](),
+	instructionType[iterGetNextOrUndef](),
+	instructionType[copyStash](),
+	instructionType[_throwAssignToConst](),
+	instructionType[_debuggerStmt](),
+	instructionType[_copySpread](),
+	instructionType[_copyRest](),
+	instructionType[_createDestructSrc](),
+	instructionType[_checkObjectCoercible](),
+	instructionType[createArgsRestStack](),
+	instructionType[_createArgsRestStash](),
+	instructionType[concatStrings](),
+	instructionType[*getTaggedTmplObject](),
+	instructionType[_loadSuper](),
+	instructionType[*newClass](),
+	instructionType[*newDerivedClass](),
+	instructionType[*newStaticFieldInit](),
+	instructionType[loadThisStash](),
+	instructionType[loadThisStack](),
+	instructionType[getThisDynamic](),
+	instructionType[throwConst](),
+	instructionType[resolveThisStack](),
+	instructionType[resolveThisStash](),
+	instructionType[resolveThisDynamic](),
+	instructionType[defineComputedKey](),
+	instructionType[loadComputedKey](),
+	instructionType[*initStaticElements](),
+	instructionType[*definePrivateMethod](),
+	instructionType[*definePrivateGetter](),
+	instructionType[*definePrivateSetter](),
+	instructionType[*definePrivateProp](),
+	instructionType[*getPrivatePropRes](),
+	instructionType[*getPrivatePropId](),
+	instructionType[*getPrivatePropIdCallee](),
+	instructionType[*getPrivatePropResCallee](),
+	instructionType[*setPrivatePropRes](),
+	instructionType[*setPrivatePropResP](),
+	instructionType[*setPrivatePropId](),
+	instructionType[*setPrivatePropIdP](),
+	instructionType[popPrivateEnv](),
+	instructionType[*privateInRes](),
+	instructionType[*privateInId](),
+	instructionType[*getPrivateRefRes](),
+	instructionType[*getPrivateRefId](),
+	instructionType[*yieldMarker](),
+}
+
+// programSingletons are the instructions whose identity is significant.
+var programSingletons = [...]*yieldMarker{await, yield, yieldRes, yieldDelegate, yieldDelegateRes, yieldEmpty}
+
+var (
+	typeProgramPtr       = reflect.TypeOf((*Program)(nil))
+	typeFilePtr          = reflect.TypeOf((*file.File)(nil))
+	typeRegexpPatternPtr = reflect.TypeOf((*regexpPattern)(nil))
+	typeInstruction      = reflect.TypeOf((*instruction)(nil)).Elem()
+	typeEmptyInterface   = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+var programInstructionIndex = sync.OnceValue(func() map[reflect.Type]uint64 {
+	m := make(map[reflect.Type]uint64, len(programInstructionTypes))
+	for i, t := range programInstructionTypes {
+		m[t] = uint64(i)
+	}
+	return m
+})
+
+var programFingerprint = sync.OnceValue(func() uint64 {
+	h := fnv.New64a()
+	seen := make(map[reflect.Type]bool)
+	writeTypeSignature(h, typeProgramPtr, seen)
+	for _, t := range programInstructionTypes {
+		writeTypeSignature(h, t, seen)
+	}
+	return h.Sum64()
+})
+
+func writeTypeSignature(w io.Writer, t reflect.Type, seen map[reflect.Type]bool) {
+	fmt.Fprintf(w, "%s:%s;", t, t.Kind())
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			io.WriteString(w, f.Name)
+			writeTypeSignature(w, f.Type, seen)
+		}
+	case reflect.Pointer:
+		if t != typeFilePtr && t != typeRegexpPatternPtr {
+			writeTypeSignature(w, t.Elem(), seen)
+		}
+	case reflect.Slice:
+		writeTypeSignature(w, t.Elem(), seen)
+	case reflect.Map:
+		writeTypeSignature(w, t.Key(), seen)
+		writeTypeSignature(w, t.Elem(), seen)
+	}
+}
+
+type programPointer struct {
+	typ  reflect.Type
+	addr uintptr
+}
+
+type programEncoder struct {
+	serializer
+	pointers map[programPointer]uint64
+}
+
+type programDecoder struct {
+	deserializer
+	pointers []reflect.Value
+	strings  map[string]string // interned strings, as the compiler does it for the same literals
+}
+
+type programMarshalError struct {
+	err error
+}
+
+// MarshalBinary encodes the Program into a binary form which can be decoded by UnmarshalProgram. This allows caching
+// compiled code (for example on disk) and skipping parsing and compilation when it is loaded.
+// The format is specific to the version of goja: if the data was produced by a version with a different instruction
+// set, UnmarshalProgram returns an error, in which case the source has to be compiled again.
+// The source map of the compiled source is preserved if it has been loaded by the parser (see
+// file.File.SetSourceMapData).
+// The data is protected by a checksum, so accidental corruption (such as a truncated or damaged cache file) is
+// detected and UnmarshalProgram returns an error. It also performs sanity checks of the decoded code, however it is
+// not a complete bytecode verifier, so the data must come from a trusted source.
+func (p *Program) MarshalBinary() (data []byte, err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			if me, ok := x.(*programMarshalError); ok {
+				err = me.err
+				return
+			}
+			panic(x)
+		}
+	}()
+	e := &programEncoder{
+		pointers: make(map[programPointer]uint64),
+	}
+	e.buf = append(e.buf, programMagic...)
+	e.writeByte(programFormatVersion)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, programFingerprint())
+	e.buf = append(e.buf, make([]byte, 8+sha256.Size)...)
+	for _, s := range programSingletons {
+		e.pointers[programPointer{typ: reflect.TypeOf(s), addr: uintptr(unsafe.Pointer(s))}] = uint64(len(e.pointers)) + 1
+	}
+	e.writePointer(reflect.ValueOf(p))
+	setProgramChecksum(e.buf)
+	return e.buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, see UnmarshalProgram.
+func (p *Program) UnmarshalBinary(data []byte) error {
+	prg, err := UnmarshalProgram(data)
+	if err != nil {
+		return err
+	}
+	*p = *prg
+	return nil
+}
+
+// UnmarshalProgram decodes a Program previously encoded by Program.MarshalBinary.
+func UnmarshalProgram(data []byte) (p *Program, err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			if _, ok := x.(*deserializeError); ok {
+				err = errInvalidProgramData
+				return
+			}
+			panic(x)
+		}
+	}()
+	if len(data) < len(programMagic)+9 || string(data[:len(programMagic)]) != programMagic {
+		return nil, errInvalidProgramData
+	}
+	if data[len(programMagic)] != programFormatVersion ||
+		binary.LittleEndian.Uint64(data[len(programMagic)+1:]) != programFingerprint() {
+		return nil, errIncompatibleProgram
+	}
+	if len(data) < programHeaderSize || !bytes.Equal(data[len(programMagic)+9:programHeaderSize], programChecksum(data)) {
+		return nil, errInvalidProgramData
+	}
+	d := &programDecoder{
+		strings: make(map[string]string),
+	}
+	d.data = data
+	d.pos = programHeaderSize
+	for _, s := range programSingletons {
+		d.pointers = append(d.pointers, reflect.ValueOf(s))
+	}
+	p, _ = d.readPointer(typeProgramPtr).Interface().(*Program)
+	if p == nil || d.pos != len(d.data) || !verifyProgram(p, programFrame{}, make(map[*Program]bool)) {
+		d.fail()
+	}
+	return p, nil
+}
+
+// programChecksum returns the payload length and the checksum of the payload as they are stored in the header.
+func programChecksum(data []byte) []byte {
+	payload := data[programHeaderSize:]
+	sum := sha256.Sum256(payload)
+	return append(binary.LittleEndian.AppendUint64(nil, uint64(len(payload))), sum[:]...)
+}
+
+// setProgramChecksum stores the payload length and the checksum in the header.
+func setProgramChecksum(data []byte) {
+	copy(data[len(programMagic)+9:programHeaderSize], programChecksum(data))
+}
+
+// accessible returns a value through which an unexported struct field can be read and set.
+func accessible(v reflect.Value) reflect.Value {
+	if v.CanAddr() && !v.CanSet() {
+		return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+	}
+	return v
+}
+
+func (e *programEncoder) fail(format string, args ...interface{}) {
+	panic(&programMarshalError{err: fmt.Errorf("goja: cannot marshal program: "+format, args...)})
+}
+
+func (e *programEncoder) writeStr(s string) {
+	e.writeInt(len(s))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *programEncoder) writeFlag(b bool) {
+	if b {
+		e.writeByte(1)
+	} else {
+		e.writeByte(0)
+	}
+}
+
+func (e *programEncoder) write(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Bool:
+		e.writeFlag(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.buf = binary.AppendVarint(e.buf, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		e.writeUint(v.Uint())
+	case reflect.String:
+		e.writeStr(v.String())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			e.write(accessible(v.Field(i)))
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			e.writeUint(0)
+			return
+		}
+		e.writeInt(v.Len() + 1)
+		for i := 0; i < v.Len(); i++ {
+			e.write(v.Index(i))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		if v.Type().Key().Kind() != reflect.String {
+			e.fail("unsupported map type %s", v.Type())
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		e.writeInt(len(keys))
+		for _, key := range keys {
+			e.write(key)
+			e.write(v.MapIndex(key))
+		}
+	case reflect.Pointer:
+		e.writePointer(v)
+	case reflect.Interface:
+		e.writeInterface(v)
+	default:
+		e.fail("unsupported type %s", v.Type())
+	}
+}
+
+func (e *programEncoder) writePointer(v reflect.Value) {
+	if v.IsNil() {
+		e.writeUint(0)
+		return
+	}
+	key := programPointer{typ: v.Type(), addr: v.Pointer()}
+	if id, exists := e.pointers[key]; exists {
+		e.writeUint(id)
+		return
+	}
+	id := uint64(len(e.pointers)) + 1
+	e.pointers[key] = id
+	e.writeUint(id)
+	switch p := v.Interface().(type) {
+	case *file.File:
+		e.writeStr(p.Name())
+		e.writeStr(p.Source())
+		e.buf = binary.AppendVarint(e.buf, int64(p.Base()))
+		e.writeStr(string(p.SourceMapData()))
+	case *regexpPattern:
+		e.writeStr(p.src)
+		e.writeStr(p.flags())
+	default:
+		e.write(v.Elem())
+	}
+}
+
+func (e *programEncoder) writeInterface(v reflect.Value) {
+	switch v.Type() {
+	case typeInstruction:
+		if v.IsNil() {
+			e.writeUint(0)
+			return
+		}
+		elem := v.Elem()
+		idx, exists := programInstructionIndex()[elem.Type()]
+		if !exists {
+			e.fail("unsupported instruction %s", elem.Type())
+		}
+		e.writeUint(idx + 1)
+		c := reflect.New(elem.Type()).Elem()
+		c.Set(elem)
+		e.write(c)
+	case typeEmptyInterface:
+		switch c := v.Interface().(type) {
+		case nil:
+			e.writeByte(0)
+		case Value:
+			e.writeByte(1)
+			e.writeConst(c)
+		case referenceError:
+			e.writeByte(2)
+			e.writeStr(string(c))
+		default:
+			e.fail("unsupported value %T", c)
+		}
+	default:
+		// Value or one of its sub-interfaces (such as String)
+		if v.IsNil() {
+			e.writeByte(0)
+			return
+		}
+		c, ok := v.Interface().(Value)
+		if !ok {
+			e.fail("unsupported value %T", v.Interface())
+		}
+		e.writeConst(c)
+	}
+}
+
+func (e *programEncoder) writeConst(v Value) {
+	switch c := v.(type) {
+	case valueUndefined, valueNull, valueBool, valueInt, valueFloat, String, *valueBigInt:
+		e.writeValue(v)
+	case *valueProperty:
+		// data properties are used by tagged template objects
+		if c.accessor || c.value == nil {
+			e.fail("unsupported constant %T", v)
+		}
+		e.writeByte(programTagValueProperty)
+		e.writeFlag(c.writable)
+		e.writeFlag(c.configurable)
+		e.writeFlag(c.enumerable)
+		e.writeConst(c.value)
+	default:
+		e.fail("unsupported constant %T", v)
+	}
+}
+
+func (d *programDecoder) readStr() string {
+	return d.intern(d.readBytes())
+}
+
+func (d *programDecoder) intern(b []byte) string {
+	if s, exists := d.strings[string(b)]; exists {
+		return s
+	}
+	s := string(b)
+	d.strings[s] = s
+	return s
+}
+
+func (d *programDecoder) read(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(d.readFlag())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := d.readVarint()
+		if v.OverflowInt(i) {
+			d.fail()
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := d.readUint()
+		if v.OverflowUint(u) {
+			d.fail()
+		}
+		v.SetUint(u)
+	case reflect.String:
+		v.SetString(d.readStr())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			d.read(accessible(v.Field(i)))
+		}
+	case reflect.Slice:
+		l := d.readLen(1)
+		if l == 0 {
+			return
+		}
+		s := reflect.MakeSlice(v.Type(), l-1, l-1)
+		for i := 0; i < l-1; i++ {
+			d.read(s.Index(i))
+		}
+		v.Set(s)
+	case reflect.Map:
+		l := d.readLen(1)
+		m := reflect.MakeMapWithSize(v.Type(), l)
+		for i := 0; i < l; i++ {
+			key := reflect.New(v.Type().Key()).Elem()
+			d.read(key)
+			val := reflect.New(v.Type().Elem()).Elem()
+			d.read(val)
+			m.SetMapIndex(key, val)
+		}
+		v.Set(m)
+	case reflect.Pointer:
+		v.Set(d.readPointer(v.Type()))
+	case reflect.Interface:
+		if i := d.readInterface(v.Type()); i.IsValid() {
+			v.Set(i)
+		}
+	default:
+		d.fail()
+	}
+}
+
+func (d *programDecoder) readPointer(t reflect.Type) reflect.Value {
+	id := d.readUint()
+	if id == 0 {
+		return reflect.Zero(t)
+	}
+	if id <= uint64(len(d.pointers)) {
+		p := d.pointers[id-1]
+		if !p.IsValid() || p.Type() != t {
+			d.fail()
+		}
+		return p
+	}
+	if id != uint64(len(d.pointers))+1 {
+		d.fail()
+	}
+	idx := len(d.pointers)
+	d.pointers = append(d.pointers, reflect.Value{})
+	var p reflect.Value
+	switch t {
+	case typeFilePtr:
+		name := d.readStr()
+		src := d.readStr()
+		base := d.readVarint()
+		if base < 1 || base > math.MaxInt32 {
+			d.fail()
+		}
+		f := file.NewFile(name, src, int(base))
+		if sm := d.readBytes(); len(sm) > 0 {
+			if f.SetSourceMapData(bytes.Clone(sm)) != nil {
+				d.fail()
+			}
+		}
+		p = reflect.ValueOf(f)
+	case typeRegexpPatternPtr:
+		pattern, err := compileRegexp(d.readStr(), d.readStr())
+		if err != nil {
+			d.fail()
+		}
+		p = reflect.ValueOf(pattern)
+	default:
+		p = reflect.New(t.Elem())
+		d.pointers[idx] = p
+		d.read(p.Elem())
+	}
+	d.pointers[idx] = p
+	return p
+}
+
+func (d *programDecoder) readInterface(t reflect.Type) reflect.Value {
+	switch t {
+	case typeInstruction:
+		idx := d.readUint()
+		if idx == 0 {
+			return reflect.Value{}
+		}
+		if idx > uint64(len(programInstructionTypes)) {
+			d.fail()
+		}
+		v := reflect.New(programInstructionTypes[idx-1]).Elem()
+		d.read(v)
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			// a nil instruction is encoded as 0, a nil pointer of a specific type is never produced
+			d.fail()
+		}
+		return v
+	case typeEmptyInterface:
+		switch d.readByte() {
+		case 0:
+			return reflect.Value{}
+		case 1:
+			if v := d.readConst(); v != nil {
+				return reflect.ValueOf(v)
+			}
+		case 2:
+			return reflect.ValueOf(referenceError(d.readStr()))
+		}
+	default:
+		v := d.readConst()
+		if v == nil {
+			return reflect.Value{}
+		}
+		if rv := reflect.ValueOf(v); rv.Type().Implements(t) {
+			return rv
+		}
+	}
+	d.fail()
+	return reflect.Value{}
+}
+
+func (d *programDecoder) readConst() Value {
+	if d.pos >= len(d.data) {
+		d.fail()
+	}
+	switch d.data[d.pos] {
+	case 0:
+		d.pos++
+		return nil
+	case serTagASCIIString:
+		d.pos++
+		return asciiString(d.readStr())
+	case serTagUndefined, serTagNull, serTagTrue, serTagFalse, serTagInt, serTagFloat, serTagUnicodeString,
+		serTagBigInt:
+		return d.readValue()
+	case programTagValueProperty:
+		d.pos++
+		p := &valueProperty{
+			writable:     d.readFlag(),
+			configurable: d.readFlag(),
+			enumerable:   d.readFlag(),
+		}
+		if p.value = d.readConst(); p.value == nil {
+			d.fail()
+		}
+		return p
+	}
+	d.fail()
+	return nil
+}
+
+// programFrame describes the stashes and the stack frame the code of a Program can access. The sizes are upper
+// bounds: they are the totals declared by the Program and the Programs it is nested in.
+type programFrame struct {
+	stashes   int
+	stashSize uint32
+	stackSize int
+	numArgs   int
+}
+
+func (f *programFrame) stashOk(s uint32) bool {
+	return int(s>>24) <= f.stashes && s&0x00FFFFFF < f.stashSize
+}
+
+func (f *programFrame) stackOk(idx int) bool {
+	return idx >= -f.numArgs && idx <= f.stackSize
+}
+
+func (f *programFrame) addStash(size uint32) {
+	f.stashes++
+	if size > f.stashSize {
+		f.stashSize = size
+	}
+}
+
+func (f *programFrame) addArgs(n int) {
+	if n > f.numArgs {
+		f.numArgs = n
+	}
+}
+
+// verifyProgram performs sanity checks of a decoded Program (and the Programs nested in it), so that corrupted
+// data is rejected by UnmarshalProgram rather than causing a panic when the code is run. It checks that the jump
+// targets are within the code and that the stack and stash indices do not exceed the sizes declared by the
+// Programs. It is not a complete bytecode verifier, the data must still come from a trusted source.
+func verifyProgram(p *Program, outer programFrame, seen map[*Program]bool) bool {
+	if seen[p] {
+		return true
+	}
+	seen[p] = true
+	f := programFrame{
+		stashes:   outer.stashes,
+		stashSize: outer.stashSize,
+	}
+	for _, ins := range p.code {
+		switch ins := ins.(type) {
+		case *enterFunc:
+			f.addStash(ins.stashSize)
+			f.stackSize += int(ins.stackSize)
+			f.addArgs(int(ins.numArgs))
+		case *enterFunc1:
+			f.addStash(ins.stashSize)
+			f.addArgs(int(ins.numArgs))
+		case *enterFuncStashless:
+			f.stackSize += int(ins.stackSize)
+			f.addArgs(int(ins.args))
+		case *enterFuncBody:
+			f.addStash(ins.stashSize)
+			f.stackSize += int(ins.stackSize)
+		case *enterBlock:
+			f.addStash(ins.stashSize)
+			f.stackSize += int(ins.stackSize)
+		case *enterCatchBlock:
+			f.addStash(ins.stashSize)
+			f.stackSize += int(ins.stackSize)
+		case *leaveBlock:
+			// the catch parameter is pushed by the exception handler, so it only appears here
+			f.stackSize += int(ins.stackSize)
+		case _enterWith:
+			f.addStash(0)
+		}
+	}
+
+	jumpOk := func(pc int, offset int32) bool {
+		target := pc + int(offset)
+		return target >= 0 && target <= len(p.code)
+	}
+	verifyNested := func(prg *Program) bool {
+		return prg == nil || verifyProgram(prg, f, seen)
+	}
+
+	for pc, ins := range p.code {
+		var ok bool
+		switch ins := ins.(type) {
+		case nil:
+		case loadVal:
+			ok = ins.v != nil
+		case jump:
+			ok = jumpOk(pc, int32(ins))
+		case jneP:
+			ok = jumpOk(pc, int32(ins))
+		case jeqP:
+			ok = jumpOk(pc, int32(ins))
+		case jeq:
+			ok = jumpOk(pc, int32(ins))
+		case jne:
+			ok = jumpOk(pc, int32(ins))
+		case jdef:
+			ok = jumpOk(pc, int32(ins))
+		case jdefP:
+			ok = jumpOk(pc, int32(ins))
+		case jopt:
+			ok = jumpOk(pc, int32(ins))
+		case joptc:
+			ok = jumpOk(pc, int32(ins))
+		case joptdel:
+			ok = jumpOk(pc, int32(ins))
+		case joptdelc:
+			ok = jumpOk(pc, int32(ins))
+		case joptdelP:
+			ok = jumpOk(pc, int32(ins))
+		case joptdelcP:
+			ok = jumpOk(pc, int32(ins))
+		case jcoalesc:
+			ok = jumpOk(pc, int32(ins))
+		case jcoalescP:
+			ok = jumpOk(pc, int32(ins))
+		case enumNext:
+			ok = jumpOk(pc, int32(ins))
+		case iterNext:
+			ok = jumpOk(pc, int32(ins))
+		case try:
+			ok = (ins.catchOffset <= 0 || jumpOk(pc, ins.catchOffset)) &&
+				(ins.finallyOffset <= 0 || jumpOk(pc, ins.finallyOffset))
+		case loadStack:
+			ok = f.stackOk(int(ins))
+		case loadStack1:
+			ok = f.stackOk(int(ins))
+		case loadStackLex:
+			ok = f.stackOk(int(ins))
+		case loadStack1Lex:
+			ok = f.stackOk(int(ins))
+		case storeStack:
+			ok = f.stackOk(int(ins))
+		case storeStack1:
+			ok = f.stackOk(int(ins))
+		case storeStackLex:
+			ok = f.stackOk(int(ins))
+		case storeStack1Lex:
+			ok = f.stackOk(int(ins))
+		case storeStackP:
+			ok = f.stackOk(int(ins))
+		case storeStack1P:
+			ok = f.stackOk(int(ins))
+		case storeStackLexP:
+			ok = f.stackOk(int(ins))
+		case storeStack1LexP:
+			ok = f.stackOk(int(ins))
+		case initStack:
+			ok = f.stackOk(int(ins))
+		case initStackP:
+			ok = f.stackOk(int(ins))
+		case initStack1:
+			ok = f.stackOk(int(ins))
+		case initStack1P:
+			ok = f.stackOk(int(ins))
+		case createArgsRestStack:
+			ok = ins >= 0 && int(ins) <= f.numArgs
+		case *loadMixedStack:
+			ok = int(ins.level) <= f.stashes && f.stackOk(ins.idx)
+		case *loadMixedStack1:
+			ok = int(ins.level) <= f.stashes && f.stackOk(ins.idx)
+		case *loadMixedStackLex:
+			ok = int(ins.level) <= f.stashes && f.stackOk(ins.idx)
+		case *loadMixedStack1Lex:
+			ok = int(ins.level) <= f.stashes && f.stackOk(ins.idx)
+		case *resolveMixedStack:
+			ok = int(ins.level) <= f.stashes && f.stackOk(ins.idx)
+		case *resolveMixedStack1:
+			ok = int(ins.level) <= f.stashes && f.stackOk(ins.idx)
+		case loadStash:
+			ok = f.stashOk(uint32(ins))
+		case loadStashLex:
+			ok = f.stashOk(uint32(ins))
+		case storeStash:
+			ok = f.stashOk(uint32(ins))
+		case storeStashP:
+			ok = f.stashOk(uint32(ins))
+		case storeStashLex:
+			ok = f.stashOk(uint32(ins))
+		case storeStashLexP:
+			ok = f.stashOk(uint32(ins))
+		case initStash:
+			ok = f.stashOk(uint32(ins))
+		case initStashP:
+			ok = f.stashOk(uint32(ins))
+		case cret:
+			ok = f.stashOk(uint32(ins))
+		case loadThisStash:
+			ok = f.stashOk(uint32(ins))
+		case resolveThisStash:
+			ok = f.stashOk(uint32(ins))
+		case *loadMixed:
+			ok = f.stashOk(ins.idx)
+		case *loadMixedLex:
+			ok = f.stashOk(ins.idx)
+		case *resolveMixed:
+			ok = f.stashOk(ins.idx)
+		case newFuncInstruction:
+			ok = ins.getPrg() != nil && verifyNested(ins.getPrg())
+		case *newDerivedClass:
+			ok = verifyNested(ins.ctor) && verifyNested(ins.initFields)
+		case *newClass:
+			ok = verifyNested(ins.ctor) && verifyNested(ins.initFields)
+		case *newStaticFieldInit:
+			ok = verifyNested(ins.initFields)
+		default:
+			ok = true
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
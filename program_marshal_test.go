@@ -0,0 +1,266 @@
+package goja
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/dop251/goja/parser"
+)
+
+func TestProgramMarshal(t *testing.T) {
+	const SCRIPT = `
+	class C {
+		#priv = 1;
+		static #count = 0;
+		constructor(v) {
+			this.v = v;
+			C.#count++;
+		}
+		get priv() {
+			return this.#priv;
+		}
+		static count() {
+			return C.#count;
+		}
+	}
+	class D extends C {
+		constructor() {
+			super("d");
+		}
+	}
+	function* gen() {
+		yield 1;
+		yield* [2, 3];
+	}
+	async function af() {
+		return await Promise.resolve("async");
+	}
+	function tag(strings, ...values) {
+		return strings.raw.join("|") + values.join();
+	}
+	let asyncRes;
+	af().then(v => { asyncRes = v; });
+	const re = /a(b+)c/gi;
+	const res = [
+		new C(1).priv, new D().v, C.count(), [...gen()].join(), "ABBBC".replace(re, "$1"), tag` + "`a${1}b\\n${2}`" + `,
+		12345678901234567890n * 2n, "юникод".length, -0, 1.5, null, undefined, typeof eval, (() => this)() === undefined,
+	];
+	try {
+		null.x;
+	} catch (e) {
+		res.push(e instanceof TypeError);
+	}
+	res;
+	`
+	prg := MustCompile("test.js", SCRIPT, false)
+	data, err := prg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data1, err := prg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, data1) {
+		t.Fatal("the output is not deterministic")
+	}
+	prg1, err := UnmarshalProgram(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(p *Program) string {
+		r := New()
+		v, err := r.RunProgram(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v.String() + ";" + r.Get("asyncRes").String()
+	}
+	if res, res1 := run(prg), run(prg1); res != res1 {
+		t.Fatalf("results differ: %q, %q", res, res1)
+	}
+
+	var p Program
+	if err := p.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if res, res1 := run(prg), run(&p); res != res1 {
+		t.Fatalf("results differ: %q, %q", res, res1)
+	}
+
+	prg2, err := UnmarshalProgram(mustMarshalProgram(t, MustCompile("error.js", "\n  throw new Error('x');", false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = New().RunProgram(prg2)
+	var ex *Exception
+	if !errors.As(err, &ex) || ex.stack[0].Position().String() != "error.js:2:9" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func mustMarshalProgram(t *testing.T, p *Program) []byte {
+	data, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestProgramUnmarshalInvalid(t *testing.T) {
+	data := mustMarshalProgram(t, MustCompile("test.js", "function f(a) { return a + 1; } f(1);", false))
+
+	incompatible := bytes.Clone(data)
+	incompatible[len(programMagic)+1]++
+	if _, err := UnmarshalProgram(incompatible); !errors.Is(err, errIncompatibleProgram) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	damaged := bytes.Clone(data)
+	damaged[len(damaged)-10] ^= 1
+	badChecksum := bytes.Clone(data)
+	badChecksum[programHeaderSize-1] ^= 1
+	for _, data := range [][]byte{nil, []byte("goja"), []byte("fooo12345678901234"), data[:len(data)-1],
+		append(bytes.Clone(data), 0), data[:programHeaderSize-1], damaged, badChecksum} {
+		if _, err := UnmarshalProgram(data); !errors.Is(err, errInvalidProgramData) {
+			t.Fatalf("%v: unexpected error: %v", data, err)
+		}
+	}
+
+	// the code that would panic when run is rejected
+	for _, tc := range []struct {
+		src     string
+		corrupt func(ins instruction) instruction
+	}{
+		{"var a = 1; if (a) { a++; }", func(ins instruction) instruction {
+			if _, ok := ins.(jneP); ok {
+				return jneP(1000)
+			}
+			return ins
+		}},
+		{"function f() { let a = 1; return () => a; }", func(ins instruction) instruction {
+			if s, ok := ins.(loadStashLex); ok {
+				return s + 100
+			}
+			return ins
+		}},
+		{"function f() { let a = 1; return () => a; }", func(ins instruction) instruction {
+			if s, ok := ins.(loadStashLex); ok {
+				return s + 5<<24
+			}
+			return ins
+		}},
+		{"function f(x) { var a = x; return a; }", func(ins instruction) instruction {
+			if s, ok := ins.(loadStack); ok {
+				return s + 100
+			}
+			return ins
+		}},
+		{"function f() {}", func(ins instruction) instruction {
+			if _, ok := ins.(*newFunc); ok {
+				return (*newAsyncMethod)(nil)
+			}
+			return ins
+		}},
+		{"function f() { let a = 1; return () => a; }", func(ins instruction) instruction {
+			if _, ok := ins.(*enterFunc); ok {
+				return (*enterFunc)(nil)
+			}
+			return ins
+		}},
+	} {
+		prg := MustCompile("test.js", tc.src, false)
+		found := false
+		var corrupt func(p *Program)
+		corrupt = func(p *Program) {
+			for i, ins := range p.code {
+				if f, ok := ins.(newFuncInstruction); ok {
+					corrupt(f.getPrg())
+				}
+				if c := tc.corrupt(ins); c != ins {
+					p.code[i] = c
+					found = true
+				}
+			}
+		}
+		corrupt(prg)
+		if !found {
+			t.Fatalf("%s: nothing to corrupt", tc.src)
+		}
+		if _, err := UnmarshalProgram(mustMarshalProgram(t, prg)); !errors.Is(err, errInvalidProgramData) {
+			t.Fatalf("%s: unexpected error: %v", tc.src, err)
+		}
+	}
+}
+
+func TestProgramMarshalSourceMap(t *testing.T) {
+	// the generated line 2 maps to the line 5 of orig.js
+	const SCRIPT = `var a = 1;
+throw new Error("x");
+//# sourceMappingURL=gen.js.map
+`
+	const MAP = `{"version":3,"sources":["orig.js"],"names":[],"mappings":"AAEA;AAEA"}`
+	ast, err := parser.ParseFile(nil, "gen.js", SCRIPT, 0, parser.WithSourceMapLoader(func(path string) ([]byte, error) {
+		return []byte(MAP), nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prg, err := CompileAST(ast, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prg1, err := UnmarshalProgram(mustMarshalProgram(t, prg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*Program{prg, prg1} {
+		_, err = New().RunProgram(p)
+		var ex *Exception
+		if !errors.As(err, &ex) || ex.stack[0].Position().String() != "orig.js:5:0" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func FuzzUnmarshalProgram(f *testing.F) {
+	for _, src := range []string{
+		"var a = 1; if (a) { a++; } a",
+		"function f(x) { let a = x; return () => a + 1; } f(1)();",
+		"class C { #p = 1; get p() { return this.#p; } } new C().p",
+		"function* g() { yield 1; } [...g()].join() + `${1}`",
+		"async function af() { try { await null; } catch (e) { return e; } } af()",
+		"for (const k in {a: 1}) { k; } let s = 0; for (const v of [1, 2]) { s += v; } s",
+	} {
+		data, err := MustCompile("test.js", src, false).MarshalBinary()
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := UnmarshalProgram(data)
+		if err == nil {
+			// only unmodified data passes the checksum, it must run and encode the same way
+			data1, err := p.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, data1) {
+				t.Fatal("the program has been accepted but it differs from the input")
+			}
+			r := New()
+			r.SetInstructionLimit(100000)
+			_, _ = r.RunProgram(p)
+		}
+
+		// the decoder and the sanity checks must not panic on corrupted data that passes the checksum
+		if len(data) >= programHeaderSize && string(data[:len(programMagic)]) == programMagic {
+			data = bytes.Clone(data)
+			setProgramChecksum(data)
+			_, _ = UnmarshalProgram(data)
+		}
+	})
+}
@@ -175,6 +175,20 @@ func (p *regexpPattern) findAllSubmatchIndex(s String, start int, limit int, sti
 	return p.regexp2Wrapper.findAllSubmatchIndex(s, start, limit, sticky, p.unicode)
 }
 
+// flags returns the flags of the pattern in the canonical order.
+func (p *regexpPattern) flags() string {
+	var b strings.Builder
+	for _, f := range [...]struct {
+		set  bool
+		flag byte
+	}{{p.global, 'g'}, {p.ignoreCase, 'i'}, {p.multiline, 'm'}, {p.dotAll, 's'}, {p.unicode, 'u'}, {p.sticky, 'y'}} {
+		if f.set {
+			b.WriteByte(f.flag)
+		}
+	}
+	return b.String()
+}
+
 // clone creates a copy of the regexpPattern which can be used concurrently.
 func (p *regexpPattern) clone() *regexpPattern {
 	ret := &regexpPattern{
@@ -542,6 +556,7 @@ func (r *regexpObject) getLastIndex() int64 {
 func (r *regexpObject) execRegexp(target String) (match bool, result []int) {
 	index := r.getLastIndex()
 	if index >= 0 && index <= int64(target.Length()) {
+		r.val.runtime.vm.chargeInstructions(int64(target.Length()) - index)
 		result = r.pattern.findSubmatchIndex(target, int(index))
 	}
 	match = len(result) > 0 && (!r.pattern.sticky || int64(result[0]) == index)
@@ -2,6 +2,7 @@ package goja
 
 import (
 	"bytes"
+	gocontext "context"
 	"errors"
 	"fmt"
 	"go/ast"
@@ -64,6 +65,7 @@ type global struct {
 	Promise  *Object
 	Math     *Object
 	JSON     *Object
+	Iterator *Object
 
 	AsyncFunction *Object
 
@@ -77,6 +79,7 @@ type global struct {
 	Int16Array        *Object
 	Uint32Array       *Object
 	Int32Array        *Object
+	Float16Array      *Object
 	Float32Array      *Object
 	Float64Array      *Object
 	BigInt64Array     *Object
@@ -130,8 +133,11 @@ type global struct {
 	SetIteratorPrototype          *Object
 	StringIteratorPrototype       *Object
 	RegExpStringIteratorPrototype *Object
+	IteratorHelperPrototype       *Object
+	WrapForValidIteratorPrototype *Object
 
-	ErrorPrototype *Object
+	ErrorPrototype    *Object
+	CallSitePrototype *Object
 
 	Eval *Object
 
@@ -183,8 +189,12 @@ type Runtime struct {
 	stringSingleton *stringObject
 	rand            RandSource
 	now             Now
+	loc             *time.Location
+	deterministic   bool
 	_collator       *collate.Collator
 	parserOptions   []parser.Option
+	coverage        *Coverage
+	localProfiler   _localProfiler
 
 	symbolRegistry map[unistring.String]*Symbol
 
@@ -205,12 +215,28 @@ type Runtime struct {
 	// Stack for tracking objects currently being converted to string
 	// to detect and handle circular references
 	toStringStack []*Object
+
+	// set while Error.prepareStackTrace is being called to prevent recursion
+	preparingStackTrace bool
+
+	// the context of the current Run*Context() or CallableContext call, nil if there is none
+	ctx gocontext.Context
+
+	// approximate number of bytes allocated since the last SetMemoryLimit() and the limit, 0 means no limit
+	memUsed, memLimit int64
+
+	// set if rand is the generator created by SetDeterministic with the seed, so that Reset can re-seed it
+	seededRand bool
+	randSeed   int64
 }
 
 type StackFrame struct {
 	prg      *Program
 	funcName unistring.String
 	pc       int
+
+	// set for frames of async functions that are awaiting the completion of the preceding frame
+	async bool
 }
 
 func (f *StackFrame) SrcName() string {
@@ -230,6 +256,12 @@ func (f *StackFrame) FuncName() string {
 	return f.funcName.String()
 }
 
+// IsAsync returns true if the frame belongs to an async function which is suspended in an 'await'
+// for the completion of the preceding frame, i.e. it's not a part of the synchronous call stack.
+func (f *StackFrame) IsAsync() bool {
+	return f.async
+}
+
 func (f *StackFrame) Position() file.Position {
 	if f.prg == nil || f.prg.src == nil {
 		return file.Position{}
@@ -238,6 +270,9 @@ func (f *StackFrame) Position() file.Position {
 }
 
 func (f *StackFrame) WriteToValueBuilder(b *StringBuilder) {
+	if f.async {
+		b.writeASCII("async ")
+	}
 	if f.prg != nil {
 		if n := f.prg.funcName; n != "" {
 			b.WriteString(stringValueFromRaw(n))
@@ -272,6 +307,9 @@ func (f *StackFrame) WriteToValueBuilder(b *StringBuilder) {
 }
 
 func (f *StackFrame) Write(b *bytes.Buffer) {
+	if f.async {
+		b.WriteString("async ")
+	}
 	if f.prg != nil {
 		if n := f.prg.funcName; n != "" {
 			b.WriteString(n.String())
@@ -339,6 +377,70 @@ type StackOverflowError struct {
 	baseUncatchableException
 }
 
+// MemoryLimitExceededError is thrown when the limit set by SetMemoryLimit is exceeded. It cannot be caught
+// by JavaScript code.
+type MemoryLimitExceededError struct {
+	baseUncatchableException
+	limit int64
+}
+
+// Limit returns the memory limit that was exceeded.
+func (e *MemoryLimitExceededError) Limit() int64 {
+	return e.limit
+}
+
+func (e *MemoryLimitExceededError) String() string {
+	if e == nil {
+		return "<nil>"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "memory limit of %d bytes exceeded\n", e.limit)
+	e.writeFullStack(&b)
+	return b.String()
+}
+
+func (e *MemoryLimitExceededError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "memory limit of %d bytes exceeded", e.limit)
+	e.writeShortStack(&b)
+	return b.String()
+}
+
+// InstructionLimitExceededError is thrown when the limit set by SetInstructionLimit is exceeded. It cannot be
+// caught by JavaScript code.
+type InstructionLimitExceededError struct {
+	baseUncatchableException
+	limit int64
+}
+
+// Limit returns the instruction limit that was exceeded.
+func (e *InstructionLimitExceededError) Limit() int64 {
+	return e.limit
+}
+
+func (e *InstructionLimitExceededError) String() string {
+	if e == nil {
+		return "<nil>"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "instruction limit of %d exceeded\n", e.limit)
+	e.writeFullStack(&b)
+	return b.String()
+}
+
+func (e *InstructionLimitExceededError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "instruction limit of %d exceeded", e.limit)
+	e.writeShortStack(&b)
+	return b.String()
+}
+
 func (e *InterruptedError) Value() interface{} {
 	return e.iface
 }
@@ -426,37 +528,87 @@ func (e *Exception) Stack() []StackFrame {
 	return e.stack
 }
 
-func (r *Runtime) createIterProto(val *Object) objectImpl {
-	o := newBaseObjectObj(val, r.global.ObjectPrototype, classObject)
+func (r *Runtime) init() {
+	r.rand = rand.Float64
+	r.now = time.Now
 
-	o._putSym(SymIterator, valueProp(r.newNativeFunc(r.returnThis, "[Symbol.iterator]", 0), true, false, true))
-	return o
-}
+	r.initGlobalObject()
 
-func (r *Runtime) getIteratorPrototype() *Object {
-	var o *Object
-	if o = r.global.IteratorPrototype; o == nil {
-		o = &Object{runtime: r}
-		r.global.IteratorPrototype = o
-		o.self = r.createIterProto(o)
+	r.vm = &vm{
+		r: r,
 	}
-	return o
+	r.vm.init()
 }
 
-func (r *Runtime) init() {
-	r.rand = rand.Float64
-	r.now = time.Now
-
+func (r *Runtime) initGlobalObject() {
 	r.global.ObjectPrototype = &Object{runtime: r}
 	r.newTemplatedObject(getObjectProtoTemplate(), r.global.ObjectPrototype)
 
 	r.globalObject = &Object{runtime: r}
 	r.newTemplatedObject(getGlobalObjectTemplate(), r.globalObject)
+}
 
-	r.vm = &vm{
-		r: r,
+// Reset restores the Runtime to the state it was in after New(): the global object and the global variables
+// are discarded, the built-ins are re-created (lazily, as in a new Runtime), the job queue, the interrupt
+// flag, the Symbol registry and the caches of Go types are cleared. Allocated structures such as the stacks
+// and the global scope are reused, which makes it considerably cheaper than creating a new Runtime.
+//
+// The settings (the field name mapper, the time and random sources, the parser options, the limits, the
+// trackers, etc.) are retained, the memory and instruction counters are reset and the Math.random() generator
+// set up by SetDeterministic is re-seeded, so that the execution can be replayed.
+//
+// Reset must not be called while the Runtime is running. Values obtained from the Runtime before the call
+// must not be used afterwards.
+func (r *Runtime) Reset() {
+	s := &r.global.stash
+	clear(s.values)
+	clear(s.names)
+	r.global = global{
+		stash: stash{
+			values: s.values[:0],
+			names:  s.names,
+		},
 	}
-	r.vm.init()
+	r.initGlobalObject()
+	r.stringSingleton = nil
+
+	clear(r.symbolRegistry)
+	clear(r.fieldsInfoCache)
+	clear(r.methodsInfoCache)
+
+	clear(r.jobQueue)
+	r.jobQueue = r.jobQueue[:0]
+	clear(r.toStringStack)
+	r.toStringStack = r.toStringStack[:0]
+	r.preparingStackTrace = false
+	r.ctx = nil
+	r.memUsed = 0
+	if r.seededRand {
+		r.rand = rand.New(rand.NewSource(r.randSeed)).Float64
+	}
+
+	r.vm.reset()
+}
+
+// resetSettings restores the settings to the defaults of a new Runtime: detaches the debugger, stops the profiling,
+// removes the coverage, the limits, the trackers and the field name mapper, and restores the time and random sources.
+func (r *Runtime) resetSettings() {
+	r.rand = rand.Float64
+	r.now = time.Now
+	r.loc = nil
+	r.deterministic = false
+	r.seededRand = false
+	r.parserOptions = nil
+	r.coverage = nil
+	r.fieldNameMapper = nil
+	r.promiseRejectionTracker = nil
+	r.asyncContextTracker = nil
+	r.memLimit = 0
+	_ = r.StopProfile()
+
+	r.vm.debugger = nil
+	r.vm.maxCallStackSize = math.MaxInt32
+	r.vm.instrLimit = math.MaxInt64
 }
 
 func (r *Runtime) typeErrorResult(throw bool, args ...interface{}) {
@@ -1307,14 +1459,14 @@ func New() *Runtime {
 // method. This representation is not linked to a runtime in any way and can be run in multiple runtimes (possibly
 // at the same time).
 func Compile(name, src string, strict bool) (*Program, error) {
-	return compile(name, src, strict, true, nil)
+	return compile(name, src, strict, true, nil, false, nil)
 }
 
 // CompileAST creates an internal representation of the JavaScript code that can be later run using the Runtime.RunProgram()
 // method. This representation is not linked to a runtime in any way and can be run in multiple runtimes (possibly
 // at the same time).
 func CompileAST(prg *js_ast.Program, strict bool) (*Program, error) {
-	return compileAST(prg, strict, true, nil)
+	return compileAST(prg, strict, true, nil, false, nil)
 }
 
 // MustCompile is like Compile but panics if the code cannot be compiled.
@@ -1350,17 +1502,21 @@ func Parse(name, src string, options ...parser.Option) (prg *js_ast.Program, err
 	return
 }
 
-func compile(name, src string, strict, inGlobal bool, evalVm *vm, parserOptions ...parser.Option) (p *Program, err error) {
+func compile(name, src string, strict, inGlobal bool, evalVm *vm, debug bool, cov *Coverage, parserOptions ...parser.Option) (p *Program, err error) {
 	prg, err := Parse(name, src, parserOptions...)
 	if err != nil {
 		return
 	}
 
-	return compileAST(prg, strict, inGlobal, evalVm)
+	return compileAST(prg, strict, inGlobal, evalVm, debug, cov)
 }
 
-func compileAST(prg *js_ast.Program, strict, inGlobal bool, evalVm *vm) (p *Program, err error) {
+func compileAST(prg *js_ast.Program, strict, inGlobal bool, evalVm *vm, debug bool, cov *Coverage) (p *Program, err error) {
 	c := newCompiler()
+	c.debug = debug
+	if cov != nil {
+		c.coverage = newCoverageScript(prg.File)
+	}
 
 	defer func() {
 		if x := recover(); x != nil {
@@ -1376,11 +1532,22 @@ func compileAST(prg *js_ast.Program, strict, inGlobal bool, evalVm *vm) (p *Prog
 
 	c.compile(prg, strict, inGlobal, evalVm)
 	p = c.p
+	if cov != nil {
+		cov.addScript(c.coverage)
+	}
 	return
 }
 
 func (r *Runtime) compile(name, src string, strict, inGlobal bool, evalVm *vm) (p *Program, err error) {
-	p, err = compile(name, src, strict, inGlobal, evalVm, r.parserOptions...)
+	d := r.vm.debugger
+	var cov *Coverage
+	if evalVm == nil && name != "" {
+		cov = r.coverage
+	}
+	p, err = compile(name, src, strict, inGlobal, evalVm, d != nil, cov, r.parserOptions...)
+	if err == nil && d != nil && d.scriptHandler != nil && evalVm == nil {
+		d.scriptHandler(name, src)
+	}
 	if err != nil {
 		switch x1 := err.(type) {
 		case *CompilerSyntaxError:
@@ -1412,6 +1579,77 @@ func (r *Runtime) RunScript(name, src string) (Value, error) {
 	return r.RunProgram(p)
 }
 
+// RunStringContext is like RunString, but the execution is interrupted when ctx is done (see RunProgramContext).
+func (r *Runtime) RunStringContext(ctx gocontext.Context, str string) (Value, error) {
+	return r.RunScriptContext(ctx, "", str)
+}
+
+// RunScriptContext is like RunScript, but the execution is interrupted when ctx is done (see RunProgramContext).
+func (r *Runtime) RunScriptContext(ctx gocontext.Context, name, src string) (Value, error) {
+	p, err := r.compile(name, src, false, true, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.RunProgramContext(ctx, p)
+}
+
+// RunProgramContext is like RunProgram, but the execution is interrupted when ctx is done. In this case an
+// *InterruptedError is returned which wraps ctx.Err(), so errors.Is(err, context.DeadlineExceeded) can be used
+// to check for a timeout. If ctx is already done, the program is not run.
+// While the program runs ctx is returned by Runtime.Context(), so that it is available to native functions.
+//
+// Unlike Interrupt(), there is no need to call ClearInterrupt() afterwards.
+// As with Interrupt(), native Go functions are not interrupted, they should watch ctx themselves.
+func (r *Runtime) RunProgramContext(ctx gocontext.Context, p *Program) (result Value, err error) {
+	err = r.withContext(ctx, func() (err error) {
+		result, err = r.RunProgram(p)
+		return
+	})
+	return
+}
+
+// Context returns the context of the currently running Run*Context() or CallableContext call. If there is none,
+// context.Background() is returned. Native functions can use it to make their operations cancellable.
+func (r *Runtime) Context() gocontext.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return gocontext.Background()
+}
+
+func (r *Runtime) withContext(ctx gocontext.Context, f func() error) error {
+	prev := r.ctx
+	r.ctx = ctx
+	defer func() {
+		r.ctx = prev
+	}()
+
+	if ctx.Done() == nil {
+		return f()
+	}
+
+	if err := ctx.Err(); err != nil {
+		r.vm.Interrupt(err)
+	}
+	interrupted := make(chan struct{})
+	stop := gocontext.AfterFunc(ctx, func() {
+		r.vm.Interrupt(ctx.Err())
+		close(interrupted)
+	})
+	err := f()
+	if !stop() {
+		// The interrupt has been (or is being) triggered by ctx. Wait until it's set, then reset it so that
+		// the runtime can be re-used, unless it's needed for an outer context which is also done.
+		<-interrupted
+		if prev == nil || prev.Err() == nil {
+			r.vm.ClearInterrupt()
+		}
+	}
+	return err
+}
+
 func isUncatchableException(e error) bool {
 	for ; e != nil; e = errors.Unwrap(e) {
 		if _, ok := e.(uncatchableException); ok {
@@ -1696,6 +1934,9 @@ Any other Go function is wrapped so that the arguments are automatically convert
 return value is converted to a JavaScript value (using this method).  If conversion is not possible, a TypeError is
 thrown.
 
+If the first parameter of such a function is a context.Context, it receives the value of Runtime.Context() rather than
+one of the call arguments. This allows the cancellation and deadlines of Run*Context() calls to propagate to the Go code.
+
 Functions with multiple return values return an Array. If the last return value is an `error` it is not returned but
 converted into a JS exception. If the error is *Exception, it is thrown as is, otherwise it's wrapped in a GoEerror.
 Note that if there are exactly two return values and the last is an `error`, the function returns the first value as is,
@@ -1986,12 +2227,19 @@ func (r *Runtime) toValue(i interface{}, origValue reflect.Value) Value {
 }
 
 func (r *Runtime) wrapReflectFunc(value reflect.Value) func(FunctionCall) Value {
+	hasCtx := value.Type().NumIn() > 0 && value.Type().In(0) == reflectTypeContext
 	return func(call FunctionCall) Value {
 		typ := value.Type()
 		nargs := typ.NumIn()
 		var in []reflect.Value
 
-		if l := len(call.Arguments); l < nargs {
+		args := call.Arguments
+		if hasCtx {
+			// reserve the first slot for the context
+			args = append(make([]Value, 1, len(args)+1), args...)
+		}
+
+		if l := len(args); l < nargs {
 			// fill missing arguments with zero values
 			n := nargs
 			if typ.IsVariadic() {
@@ -2008,7 +2256,11 @@ func (r *Runtime) wrapReflectFunc(value reflect.Value) func(FunctionCall) Value
 			in = make([]reflect.Value, l)
 		}
 
-		for i, a := range call.Arguments {
+		for i, a := range args {
+			if hasCtx && i == 0 {
+				in[0] = reflect.ValueOf(r.Context())
+				continue
+			}
 			var t reflect.Type
 
 			n := i
@@ -2027,6 +2279,9 @@ func (r *Runtime) wrapReflectFunc(value reflect.Value) func(FunctionCall) Value
 			v := reflect.New(t).Elem()
 			err := r.toReflectValue(a, v, &objectExportCtx{})
 			if err != nil {
+				if hasCtx {
+					i--
+				}
 				panic(r.NewTypeError("could not convert function call parameter %d: %v", i, err))
 			}
 			in[i] = v
@@ -2129,7 +2384,7 @@ func (r *Runtime) toReflectValue(v Value, dst reflect.Value, ctx *objectExportCt
 			}
 		}
 		if et.Kind() == reflect.String {
-			tme, ok := dateParse(v.String())
+			tme, ok := dateParse(v.String(), r.location())
 			if !ok {
 				return fmt.Errorf("could not convert string %v to %v", v, typ)
 			}
@@ -2432,6 +2687,7 @@ func (r *Runtime) Get(name string) Value {
 // SetRandSource sets random source for this Runtime. If not called, the default math/rand is used.
 func (r *Runtime) SetRandSource(source RandSource) {
 	r.rand = source
+	r.seededRand = false
 }
 
 // SetTimeSource sets the current time source for this Runtime.
@@ -2440,6 +2696,47 @@ func (r *Runtime) SetTimeSource(now Now) {
 	r.now = now
 }
 
+// DeterministicOptions configures the deterministic execution mode (see SetDeterministic).
+type DeterministicOptions struct {
+	// Seed for the Math.random() generator.
+	Seed int64
+	// Now is the virtual clock used by Date.now() and new Date(). If nil, the time is fixed at the Unix epoch.
+	Now Now
+}
+
+// SetDeterministic switches the Runtime into a mode where the results of the execution only depend on the code
+// and the inputs, so that a script can be replayed with bit-identical results on a different host:
+//
+//   - Math.random() uses a pseudo-random generator seeded with opts.Seed (see SetRandSource).
+//   - Date.now() and new Date() use opts.Now (see SetTimeSource).
+//   - The local time zone is UTC regardless of time.Local.
+//   - The keys of wrapped Go maps are enumerated in sorted order.
+//
+// Note that the garbage collection is not observable in goja (WeakMap and WeakSet keys cannot be enumerated and
+// there is no WeakRef or FinalizationRegistry), so no further restrictions are necessary. Native functions
+// provided by the host must be deterministic themselves.
+func (r *Runtime) SetDeterministic(opts DeterministicOptions) {
+	r.SetRandSource(rand.New(rand.NewSource(opts.Seed)).Float64)
+	r.seededRand, r.randSeed = true, opts.Seed
+	now := opts.Now
+	if now == nil {
+		epoch := time.Unix(0, 0)
+		now = func() time.Time {
+			return epoch
+		}
+	}
+	r.SetTimeSource(now)
+	r.loc = time.UTC
+	r.deterministic = true
+}
+
+func (r *Runtime) location() *time.Location {
+	if r.loc != nil {
+		return r.loc
+	}
+	return time.Local
+}
+
 // SetParserOptions sets parser options to be used by RunString, RunScript and eval() within the code.
 func (r *Runtime) SetParserOptions(opts ...parser.Option) {
 	r.parserOptions = opts
@@ -2454,6 +2751,92 @@ func (r *Runtime) SetMaxCallStackSize(size int) {
 	r.vm.maxCallStackSize = size
 }
 
+// SetInstructionLimit sets the maximum number of instructions the JavaScript code is allowed to execute and resets
+// the instruction counter. Native functions that perform work proportional to their input (such as sorting an
+// array or repeating a string) are charged accordingly. When the limit is exceeded,
+// an *InstructionLimitExceededError is thrown. It cannot be caught by the script and is returned by RunProgram or by
+// a Callable call. Unlike Interrupt(), the point at which the execution is aborted is deterministic.
+// Once the limit is reached, it has to be set again before running more code.
+// Zero or a negative value disables the limit (this is the default). The instructions are only counted while
+// a limit is set, so the Runtimes without a limit do not pay for it.
+func (r *Runtime) SetInstructionLimit(n int64) {
+	if n <= 0 {
+		n = math.MaxInt64
+	}
+	r.vm.instrLimit = n
+	r.vm.instrCount = 0
+}
+
+// InstructionsExecuted returns the number of instructions executed since the last SetInstructionLimit() call,
+// including the cost charged by native functions. It is only tracked while a limit is set.
+func (r *Runtime) InstructionsExecuted() int64 {
+	return r.vm.instrCount
+}
+
+// SetMemoryLimit sets the approximate number of bytes the JavaScript code is allowed to allocate and resets the
+// allocation counter. Objects, properties, array elements, Map and Set entries, ArrayBuffer data and strings created
+// by the code are accounted, and when
+// the total exceeds the limit a *MemoryLimitExceededError is thrown. Like *InterruptedError, it cannot be caught
+// by the script and is returned by RunProgram or by a Callable call.
+// Note that this is an allocation budget rather than a heap limit: memory released by the garbage collector is
+// not subtracted, so once the budget is exhausted the limit has to be set again before running more code.
+// The accounting is approximate and allocations made by native Go functions are not accounted.
+// Zero or a negative value disables the limit (this is the default).
+func (r *Runtime) SetMemoryLimit(bytes int64) {
+	r.memLimit = bytes
+	r.memUsed = 0
+}
+
+// MemoryUsage returns the approximate number of bytes allocated since the last SetMemoryLimit() call. It is
+// only tracked while a limit is set.
+func (r *Runtime) MemoryUsage() int64 {
+	return r.memUsed
+}
+
+const (
+	memObjectSize     = 128
+	memPropertySize   = 64
+	memValueSize      = 16
+	memSparseItemSize = 24
+	memMapEntrySize   = 64
+)
+
+func (r *Runtime) accountMemory(size int64) {
+	if r.memLimit <= 0 {
+		return
+	}
+	r.memUsed += size
+	if r.memUsed > r.memLimit && len(r.vm.callStack) > 0 {
+		ex := &MemoryLimitExceededError{
+			limit: r.memLimit,
+		}
+		ex.stack = r.vm.captureStack(nil, 0)
+		panic(ex)
+	}
+}
+
+func (r *Runtime) accountString(s String) {
+	if r.memLimit <= 0 {
+		return
+	}
+	if a, ok := s.(asciiString); ok {
+		r.accountMemory(int64(len(a)))
+	} else {
+		r.accountMemory(int64(s.Length()) * 2)
+	}
+}
+
+// accountGrowth accounts a string that is being built incrementally. size is its current size in bytes and
+// accounted is the part of it that has already been accounted.
+func (r *Runtime) accountGrowth(size int, accounted *int) {
+	if r.memLimit <= 0 || size <= *accounted {
+		return
+	}
+	delta := size - *accounted
+	*accounted = size
+	r.accountMemory(int64(delta))
+}
+
 // New is an equivalent of the 'new' operator allowing to call it directly from Go.
 func (r *Runtime) New(construct Value, args ...Value) (o *Object, err error) {
 	err = r.try(func() {
@@ -2485,6 +2868,25 @@ func AssertFunction(v Value) (Callable, bool) {
 	return nil, false
 }
 
+// CallableContext is like Callable, but the call is interrupted when ctx is done, in which case an *InterruptedError
+// wrapping ctx.Err() is returned. See RunProgramContext for details.
+type CallableContext func(ctx gocontext.Context, this Value, args ...Value) (Value, error)
+
+// AssertFunctionContext is like AssertFunction, but it returns a CallableContext.
+func AssertFunctionContext(v Value) (CallableContext, bool) {
+	if f, ok := AssertFunction(v); ok {
+		r := v.(*Object).runtime
+		return func(ctx gocontext.Context, this Value, args ...Value) (ret Value, err error) {
+			err = r.withContext(ctx, func() (err error) {
+				ret, err = f(this, args...)
+				return
+			})
+			return
+		}, true
+	}
+	return nil, false
+}
+
 // Constructor is a type that can be used to call constructors. The first argument (newTarget) can be nil
 // which sets it to the constructor function itself.
 type Constructor func(newTarget *Object, args ...Value) (*Object, error)
@@ -2714,6 +3116,10 @@ func (r *Runtime) getIterator(obj Value, method func(FunctionCall) Value) *itera
 		This: obj,
 	}))
 
+	return r.getIteratorDirect(iter)
+}
+
+func (r *Runtime) getIteratorDirect(iter *Object) *iteratorRecord {
 	var next func(FunctionCall) Value
 
 	if obj, ok := iter.self.getStr("next", nil).(*Object); ok {
@@ -2773,6 +3179,61 @@ func (ir *iteratorRecord) step() (value Value, ex *Exception) {
 	return
 }
 
+// stepResult is an equivalent of IteratorStep(). It returns nil if the iterator is done.
+// Any exception is propagated as a panic.
+func (ir *iteratorRecord) stepResult() *Object {
+	r := ir.iterator.runtime
+	if ir.next == nil {
+		panic(r.NewTypeError("iterator.next is missing or not a function"))
+	}
+	res := r.toObject(ir.next(FunctionCall{This: ir.iterator}))
+	if iteratorComplete(res) {
+		return nil
+	}
+	return res
+}
+
+// stepValue is an equivalent of IteratorStepValue(). It returns false if the iterator is done.
+// Any exception is propagated as a panic.
+func (ir *iteratorRecord) stepValue() (Value, bool) {
+	res := ir.stepResult()
+	if res == nil {
+		return nil, false
+	}
+	return iteratorValue(res), true
+}
+
+// closeThrow closes the iterator as part of an abrupt completion and re-throws ex. Any exception thrown
+// while closing is ignored.
+func (ir *iteratorRecord) closeThrow(ex interface{}) {
+	_ = tryFunc(ir.returnIter)
+	panic(ex)
+}
+
+// closeOnThrow runs f and, if it throws, closes the iterator and re-throws the exception
+// (IfAbruptCloseIterator).
+func (ir *iteratorRecord) closeOnThrow(f func()) {
+	if ex := tryFunc(f); ex != nil {
+		ir.closeThrow(ex)
+	}
+}
+
+// iteratorCloseAll closes the iterators in reverse order (IteratorCloseAll). If ex is not nil, any exceptions
+// thrown while closing are ignored and ex is re-thrown. Otherwise, the first exception thrown while closing
+// is re-thrown once all iterators are closed.
+func iteratorCloseAll(iters []*iteratorRecord, ex interface{}) {
+	for i := len(iters) - 1; i >= 0; i-- {
+		if ex != nil {
+			_ = tryFunc(iters[i].returnIter)
+		} else {
+			ex = tryFunc(iters[i].returnIter)
+		}
+	}
+	if ex != nil {
+		panic(ex)
+	}
+}
+
 func (ir *iteratorRecord) returnIter() {
 	if ir.iterator == nil {
 		return
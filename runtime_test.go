@@ -1,6 +1,7 @@
 package goja
 
 import (
+	gocontext "context"
 	"errors"
 	"fmt"
 	"math"
@@ -458,6 +459,306 @@ func TestInterrupt(t *testing.T) {
 	}
 }
 
+func TestRunContext(t *testing.T) {
+	vm := New()
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := vm.RunStringContext(ctx, "for (;;) {}")
+	var intErr *InterruptedError
+	if !errors.As(err, &intErr) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !errors.Is(err, gocontext.DeadlineExceeded) || intErr.Value() != gocontext.DeadlineExceeded {
+		t.Fatalf("Unexpected error value: %v", intErr.Value())
+	}
+
+	// the runtime is not left in the interrupted state
+	if v, err := vm.RunString("1 + 1"); err != nil || v.ToInteger() != 2 {
+		t.Fatalf("Unexpected result: %v, %v", v, err)
+	}
+
+	// an already cancelled context prevents the program from running
+	_, err = vm.RunStringContext(ctx, "var ran = true")
+	if !errors.Is(err, gocontext.DeadlineExceeded) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if vm.Get("ran") != nil {
+		t.Fatal("The program has run")
+	}
+
+	if v, err := vm.RunStringContext(gocontext.Background(), "2 + 2"); err != nil || v.ToInteger() != 4 {
+		t.Fatalf("Unexpected result: %v, %v", v, err)
+	}
+}
+
+func TestRunContextNativeFunc(t *testing.T) {
+	type ctxKey struct{}
+	vm := New()
+	vm.Set("getValue", func(ctx gocontext.Context, prefix string) string {
+		v, _ := ctx.Value(ctxKey{}).(string)
+		return prefix + v
+	})
+	vm.Set("getValueNative", func(call FunctionCall) Value {
+		v, _ := vm.Context().Value(ctxKey{}).(string)
+		return vm.ToValue(v)
+	})
+
+	ctx := gocontext.WithValue(gocontext.Background(), ctxKey{}, "value")
+	v, err := vm.RunStringContext(ctx, `getValue("the ") + ", " + getValueNative()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := v.String(); s != "the value, value" {
+		t.Fatalf("Unexpected result: %q", s)
+	}
+
+	v, err = vm.RunString(`getValue("no ") + getValueNative()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := v.String(); s != "no " {
+		t.Fatalf("Unexpected result: %q", s)
+	}
+	if vm.Context() != gocontext.Background() {
+		t.Fatal("Unexpected context")
+	}
+}
+
+func TestAssertFunctionContext(t *testing.T) {
+	vm := New()
+	v, err := vm.RunString(`(function(n) { if (n < 0) for (;;) {} return n * 2; })`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, ok := AssertFunctionContext(v)
+	if !ok {
+		t.Fatal("Not a function")
+	}
+	res, err := f(gocontext.Background(), nil, vm.ToValue(21))
+	if err != nil || res.ToInteger() != 42 {
+		t.Fatalf("Unexpected result: %v, %v", res, err)
+	}
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = f(ctx, nil, vm.ToValue(-1))
+	if !errors.Is(err, gocontext.Canceled) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	res, err = f(gocontext.Background(), nil, vm.ToValue(1))
+	if err != nil || res.ToInteger() != 2 {
+		t.Fatalf("Unexpected result: %v, %v", res, err)
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	l := time.Local
+	defer func() {
+		time.Local = l
+	}()
+	time.Local = time.FixedZone("Asia/Delhi", 5*60*60+30*60)
+
+	const SCRIPT = `
+	const d = new Date();
+	[Math.random(), Math.random(), Date.now(), d.getTimezoneOffset(), d.getHours(), String(d), Date(),
+		new Date(2020, 0, 1).getTime(), Date.parse("2020-01-01T00:00:00"), Object.keys(m).join(), Object.keys(rm).join()].join("|");
+	`
+	m := map[string]interface{}{}
+	rm := map[int]string{}
+	for i := 0; i < 20; i++ {
+		m[strconv.Itoa(i)] = i
+		rm[i] = strconv.Itoa(i)
+	}
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	var res [2]string
+	for i := range res {
+		r := New()
+		now := start
+		r.SetDeterministic(DeterministicOptions{
+			Seed: 42,
+			Now: func() time.Time {
+				now = now.Add(time.Millisecond)
+				return now
+			},
+		})
+		r.Set("m", m)
+		r.Set("rm", rm)
+		v, err := r.RunString(SCRIPT)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res[i] = v.String()
+	}
+	if res[0] != res[1] {
+		t.Fatalf("results differ: %q, %q", res[0], res[1])
+	}
+	parts := strings.Split(res[0], "|")
+	if parts[2] != "1709294400002" || parts[3] != "0" || parts[4] != "12" ||
+		parts[7] != "1577836800000" || parts[8] != "1577836800000" {
+		t.Fatalf("unexpected result: %q", res[0])
+	}
+	if parts[9] != "0,1,10,11,12,13,14,15,16,17,18,19,2,3,4,5,6,7,8,9" || parts[10] != parts[9] {
+		t.Fatalf("unexpected key order: %q", res[0])
+	}
+
+	r := New()
+	r.SetDeterministic(DeterministicOptions{})
+	v, err := r.RunString(`Date.now()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ToInteger() != 0 {
+		t.Fatalf("unexpected time: %v", v)
+	}
+}
+
+func TestInstructionLimit(t *testing.T) {
+	const SCRIPT = `
+	let sum = 0;
+	for (let i = 0; i < 1000; i++) {
+		sum += i;
+	}
+	sum;
+	`
+	var counts [2]int64
+	for i := range counts {
+		r := New()
+		r.SetInstructionLimit(1e6)
+		if _, err := r.RunString(SCRIPT); err != nil {
+			t.Fatal(err)
+		}
+		counts[i] = r.InstructionsExecuted()
+	}
+	if counts[0] != counts[1] || counts[0] < 1000 {
+		t.Fatalf("unexpected instruction counts: %v", counts)
+	}
+
+	r := New()
+	r.SetInstructionLimit(counts[0] - 1)
+	_, err := r.RunString(SCRIPT)
+	var ex *InstructionLimitExceededError
+	if !errors.As(err, &ex) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ex.Limit() != counts[0]-1 || r.InstructionsExecuted() != counts[0]-1 {
+		t.Fatalf("unexpected limit: %d, executed: %d", ex.Limit(), r.InstructionsExecuted())
+	}
+	if !strings.HasPrefix(err.Error(), "instruction limit of ") {
+		t.Fatalf("unexpected error message: %v", err)
+	}
+
+	r.SetInstructionLimit(1e4)
+	_, err = r.RunString(`for (;;) { try { for (;;) {} } catch (e) {} }`)
+	if !errors.As(err, &ex) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r.SetInstructionLimit(1e9)
+	if _, err := r.RunString(`const a = new Array(10000).fill(0);`); err != nil {
+		t.Fatal(err)
+	}
+	before := r.InstructionsExecuted()
+	if _, err := r.RunString(`a.sort();`); err != nil {
+		t.Fatal(err)
+	}
+	if cost := r.InstructionsExecuted() - before; cost < 10000 {
+		t.Fatalf("sort is not charged: %d", cost)
+	}
+
+	// each of these executes about 1000 instructions, but performs 10^6 units of native work
+	for _, script := range []string{
+		`"x".repeat(1e6)`,
+		`new Uint8Array(1e6)`,
+		`new ArrayBuffer(1e6)`,
+		`const a = new Uint8Array(1e4); for (let i = 0; i < 100; i++) { a.fill(1); }`,
+		`const a = new Uint8Array(2e4); for (let i = 0; i < 100; i++) { a.copyWithin(0, 1e4); }`,
+		`const a = new Uint8Array(1e4), b = new Uint8Array(1e4); for (let i = 0; i < 100; i++) { a.set(b); }`,
+		`const a = new Uint8Array(1e4); for (let i = 0; i < 100; i++) { a.indexOf(1); }`,
+		`const a = new Uint8Array(1e4); for (let i = 0; i < 100; i++) { a.reverse(); }`,
+		`const s = "x".repeat(1e4); for (let i = 0; i < 100; i++) { s.indexOf("y"); }`,
+		`const s = "x".repeat(1e4); for (let i = 0; i < 100; i++) { s.includes("y"); }`,
+		`const s = "x".repeat(1e4); for (let i = 0; i < 100; i++) { s.replaceAll("y", "z"); }`,
+		`const s = "x".repeat(1e4); for (let i = 0; i < 100; i++) { s.split("y"); }`,
+		`const s = "x".repeat(1e4); for (let i = 0; i < 100; i++) { /y/.test(s); }`,
+		`const s = "x".repeat(1e4); for (let i = 0; i < 100; i++) { s.replace(/y/g, "z"); }`,
+	} {
+		r := New()
+		r.SetInstructionLimit(1e5)
+		_, err = r.RunString(script)
+		if !errors.As(err, &ex) {
+			t.Fatalf("%s: unexpected error: %v", script, err)
+		}
+	}
+
+	r = New()
+	if _, err := r.RunString(SCRIPT); err != nil {
+		t.Fatal(err)
+	}
+	if executed := r.InstructionsExecuted(); executed != 0 {
+		t.Fatalf("instructions are counted without a limit: %d", executed)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	for _, script := range []string{
+		`let s = "x"; for (;;) { s += s; }`,
+		`"x".repeat(1e10)`,
+		`const a = []; for (let i = 0;; i++) { a.push(i); }`,
+		`const a = []; for (let i = 0;; i++) { a[i * 10000] = i; }`,
+		`const l = []; for (;;) { l.push({a: 1, b: 2}); }`,
+		`const o = {}; for (let i = 0;; i++) { o["p" + i] = i; }`,
+		`let s = "x"; for (;;) { try { s = s + s; } catch (e) {} }`,
+		`const m = new Map(); for (let i = 0; i < 300000; i++) { m.set(i, i); }`,
+		`const s = new Set(); for (let i = 0; i < 300000; i++) { s.add(i); }`,
+		`new ArrayBuffer(1 << 28)`,
+		`const b = new ArrayBuffer(0, {maxByteLength: 1 << 28}); b.resize(1 << 28)`,
+		`new Float64Array(1 << 25)`,
+		`"x".repeat(1000).replace(/x/g, "y".repeat(4000))`,
+		`"x".repeat(1000).replace(/x/g, () => "y".repeat(4000))`,
+		`"x".repeat(1000).replaceAll("x", "y".repeat(4000))`,
+		`"x".repeat(1000).replace("x", "y".repeat(1 << 20))`,
+		`"x".padStart(1 << 21)`,
+		`"x".padEnd(1 << 21, "\u00e9")`,
+		`const a = new Array(100000).fill("x".repeat(60)); JSON.stringify(a)`,
+		`new Array(100000).join("x".repeat(60))`,
+		"const s = 'x'.repeat(1000); const a = []; for (;;) { a.push(`${s}${s}`); }",
+		`const s = "x".repeat(1000); const a = []; for (;;) { a.push(s.concat(s)); }`,
+	} {
+		r := New()
+		r.SetMemoryLimit(1 << 20)
+		_, err := r.RunString(script)
+		var ex *MemoryLimitExceededError
+		if !errors.As(err, &ex) {
+			t.Fatalf("%s: unexpected error: %v", script, err)
+		}
+		if ex.Limit() != 1<<20 {
+			t.Fatalf("%s: unexpected limit: %d", script, ex.Limit())
+		}
+		if !strings.HasPrefix(err.Error(), "memory limit of 1048576 bytes exceeded at ") {
+			t.Fatalf("%s: unexpected error message: %v", script, err)
+		}
+	}
+
+	r := New()
+	r.SetMemoryLimit(1 << 20)
+	if _, err := r.RunString(`const o = {a: [1, 2, 3], s: "x".repeat(1000)}`); err != nil {
+		t.Fatal(err)
+	}
+	if used := r.MemoryUsage(); used <= 1000 || used > 1<<20 {
+		t.Fatalf("unexpected memory usage: %d", used)
+	}
+
+	r.SetMemoryLimit(0)
+	if _, err := r.RunString(`"x".repeat(1 << 21).length`); err != nil {
+		t.Fatal(err)
+	}
+	if used := r.MemoryUsage(); used != 0 {
+		t.Fatalf("unexpected memory usage: %d", used)
+	}
+}
+
 func TestRuntime_ExportToNumbers(t *testing.T) {
 	vm := New()
 	t.Run("int8/no overflow", func(t *testing.T) {
@@ -2808,6 +3109,38 @@ assert.sameValue(callCount, 3, '"then"" invoked once for every iterated value');
 	testScriptWithTestLib(SCRIPT, _undefined, t)
 }
 
+func TestPromiseTry(t *testing.T) {
+	testAsyncFuncWithTestLib(`
+	assert.sameValue(await Promise.try((a, b) => a + b, 1, 2), 3);
+	let called = false;
+	const p = Promise.try(() => { called = true; throw new Test262Error(); });
+	assert(called, "callback is called synchronously");
+	try {
+		await p;
+		throw new Error("should have been rejected");
+	} catch (e) {
+		assert(e instanceof Test262Error);
+	}
+	try {
+		await Promise.try(42);
+		throw new Error("should have been rejected");
+	} catch (e) {
+		assert(e instanceof TypeError);
+	}
+	`, _undefined, t)
+}
+
+func TestPromiseWithResolvers(t *testing.T) {
+	testAsyncFuncWithTestLib(`
+	const {promise, resolve, reject} = Promise.withResolvers();
+	assert(promise instanceof Promise);
+	assert.sameValue(typeof reject, "function");
+	resolve(42);
+	assert.sameValue(await promise, 42);
+	assert.throws(TypeError, () => Promise.withResolvers.call({}));
+	`, _undefined, t)
+}
+
 func TestPromiseExport(t *testing.T) {
 	vm := New()
 	p, _, _ := vm.NewPromise()
@@ -2998,14 +3331,83 @@ func TestAsyncStacktrace(t *testing.T) {
 	} catch (e) {
 		assertStack(e, [
 			["test.js", "bar", 9, 10],
-			["test.js", "foo", 4, 13],
-			["test.js", "test", 13, 12],
+			["test.js", "async foo", 4, 13],
+			["test.js", "async test", 13, 12],
 		]);
 	}
 	`
 	testAsyncFuncWithTestLibX(SCRIPT, _undefined, t)
 }
 
+func TestAsyncStacktraceGo(t *testing.T) {
+	const SCRIPT = `
+	async function inner() {
+		await null;
+		throw new Error("inner");
+	}
+	async function middle() {
+		await inner();
+	}
+	async function outer() {
+		const p = middle();
+		p.then(() => {}); // not an await, ignored
+		await p;
+	}
+	let err;
+	outer().catch(e => { err = e; });
+	`
+	r := New()
+	_, err := r.RunString(SCRIPT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := r.Get("err").(*Object)
+	if !ok {
+		t.Fatal("err is not an object")
+	}
+	stack := e.self.(*errorObject).stack
+	if len(stack) != 3 {
+		t.Fatalf("Unexpected stack: %v", stack)
+	}
+	for i, name := range []string{"inner", "middle", "outer"} {
+		if frame := stack[i]; frame.FuncName() != name || frame.IsAsync() != (i > 0) {
+			t.Fatalf("Unexpected frame %d: %#v", i, frame)
+		}
+	}
+
+	_, err = r.RunString(`
+	Error.prepareStackTrace = (e, callSites) => callSites.map(c => c.isAsync());
+	err = undefined;
+	outer().catch(e => { err = e; });
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := r.RunString(`err.stack.join()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := res.String(); s != "false,true,true" {
+		t.Fatalf("Unexpected isAsync() values: %s", s)
+	}
+
+	_, err = r.RunString(`
+	delete Error.prepareStackTrace;
+	err = undefined;
+	outer().catch(e => { err = e; });
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = r.RunString(`err.stack`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := res.String(); !strings.Contains(s, "\n\tat async middle (<eval>:7:14(") || !strings.Contains(s, "\n\tat async outer (<eval>:12:9(") {
+		t.Fatalf("Unexpected stack: %s", s)
+	}
+}
+
 func TestPanicPropagation(t *testing.T) {
 	r := New()
 	r.Set("doPanic", func() {
@@ -3266,3 +3668,225 @@ func BenchmarkNew(b *testing.B) {
 		New()
 	}
 }
+
+func TestErrorCaptureStackTrace(t *testing.T) {
+	// Do not reformat, assertions depend on the line and column numbers
+	const SCRIPT = `
+	function MyError(message) {
+		this.message = message;
+		Error.captureStackTrace(this, MyError);
+	}
+	function f() {
+		return new MyError("test");
+	}
+	const e = f();
+	assert.sameValue(e.stack, "Error: test\n\tat f (test.js:7:10(3))\n\tat test.js:9:13(4)\n", "stack");
+	const desc = Object.getOwnPropertyDescriptor(e, "stack");
+	assert.sameValue(desc.enumerable, false, "enumerable");
+	assert.sameValue(desc.configurable, true, "configurable");
+	e.stack = "overwritten";
+	assert.sameValue(e.stack, "overwritten", "overwritten");
+
+	const o = {};
+	Error.captureStackTrace(o, function notOnStack() {});
+	assert.sameValue(o.stack, "Error\n", "fn not on stack");
+
+	const err = new Error("msg");
+	Error.captureStackTrace(err);
+	assert.sameValue(err.stack, "Error: msg\n\tat test.js:22:25(70)\n", "error object");
+
+	assert.throws(TypeError, () => Error.captureStackTrace(1));
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestErrorStackTraceLimit(t *testing.T) {
+	const SCRIPT = `
+	function rec(n) {
+		return n === 0 ? new Error("deep") : rec(n - 1);
+	}
+	assert.sameValue(Error.stackTraceLimit, 10);
+	assert.sameValue(rec(20).stack.split("\n\tat ").length - 1, 10, "default limit");
+
+	Error.stackTraceLimit = 2;
+	assert.sameValue(rec(20).stack.split("\n\tat ").length - 1, 2, "limit 2");
+
+	Error.stackTraceLimit = 0;
+	assert.sameValue(rec(20).stack, "Error: deep\n", "limit 0");
+
+	Error.stackTraceLimit = undefined;
+	const e = rec(5);
+	assert(!e.hasOwnProperty("stack"), "no stack if the limit is not a number");
+	assert.sameValue(e.stack, undefined);
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestErrorStackTraceLimitGoStack(t *testing.T) {
+	vm := New()
+	_, err := vm.RunString(`
+	Error.stackTraceLimit = 2;
+	function rec(n) {
+		if (n === 0) {
+			throw new Error("deep");
+		}
+		rec(n - 1);
+	}
+	rec(20);
+	`)
+	var ex *Exception
+	if !errors.As(err, &ex) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the limit only applies to the 'stack' property
+	if l := len(ex.Stack()); l != 22 {
+		t.Fatalf("unexpected stack length: %d", l)
+	}
+}
+
+func BenchmarkNewErrorDeepStack(b *testing.B) {
+	vm := New()
+	_, err := vm.RunString(`
+	function rec(n, count) {
+		if (n > 0) {
+			rec(n - 1, count);
+			return;
+		}
+		for (let i = 0; i < count; i++) {
+			new Error("deep");
+		}
+	}
+	`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var rec func(int, int)
+	err = vm.ExportTo(vm.Get("rec"), &rec)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	rec(5000, b.N)
+}
+
+func TestErrorPrepareStackTrace(t *testing.T) {
+	// Do not reformat, assertions depend on the line and column numbers
+	const SCRIPT = `
+	let calls = 0;
+	Error.prepareStackTrace = function(err, callSites) {
+		calls++;
+		assert.sameValue(this, Error, "this");
+		return callSites;
+	};
+	function foo() {
+		return [1].map(function bar() {
+			return new Error("test");
+		})[0];
+	}
+	const e = foo();
+	assert.sameValue(calls, 0, "stack is formatted lazily");
+	const sites = e.stack;
+	assert.sameValue(calls, 1, "calls");
+	e.stack;
+	assert.sameValue(calls, 1, "the result is cached");
+
+	assert.sameValue(sites[0].getFunctionName(), "bar");
+	assert.sameValue(sites[0].getFileName(), "test.js");
+	assert.sameValue(sites[0].getLineNumber(), 10);
+	assert.sameValue(sites[0].getColumnNumber(), 11);
+	assert.sameValue(sites[0].isNative(), false);
+	assert.sameValue(sites[0].isToplevel(), false);
+
+	assert.sameValue(sites[1].getFunctionName(), "map");
+	assert.sameValue(sites[1].isNative(), true);
+	assert.sameValue(sites[1].getFileName(), undefined);
+	assert.sameValue(sites[1].getLineNumber(), null);
+	assert.sameValue(sites[1].toString(), "map (native)");
+
+	assert.sameValue(sites[2].getFunctionName(), "foo");
+	assert.sameValue(sites[3].getFunctionName(), null);
+	assert.sameValue(sites[3].isToplevel(), true);
+	assert.sameValue(sites[3].toString(), "test.js:13:15(8)");
+	assert.sameValue(sites.length, 4);
+
+	assert.throws(TypeError, () => sites[0].getFileName.call({}));
+
+	Error.prepareStackTrace = function(err) {
+		return "nested: " + new Error("inner").stack.split("\n")[0];
+	};
+	assert.sameValue(new Error("outer").stack, "nested: Error: inner");
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestRuntimeReset(t *testing.T) {
+	type S struct {
+		Field int
+	}
+	r := New()
+	r.SetFieldNameMapper(UncapFieldNameMapper())
+	r.Set("s", S{Field: 1})
+	_, err := r.RunString(`
+	var x = 1;
+	let y = 2;
+	Array.prototype.foo = 1;
+	globalThis.z = Symbol.for("key");
+	Promise.resolve().then(() => {});
+	if (s.field !== 1) {
+		throw new Error("field name mapper");
+	}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sym := r.Get("z")
+	r.Interrupt("stop")
+
+	r.Reset()
+	v, err := r.RunString(`
+	typeof x === "undefined" && typeof y === "undefined" && typeof z === "undefined" && !("foo" in []) &&
+	[1, 2].map(x => x * 2).join() === "2,4";
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.ToBoolean() {
+		t.Fatal("unexpected state after Reset")
+	}
+	if _, err := r.RunString(`let y = 3; y`); err != nil {
+		t.Fatalf("redeclaration after Reset: %v", err)
+	}
+	v, err = r.RunString(`Symbol.for("key")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == sym {
+		t.Fatal("the Symbol registry has not been cleared")
+	}
+
+	r.Set("s", S{Field: 2})
+	v, err = r.RunString(`s.field`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ToInteger() != 2 {
+		t.Fatalf("unexpected value: %v", v)
+	}
+}
+
+func TestRuntimeResetDeterministic(t *testing.T) {
+	r := New()
+	r.SetDeterministic(DeterministicOptions{Seed: 42})
+	v1, err := r.RunString(`[Math.random(), Math.random()].join()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Reset()
+	v2, err := r.RunString(`[Math.random(), Math.random()].join()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1.String() != v2.String() {
+		t.Fatalf("the random sequence has not been restarted: %v, %v", v1, v2)
+	}
+}
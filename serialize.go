@@ -0,0 +1,747 @@
+package goja
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+
+	"github.com/dop251/goja/unistring"
+)
+
+// The serialization format is a version byte followed by a single encoded value. Each value starts
+// with a tag byte. Objects are numbered in the order they are first encountered, subsequent occurrences
+// of the same object are encoded as references, which preserves shared references and cycles.
+const serializationVersion = 1
+
+const (
+	serTagUndefined byte = iota + 1
+	serTagNull
+	serTagTrue
+	serTagFalse
+	serTagInt
+	serTagFloat
+	serTagASCIIString
+	serTagUnicodeString
+	serTagBigInt
+	serTagObjectRef
+	serTagObject
+	serTagArray
+	serTagBooleanObject
+	serTagNumberObject
+	serTagBigIntObject
+	serTagStringObject
+	serTagDate
+	serTagRegExp
+	serTagMap
+	serTagSet
+	serTagArrayBuffer
+	serTagTransferredArrayBuffer
+	serTagTypedArray
+	serTagDataView
+	serTagError
+)
+
+var errInvalidSerializedData = errors.New("goja: invalid serialized data")
+
+type serializer struct {
+	r       *Runtime
+	buf     []byte
+	objects map[*Object]uint64
+
+	// ArrayBuffers that are being transferred rather than copied (see structuredClone())
+	transfer []*arrayBufferObject
+}
+
+type deserializer struct {
+	r       *Runtime
+	data    []byte
+	pos     int
+	objects []*Object
+
+	transferred []*arrayBufferObject
+}
+
+type deserializeError struct {
+	err error
+}
+
+// Serialize encodes the value using the structured clone algorithm (the same one that is used by structuredClone())
+// into a binary form that can be decoded with Deserialize, possibly by a different Runtime. Shared references and
+// cycles are preserved. Supported values are primitives (except Symbols), plain objects and arrays, Boolean, Number,
+// BigInt and String wrapper objects, Date, RegExp, Map, Set, ArrayBuffer, typed arrays, DataView and Error objects.
+// Only own enumerable string-keyed properties of plain objects and arrays are serialized, and their prototypes
+// are not preserved.
+//
+// An error is returned if the value (or any value reachable from it) cannot be serialized or if a JavaScript
+// exception is thrown in the process (for example by a getter).
+func (r *Runtime) Serialize(v Value) (data []byte, err error) {
+	err = r.try(func() {
+		data = r.serialize(v, nil)
+	})
+	return
+}
+
+// Deserialize decodes a value previously encoded by Serialize.
+func (r *Runtime) Deserialize(data []byte) (v Value, err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			if de, ok := x.(*deserializeError); ok {
+				err = de.err
+				return
+			}
+			panic(x)
+		}
+	}()
+	err = r.try(func() {
+		v = r.deserialize(data, nil)
+	})
+	return
+}
+
+func (r *Runtime) serialize(v Value, transfer []*arrayBufferObject) []byte {
+	s := &serializer{
+		r:        r,
+		buf:      []byte{serializationVersion},
+		transfer: transfer,
+	}
+	s.writeValue(v)
+	return s.buf
+}
+
+func (r *Runtime) deserialize(data []byte, transferred []*arrayBufferObject) Value {
+	d := &deserializer{
+		r:           r,
+		data:        data,
+		transferred: transferred,
+	}
+	if d.readByte() != serializationVersion {
+		d.fail()
+	}
+	v := d.readValue()
+	if d.pos != len(d.data) {
+		d.fail()
+	}
+	return v
+}
+
+func (s *serializer) cannotClone(v Value) {
+	panic(s.r.NewTypeError("%s could not be cloned", v.String()))
+}
+
+func (s *serializer) writeByte(b byte) {
+	s.buf = append(s.buf, b)
+}
+
+func (s *serializer) writeUint(u uint64) {
+	s.buf = binary.AppendUvarint(s.buf, u)
+}
+
+func (s *serializer) writeInt(i int) {
+	s.writeUint(uint64(i))
+}
+
+func (s *serializer) writeBytes(b []byte) {
+	s.writeInt(len(b))
+	s.buf = append(s.buf, b...)
+}
+
+func (s *serializer) writeString(str String) {
+	var u unicodeString
+	switch str := str.(type) {
+	case asciiString:
+		s.writeByte(serTagASCIIString)
+		s.writeInt(len(str))
+		s.buf = append(s.buf, str...)
+		return
+	case unicodeString:
+		u = str
+	case *importedString:
+		str.ensureScanned()
+		if str.u == nil {
+			s.writeString(asciiString(str.s))
+			return
+		}
+		u = str.u
+	default:
+		s.writeString(newStringValue(str.String()))
+		return
+	}
+	s.writeByte(serTagUnicodeString)
+	s.writeInt(len(u))
+	for _, c := range u {
+		s.buf = binary.LittleEndian.AppendUint16(s.buf, c)
+	}
+}
+
+func (s *serializer) writeFloat(f float64) {
+	s.buf = binary.LittleEndian.AppendUint64(s.buf, math.Float64bits(f))
+}
+
+func (s *serializer) writeBigInt(b *big.Int) {
+	if b.Sign() < 0 {
+		s.writeByte(1)
+	} else {
+		s.writeByte(0)
+	}
+	s.writeBytes(b.Bytes())
+}
+
+func (s *serializer) writeValue(v Value) {
+	switch v := v.(type) {
+	case valueUndefined:
+		s.writeByte(serTagUndefined)
+	case valueNull:
+		s.writeByte(serTagNull)
+	case valueBool:
+		if v {
+			s.writeByte(serTagTrue)
+		} else {
+			s.writeByte(serTagFalse)
+		}
+	case valueInt:
+		s.writeByte(serTagInt)
+		s.buf = binary.AppendVarint(s.buf, int64(v))
+	case valueFloat:
+		s.writeByte(serTagFloat)
+		s.writeFloat(float64(v))
+	case String:
+		s.writeString(v)
+	case *valueBigInt:
+		s.writeByte(serTagBigInt)
+		s.writeBigInt((*big.Int)(v))
+	case *Object:
+		s.writeObject(v)
+	default:
+		s.cannotClone(v)
+	}
+}
+
+func (s *serializer) writeObject(o *Object) {
+	if id, exists := s.objects[o]; exists {
+		s.writeByte(serTagObjectRef)
+		s.writeUint(id)
+		return
+	}
+	if s.objects == nil {
+		s.objects = make(map[*Object]uint64)
+	}
+	s.objects[o] = uint64(len(s.objects))
+
+	switch obj := o.self.(type) {
+	case *primitiveValueObject:
+		switch pValue := obj.pValue.(type) {
+		case valueBool:
+			s.writeByte(serTagBooleanObject)
+			s.writeValue(pValue)
+		case valueInt, valueFloat:
+			s.writeByte(serTagNumberObject)
+			s.writeValue(pValue)
+		case *valueBigInt:
+			s.writeByte(serTagBigIntObject)
+			s.writeBigInt((*big.Int)(pValue))
+		default:
+			s.cannotClone(o)
+		}
+	case *stringObject:
+		s.writeByte(serTagStringObject)
+		s.writeString(obj.value)
+	case *dateObject:
+		s.writeByte(serTagDate)
+		if obj.msec == timeUnset {
+			s.writeFloat(math.NaN())
+		} else {
+			s.writeFloat(float64(obj.msec))
+		}
+	case *regexpObject:
+		s.writeByte(serTagRegExp)
+		s.writeString(obj.source)
+		s.writeString(asciiString(obj.pattern.flags()))
+	case *mapObject:
+		s.writeByte(serTagMap)
+		var entries []Value
+		for item := obj.m.iterFirst; item != nil; item = item.iterNext {
+			if item.key != nil {
+				entries = append(entries, item.key, item.value)
+			}
+		}
+		s.writeInt(len(entries) / 2)
+		for _, v := range entries {
+			s.writeValue(v)
+		}
+	case *setObject:
+		s.writeByte(serTagSet)
+		var keys []Value
+		for item := obj.m.iterFirst; item != nil; item = item.iterNext {
+			if item.key != nil {
+				keys = append(keys, item.key)
+			}
+		}
+		s.writeInt(len(keys))
+		for _, v := range keys {
+			s.writeValue(v)
+		}
+	case *arrayBufferObject:
+		s.writeArrayBuffer(obj)
+	case *typedArrayObject:
+		if obj.isOutOfBounds() {
+			panic(s.r.NewTypeError("Cannot clone a TypedArray that is out of bounds"))
+		}
+		kind := s.r.typedArrayKind(obj.defaultCtor)
+		if kind < 0 {
+			s.cannotClone(o)
+		}
+		s.writeByte(serTagTypedArray)
+		s.writeByte(byte(kind))
+		s.writeObject(obj.viewedArrayBuf.val)
+		s.writeInt(obj.offset)
+		if obj.lengthTracking {
+			s.writeByte(1)
+		} else {
+			s.writeByte(0)
+			s.writeInt(obj.length)
+		}
+	case *dataViewObject:
+		if obj.isOutOfBounds() {
+			panic(s.r.NewTypeError("Cannot clone a DataView that is out of bounds"))
+		}
+		s.writeByte(serTagDataView)
+		s.writeObject(obj.viewedArrayBuf.val)
+		s.writeInt(obj.byteOffset)
+		if obj.lengthTracking {
+			s.writeByte(1)
+		} else {
+			s.writeByte(0)
+			s.writeInt(obj.byteLen)
+		}
+	case *errorObject:
+		s.writeError(obj)
+	case *arrayObject, *sparseArrayObject:
+		s.writeByte(serTagArray)
+		s.writeUint(uint64(toLength(o.self.getStr("length", nil))))
+		s.writeProperties(o)
+	case *baseObject:
+		s.writeByte(serTagObject)
+		s.writeProperties(o)
+	default:
+		s.cannotClone(o)
+	}
+}
+
+func (s *serializer) writeProperties(o *Object) {
+	keys := o.self.stringKeys(false, nil)
+	s.writeInt(len(keys))
+	for _, key := range keys {
+		s.writeString(key.toString())
+		s.writeValue(nilSafe(o.self.getStr(key.string(), nil)))
+	}
+}
+
+func (s *serializer) writeArrayBuffer(buf *arrayBufferObject) {
+	for i, t := range s.transfer {
+		if t == buf {
+			s.writeByte(serTagTransferredArrayBuffer)
+			s.writeInt(i)
+			return
+		}
+	}
+	if buf.detached {
+		panic(s.r.NewTypeError("Cannot clone a detached ArrayBuffer"))
+	}
+	s.writeByte(serTagArrayBuffer)
+	s.writeBytes(buf.data)
+	s.buf = binary.AppendVarint(s.buf, int64(buf.maxByteLen))
+	if buf.immutable {
+		s.writeByte(1)
+	} else {
+		s.writeByte(0)
+	}
+}
+
+func (s *serializer) writeError(e *errorObject) {
+	s.writeByte(serTagError)
+	name := "Error"
+	if v := e.getStr("name", nil); v != nil {
+		switch n := v.String(); n {
+		case "EvalError", "RangeError", "ReferenceError", "SyntaxError", "TypeError", "URIError":
+			name = n
+		}
+	}
+	s.writeString(asciiString(name))
+	writeOwnDataProp := func(name unistring.String, toString bool) {
+		switch v := e.getOwnPropStr(name).(type) {
+		case nil:
+			s.writeByte(0)
+		case *valueProperty:
+			if v.accessor {
+				s.writeByte(0)
+				return
+			}
+			s.writeByte(1)
+			if toString {
+				s.writeString(v.value.toString())
+			} else {
+				s.writeValue(v.value)
+			}
+		default:
+			s.writeByte(1)
+			if toString {
+				s.writeString(v.toString())
+			} else {
+				s.writeValue(v)
+			}
+		}
+	}
+	writeOwnDataProp("message", true)
+	writeOwnDataProp(propNameStack, true)
+	if e.hasOwnPropertyStr("cause") {
+		writeOwnDataProp("cause", false)
+	} else {
+		s.writeByte(0)
+	}
+}
+
+// typedArrayKind returns the index of the typed array constructor in the list returned by typedArrayCtors(),
+// or -1 if not found.
+func (r *Runtime) typedArrayKind(ctor *Object) int {
+	for i, c := range r.typedArrayCtors() {
+		if c == ctor {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Runtime) typedArrayCtors() []*Object {
+	return []*Object{
+		r.getInt8Array(),
+		r.getUint8Array(),
+		r.getUint8ClampedArray(),
+		r.getInt16Array(),
+		r.getUint16Array(),
+		r.getInt32Array(),
+		r.getUint32Array(),
+		r.getFloat16Array(),
+		r.getFloat32Array(),
+		r.getFloat64Array(),
+		r.getBigInt64Array(),
+		r.getBigUint64Array(),
+	}
+}
+
+func (d *deserializer) fail() {
+	panic(&deserializeError{err: errInvalidSerializedData})
+}
+
+func (d *deserializer) readByte() byte {
+	if d.pos >= len(d.data) {
+		d.fail()
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b
+}
+
+func (d *deserializer) readUint() uint64 {
+	u, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		d.fail()
+	}
+	d.pos += n
+	return u
+}
+
+func (d *deserializer) readVarint() int64 {
+	i, n := binary.Varint(d.data[d.pos:])
+	if n <= 0 {
+		d.fail()
+	}
+	d.pos += n
+	return i
+}
+
+// readLen reads a length of a sequence of items of the given size and makes sure there is enough data left.
+func (d *deserializer) readLen(itemSize int) int {
+	l := d.readUint()
+	if l > uint64(len(d.data)-d.pos)/uint64(itemSize) {
+		d.fail()
+	}
+	return int(l)
+}
+
+func (d *deserializer) readBytes() []byte {
+	l := d.readLen(1)
+	b := d.data[d.pos : d.pos+l]
+	d.pos += l
+	return b
+}
+
+func (d *deserializer) readFloat() float64 {
+	if len(d.data)-d.pos < 8 {
+		d.fail()
+	}
+	f := math.Float64frombits(binary.LittleEndian.Uint64(d.data[d.pos:]))
+	d.pos += 8
+	return f
+}
+
+func (d *deserializer) readBigInt() *big.Int {
+	neg := d.readByte()
+	b := new(big.Int).SetBytes(d.readBytes())
+	if neg != 0 {
+		b.Neg(b)
+	}
+	return b
+}
+
+func (d *deserializer) readString() String {
+	switch d.readByte() {
+	case serTagASCIIString:
+		return asciiString(d.readBytes())
+	case serTagUnicodeString:
+		return d.readUnicodeString()
+	}
+	d.fail()
+	return nil
+}
+
+func (d *deserializer) readUnicodeString() String {
+	l := d.readLen(2)
+	if l == 0 {
+		d.fail()
+	}
+	u := make(unicodeString, l)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(d.data[d.pos:])
+		d.pos += 2
+	}
+	if u[0] != unistring.BOM {
+		d.fail()
+	}
+	return u
+}
+
+func (d *deserializer) readFlag() bool {
+	switch d.readByte() {
+	case 0:
+		return false
+	case 1:
+		return true
+	}
+	d.fail()
+	return false
+}
+
+// addObject registers a new object so that it can be referenced by subsequent values. It must be called
+// before reading any nested values.
+func (d *deserializer) addObject(o *Object) *Object {
+	d.objects = append(d.objects, o)
+	return o
+}
+
+// reserveObject is like addObject but for objects that can only be created after reading their nested values.
+func (d *deserializer) reserveObject() int {
+	d.objects = append(d.objects, nil)
+	return len(d.objects) - 1
+}
+
+func (d *deserializer) readObject() *Object {
+	o, ok := d.readValue().(*Object)
+	if !ok {
+		d.fail()
+	}
+	return o
+}
+
+func (d *deserializer) readArrayBuffer() *arrayBufferObject {
+	if buf, ok := d.readObject().self.(*arrayBufferObject); ok {
+		return buf
+	}
+	d.fail()
+	return nil
+}
+
+func (d *deserializer) readValue() Value {
+	r := d.r
+	switch tag := d.readByte(); tag {
+	case serTagUndefined:
+		return _undefined
+	case serTagNull:
+		return _null
+	case serTagTrue:
+		return valueTrue
+	case serTagFalse:
+		return valueFalse
+	case serTagInt:
+		return intToValue(d.readVarint())
+	case serTagFloat:
+		return floatToValue(d.readFloat())
+	case serTagASCIIString:
+		return asciiString(d.readBytes())
+	case serTagUnicodeString:
+		return d.readUnicodeString()
+	case serTagBigInt:
+		return (*valueBigInt)(d.readBigInt())
+	case serTagObjectRef:
+		id := d.readUint()
+		if id >= uint64(len(d.objects)) || d.objects[id] == nil {
+			d.fail()
+		}
+		return d.objects[id]
+	case serTagObject:
+		o := d.addObject(r.NewObject())
+		d.readProperties(o)
+		return o
+	case serTagArray:
+		l := d.readUint()
+		if l > math.MaxUint32 {
+			d.fail()
+		}
+		o := d.addObject(r.newArrayLength(int64(l)))
+		d.readProperties(o)
+		return o
+	case serTagBooleanObject, serTagNumberObject:
+		id := d.reserveObject()
+		v := d.readValue()
+		switch v.(type) {
+		case valueBool:
+			if tag != serTagBooleanObject {
+				d.fail()
+			}
+		case valueInt, valueFloat:
+			if tag != serTagNumberObject {
+				d.fail()
+			}
+		default:
+			d.fail()
+		}
+		d.objects[id] = v.ToObject(r)
+		return d.objects[id]
+	case serTagBigIntObject:
+		return d.addObject((*valueBigInt)(d.readBigInt()).ToObject(r))
+	case serTagStringObject:
+		return d.addObject(d.readString().ToObject(r))
+	case serTagDate:
+		f := d.readFloat()
+		o := r.newDateObject(timeFromMsec(int64(f)), !math.IsNaN(f), r.getDatePrototype())
+		return d.addObject(o)
+	case serTagRegExp:
+		id := d.reserveObject()
+		source := d.readString()
+		flags := d.readString()
+		d.objects[id] = r._newRegExp(source, flags.String(), r.getRegExpPrototype()).val
+		return d.objects[id]
+	case serTagMap:
+		o := &Object{runtime: r}
+		mo := &mapObject{}
+		mo.class = classObject
+		mo.val = o
+		mo.extensible = true
+		o.self = mo
+		mo.prototype = r.getMapPrototype()
+		mo.init()
+		d.addObject(o)
+		for n := d.readLen(2); n > 0; n-- {
+			k := d.readValue()
+			v := d.readValue()
+			mo.m.set(k, v)
+		}
+		return o
+	case serTagSet:
+		m := r.newOrderedMap()
+		o := d.addObject(r.newSetFromMap(m))
+		for n := d.readLen(1); n > 0; n-- {
+			m.set(d.readValue(), nil)
+		}
+		return o
+	case serTagArrayBuffer:
+		buf := r._newArrayBuffer(r.getArrayBufferPrototype(), nil)
+		buf.data = append([]byte(nil), d.readBytes()...)
+		maxByteLen := d.readVarint()
+		if maxByteLen < -1 || maxByteLen >= 0 && maxByteLen < int64(len(buf.data)) || maxByteLen > maxInt {
+			d.fail()
+		}
+		buf.maxByteLen = int(maxByteLen)
+		buf.immutable = d.readFlag()
+		return d.addObject(buf.val)
+	case serTagTransferredArrayBuffer:
+		idx := d.readUint()
+		if idx >= uint64(len(d.transferred)) {
+			d.fail()
+		}
+		return d.addObject(d.transferred[idx].val)
+	case serTagTypedArray:
+		id := d.reserveObject()
+		ctors := r.typedArrayCtors()
+		kind := int(d.readByte())
+		if kind >= len(ctors) {
+			d.fail()
+		}
+		buf := d.readArrayBuffer()
+		args := []Value{buf.val, intToValue(int64(d.readUint()))}
+		if !d.readFlag() {
+			args = append(args, intToValue(int64(d.readUint())))
+		}
+		d.objects[id] = r.toConstructor(ctors[kind])(args, ctors[kind])
+		return d.objects[id]
+	case serTagDataView:
+		id := d.reserveObject()
+		buf := d.readArrayBuffer()
+		args := []Value{buf.val, intToValue(int64(d.readUint()))}
+		if !d.readFlag() {
+			args = append(args, intToValue(int64(d.readUint())))
+		}
+		ctor := r.getDataView()
+		d.objects[id] = r.toConstructor(ctor)(args, ctor)
+		return d.objects[id]
+	case serTagError:
+		return d.readError()
+	}
+	d.fail()
+	return nil
+}
+
+func (d *deserializer) readProperties(o *Object) {
+	for n := d.readLen(2); n > 0; n-- {
+		key := d.readString()
+		createDataPropertyOrThrow(o, key, d.readValue())
+	}
+}
+
+func (d *deserializer) readError() Value {
+	r := d.r
+	var ctor *Object
+	switch d.readString().String() {
+	case "Error":
+		ctor = r.getError()
+	case "EvalError":
+		ctor = r.getEvalError()
+	case "RangeError":
+		ctor = r.getRangeError()
+	case "ReferenceError":
+		ctor = r.getReferenceError()
+	case "SyntaxError":
+		ctor = r.getSyntaxError()
+	case "TypeError":
+		ctor = r.getTypeError()
+	case "URIError":
+		ctor = r.getURIError()
+	default:
+		d.fail()
+	}
+	e := r.newErrorObject(r.toObject(ctor.self.getStr("prototype", nil)), classError)
+	d.addObject(e.val)
+	if d.readFlag() {
+		e._putProp("message", d.readString(), true, false, true)
+	}
+	// the stack is not captured at the point of deserialization, it is either copied or absent
+	e.stack = nil
+	e.stackPropAdded = true
+	if d.readFlag() {
+		e._putProp(propNameStack, d.readString(), true, false, true)
+	}
+	if d.readFlag() {
+		e._putProp("cause", d.readValue(), true, false, true)
+	}
+	return e.val
+}
@@ -0,0 +1,163 @@
+package goja
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStructuredClone(t *testing.T) {
+	const SCRIPT = `
+	const o = {a: 1, b: "str", c: "юникод", d: [1, , 3], e: 12345678901234567890n, f: -0, g: NaN, u: undefined, n: null};
+	o.self = o;
+	o.d.extra = "x";
+	const c = structuredClone(o);
+	assert(c !== o, "copy");
+	assert.sameValue(c.self, c, "cycle");
+	assert.sameValue(c.a, 1);
+	assert.sameValue(c.b, "str");
+	assert.sameValue(c.c, "юникод");
+	assert(Array.isArray(c.d), "array");
+	assert.sameValue(c.d.length, 3);
+	assert(!(1 in c.d), "hole");
+	assert.sameValue(c.d.extra, "x");
+	assert.sameValue(c.e, 12345678901234567890n);
+	assert.sameValue(c.f, -0);
+	assert.sameValue(c.g, NaN);
+	assert("u" in c, "undefined property");
+	assert.sameValue(c.n, null);
+
+	const shared = {};
+	const arr = structuredClone([shared, shared]);
+	assert.sameValue(arr[0], arr[1], "shared references");
+
+	const m = new Map([[1, {x: 1}], ["k", new Set([1, 2])]]);
+	const mc = structuredClone(m);
+	assert(mc instanceof Map, "Map");
+	assert.sameValue(mc.get(1).x, 1);
+	assert(mc.get("k") instanceof Set, "Set");
+	assert(mc.get("k").has(2));
+
+	const d = structuredClone(new Date(1000));
+	assert(d instanceof Date, "Date");
+	assert.sameValue(d.getTime(), 1000);
+	assert.sameValue(structuredClone(new Date(NaN)).getTime(), NaN, "invalid Date");
+
+	const re = structuredClone(/a+b/gi);
+	assert(re instanceof RegExp, "RegExp");
+	assert.sameValue(re.source, "a+b");
+	assert.sameValue(re.flags, "gi");
+	assert.sameValue(re.lastIndex, 0);
+
+	assert.sameValue(typeof structuredClone(Object(1)), "object");
+	assert.sameValue(structuredClone(Object(1)).valueOf(), 1);
+	assert.sameValue(structuredClone(Object("s")).valueOf(), "s");
+	assert.sameValue(structuredClone(Object(true)).valueOf(), true);
+	assert.sameValue(structuredClone(Object(1n)).valueOf(), 1n);
+
+	const e = new TypeError("msg", {cause: 42});
+	const ec = structuredClone(e);
+	assert(ec instanceof TypeError, "TypeError");
+	assert.sameValue(ec.message, "msg");
+	assert.sameValue(ec.cause, 42);
+	assert.sameValue(ec.stack, e.stack);
+
+	class MyError extends Error {}
+	assert.sameValue(Object.getPrototypeOf(structuredClone(new MyError())), Error.prototype);
+
+	assert.throws(TypeError, () => structuredClone(function() {}));
+	assert.throws(TypeError, () => structuredClone(Symbol()));
+	assert.throws(TypeError, () => structuredClone(new WeakMap()));
+	assert.throws(TypeError, () => structuredClone({p: new Proxy({}, {})}));
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestStructuredCloneArrayBuffers(t *testing.T) {
+	const SCRIPT = `
+	const buf = new ArrayBuffer(8, {maxByteLength: 16});
+	const u8 = new Uint8Array(buf, 2);
+	const f64 = new Float64Array(buf, 0, 1);
+	const dv = new DataView(buf, 1, 2);
+	u8[0] = 42;
+	const c = structuredClone({buf, u8, f64, dv});
+	assert(c.buf !== buf, "copy");
+	assert.sameValue(c.u8.buffer, c.buf, "shared buffer");
+	assert.sameValue(c.f64.buffer, c.buf, "shared buffer");
+	assert.sameValue(c.dv.buffer, c.buf, "shared buffer");
+	assert(c.buf.resizable, "resizable");
+	assert.sameValue(c.buf.maxByteLength, 16);
+	assert(c.u8 instanceof Uint8Array, "Uint8Array");
+	assert(c.f64 instanceof Float64Array, "Float64Array");
+	assert(c.dv instanceof DataView, "DataView");
+	assert.sameValue(c.u8[0], 42);
+	assert.sameValue(c.dv.byteOffset, 1);
+	assert.sameValue(c.dv.byteLength, 2);
+	c.buf.resize(12);
+	assert.sameValue(c.u8.length, 10, "length tracking");
+	assert.sameValue(c.f64.length, 1, "fixed length");
+	assert.sameValue(buf.byteLength, 8, "original is not affected");
+
+	const t = new ArrayBuffer(4);
+	new Uint8Array(t)[3] = 7;
+	const tc = structuredClone({t, view: new Uint8Array(t)}, {transfer: [t]});
+	assert(t.detached, "transferred buffer is detached");
+	assert.sameValue(tc.t.byteLength, 4);
+	assert.sameValue(tc.view[3], 7);
+	assert.sameValue(tc.view.buffer, tc.t);
+
+	const unused = new ArrayBuffer(1);
+	structuredClone(1, {transfer: [unused]});
+	assert(unused.detached, "transferred buffer that is not referenced");
+
+	const dup = new ArrayBuffer(1);
+	assert.throws(TypeError, () => structuredClone(1, {transfer: [dup, dup]}));
+	assert.throws(TypeError, () => structuredClone(1, {transfer: [{}]}));
+	assert.throws(TypeError, () => structuredClone(t));
+	assert(!dup.detached);
+	`
+	testScriptWithTestLib(SCRIPT, _undefined, t)
+}
+
+func TestSerializeDeserialize(t *testing.T) {
+	r1 := New()
+	v, err := r1.RunString(`
+	const o = {date: new Date(0), list: [1, "two", 3n], map: new Map([["k", new Uint16Array([1, 2])]])};
+	o.list.push(o);
+	o;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := r1.Serialize(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r2 := New()
+	v2, err := r2.Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2.Set("o", v2)
+	res, err := r2.RunString(`
+	o.date instanceof Date && o.date.getTime() === 0 &&
+	o.list[0] === 1 && o.list[1] === "two" && o.list[2] === 3n && o.list[3] === o &&
+	o.map.get("k") instanceof Uint16Array && o.map.get("k")[1] === 2;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.ToBoolean() {
+		t.Fatal("unexpected result")
+	}
+
+	if _, err := r1.Serialize(r1.ToValue(func() {})); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, data := range [][]byte{nil, {0}, data[:len(data)-1], append(data, 0), {serializationVersion, serTagObjectRef, 0}} {
+		if _, err := r2.Deserialize(data); !errors.Is(err, errInvalidSerializedData) {
+			t.Fatalf("%v: unexpected error: %v", data, err)
+		}
+	}
+}
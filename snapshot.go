@@ -0,0 +1,631 @@
+package goja
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/dop251/goja/unistring"
+)
+
+// Snapshot is a copy of the heap of a Runtime (the global object, global variables, the built-in objects
+// and everything reachable from them) taken after it has been bootstrapped. Creating new Runtimes from a
+// Snapshot is typically much faster than re-running the bootstrap code in each of them: only the objects
+// the code has created or modified are copied, the built-in objects it has not modified are created lazily
+// as in a new Runtime, and none of the computation is repeated. Copying an object costs about as much as
+// creating it, so the gain depends on how much work the code does besides creating the objects. For example,
+// the bootstrap code of BenchmarkSnapshot (a few dozen functions and classes, some lookup tables computed
+// at start-up) takes about 7.5ms to run and 0.36ms to restore from a Snapshot.
+//
+// A Snapshot is immutable and it is safe to call NewRuntime() concurrently from multiple goroutines.
+//
+// Only the ECMAScript state is captured, Runtime settings (such as the field name mapper, the time and
+// random sources, the limits and the trackers) are not and need to be set on every new Runtime if required.
+type Snapshot struct {
+	r    *Runtime
+	plan *snapshotPlan
+}
+
+type snapshotError struct {
+	err error
+}
+
+var errSnapshotBusy = errors.New("goja: cannot snapshot a Runtime while it is running or has pending jobs")
+
+// Snapshot captures the current state of the Runtime so that independent copies of it can be created with
+// Snapshot.NewRuntime(). It must be called when the Runtime is idle, i.e. not from within a running script
+// and with the job queue drained.
+//
+// The heap must not contain anything that cannot be copied between Runtimes: Go functions and values
+// exposed with ToValue() or Set(), native Proxy handlers, functions created internally by the built-ins
+// (such as the revoke function returned by Proxy.revocable()), generator objects, suspended async functions
+// and Promise reactions that have not run yet. An error is returned if any of these are encountered.
+// Built-in functions (including the ones that have been assigned to other objects or variables) and bound
+// functions are fine.
+func (r *Runtime) Snapshot() (*Snapshot, error) {
+	if len(r.vm.callStack) > 0 || len(r.jobQueue) > 0 {
+		return nil, errSnapshotBusy
+	}
+	base := New()
+	if err := cloneRuntime(r, base); err != nil {
+		return nil, err
+	}
+	plan, err := makeSnapshotPlan(base)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{r: base, plan: plan}, nil
+}
+
+// NewRuntime creates a new Runtime with a copy of the heap captured by the Snapshot. The Runtimes created
+// this way are independent of each other and of the Runtime the Snapshot was taken from.
+func (s *Snapshot) NewRuntime() *Runtime {
+	r := New()
+	s.restore(r)
+	return r
+}
+
+// restore copies the heap into r, which must be either new or Reset.
+func (s *Snapshot) restore(r *Runtime) {
+	s.plan.restore(r)
+}
+
+// snapshotIntrinsics maps the fields of the global struct to the methods that create them. The fields
+// that are set in the source Runtime are created in the destination one and the objects are paired up,
+// so that Go code referring to them (such as the built-in functions) keeps working in the copy.
+// A nil function means the object always exists. Every *Object field of global must be listed here
+// (see TestSnapshotIntrinsics), the other fields are handled by cloneRuntime explicitly.
+var snapshotIntrinsics = map[string]func(*Runtime) *Object{
+	"Object":                        (*Runtime).getObject,
+	"Array":                         (*Runtime).getArray,
+	"Function":                      (*Runtime).getFunction,
+	"String":                        (*Runtime).getString,
+	"Number":                        (*Runtime).getNumber,
+	"BigInt":                        (*Runtime).getBigInt,
+	"Boolean":                       (*Runtime).getBoolean,
+	"RegExp":                        (*Runtime).getRegExp,
+	"Date":                          (*Runtime).getDate,
+	"Symbol":                        (*Runtime).getSymbol,
+	"Proxy":                         (*Runtime).getProxy,
+	"Reflect":                       (*Runtime).getReflect,
+	"Promise":                       (*Runtime).getPromise,
+	"Math":                          (*Runtime).getMath,
+	"JSON":                          (*Runtime).getJSON,
+	"Iterator":                      (*Runtime).getIteratorConstructor,
+	"AsyncFunction":                 (*Runtime).getAsyncFunction,
+	"ArrayBuffer":                   (*Runtime).getArrayBuffer,
+	"DataView":                      (*Runtime).getDataView,
+	"TypedArray":                    (*Runtime).getTypedArray,
+	"Uint8Array":                    (*Runtime).getUint8Array,
+	"Uint8ClampedArray":             (*Runtime).getUint8ClampedArray,
+	"Int8Array":                     (*Runtime).getInt8Array,
+	"Uint16Array":                   (*Runtime).getUint16Array,
+	"Int16Array":                    (*Runtime).getInt16Array,
+	"Uint32Array":                   (*Runtime).getUint32Array,
+	"Int32Array":                    (*Runtime).getInt32Array,
+	"Float16Array":                  (*Runtime).getFloat16Array,
+	"Float32Array":                  (*Runtime).getFloat32Array,
+	"Float64Array":                  (*Runtime).getFloat64Array,
+	"BigInt64Array":                 (*Runtime).getBigInt64Array,
+	"BigUint64Array":                (*Runtime).getBigUint64Array,
+	"WeakSet":                       (*Runtime).getWeakSet,
+	"WeakMap":                       (*Runtime).getWeakMap,
+	"Map":                           (*Runtime).getMap,
+	"Set":                           (*Runtime).getSet,
+	"Error":                         (*Runtime).getError,
+	"AggregateError":                (*Runtime).getAggregateError,
+	"TypeError":                     (*Runtime).getTypeError,
+	"ReferenceError":                (*Runtime).getReferenceError,
+	"SyntaxError":                   (*Runtime).getSyntaxError,
+	"RangeError":                    (*Runtime).getRangeError,
+	"EvalError":                     (*Runtime).getEvalError,
+	"URIError":                      (*Runtime).getURIError,
+	"GoError":                       (*Runtime).getGoError,
+	"ObjectPrototype":               nil, // created by init(),
+	"ArrayPrototype":                (*Runtime).getArrayPrototype,
+	"NumberPrototype":               (*Runtime).getNumberPrototype,
+	"BigIntPrototype":               (*Runtime).getBigIntPrototype,
+	"StringPrototype":               (*Runtime).getStringPrototype,
+	"BooleanPrototype":              (*Runtime).getBooleanPrototype,
+	"FunctionPrototype":             (*Runtime).getFunctionPrototype,
+	"RegExpPrototype":               (*Runtime).getRegExpPrototype,
+	"DatePrototype":                 (*Runtime).getDatePrototype,
+	"SymbolPrototype":               (*Runtime).getSymbolPrototype,
+	"ArrayBufferPrototype":          (*Runtime).getArrayBufferPrototype,
+	"DataViewPrototype":             (*Runtime).getDataViewPrototype,
+	"TypedArrayPrototype":           (*Runtime).getTypedArrayPrototype,
+	"WeakSetPrototype":              (*Runtime).getWeakSetPrototype,
+	"WeakMapPrototype":              (*Runtime).getWeakMapPrototype,
+	"MapPrototype":                  (*Runtime).getMapPrototype,
+	"SetPrototype":                  (*Runtime).getSetPrototype,
+	"PromisePrototype":              (*Runtime).getPromisePrototype,
+	"GeneratorFunctionPrototype":    (*Runtime).getGeneratorFunctionPrototype,
+	"GeneratorFunction":             (*Runtime).getGeneratorFunction,
+	"GeneratorPrototype":            (*Runtime).getGeneratorPrototype,
+	"AsyncFunctionPrototype":        (*Runtime).getAsyncFunctionPrototype,
+	"IteratorPrototype":             (*Runtime).getIteratorPrototype,
+	"ArrayIteratorPrototype":        (*Runtime).getArrayIteratorPrototype,
+	"MapIteratorPrototype":          (*Runtime).getMapIteratorPrototype,
+	"SetIteratorPrototype":          (*Runtime).getSetIteratorPrototype,
+	"StringIteratorPrototype":       (*Runtime).getStringIteratorPrototype,
+	"RegExpStringIteratorPrototype": (*Runtime).getRegExpStringIteratorPrototype,
+	"IteratorHelperPrototype":       (*Runtime).getIteratorHelperPrototype,
+	"WrapForValidIteratorPrototype": (*Runtime).getWrapForValidIteratorPrototype,
+	"ErrorPrototype":                (*Runtime).getErrorPrototype,
+	"CallSitePrototype":             (*Runtime).getCallSitePrototype,
+	"Eval":                          (*Runtime).getEval,
+	"thrower":                       (*Runtime).getThrower,
+	"weakSetAdder":                  (*Runtime).getWeakSetPrototype,
+	"weakMapAdder":                  (*Runtime).getWeakMapPrototype,
+	"mapAdder":                      (*Runtime).getMapPrototype,
+	"setAdder":                      (*Runtime).getSetPrototype,
+	"arrayValues":                   (*Runtime).getArrayValues,
+	"arrayToString":                 (*Runtime).getArrayToString,
+	"stringproto_trimEnd":           (*Runtime).getStringproto_trimEnd,
+	"stringproto_trimStart":         (*Runtime).getStringproto_trimStart,
+	"parseFloat":                    (*Runtime).getParseFloat,
+	"parseInt":                      (*Runtime).getParseInt,
+	"typedArrayValues":              (*Runtime).getTypedArrayValues,
+}
+
+var (
+	gojaPkgPath = reflect.TypeOf(Runtime{}).PkgPath()
+
+	typeRuntimePtr        = reflect.TypeOf((*Runtime)(nil))
+	typeVmPtr             = reflect.TypeOf((*vm)(nil))
+	typeSymbolPtr         = reflect.TypeOf((*Symbol)(nil))
+	typeObjectTemplatePtr = reflect.TypeOf((*objectTemplate)(nil))
+	typeBigIntPtr         = reflect.TypeOf((*valueBigInt)(nil))
+	typeUnicodeString     = reflect.TypeOf(unicodeString(nil))
+	typeBoundFuncPtr      = reflect.TypeOf((*boundFuncObject)(nil))
+	typeProxyPtr          = reflect.TypeOf((*proxyObject)(nil))
+	typeBaseObject        = reflect.TypeOf(baseObject{})
+)
+
+// snapshotHostTypes are the object implementations that wrap Go values.
+var snapshotHostTypes = map[reflect.Type]struct{}{
+	reflect.TypeOf((*objectGoReflect)(nil)):      {},
+	reflect.TypeOf((*objectGoMapSimple)(nil)):    {},
+	reflect.TypeOf((*objectGoMapReflect)(nil)):   {},
+	reflect.TypeOf((*objectGoSlice)(nil)):        {},
+	reflect.TypeOf((*objectGoSliceReflect)(nil)): {},
+	reflect.TypeOf((*objectGoArrayReflect)(nil)): {},
+	reflect.TypeOf((*dynamicObject)(nil)):        {},
+	reflect.TypeOf((*dynamicArray)(nil)):         {},
+}
+
+type snapshotAnchor struct {
+	src, dst *Object
+	path     snapshotPath
+}
+
+// snapshotPath describes how an anchored object is found: it is either the global object (empty name),
+// an intrinsic (name is the field of global) or a property of the anchor at index parent.
+type snapshotPath struct {
+	parent   int
+	name     unistring.String
+	sym      *Symbol
+	accessor int // 0 for the value, 1 for the getter and 2 for the setter of an accessor property
+}
+
+// snapshotCloner copies the heap of one Runtime into another. The built-in objects are paired up with their
+// counterparts in the destination Runtime (anchored) and their state is copied in place, everything else is
+// deep-copied using reflection. The addresses of all copied values (including struct fields and slice
+// elements) are recorded, so that shared and interior pointers (such as the ones used by typed arrays and
+// function length properties) are preserved. It is only used when a Snapshot is taken, to make its private copy
+// of the heap (snapshotPlan is used to restore it) and to pair up the built-ins for the plan.
+type snapshotCloner struct {
+	src, dst *Runtime
+
+	ptrs        map[unsafe.Pointer]unsafe.Pointer
+	anchors     []snapshotAnchor
+	dstAnchored map[*Object]struct{}
+
+	boundFuncs []*boundFuncObject
+	proxies    []*proxyObject
+}
+
+func cloneRuntime(src, dst *Runtime) (err error) {
+	c := &snapshotCloner{
+		src:         src,
+		dst:         dst,
+		ptrs:        make(map[unsafe.Pointer]unsafe.Pointer),
+		dstAnchored: make(map[*Object]struct{}),
+	}
+	defer func() {
+		if x := recover(); x != nil {
+			if e, ok := x.(*snapshotError); ok {
+				err = e.err
+				return
+			}
+			panic(x)
+		}
+	}()
+
+	if src.hash != nil {
+		// the hash tables of Maps and Sets are only valid with the same seed
+		dst.getHash().SetSeed(src.hash.Seed())
+		c.ptrs[unsafe.Pointer(src.hash)] = unsafe.Pointer(dst.hash)
+	}
+
+	c.anchorAll()
+
+	c.register(unsafe.Pointer(&src.global.stash), unsafe.Pointer(&dst.global.stash), reflect.TypeOf(stash{}))
+	for _, a := range c.anchors {
+		if reflect.TypeOf(a.src.self) == reflect.TypeOf(a.dst.self) {
+			c.register(unsafe.Pointer(reflect.ValueOf(a.src.self).Pointer()), unsafe.Pointer(reflect.ValueOf(a.dst.self).Pointer()), reflect.TypeOf(a.src.self).Elem())
+		}
+	}
+
+	for _, a := range c.anchors {
+		c.copyAnchored(a.src, a.dst)
+	}
+	c.cloneStruct(reflect.ValueOf(&dst.global.stash).Elem(), reflect.ValueOf(&src.global.stash).Elem(), false)
+	c.cloneInto(reflect.ValueOf(&dst.symbolRegistry).Elem(), reflect.ValueOf(&src.symbolRegistry).Elem())
+
+	for _, f := range c.boundFuncs {
+		f.f = dst.boundCallable(dst.toCallable(f.wrapped), f.boundArgs)
+		f.construct = dst.boundConstruct(f.val, f.wrapped.self.assertConstructor(), f.boundArgs)
+	}
+	for _, p := range c.proxies {
+		p.call, _ = p.target.self.assertCallable()
+		p.ctor = p.target.self.assertConstructor()
+	}
+
+	dst.idSeq = src.idSeq
+	return nil
+}
+
+func (c *snapshotCloner) anchorAll() {
+	c.anchorIntrinsics()
+	c.anchor(c.src.globalObject, c.dst.globalObject, snapshotPath{parent: -1})
+	c.anchorBuiltins()
+}
+
+func (c *snapshotCloner) fail(format string, args ...interface{}) {
+	panic(&snapshotError{err: fmt.Errorf("goja: cannot snapshot the Runtime: "+format, args...)})
+}
+
+func (c *snapshotCloner) anchorIntrinsics() {
+	sg := reflect.ValueOf(&c.src.global).Elem()
+	dg := reflect.ValueOf(&c.dst.global).Elem()
+	for i := 0; i < sg.NumField(); i++ {
+		// an object field that is missing from snapshotIntrinsics would keep pointing to the source Runtime
+		if f := sg.Type().Field(i); f.Type == typeObject {
+			if _, exists := snapshotIntrinsics[f.Name]; !exists {
+				c.fail("global.%s is not in snapshotIntrinsics", f.Name)
+			}
+		}
+	}
+	for name, get := range snapshotIntrinsics {
+		if get != nil && sg.FieldByName(name).Pointer() != 0 {
+			get(c.dst)
+		}
+	}
+	for name := range snapshotIntrinsics {
+		so := (*Object)(unsafe.Pointer(sg.FieldByName(name).Pointer()))
+		df := accessible(dg.FieldByName(name))
+		if so == nil {
+			// let it be created lazily, as it would be in the source Runtime
+			df.Set(reflect.Zero(df.Type()))
+			continue
+		}
+		do := (*Object)(unsafe.Pointer(df.Pointer()))
+		if do == nil {
+			panic(fmt.Errorf("snapshotIntrinsics: %s has not been created", name))
+		}
+		c.anchor(so, do, snapshotPath{parent: -1, name: unistring.String(name)})
+	}
+	if c.src.global.stdRegexpProto == nil {
+		c.dst.global.stdRegexpProto = nil
+	}
+}
+
+// anchorBuiltins pairs up the objects that are reachable from the already anchored ones through the same
+// property paths in both Runtimes. The built-in functions are only paired up if they are backed by the same
+// Go function, so that the ones that have been replaced are not matched by accident.
+func (c *snapshotCloner) anchorBuiltins() {
+	for i := 0; i < len(c.anchors); i++ {
+		a := c.anchors[i]
+		sb := snapshotBaseObject(a.src.self)
+		if sb == nil {
+			continue
+		}
+		// only the source properties that exist are looked at, this does not materialise templated ones
+		for name, sv := range sb.values {
+			if sv != nil {
+				c.anchorProp(sv, a.dst.self.getOwnPropStr(name), snapshotPath{parent: i, name: name})
+			}
+		}
+		if sb.symValues != nil {
+			iter := sb.symValues.newIter()
+			for {
+				entry := iter.next()
+				if entry == nil {
+					break
+				}
+				if entry.value != nil {
+					sym := entry.key.(*Symbol)
+					c.anchorProp(entry.value, a.dst.self.getOwnPropSym(sym), snapshotPath{parent: i, sym: sym})
+				}
+			}
+		}
+	}
+}
+
+func (c *snapshotCloner) anchorProp(sv, dv Value, path snapshotPath) {
+	if sp, ok := sv.(*valueProperty); ok {
+		dp, ok := dv.(*valueProperty)
+		if !ok || sp.accessor != dp.accessor {
+			return
+		}
+		if sp.accessor {
+			path.accessor = 1
+			c.anchorBuiltin(sp.getterFunc, dp.getterFunc, path)
+			path.accessor = 2
+			c.anchorBuiltin(sp.setterFunc, dp.setterFunc, path)
+			return
+		}
+		sv, dv = sp.value, dp.value
+	} else if dp, ok := dv.(*valueProperty); ok {
+		if dp.accessor {
+			return
+		}
+		dv = dp.value
+	}
+	so, _ := sv.(*Object)
+	do, _ := dv.(*Object)
+	c.anchorBuiltin(so, do, path)
+}
+
+func (c *snapshotCloner) anchorBuiltin(so, do *Object, path snapshotPath) {
+	if so == nil || do == nil || reflect.TypeOf(so.self) != reflect.TypeOf(do.self) {
+		return
+	}
+	switch s := so.self.(type) {
+	case *nativeFuncObject:
+		d := do.self.(*nativeFuncObject)
+		if !sameFunc(s.f, d.f) || !sameFunc(s.construct, d.construct) {
+			return
+		}
+	case *templatedFuncObject:
+		d := do.self.(*templatedFuncObject)
+		if !sameFunc(s.f, d.f) || !sameFunc(s.construct, d.construct) {
+			return
+		}
+	}
+	c.anchor(so, do, path)
+}
+
+func (c *snapshotCloner) anchor(so, do *Object, path snapshotPath) {
+	if _, exists := c.ptrs[unsafe.Pointer(so)]; exists {
+		return
+	}
+	if _, exists := c.dstAnchored[do]; exists {
+		return
+	}
+	c.ptrs[unsafe.Pointer(so)] = unsafe.Pointer(do)
+	c.dstAnchored[do] = struct{}{}
+	c.anchors = append(c.anchors, snapshotAnchor{src: so, dst: do, path: path})
+}
+
+func (c *snapshotCloner) copyAnchored(so, do *Object) {
+	do.id = so.id
+	c.cloneInto(reflect.ValueOf(&do.weakRefs).Elem(), reflect.ValueOf(&so.weakRefs).Elem())
+	if reflect.TypeOf(so.self) == reflect.TypeOf(do.self) {
+		// keep the Go functions, they are equivalent but bound to the destination Runtime
+		c.cloneStruct(reflect.ValueOf(do.self).Elem(), reflect.ValueOf(so.self).Elem(), true)
+	} else {
+		// the implementation has changed, e.g. an array has become sparse
+		c.cloneInto(reflect.ValueOf(&do.self).Elem(), reflect.ValueOf(&so.self).Elem())
+	}
+}
+
+// register records the addresses of a value and all its fields and elements.
+func (c *snapshotCloner) register(src, dst unsafe.Pointer, t reflect.Type) {
+	if t.Size() == 0 {
+		return
+	}
+	c.ptrs[src] = dst
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			c.register(unsafe.Add(src, f.Offset), unsafe.Add(dst, f.Offset), f.Type)
+		}
+	case reflect.Array:
+		et := t.Elem()
+		for i := 0; i < t.Len(); i++ {
+			c.register(unsafe.Add(src, uintptr(i)*et.Size()), unsafe.Add(dst, uintptr(i)*et.Size()), et)
+		}
+	}
+}
+
+func (c *snapshotCloner) cloneStruct(dst, src reflect.Value, skipFuncs bool) {
+	t := src.Type()
+	if t.Name() != "" && t.PkgPath() != gojaPkgPath {
+		c.fail("it references a Go value of type %s", t)
+	}
+	if !src.CanAddr() {
+		tmp := reflect.New(t).Elem()
+		tmp.Set(src)
+		src = tmp
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf, df := accessible(src.Field(i)), accessible(dst.Field(i))
+		switch sf.Kind() {
+		case reflect.Func:
+			if !skipFuncs && !sf.IsNil() {
+				c.fail("it references a Go function (in %s)", t)
+			}
+		case reflect.Struct:
+			c.cloneStruct(df, sf, skipFuncs)
+		default:
+			c.cloneInto(df, sf)
+		}
+	}
+}
+
+func (c *snapshotCloner) cloneFresh(src reflect.Value) reflect.Value {
+	ret := reflect.New(src.Type()).Elem()
+	c.cloneInto(ret, src)
+	return ret
+}
+
+func (c *snapshotCloner) cloneInto(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if !src.IsNil() {
+			dst.Set(c.clonePtr(src))
+		}
+	case reflect.Interface:
+		if !src.IsNil() {
+			dst.Set(c.cloneFresh(src.Elem()))
+		}
+	case reflect.Struct:
+		c.cloneStruct(dst, src, false)
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		if src.Type() == typeUnicodeString {
+			// immutable
+			dst.Set(src)
+			return
+		}
+		l := src.Len()
+		s := reflect.MakeSlice(src.Type(), l, l)
+		switch src.Type().Elem().Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64, reflect.String:
+			reflect.Copy(s, src)
+		default:
+			for i := 0; i < l; i++ {
+				se, de := src.Index(i), s.Index(i)
+				c.register(unsafe.Pointer(se.UnsafeAddr()), unsafe.Pointer(de.UnsafeAddr()), se.Type())
+				c.cloneInto(de, se)
+			}
+		}
+		dst.Set(s)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			c.cloneInto(dst.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(c.cloneFresh(iter.Key()), c.cloneFresh(iter.Value()))
+		}
+		dst.Set(m)
+	case reflect.Func:
+		if !src.IsNil() {
+			c.fail("it references a Go function")
+		}
+	case reflect.Chan, reflect.UnsafePointer:
+		if !src.IsNil() {
+			c.fail("it references a Go value of type %s", src.Type())
+		}
+	default:
+		dst.Set(src)
+	}
+}
+
+func (c *snapshotCloner) clonePtr(src reflect.Value) reflect.Value {
+	t := src.Type()
+	switch t {
+	case typeRuntimePtr:
+		return reflect.ValueOf(c.dst)
+	case typeSymbolPtr, typeObjectTemplatePtr, typeBigIntPtr, typeProgramPtr, typeFilePtr:
+		// immutable
+		return src
+	case typeVmPtr:
+		c.fail("generator objects and suspended async functions cannot be copied")
+	}
+	p := unsafe.Pointer(src.Pointer())
+	if d, exists := c.ptrs[p]; exists {
+		return reflect.NewAt(t.Elem(), d)
+	}
+	if t == typeRegexpPatternPtr {
+		ret := (*regexpPattern)(p).clone()
+		c.ptrs[p] = unsafe.Pointer(ret)
+		return reflect.ValueOf(ret)
+	}
+	if _, exists := snapshotHostTypes[t]; exists {
+		c.fail("it references a Go value")
+	}
+	skipFuncs := t == typeBoundFuncPtr || t == typeProxyPtr
+	if !skipFuncs {
+		switch f := src.Interface().(type) {
+		case *nativeFuncObject:
+			c.fail("native function %s is not a built-in", snapshotFuncName(&f.baseObject))
+		case *templatedFuncObject:
+			c.fail("native function %s is not a built-in", snapshotFuncName(&f.baseObject))
+		case *wrappedFuncObject:
+			c.fail("native function %s is not a built-in", snapshotFuncName(&f.baseObject))
+		}
+	}
+	et := t.Elem()
+	ret := reflect.New(et)
+	c.register(p, unsafe.Pointer(ret.Pointer()), et)
+	if et.Kind() == reflect.Struct {
+		c.cloneStruct(ret.Elem(), src.Elem(), skipFuncs)
+	} else {
+		c.cloneInto(ret.Elem(), src.Elem())
+	}
+	switch t {
+	case typeBoundFuncPtr:
+		c.boundFuncs = append(c.boundFuncs, ret.Interface().(*boundFuncObject))
+	case typeProxyPtr:
+		sp, dp := src.Interface().(*proxyObject), ret.Interface().(*proxyObject)
+		if sp.target != nil {
+			c.proxies = append(c.proxies, dp)
+		} else {
+			// A revoked proxy. The functions are only checked for nil, calling it throws a TypeError because
+			// the handler is nil.
+			if sp.call != nil {
+				dp.call = dp.apply
+			}
+			if sp.ctor != nil {
+				dp.ctor = dp.construct
+			}
+		}
+	}
+	return ret
+}
+
+func snapshotBaseObject(impl objectImpl) *baseObject {
+	if o, ok := impl.(*baseObject); ok {
+		return o
+	}
+	v := reflect.ValueOf(impl)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	f := v.Elem().FieldByName("baseObject")
+	if !f.IsValid() || f.Type() != typeBaseObject {
+		return nil
+	}
+	return (*baseObject)(unsafe.Pointer(f.UnsafeAddr()))
+}
+
+func snapshotFuncName(o *baseObject) string {
+	if prop, ok := o.values["name"].(*valueProperty); ok && !prop.accessor {
+		if s, ok := prop.value.(String); ok && s.Length() > 0 {
+			return fmt.Sprintf("%q", s.String())
+		}
+	}
+	if s, ok := o.values["name"].(String); ok && s.Length() > 0 {
+		return fmt.Sprintf("%q", s.String())
+	}
+	return "(anonymous)"
+}
+
+func sameFunc(f1, f2 interface{}) bool {
+	return reflect.ValueOf(f1).Pointer() == reflect.ValueOf(f2).Pointer()
+}
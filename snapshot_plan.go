@@ -0,0 +1,1206 @@
+package goja
+
+import (
+	"hash/maphash"
+	"reflect"
+	"sort"
+	"unsafe"
+
+	"github.com/dop251/goja/unistring"
+)
+
+// snapshotPlan is a recipe for recreating the heap of a Snapshot in a new Runtime. It is computed once, when the
+// Snapshot is taken, so that restoring it does not need to traverse the heap using reflection: the objects are
+// copied as raw memory and the pointers in them are redirected using the offsets recorded in the plan.
+//
+// The built-in objects that have not been modified are not copied at all. The ones that are referenced from the
+// copied objects are looked up in the new Runtime using the property path they have been found by (which creates
+// them if they have not been created yet), the modified ones are overwritten in place.
+//
+// The memory locations the pointers can be redirected to (the Runtime, its hash, the built-ins and the copies)
+// are called targets. Their addresses in the new Runtime are only known when the plan is restored, so the plan
+// refers to them by index.
+type snapshotPlan struct {
+	nTargets int
+
+	hasHash    bool
+	hashSeed   maphash.Seed
+	hashTarget int
+
+	nilIntrinsics     []uintptr
+	nilStdRegexpProto bool
+
+	anchors []planAnchor
+	allocs  []planAlloc
+	cells   []planCell
+	maps    []planMap
+	fixups  []planFixup
+
+	idSeq uint64
+}
+
+const (
+	planNil      = -1 // the pointer is set to nil
+	planNoTarget = -2 // the pointer is left as is
+)
+
+// planSlot is a pointer that needs to be redirected: the word at off is set to the address of the target plus delta.
+type planSlot struct {
+	off    uintptr
+	target int
+	delta  uintptr
+}
+
+// planAnchor is a built-in object that is looked up in the new Runtime.
+type planAnchor struct {
+	target     int
+	implTarget int
+
+	// how the object is found: the global object, an intrinsic (a field of global, created by get if not nil)
+	// or a property of an already resolved object.
+	global   bool
+	parent   int
+	get      func(*Runtime) *Object
+	field    uintptr
+	name     unistring.String
+	sym      *Symbol
+	accessor int
+
+	id         uint64
+	weakRefs   int
+	self       objectImpl // the implementation if its type has changed, its data is set to selfTarget
+	selfTarget int
+}
+
+type planAllocKind int
+
+const (
+	planAllocMemory planAllocKind = iota
+	planAllocMap
+	planAllocPattern
+)
+
+// planAlloc is a copy of a block of memory, a map or a regexp pattern.
+type planAlloc struct {
+	kind   planAllocKind
+	target int
+
+	src     reflect.Value
+	mapType reflect.Type
+	mapLen  int
+	pattern *regexpPattern
+}
+
+// planCell is a block of memory in the new Runtime whose pointers need to be redirected. The cells that are not
+// copies (i.e. the built-ins and the fields of the Runtime) are overwritten with src first, keeping the words at
+// the keep offsets (the Go functions of the built-ins, which are bound to the Runtime).
+type planCell struct {
+	target int
+	delta  uintptr
+	src    reflect.Value
+	keep   []uintptr
+	slots  []planSlot
+}
+
+// planValue is a key or a value of a map entry.
+type planValue struct {
+	v     reflect.Value
+	slots []planSlot
+}
+
+// planMap holds the entries of a map. The property maps, which are the most common by far, are stored
+// separately so that they can be filled without reflection.
+type planMap struct {
+	target  int
+	typ     reflect.Type
+	entries [][2]planValue
+
+	props      []planProp
+	hashTables []planHashEntry
+}
+
+type planProp struct {
+	name  unistring.String
+	value Value
+	slot  planSlot
+}
+
+type planHashEntry struct {
+	h     uint64
+	entry planSlot
+}
+
+// planFixup is a bound function or a Proxy whose Go functions need to be re-created. The fixups are ordered
+// so that the wrapped functions and the targets come first.
+type planFixup struct {
+	target int
+	proxy  bool
+
+	revokedCall, revokedCtor bool
+}
+
+var (
+	typeMapStrValue   = reflect.TypeOf(map[unistring.String]Value(nil))
+	typeMapHashTable  = reflect.TypeOf(map[uint64]*mapEntry(nil))
+	typeHashPtr       = reflect.TypeOf((*maphash.Hash)(nil))
+	typeOrderedMap    = reflect.TypeOf(orderedMap{})
+	typeStash         = reflect.TypeOf(stash{})
+	typeSymbolMap     = reflect.TypeOf(map[unistring.String]*Symbol(nil))
+	typeWeakRefsMap   = reflect.TypeOf(map[weakMap]Value(nil))
+	typeProxyObject   = reflect.TypeOf(proxyObject{})
+	typeBoundFunction = reflect.TypeOf(boundFuncObject{})
+	typeObjectImpl    = reflect.TypeOf((*objectImpl)(nil)).Elem()
+)
+
+func (p *snapshotPlan) restore(r *Runtime) {
+	addrs := make([]unsafe.Pointer, p.nTargets)
+	addrs[0] = unsafe.Pointer(r)
+	if p.hasHash {
+		// the hash tables of Maps and Sets are only valid with the same seed
+		h := r.getHash()
+		h.SetSeed(p.hashSeed)
+		if p.hashTarget >= 0 {
+			addrs[p.hashTarget] = unsafe.Pointer(h)
+		}
+	}
+
+	g := unsafe.Pointer(&r.global)
+	for _, off := range p.nilIntrinsics {
+		*(**Object)(unsafe.Add(g, off)) = nil
+	}
+	if p.nilStdRegexpProto {
+		r.global.stdRegexpProto = nil
+	}
+
+	for i := range p.anchors {
+		a := &p.anchors[i]
+		var o *Object
+		switch {
+		case a.global:
+			o = r.globalObject
+		case a.parent < 0:
+			if a.get != nil {
+				a.get(r)
+			}
+			o = *(**Object)(unsafe.Add(g, a.field))
+		default:
+			o = a.resolve((*Object)(addrs[a.parent]))
+		}
+		if o == nil {
+			// this cannot happen because the same lookup has succeeded in a new Runtime when the plan was made
+			panic("goja: a built-in object of the Snapshot could not be found")
+		}
+		addrs[a.target] = unsafe.Pointer(o)
+		if a.implTarget >= 0 {
+			addrs[a.implTarget] = ifaceData(unsafe.Pointer(&o.self))
+		}
+	}
+
+	for i := range p.allocs {
+		a := &p.allocs[i]
+		switch a.kind {
+		case planAllocMemory:
+			v := reflect.New(a.src.Type())
+			v.Elem().Set(a.src)
+			addrs[a.target] = v.UnsafePointer()
+		case planAllocMap:
+			addrs[a.target] = reflect.MakeMapWithSize(a.mapType, a.mapLen).UnsafePointer()
+		case planAllocPattern:
+			addrs[a.target] = unsafe.Pointer(a.pattern.clone())
+		}
+	}
+
+	var kept []unsafe.Pointer
+	for i := range p.cells {
+		c := &p.cells[i]
+		base := unsafe.Add(addrs[c.target], c.delta)
+		if c.src.IsValid() {
+			kept = kept[:0]
+			for _, off := range c.keep {
+				kept = append(kept, *(*unsafe.Pointer)(unsafe.Add(base, off)))
+			}
+			reflect.NewAt(c.src.Type(), base).Elem().Set(c.src)
+			for j, off := range c.keep {
+				*(*unsafe.Pointer)(unsafe.Add(base, off)) = kept[j]
+			}
+		}
+		patchSlots(base, c.slots, addrs)
+	}
+
+	for i := range p.maps {
+		p.maps[i].fill(addrs)
+	}
+
+	for i := range p.anchors {
+		a := &p.anchors[i]
+		o := (*Object)(addrs[a.target])
+		o.id = a.id
+		if a.weakRefs >= 0 {
+			o.weakRefs = *(*map[weakMap]Value)(unsafe.Pointer(&addrs[a.weakRefs]))
+		}
+		if a.self != nil {
+			o.self = a.self
+			setIfaceData(unsafe.Pointer(&o.self), addrs[a.selfTarget])
+		}
+	}
+
+	for _, f := range p.fixups {
+		if f.proxy {
+			p := (*proxyObject)(addrs[f.target])
+			if p.target != nil {
+				p.call, _ = p.target.self.assertCallable()
+				p.ctor = p.target.self.assertConstructor()
+			} else {
+				// A revoked proxy. The functions are only checked for nil, calling it throws a TypeError because
+				// the handler is nil.
+				if f.revokedCall {
+					p.call = p.apply
+				}
+				if f.revokedCtor {
+					p.ctor = p.construct
+				}
+			}
+		} else {
+			b := (*boundFuncObject)(addrs[f.target])
+			b.f = r.boundCallable(r.toCallable(b.wrapped), b.boundArgs)
+			b.construct = r.boundConstruct(b.val, b.wrapped.self.assertConstructor(), b.boundArgs)
+		}
+	}
+
+	r.idSeq = p.idSeq
+}
+
+func (s planSlot) addr(addrs []unsafe.Pointer) unsafe.Pointer {
+	if s.target == planNil {
+		return nil
+	}
+	return unsafe.Add(addrs[s.target], s.delta)
+}
+
+func patchSlots(base unsafe.Pointer, slots []planSlot, addrs []unsafe.Pointer) {
+	for _, s := range slots {
+		*(*unsafe.Pointer)(unsafe.Add(base, s.off)) = s.addr(addrs)
+	}
+}
+
+func (a *planAnchor) resolve(parent *Object) *Object {
+	if parent == nil {
+		return nil
+	}
+	var v Value
+	if a.sym != nil {
+		v = parent.self.getOwnPropSym(a.sym)
+	} else {
+		v = parent.self.getOwnPropStr(a.name)
+	}
+	if prop, ok := v.(*valueProperty); ok {
+		switch a.accessor {
+		case 1:
+			return prop.getterFunc
+		case 2:
+			return prop.setterFunc
+		}
+		v = prop.value
+	}
+	o, _ := v.(*Object)
+	return o
+}
+
+func (m *planMap) fill(addrs []unsafe.Pointer) {
+	ptr := unsafe.Pointer(&addrs[m.target])
+	switch {
+	case m.props != nil:
+		values := *(*map[unistring.String]Value)(ptr)
+		for _, prop := range m.props {
+			v := prop.value
+			if prop.slot.target != planNoTarget {
+				setIfaceData(unsafe.Pointer(&v), prop.slot.addr(addrs))
+			}
+			values[prop.name] = v
+		}
+	case m.hashTables != nil:
+		table := *(*map[uint64]*mapEntry)(ptr)
+		for _, e := range m.hashTables {
+			table[e.h] = (*mapEntry)(e.entry.addr(addrs))
+		}
+	default:
+		mv := reflect.NewAt(m.typ, ptr).Elem()
+		for _, e := range m.entries {
+			mv.SetMapIndex(e[0].value(addrs), e[1].value(addrs))
+		}
+	}
+}
+
+func (v *planValue) value(addrs []unsafe.Pointer) reflect.Value {
+	if len(v.slots) == 0 {
+		return v.v
+	}
+	ret := reflect.New(v.v.Type())
+	ret.Elem().Set(v.v)
+	patchSlots(ret.UnsafePointer(), v.slots, addrs)
+	return ret.Elem()
+}
+
+// ifaceData returns the data word of the interface value at p.
+func ifaceData(p unsafe.Pointer) unsafe.Pointer {
+	return (*[2]unsafe.Pointer)(p)[1]
+}
+
+func setIfaceData(p unsafe.Pointer, data unsafe.Pointer) {
+	(*[2]unsafe.Pointer)(p)[1] = data
+}
+
+// directIface reports whether the values of type t are stored in the data word of an interface rather
+// than pointed to by it.
+func directIface(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return true
+	case reflect.Struct:
+		return t.NumField() == 1 && directIface(t.Field(0).Type)
+	case reflect.Array:
+		return t.Len() == 1 && directIface(t.Elem())
+	}
+	return false
+}
+
+type planFuncMode int
+
+const (
+	planFuncsFail planFuncMode = iota
+	planFuncsClear
+	planFuncsKeep
+)
+
+// planRawSlot is a pointer found when walking the heap, before it is resolved to a target.
+type planRawSlot struct {
+	off  uintptr
+	ptr  unsafe.Pointer
+	kind planSlotKind
+}
+
+type planSlotKind int
+
+const (
+	planSlotPtr planSlotKind = iota
+	planSlotMap
+	planSlotPattern
+	planSlotHash
+	planSlotNil
+)
+
+// planBlock is a block of memory in the source heap that has been walked.
+type planBlock struct {
+	ptr   unsafe.Pointer
+	start uintptr
+	typ   reflect.Type
+	funcs planFuncMode
+	slots []planRawSlot
+	keep  []uintptr
+
+	// the number of the elements that have been walked (for the backing arrays of slices)
+	walked int
+}
+
+type planBlockKey struct {
+	p unsafe.Pointer
+	t reflect.Type
+}
+
+// planGroup is a set of overlapping blocks that are copied as a whole (e.g. an object and a pointer to its
+// embedded baseObject, or a slice and a sub-slice of it).
+type planGroup struct {
+	ptr        unsafe.Pointer
+	start, end uintptr
+	typ        reflect.Type
+	target     int
+}
+
+// planFixed is a range of the source memory that is not copied: the Runtime and the built-ins.
+type planFixed struct {
+	start, end uintptr
+	target     func() int
+}
+
+type planMapInfo struct {
+	target  int
+	typ     reflect.Type
+	entries [][2]planValue
+	slots   [][2][]planRawSlot
+}
+
+type planBuilder struct {
+	c    *snapshotCloner
+	base *Runtime
+	plan *snapshotPlan
+
+	anchorIdx     map[unsafe.Pointer]int
+	anchorTargets []int
+	implTargets   []int
+	neededIdx     []int
+	needed        []planAnchor
+
+	fixed   []planFixed
+	blocks  []*planBlock
+	visited map[planBlockKey]*planBlock
+	mapInfo map[unsafe.Pointer]*planMapInfo
+	mapList []*planMapInfo
+	pattern map[unsafe.Pointer]int
+	groups  []planGroup
+
+	walkTypes map[reflect.Type]bool
+	seen      map[unsafe.Pointer]unsafe.Pointer
+}
+
+// makeSnapshotPlan computes the plan for the heap of base, which must not be modified afterwards.
+func makeSnapshotPlan(base *Runtime) (plan *snapshotPlan, err error) {
+	ref := New()
+	c := &snapshotCloner{
+		src:         base,
+		dst:         ref,
+		ptrs:        make(map[unsafe.Pointer]unsafe.Pointer),
+		dstAnchored: make(map[*Object]struct{}),
+	}
+	b := &planBuilder{
+		c:    c,
+		base: base,
+		plan: &snapshotPlan{
+			nTargets:   1,
+			hashTarget: -1,
+			idSeq:      base.idSeq,
+		},
+		anchorIdx: make(map[unsafe.Pointer]int),
+		visited:   make(map[planBlockKey]*planBlock),
+		mapInfo:   make(map[unsafe.Pointer]*planMapInfo),
+		pattern:   make(map[unsafe.Pointer]int),
+		walkTypes: make(map[reflect.Type]bool),
+		seen:      make(map[unsafe.Pointer]unsafe.Pointer),
+	}
+	defer func() {
+		if x := recover(); x != nil {
+			if e, ok := x.(*snapshotError); ok {
+				err = e.err
+				return
+			}
+			panic(x)
+		}
+	}()
+	c.anchorAll()
+	b.build()
+	return b.plan, nil
+}
+
+func (b *planBuilder) build() {
+	base, plan := b.base, b.plan
+	if base.hash != nil {
+		plan.hasHash = true
+		plan.hashSeed = base.hash.Seed()
+	}
+
+	for name := range snapshotIntrinsics {
+		f, _ := reflect.TypeOf(global{}).FieldByName(name)
+		if *(**Object)(unsafe.Add(unsafe.Pointer(&base.global), f.Offset)) == nil {
+			plan.nilIntrinsics = append(plan.nilIntrinsics, f.Offset)
+		}
+	}
+	sort.Slice(plan.nilIntrinsics, func(i, j int) bool { return plan.nilIntrinsics[i] < plan.nilIntrinsics[j] })
+	plan.nilStdRegexpProto = base.global.stdRegexpProto == nil
+
+	b.anchorTargets = make([]int, len(b.c.anchors))
+	b.implTargets = make([]int, len(b.c.anchors))
+	b.neededIdx = make([]int, len(b.c.anchors))
+	for i, a := range b.c.anchors {
+		b.anchorIdx[unsafe.Pointer(a.src)] = i
+		b.anchorTargets[i] = -1
+		b.implTargets[i] = -1
+	}
+
+	rt := uintptr(unsafe.Pointer(base))
+	b.fixed = append(b.fixed, planFixed{start: rt, end: rt + unsafe.Sizeof(*base), target: func() int { return 0 }})
+	sameType := make([]bool, len(b.c.anchors))
+	for i, a := range b.c.anchors {
+		if reflect.TypeOf(a.src.self) != reflect.TypeOf(a.dst.self) {
+			continue
+		}
+		sameType[i] = true
+		impl := uintptr(ifaceData(unsafe.Pointer(&a.src.self)))
+		i := i
+		b.fixed = append(b.fixed, planFixed{
+			start:  impl,
+			end:    impl + reflect.TypeOf(a.src.self).Elem().Size(),
+			target: func() int { return b.implTarget(i) },
+		})
+	}
+	sort.Slice(b.fixed, func(i, j int) bool { return b.fixed[i].start < b.fixed[j].start })
+
+	var fixedBlocks []*planBlock
+	var fixedCells []planCell
+	addFixed := func(p unsafe.Pointer, t reflect.Type, funcs planFuncMode, target func() int, delta uintptr) {
+		blk := &planBlock{ptr: p, start: uintptr(p), typ: t, funcs: funcs}
+		b.walk(blk, p, t, 0)
+		fixedBlocks = append(fixedBlocks, blk)
+		fixedCells = append(fixedCells, planCell{target: target(), delta: delta, src: reflect.NewAt(t, p).Elem()})
+	}
+
+	type anchorState struct {
+		i        int
+		weakRefs *planBlock
+		self     *planBlock
+	}
+	var states []anchorState
+	for i, a := range b.c.anchors {
+		st := anchorState{i: i}
+		modified := !sameType[i] || !b.equalAnchor(a.src, a.dst)
+		if modified {
+			if sameType[i] {
+				t := reflect.TypeOf(a.src.self).Elem()
+				addFixed(ifaceData(unsafe.Pointer(&a.src.self)), t, planFuncsKeep, func() int { return b.implTarget(i) }, 0)
+			} else {
+				st.self = &planBlock{}
+				b.walk(st.self, unsafe.Pointer(&a.src.self), typeObjectImpl, 0)
+			}
+		}
+		if a.src.weakRefs != nil {
+			st.weakRefs = &planBlock{}
+			b.walk(st.weakRefs, unsafe.Pointer(&a.src.weakRefs), typeWeakRefsMap, 0)
+		}
+		if modified || a.src.id != 0 || a.src.weakRefs != nil {
+			b.anchorTarget(i)
+			states = append(states, st)
+		}
+	}
+	addFixed(unsafe.Pointer(&base.global.stash), typeStash, planFuncsFail, func() int { return 0 },
+		unsafe.Offsetof(base.global)+unsafe.Offsetof(base.global.stash))
+	addFixed(unsafe.Pointer(&base.symbolRegistry), typeSymbolMap, planFuncsFail, func() int { return 0 },
+		unsafe.Offsetof(base.symbolRegistry))
+
+	b.groupBlocks()
+
+	for i, blk := range fixedBlocks {
+		fixedCells[i].keep = blk.keep
+		fixedCells[i].slots = b.resolveSlots(nil, blk.slots, 0)
+	}
+	for gi := range b.groups {
+		g := &b.groups[gi]
+		plan.allocs = append(plan.allocs, planAlloc{
+			kind:   planAllocMemory,
+			target: g.target,
+			src:    reflect.NewAt(g.typ, g.ptr).Elem(),
+		})
+	}
+	cells := make([][]planSlot, len(b.groups))
+	for _, blk := range b.blocks {
+		gi := b.findGroup(blk.start)
+		g := &b.groups[gi]
+		cells[gi] = b.resolveSlots(cells[gi], blk.slots, blk.start-g.start)
+	}
+	for gi, slots := range cells {
+		plan.cells = append(plan.cells, planCell{target: b.groups[gi].target, slots: dedupSlots(slots)})
+	}
+	plan.cells = append(plan.cells, fixedCells...)
+
+	for _, st := range states {
+		a := b.c.anchors[st.i]
+		pa := &b.needed[b.neededIdx[st.i]]
+		pa.id = a.src.id
+		if st.weakRefs != nil {
+			pa.weakRefs = b.resolveSlots(nil, st.weakRefs.slots, 0)[0].target
+		}
+		if st.self != nil {
+			pa.self = a.src.self
+			pa.selfTarget = b.resolveSlots(nil, st.self.slots, 0)[0].target
+		}
+	}
+
+	for _, m := range b.mapList {
+		pm := planMap{target: m.target, typ: m.typ}
+		for i, e := range m.entries {
+			e[0].slots = b.resolveSlots(nil, m.slots[i][0], 0)
+			e[1].slots = b.resolveSlots(nil, m.slots[i][1], 0)
+			pm.entries = append(pm.entries, e)
+		}
+		pm.optimise()
+		plan.maps = append(plan.maps, pm)
+	}
+
+	plan.anchors = b.needed
+	b.makeFixups()
+}
+
+func (b *planBuilder) newTarget() int {
+	t := b.plan.nTargets
+	b.plan.nTargets++
+	return t
+}
+
+// anchorTarget returns the target of an anchored object, adding it (and the objects it is found through)
+// to the ones that are looked up when the plan is restored.
+func (b *planBuilder) anchorTarget(i int) int {
+	if t := b.anchorTargets[i]; t >= 0 {
+		return t
+	}
+	a := b.c.anchors[i]
+	pa := planAnchor{
+		parent:     -1,
+		implTarget: -1,
+		weakRefs:   -1,
+		selfTarget: -1,
+	}
+	switch p := a.path; {
+	case p.parent >= 0:
+		pa.parent = b.anchorTarget(p.parent)
+		pa.name, pa.sym, pa.accessor = p.name, p.sym, p.accessor
+	case p.name == "":
+		pa.global = true
+	default:
+		f, _ := reflect.TypeOf(global{}).FieldByName(string(p.name))
+		pa.field = f.Offset
+		pa.get = snapshotIntrinsics[string(p.name)]
+	}
+	pa.target = b.newTarget()
+	b.anchorTargets[i] = pa.target
+	b.neededIdx[i] = len(b.needed)
+	b.needed = append(b.needed, pa)
+	return pa.target
+}
+
+func (b *planBuilder) implTarget(i int) int {
+	if t := b.implTargets[i]; t >= 0 {
+		return t
+	}
+	b.anchorTarget(i)
+	t := b.newTarget()
+	b.implTargets[i] = t
+	b.needed[b.neededIdx[i]].implTarget = t
+	return t
+}
+
+func (b *planBuilder) fail(format string, args ...interface{}) {
+	b.c.fail(format, args...)
+}
+
+// needsWalk reports whether the values of type t may contain pointers that need to be redirected.
+func needsWalk(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr:
+		switch t {
+		case typeSymbolPtr, typeObjectTemplatePtr, typeBigIntPtr, typeProgramPtr, typeFilePtr:
+			return false
+		}
+		return true
+	case reflect.Interface, reflect.Map, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return true
+	case reflect.Slice:
+		return t != typeUnicodeString
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if needsWalk(t.Field(i).Type) {
+				return true
+			}
+		}
+	case reflect.Array:
+		return t.Len() > 0 && needsWalk(t.Elem())
+	}
+	return false
+}
+
+func (b *planBuilder) needsWalk(t reflect.Type) bool {
+	ret, exists := b.walkTypes[t]
+	if !exists {
+		ret = needsWalk(t)
+		b.walkTypes[t] = ret
+	}
+	return ret
+}
+
+// walk records the pointers in the value of type t at p, which is at the offset off in blk.
+func (b *planBuilder) walk(blk *planBlock, p unsafe.Pointer, t reflect.Type, off uintptr) {
+	if !b.needsWalk(t) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		b.walkPtr(blk, *(*unsafe.Pointer)(p), t, off)
+	case reflect.Interface:
+		iv := reflect.NewAt(t, p).Elem()
+		if iv.IsNil() {
+			return
+		}
+		dt := iv.Elem().Type()
+		data := ifaceData(p)
+		if directIface(dt) {
+			b.walk(blk, unsafe.Add(p, unsafe.Sizeof(data)), dt, off+unsafe.Sizeof(data))
+		} else if b.needsWalk(dt) {
+			blk.slots = append(blk.slots, planRawSlot{off: off + unsafe.Sizeof(data), ptr: data})
+			b.visit(data, dt, 1)
+		}
+	case reflect.Struct:
+		if t.Name() != "" && t.PkgPath() != gojaPkgPath {
+			b.fail("it references a Go value of type %s", t)
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			b.walk(blk, unsafe.Add(p, f.Offset), f.Type, off+f.Offset)
+		}
+	case reflect.Array:
+		et := t.Elem()
+		for i := 0; i < t.Len(); i++ {
+			b.walk(blk, unsafe.Add(p, uintptr(i)*et.Size()), et, off+uintptr(i)*et.Size())
+		}
+	case reflect.Slice:
+		hdr := (*struct {
+			data     unsafe.Pointer
+			len, cap int
+		})(p)
+		if hdr.data == nil || hdr.cap == 0 || t.Elem().Size() == 0 {
+			return
+		}
+		blk.slots = append(blk.slots, planRawSlot{off: off, ptr: hdr.data})
+		b.visit(hdr.data, reflect.ArrayOf(hdr.cap, t.Elem()), hdr.len)
+	case reflect.Map:
+		m := *(*unsafe.Pointer)(p)
+		if m == nil {
+			return
+		}
+		blk.slots = append(blk.slots, planRawSlot{off: off, ptr: m, kind: planSlotMap})
+		b.visitMap(m, t)
+	case reflect.Func:
+		if *(*unsafe.Pointer)(p) == nil {
+			return
+		}
+		switch blk.funcs {
+		case planFuncsKeep:
+			blk.keep = append(blk.keep, off)
+		case planFuncsClear:
+			blk.slots = append(blk.slots, planRawSlot{off: off, kind: planSlotNil})
+		default:
+			b.fail("it references a Go function (in %s)", blk.typ)
+		}
+	case reflect.Chan, reflect.UnsafePointer:
+		if *(*unsafe.Pointer)(p) != nil {
+			b.fail("it references a Go value of type %s", t)
+		}
+	}
+}
+
+func (b *planBuilder) walkPtr(blk *planBlock, q unsafe.Pointer, t reflect.Type, off uintptr) {
+	if q == nil {
+		return
+	}
+	switch t {
+	case typeVmPtr:
+		b.fail("generator objects and suspended async functions cannot be copied")
+	case typeHashPtr:
+		if q == unsafe.Pointer(b.base.hash) {
+			blk.slots = append(blk.slots, planRawSlot{off: off, ptr: q, kind: planSlotHash})
+			return
+		}
+	case typeRegexpPatternPtr:
+		if _, exists := b.pattern[q]; !exists {
+			target := b.newTarget()
+			b.pattern[q] = target
+			b.plan.allocs = append(b.plan.allocs, planAlloc{kind: planAllocPattern, target: target, pattern: (*regexpPattern)(q)})
+		}
+		blk.slots = append(blk.slots, planRawSlot{off: off, ptr: q, kind: planSlotPattern})
+		return
+	}
+	if t.Elem().Size() == 0 {
+		return
+	}
+	if _, exists := snapshotHostTypes[t]; exists {
+		b.fail("it references a Go value")
+	}
+	blk.slots = append(blk.slots, planRawSlot{off: off, ptr: q})
+	b.visit(q, t.Elem(), 1)
+}
+
+// visit walks the value of type t at p unless it has already been walked or it is not copied. For arrays
+// (the backing arrays of slices) only the first n elements are walked.
+func (b *planBuilder) visit(p unsafe.Pointer, t reflect.Type, n int) {
+	if t == typeObject.Elem() {
+		if _, exists := b.anchorIdx[p]; exists {
+			return
+		}
+	}
+	if b.findFixed(uintptr(p)) >= 0 {
+		return
+	}
+	key := planBlockKey{p: p, t: t}
+	blk := b.visited[key]
+	if blk == nil {
+		blk = &planBlock{ptr: p, start: uintptr(p), typ: t}
+		switch t {
+		case typeBoundFunction, typeProxyObject:
+			blk.funcs = planFuncsClear
+		default:
+			switch impl := reflect.NewAt(t, p).Interface().(type) {
+			case *nativeFuncObject:
+				b.fail("native function %s is not a built-in", snapshotFuncName(&impl.baseObject))
+			case *templatedFuncObject:
+				b.fail("native function %s is not a built-in", snapshotFuncName(&impl.baseObject))
+			case *wrappedFuncObject:
+				b.fail("native function %s is not a built-in", snapshotFuncName(&impl.baseObject))
+			}
+		}
+		b.visited[key] = blk
+		b.blocks = append(b.blocks, blk)
+	}
+	if t.Kind() != reflect.Array {
+		if blk.walked == 0 {
+			blk.walked = 1
+			b.walk(blk, p, t, 0)
+		}
+		return
+	}
+	et := t.Elem()
+	for ; blk.walked < n; blk.walked++ {
+		off := uintptr(blk.walked) * et.Size()
+		b.walk(blk, unsafe.Add(p, off), et, off)
+	}
+}
+
+func (b *planBuilder) visitMap(m unsafe.Pointer, t reflect.Type) {
+	if _, exists := b.mapInfo[m]; exists {
+		return
+	}
+	mv := reflect.NewAt(t, unsafe.Pointer(&m)).Elem()
+	info := &planMapInfo{target: b.newTarget(), typ: t}
+	b.mapInfo[m] = info
+	b.mapList = append(b.mapList, info)
+	b.plan.allocs = append(b.plan.allocs, planAlloc{kind: planAllocMap, target: info.target, mapType: t, mapLen: mv.Len()})
+	iter := mv.MapRange()
+	for iter.Next() {
+		var e [2]planValue
+		var slots [2][]planRawSlot
+		for j, v := range [2]reflect.Value{iter.Key(), iter.Value()} {
+			c := reflect.New(v.Type()).Elem()
+			c.Set(v)
+			blk := &planBlock{typ: t}
+			b.walk(blk, unsafe.Pointer(c.UnsafeAddr()), v.Type(), 0)
+			e[j].v = c
+			slots[j] = blk.slots
+		}
+		info.entries = append(info.entries, e)
+		info.slots = append(info.slots, slots)
+	}
+}
+
+func (b *planBuilder) findFixed(p uintptr) int {
+	i := sort.Search(len(b.fixed), func(i int) bool { return b.fixed[i].end > p })
+	if i < len(b.fixed) && b.fixed[i].start <= p {
+		return i
+	}
+	return -1
+}
+
+// groupBlocks merges the overlapping blocks, each group is copied as one allocation.
+func (b *planBuilder) groupBlocks() {
+	blocks := make([]*planBlock, 0, len(b.blocks))
+	for _, blk := range b.blocks {
+		if blk.typ.Size() > 0 {
+			blocks = append(blocks, blk)
+		}
+	}
+	b.blocks = blocks
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].start != blocks[j].start {
+			return blocks[i].start < blocks[j].start
+		}
+		return blocks[i].typ.Size() > blocks[j].typ.Size()
+	})
+	for _, blk := range blocks {
+		end := blk.start + blk.typ.Size()
+		if n := len(b.groups); n > 0 && blk.start < b.groups[n-1].end {
+			g := &b.groups[n-1]
+			if end > g.end {
+				// overlapping slices of the same backing array
+				if g.typ.Kind() != reflect.Array || blk.typ.Kind() != reflect.Array || g.typ.Elem() != blk.typ.Elem() {
+					b.fail("overlapping values of types %s and %s", g.typ, blk.typ)
+				}
+				g.end = end
+				g.typ = reflect.ArrayOf(int((g.end-g.start)/g.typ.Elem().Size()), g.typ.Elem())
+			}
+			continue
+		}
+		b.groups = append(b.groups, planGroup{ptr: blk.ptr, start: blk.start, end: end, typ: blk.typ, target: b.newTarget()})
+	}
+}
+
+func (b *planBuilder) findGroup(p uintptr) int {
+	i := sort.Search(len(b.groups), func(i int) bool { return b.groups[i].end > p })
+	if i < len(b.groups) && b.groups[i].start <= p {
+		return i
+	}
+	return -1
+}
+
+func (b *planBuilder) resolveSlots(slots []planSlot, raw []planRawSlot, off uintptr) []planSlot {
+	for _, s := range raw {
+		ps := planSlot{off: s.off + off}
+		switch s.kind {
+		case planSlotNil:
+			ps.target = planNil
+		case planSlotMap:
+			ps.target = b.mapInfo[s.ptr].target
+		case planSlotPattern:
+			ps.target = b.pattern[s.ptr]
+		case planSlotHash:
+			if b.plan.hashTarget < 0 {
+				b.plan.hashTarget = b.newTarget()
+			}
+			ps.target = b.plan.hashTarget
+		default:
+			p := uintptr(s.ptr)
+			if i, exists := b.anchorIdx[s.ptr]; exists {
+				ps.target = b.anchorTarget(i)
+			} else if i := b.findFixed(p); i >= 0 {
+				ps.target, ps.delta = b.fixed[i].target(), p-b.fixed[i].start
+			} else if i := b.findGroup(p); i >= 0 {
+				ps.target, ps.delta = b.groups[i].target, p-b.groups[i].start
+			} else {
+				b.fail("a pointer could not be resolved")
+			}
+		}
+		slots = append(slots, ps)
+	}
+	return slots
+}
+
+func dedupSlots(slots []planSlot) []planSlot {
+	sort.Slice(slots, func(i, j int) bool { return slots[i].off < slots[j].off })
+	ret := slots[:0]
+	for i, s := range slots {
+		if i == 0 || s.off != slots[i-1].off {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// optimise stores the entries of the property maps and the hash tables of Maps and Sets so that they can be
+// filled without reflection.
+func (m *planMap) optimise() {
+	switch m.typ {
+	case typeMapStrValue:
+		props := make([]planProp, 0, len(m.entries))
+		for _, e := range m.entries {
+			prop := planProp{name: e[0].v.Interface().(unistring.String), slot: planSlot{target: planNoTarget}}
+			if v := e[1].v.Interface(); v != nil {
+				prop.value = v.(Value)
+			}
+			switch len(e[1].slots) {
+			case 0:
+			case 1:
+				if s := e[1].slots[0]; s.off == unsafe.Sizeof(uintptr(0)) {
+					prop.slot = s
+					break
+				}
+				fallthrough
+			default:
+				return
+			}
+			props = append(props, prop)
+		}
+		m.props, m.entries = props, nil
+	case typeMapHashTable:
+		table := make([]planHashEntry, 0, len(m.entries))
+		for _, e := range m.entries {
+			table = append(table, planHashEntry{h: e[0].v.Uint(), entry: e[1].slots[0]})
+		}
+		m.hashTables, m.entries = table, nil
+	}
+}
+
+// makeFixups orders the bound functions and the Proxies so that the functions they wrap are fixed up first.
+func (b *planBuilder) makeFixups() {
+	done := make(map[*Object]bool)
+	var visit func(o *Object)
+	visit = func(o *Object) {
+		if o == nil || done[o] {
+			return
+		}
+		done[o] = true
+		var f planFixup
+		switch impl := o.self.(type) {
+		case *boundFuncObject:
+			visit(impl.wrapped)
+			f.target = b.targetOf(unsafe.Pointer(impl))
+		case *proxyObject:
+			visit(impl.target)
+			f = planFixup{target: b.targetOf(unsafe.Pointer(impl)), proxy: true}
+			if impl.target == nil {
+				f.revokedCall, f.revokedCtor = impl.call != nil, impl.ctor != nil
+			}
+		default:
+			return
+		}
+		b.plan.fixups = append(b.plan.fixups, f)
+	}
+	for _, blk := range b.blocks {
+		if blk.typ == typeObject.Elem() {
+			visit((*Object)(blk.ptr))
+		}
+	}
+}
+
+func (b *planBuilder) targetOf(p unsafe.Pointer) int {
+	i := b.findGroup(uintptr(p))
+	if i < 0 || b.groups[i].start != uintptr(p) {
+		b.fail("a bound function or a Proxy is not copied")
+	}
+	return b.groups[i].target
+}
+
+// equalAnchor reports whether an anchored object is in the same state as its counterpart in a new Runtime, in
+// which case it does not need to be copied. Differences in what has been materialised from the templates are
+// ignored by discarding them in the new Runtime, which is only used for the comparison.
+func (b *planBuilder) equalAnchor(so, do *Object) bool {
+	if st, dt := snapshotTemplated(so.self), snapshotTemplated(do.self); st != nil && dt != nil {
+		if st.protoMaterialised {
+			dt.materialiseProto()
+		} else {
+			dt.protoMaterialised, dt.prototype = false, nil
+		}
+		for name := range dt.values {
+			if _, exists := st.values[name]; !exists {
+				if _, exists := dt.tmpl.props[name]; exists {
+					delete(dt.values, name)
+				}
+			}
+		}
+		if st.symValues == nil {
+			dt.symValues = nil
+		} else {
+			dt.materialiseSymbols()
+		}
+		if st.propNames == nil {
+			dt.propNames = nil
+		} else {
+			dt.materialisePropNames()
+		}
+	}
+	clear(b.seen)
+	return b.equal(ifaceData(unsafe.Pointer(&so.self)), ifaceData(unsafe.Pointer(&do.self)), reflect.TypeOf(so.self).Elem())
+}
+
+func snapshotTemplated(impl objectImpl) *templatedObject {
+	switch o := impl.(type) {
+	case *templatedObject:
+		return o
+	case *templatedFuncObject:
+		return &o.templatedObject
+	case *templatedArrayObject:
+		return &o.templatedObject
+	}
+	return nil
+}
+
+// equal reports whether the values of type t at p (in the source heap) and q (in the new Runtime) are the same,
+// treating the anchored objects as equal to their counterparts.
+func (b *planBuilder) equal(p, q unsafe.Pointer, t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr:
+		x, y := *(*unsafe.Pointer)(p), *(*unsafe.Pointer)(q)
+		if x == nil || y == nil {
+			return x == y
+		}
+		switch t {
+		case typeObject:
+			i, exists := b.anchorIdx[x]
+			return exists && b.c.anchors[i].dst == (*Object)(y)
+		case typeRuntimePtr, typeHashPtr:
+			return true
+		case typeSymbolPtr, typeObjectTemplatePtr, typeBigIntPtr, typeProgramPtr, typeFilePtr:
+			return x == y
+		}
+		if seen, exists := b.seen[x]; exists {
+			return seen == y
+		}
+		b.seen[x] = y
+		return b.equal(x, y, t.Elem())
+	case reflect.Interface:
+		if *(*unsafe.Pointer)(p) != *(*unsafe.Pointer)(q) {
+			return false
+		}
+		v := reflect.NewAt(t, p).Elem()
+		if v.IsNil() {
+			return true
+		}
+		dt := v.Elem().Type()
+		if directIface(dt) {
+			off := unsafe.Sizeof(uintptr(0))
+			return b.equal(unsafe.Add(p, off), unsafe.Add(q, off), dt)
+		}
+		return b.equal(ifaceData(p), ifaceData(q), dt)
+	case reflect.Struct:
+		if t == typeOrderedMap {
+			return b.equalOrderedMap((*orderedMap)(p), (*orderedMap)(q))
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !b.equal(unsafe.Add(p, f.Offset), unsafe.Add(q, f.Offset), f.Type) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array:
+		et := t.Elem()
+		for i := 0; i < t.Len(); i++ {
+			off := uintptr(i) * et.Size()
+			if !b.equal(unsafe.Add(p, off), unsafe.Add(q, off), et) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		x, y := reflect.NewAt(t, p).Elem(), reflect.NewAt(t, q).Elem()
+		if x.IsNil() != y.IsNil() || x.Len() != y.Len() {
+			return false
+		}
+		if x.Len() == 0 {
+			return true
+		}
+		return b.equal(x.UnsafePointer(), y.UnsafePointer(), reflect.ArrayOf(x.Len(), t.Elem()))
+	case reflect.Map:
+		x, y := reflect.NewAt(t, p).Elem(), reflect.NewAt(t, q).Elem()
+		if x.IsNil() != y.IsNil() || x.Len() != y.Len() {
+			return false
+		}
+		iter := x.MapRange()
+		for iter.Next() {
+			yv := y.MapIndex(iter.Key())
+			if !yv.IsValid() {
+				return false
+			}
+			cx, cy := reflect.New(t.Elem()), reflect.New(t.Elem())
+			cx.Elem().Set(iter.Value())
+			cy.Elem().Set(yv)
+			if !b.equal(cx.UnsafePointer(), cy.UnsafePointer(), t.Elem()) {
+				return false
+			}
+		}
+		return true
+	case reflect.Func:
+		// the Go functions of the built-ins are bound to their Runtimes, they have been compared by anchorBuiltin
+		return true
+	case reflect.String:
+		return *(*string)(p) == *(*string)(q)
+	case reflect.Chan, reflect.UnsafePointer:
+		return *(*unsafe.Pointer)(p) == *(*unsafe.Pointer)(q)
+	}
+	return unsafe.String((*byte)(p), t.Size()) == unsafe.String((*byte)(q), t.Size())
+}
+
+func (b *planBuilder) equalOrderedMap(x, y *orderedMap) bool {
+	if x.size != y.size {
+		return false
+	}
+	for ex, ey := x.iterFirst, y.iterFirst; ex != nil || ey != nil; ex, ey = ex.iterNext, ey.iterNext {
+		if ex == nil || ey == nil ||
+			!b.equal(unsafe.Pointer(&ex.key), unsafe.Pointer(&ey.key), typeValue) ||
+			!b.equal(unsafe.Pointer(&ex.value), unsafe.Pointer(&ey.value), typeValue) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,425 @@
+package goja
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	r := New()
+	_, err := r.RunString(`
+	var counter = 0;
+	let greeting = "hello";
+	const re = /a(b)/g;
+	class Point {
+		#x;
+		constructor(x) { this.#x = x; }
+		get x() { return this.#x; }
+	}
+	function inc() { return ++counter; }
+	const key = {};
+	const m = new Map([["a", 1], [key, 2]]);
+	const s = new Set(["x"]);
+	const buf = new ArrayBuffer(4);
+	const u8 = new Uint8Array(buf);
+	u8.set([1, 2, 3]);
+	const bound = inc.bind(null);
+	Array.prototype.sum = function() { return this.reduce((a, b) => a + b, 0); };
+	const sym = Symbol.for("k");
+	const obj = {[sym]: 1, get g() { return 2; }};
+	const wm = new WeakMap([[obj, 5]]);
+	const p = new Proxy(function() { return 1; }, {get: (t, k) => k});
+	const err = new TypeError("boom");
+	const max = Math.max;
+	delete Math.min;
+	[1, 2].map(x => x); // materialise Array.prototype.map
+	const builtinKeys = new Map([[Math, "math"]]);
+	const weakBuiltinKeys = new WeakMap([[JSON, 1]]);
+	Array.prototype.map.tag = "t";
+	function makeCounter() {
+		let c = 0;
+		return [() => ++c, () => c];
+	}
+	const [incShared, getShared] = makeCounter();
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := r.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RunString(`counter = 100; m.set("a", 100);`); err != nil {
+		t.Fatal(err)
+	}
+
+	const check = `
+	assert.sameValue(counter, 0, "counter");
+	assert.sameValue(greeting, "hello");
+	assert.sameValue(re.exec("ab")[1], "b", "RegExp");
+	assert.sameValue(re.lastIndex, 2, "RegExp lastIndex");
+	assert.sameValue(new Point(3).x, 3, "class");
+	assert.sameValue(m.get("a"), 1, "Map");
+	assert.sameValue(m.get(key), 2, "Map object key");
+	assert(s.has("x"), "Set");
+	assert.sameValue(u8[1], 2, "Uint8Array");
+	assert.sameValue(u8.buffer, buf, "typed array buffer");
+	new Uint8Array(buf)[0] = 42;
+	assert.sameValue(u8[0], 42, "shared buffer");
+	assert.sameValue(bound(), 1, "bound function");
+	assert.sameValue(Object.getPrototypeOf(bound), Function.prototype);
+	assert.sameValue([1, 2, 3].sum(), 6, "modified built-in");
+	assert.sameValue(Symbol.for("k"), sym, "symbol registry");
+	assert.sameValue(obj[sym], 1);
+	assert.sameValue(obj.g, 2);
+	assert.sameValue(wm.get(obj), 5, "WeakMap");
+	assert.sameValue(p.foo, "foo", "Proxy");
+	assert.sameValue(p(), 1, "callable Proxy");
+	assert(err instanceof TypeError, "Error");
+	assert.sameValue(err.message, "boom");
+	assert.sameValue(max, Math.max, "built-in identity");
+	assert.sameValue(max(1, 2), 2);
+	assert.sameValue(Math.min, undefined, "deleted built-in");
+	assert.sameValue(Array.prototype.map.call([1], x => x + 1)[0], 2);
+	assert.sameValue(JSON.stringify({a: [1]}), '{"a":[1]}');
+	assert.sameValue(builtinKeys.get(Math), "math", "built-in Map key");
+	assert.sameValue(weakBuiltinKeys.get(JSON), 1, "built-in WeakMap key");
+	assert.sameValue(Array.prototype.map.tag, "t", "modified built-in function");
+	incShared();
+	assert.sameValue(getShared(), 1, "shared scope");
+	assert.sameValue(String.prototype.leaked, undefined, "untouched built-in");
+	String.prototype.leaked = 1;
+	inc();
+	m.set("b", 3);
+	greeting = "bye";
+	counter === 2 && m.get("b") === 3;
+	`
+
+	for i := 0; i < 2; i++ {
+		snap.NewRuntime().testScriptWithTestLib(check, valueTrue, t)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r1 := snap.NewRuntime()
+			v, err := r1.RunString(`inc() + [1, 2].sum()`)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if v.ToInteger() != 4 {
+				t.Errorf("unexpected result: %v", v)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSnapshotBootstrap(t *testing.T) {
+	const check = `
+	const results = [
+		util.crc32("The quick brown fox jumps over the lazy dog"),
+		util.escape("<a href='x'>&</a>"),
+		util.unescape("&lt;&euro;&unknown;"),
+		util.isPrime(4999), util.isPrime(4997),
+		util.classify("a1 ?").join(),
+		util.camelCase("snake_case-name"),
+		JSON.stringify(util.pick({a: 1, b: 2}, "b", "c")),
+		dispatch("GET", "/orders/42"), dispatch("DELETE", "/users/7"), dispatch("PUT", "/users"),
+		JSON.stringify(config), Object.isFrozen(config),
+	];
+	try {
+		requestSchema({id: "1", user: {name: "n", age: "x"}, tags: []}, "req");
+	} catch (e) {
+		results.push(e instanceof ValidationError, e instanceof Error, e.message);
+	}
+	events.emit("request");
+	events.emit("request");
+	results.push(handled);
+	JSON.stringify(results);
+	`
+	r := New()
+	if _, err := r.RunString(snapshotBootstrap); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := r.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := r.RunString(check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		v, err := snap.NewRuntime().RunString(check)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !v.SameAs(expected) {
+			t.Fatalf("%d: %v != %v", i, v, expected)
+		}
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	r := New()
+	r.Set("f", func() {})
+	if _, err := r.Snapshot(); err == nil {
+		t.Fatal("expected an error for a Go function")
+	}
+
+	r = New()
+	r.Set("o", map[string]interface{}{})
+	if _, err := r.Snapshot(); err == nil {
+		t.Fatal("expected an error for a Go value")
+	}
+
+	r = New()
+	r.Set("snapshot", func() bool {
+		_, err := r.Snapshot()
+		return errors.Is(err, errSnapshotBusy)
+	})
+	v, err := r.RunString(`snapshot()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.ToBoolean() {
+		t.Fatal("expected an error while running")
+	}
+}
+
+func TestSnapshotIntrinsics(t *testing.T) {
+	// the fields of global that are not intrinsic objects and are handled by cloneRuntime explicitly
+	handled := map[string]bool{
+		"stash":          true,
+		"stdRegexpProto": true,
+	}
+	typ := reflect.TypeOf(global{})
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Type == typeObject {
+			if _, exists := snapshotIntrinsics[f.Name]; !exists {
+				t.Errorf("global.%s is not in snapshotIntrinsics", f.Name)
+			}
+		} else if !handled[f.Name] {
+			t.Errorf("global.%s (%s) is not handled by cloneRuntime", f.Name, f.Type)
+		}
+	}
+	for name := range snapshotIntrinsics {
+		if f, exists := typ.FieldByName(name); !exists || f.Type != typeObject {
+			t.Errorf("snapshotIntrinsics: global.%s does not exist", name)
+		}
+	}
+
+	// all the intrinsics are created in the source Runtime, which is the case the mapping matters most in
+	r := New()
+	g := reflect.ValueOf(&r.global).Elem()
+	for name, get := range snapshotIntrinsics {
+		if get != nil {
+			get(r)
+		}
+		if g.FieldByName(name).IsNil() {
+			t.Errorf("snapshotIntrinsics: %s does not create global.%s", name, name)
+		}
+	}
+	snap, err := r.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r1 := snap.NewRuntime()
+	g1 := reflect.ValueOf(&r1.global).Elem()
+	for name, get := range snapshotIntrinsics {
+		// the ones that have not been modified are created lazily, as in a new Runtime
+		if get != nil {
+			get(r1)
+		}
+		if o := accessible(g1.FieldByName(name)).Interface().(*Object); o == accessible(g.FieldByName(name)).Interface().(*Object) || o.runtime != r1 {
+			t.Errorf("global.%s refers to the source Runtime", name)
+		}
+	}
+}
+
+// snapshotBootstrap resembles the library code a service runs before the user scripts: helpers, classes,
+// lookup tables computed at start-up and some configuration.
+const snapshotBootstrap = `
+"use strict";
+const util = (function() {
+	const crcTable = new Int32Array(256);
+	for (let n = 0; n < 256; n++) {
+		let c = n;
+		for (let k = 0; k < 8; k++) {
+			c = c & 1 ? 0xEDB88320 ^ (c >>> 1) : c >>> 1;
+		}
+		crcTable[n] = c;
+	}
+	const entities = {};
+	const named = ["amp", "lt", "gt", "quot", "apos", "nbsp", "copy", "reg", "deg", "plusmn", "micro", "para",
+		"middot", "frac14", "frac12", "frac34", "times", "divide", "euro", "trade"];
+	const codes = [38, 60, 62, 34, 39, 160, 169, 174, 176, 177, 181, 182, 183, 188, 189, 190, 215, 247, 8364, 8482];
+	named.forEach((name, i) => { entities[name] = String.fromCharCode(codes[i]); });
+	const reverseEntities = new Map(Object.entries(entities).map(([k, v]) => [v, "&" + k + ";"]));
+	const primes = [];
+	const maxPrime = 5000;
+	const sieve = new Uint8Array(maxPrime);
+	for (let i = 2; i < maxPrime; i++) {
+		if (!sieve[i]) {
+			primes.push(i);
+			for (let j = i * i; j < maxPrime; j += i) {
+				sieve[j] = 1;
+			}
+		}
+	}
+	const charClass = [];
+	for (let i = 0; i < 128; i++) {
+		const ch = String.fromCharCode(i);
+		charClass.push(/[a-z]/i.test(ch) ? 1 : /[0-9]/.test(ch) ? 2 : /\s/.test(ch) ? 3 : 0);
+	}
+	return {
+		crc32(s) {
+			let crc = -1;
+			for (let i = 0; i < s.length; i++) {
+				crc = crcTable[(crc ^ s.charCodeAt(i)) & 0xFF] ^ (crc >>> 8);
+			}
+			return (crc ^ -1) >>> 0;
+		},
+		escape(s) {
+			return s.replace(/[&<>"']/g, ch => reverseEntities.get(ch));
+		},
+		unescape(s) {
+			return s.replace(/&(\w+);/g, (m, name) => entities[name] || m);
+		},
+		isPrime(n) {
+			return primes.indexOf(n) >= 0;
+		},
+		classify(s) {
+			return Array.from(s, ch => charClass[ch.charCodeAt(0)] || 0);
+		},
+		camelCase(s) {
+			return s.replace(/[-_](\w)/g, (m, c) => c.toUpperCase());
+		},
+		pick(obj, ...keys) {
+			return Object.fromEntries(keys.filter(k => k in obj).map(k => [k, obj[k]]));
+		},
+		deepMerge(target, source) {
+			for (const [k, v] of Object.entries(source)) {
+				target[k] = v && typeof v === "object" && !Array.isArray(v) ? util.deepMerge(target[k] || {}, v) : v;
+			}
+			return target;
+		},
+	};
+})();
+
+class EventEmitter {
+	#listeners = new Map();
+	on(name, fn) {
+		if (!this.#listeners.has(name)) {
+			this.#listeners.set(name, []);
+		}
+		this.#listeners.get(name).push(fn);
+		return this;
+	}
+	emit(name, ...args) {
+		for (const fn of this.#listeners.get(name) || []) {
+			fn(...args);
+		}
+	}
+}
+
+class ValidationError extends Error {
+	constructor(path, message) {
+		super(path + ": " + message);
+		this.path = path;
+	}
+}
+
+const schema = {
+	string: () => (v, path) => { if (typeof v !== "string") throw new ValidationError(path, "not a string"); },
+	number: () => (v, path) => { if (typeof v !== "number") throw new ValidationError(path, "not a number"); },
+	object: (fields) => (v, path) => {
+		for (const [k, check] of Object.entries(fields)) {
+			check(v[k], path + "." + k);
+		}
+	},
+	array: (item) => (v, path) => v.forEach((x, i) => item(x, path + "[" + i + "]")),
+};
+
+const requestSchema = schema.object({
+	id: schema.string(),
+	user: schema.object({name: schema.string(), age: schema.number()}),
+	tags: schema.array(schema.string()),
+});
+
+const routes = [];
+function route(method, pattern, handler) {
+	const names = [];
+	const re = new RegExp("^" + pattern.replace(/:(\w+)/g, (m, name) => { names.push(name); return "([^/]+)"; }) + "$");
+	routes.push({method, re, names, handler});
+}
+["users", "orders", "products", "invoices", "reports", "settings"].forEach(res => {
+	route("GET", "/" + res, () => res);
+	route("GET", "/" + res + "/:id", params => res + " " + params.id);
+	route("POST", "/" + res, () => "created");
+	route("DELETE", "/" + res + "/:id", params => "deleted " + params.id);
+});
+
+function dispatch(method, path) {
+	for (const r of routes) {
+		const m = r.method === method && r.re.exec(path);
+		if (m) {
+			return r.handler(Object.fromEntries(r.names.map((name, i) => [name, m[i + 1]])));
+		}
+	}
+	return null;
+}
+
+const config = Object.freeze(util.deepMerge({
+	limits: {requests: 100, size: 1 << 20},
+	locale: {language: "en", currency: "EUR"},
+}, {
+	limits: {requests: 1000},
+	features: {escape: true, validate: true},
+}));
+
+const events = new EventEmitter();
+let handled = 0;
+events.on("request", () => handled++);
+`
+
+func BenchmarkSnapshot(b *testing.B) {
+	prg := MustCompile("bootstrap.js", snapshotBootstrap, false)
+	b.Run("compile", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r := New()
+			if _, err := r.RunString(snapshotBootstrap); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("run", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r := New()
+			if _, err := r.RunProgram(prg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("snapshot", func(b *testing.B) {
+		r := New()
+		if _, err := r.RunProgram(prg); err != nil {
+			b.Fatal(err)
+		}
+		snap, err := r.Snapshot()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			snap.NewRuntime()
+		}
+	})
+}
@@ -210,6 +210,14 @@ func (b *StringBuilder) ascii() bool {
 	return len(b.unicodeBuilder.buf) == 0
 }
 
+// memSize returns the approximate number of bytes used by the string being built.
+func (b *StringBuilder) memSize() int {
+	if b.ascii() {
+		return b.asciiBuilder.Len()
+	}
+	return len(b.unicodeBuilder.buf) * 2
+}
+
 func (b *StringBuilder) WriteString(s String) {
 	a, u := devirtualizeString(s)
 	if u != nil {
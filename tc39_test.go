@@ -303,7 +303,6 @@ var (
 	featuresBlackList = []string{
 		"async-iteration",
 		"Symbol.asyncIterator",
-		"resizable-arraybuffer",
 		"regexp-named-groups",
 		"regexp-duplicate-named-groups",
 		"regexp-unicode-property-escapes",
@@ -326,30 +325,35 @@ var (
 		"ShadowRealm",
 		"SharedArrayBuffer",
 		"decorators",
-		"immutable-arraybuffer",
-		"joint-iteration",
-		"iterator-sequencing",
 
 		"regexp-duplicate-named-groups",
 		"regexp-v-flag",
-		"iterator-helpers",
 		"symbols-as-weakmap-keys",
-		"uint8array-base64",
 		"String.prototype.toWellFormed",
 		"explicit-resource-management",
-		"set-methods",
-		"promise-try",
-		"promise-with-resolvers",
-		"array-grouping",
 		"Math.sumPrecise",
-		"Float16Array",
-		"arraybuffer-transfer",
-		"Array.fromAsync",
 		"String.prototype.isWellFormed",
 
 		"source-phase-imports",
 		"import-attributes",
 		"import-defer",
+
+		// Implemented, but not yet verified against test262 at the commit in .tc39_test262_checkout.sh (the suite
+		// could not be fetched when they were added). Remove after running it, moving the remaining failures into
+		// skipList with a reason.
+		"iterator-helpers",
+		"joint-iteration",
+		"iterator-sequencing",
+		"set-methods",
+		"array-grouping",
+		"promise-try",
+		"promise-with-resolvers",
+		"Array.fromAsync",
+		"Float16Array",
+		"uint8array-base64",
+		"resizable-arraybuffer",
+		"arraybuffer-transfer",
+		"immutable-arraybuffer",
 	}
 )
 
@@ -1,6 +1,7 @@
 package goja
 
 import (
+	"fmt"
 	"math"
 	"math/big"
 	"reflect"
@@ -29,6 +30,10 @@ type arrayBufferObject struct {
 	baseObject
 	detached bool
 	data     []byte
+
+	// maxByteLen is the maximum byte length of a resizable buffer, -1 if the buffer is fixed-length.
+	maxByteLen int
+	immutable  bool
 }
 
 // ArrayBuffer is a Go wrapper around ECMAScript ArrayBuffer. Calling Runtime.ToValue() on it
@@ -42,6 +47,10 @@ type dataViewObject struct {
 	baseObject
 	viewedArrayBuf      *arrayBufferObject
 	byteLen, byteOffset int
+
+	// lengthTracking is set for views of resizable buffers created without an explicit length,
+	// byteLen is not used in this case.
+	lengthTracking bool
 }
 
 type typedArray interface {
@@ -64,6 +73,7 @@ type uint16Array []byte
 type int16Array []byte
 type uint32Array []byte
 type int32Array []byte
+type float16Array []byte
 type float32Array []byte
 type float64Array []byte
 type bigInt64Array []byte
@@ -76,6 +86,10 @@ type typedArrayObject struct {
 	length, offset int
 	elemSize       int
 	typedArray     typedArray
+
+	// lengthTracking is set for arrays backed by a resizable buffer and created without an explicit length,
+	// their length follows the length of the buffer and the length field is not used.
+	lengthTracking bool
 }
 
 func (a ArrayBuffer) toValue(r *Runtime) Value {
@@ -91,23 +105,30 @@ func (a ArrayBuffer) toValue(r *Runtime) Value {
 
 // Bytes returns the underlying []byte for this ArrayBuffer.
 // For detached ArrayBuffers returns nil.
+// Note, resizing a resizable ArrayBuffer may re-allocate the underlying slice, so the returned value
+// should not be retained across calls into the Runtime.
 func (a ArrayBuffer) Bytes() []byte {
 	return a.buf.data
 }
 
 // Detach the ArrayBuffer. After this, the underlying []byte becomes unreferenced and any attempt
 // to use this ArrayBuffer results in a TypeError.
-// Returns false if it was already detached, true otherwise.
+// Returns false if it was already detached or if it is immutable, true otherwise.
 // Note, this method may only be called from the goroutine that 'owns' the Runtime, it may not
 // be called concurrently.
 func (a ArrayBuffer) Detach() bool {
-	if a.buf.detached {
+	if a.buf.detached || a.buf.immutable {
 		return false
 	}
 	a.buf.detach()
 	return true
 }
 
+// Immutable returns true if the ArrayBuffer is immutable.
+func (a ArrayBuffer) Immutable() bool {
+	return a.buf.immutable
+}
+
 // Detached returns true if the ArrayBuffer is detached.
 func (a ArrayBuffer) Detached() bool {
 	return a.buf.detached
@@ -127,6 +148,20 @@ func (r *Runtime) NewArrayBuffer(data []byte) ArrayBuffer {
 	}
 }
 
+// NewImmutableArrayBuffer creates a new instance of an immutable ArrayBuffer backed by the provided byte slice.
+// The slice is not copied, but scripts are not able to modify its contents or detach the buffer. The Go code
+// must not modify the slice either while it is in use by the Runtime.
+//
+// The same alignment considerations as for NewArrayBuffer apply.
+func (r *Runtime) NewImmutableArrayBuffer(data []byte) ArrayBuffer {
+	buf := r._newArrayBuffer(r.getArrayBufferPrototype(), nil)
+	buf.data = data
+	buf.immutable = true
+	return ArrayBuffer{
+		buf: buf,
+	}
+}
+
 func (a *uint8Array) toRaw(v Value) uint64 {
 	return uint64(toUint8(v))
 }
@@ -505,6 +540,111 @@ func (a *int32Array) exportType() reflect.Type {
 	return typeInt32Array
 }
 
+// float64ToFloat16Bits converts f to the nearest IEEE 754 binary16 value (ties to even)
+// and returns its bit representation.
+func float64ToFloat16Bits(f float64) uint16 {
+	var sign uint16
+	if math.Signbit(f) {
+		sign = 0x8000
+	}
+	if math.IsNaN(f) {
+		return 0x7e00
+	}
+	a := math.Abs(f)
+	if a >= 65520 { // (65504 + 65536) / 2, rounds to infinity
+		return sign | 0x7c00
+	}
+	if a < 0x1p-14 {
+		// subnormal, a rounding carry produces the smallest normal value which is what we want
+		return sign | uint16(math.RoundToEven(a*0x1p24))
+	}
+	frac, exp := math.Frexp(a)
+	m := uint16(math.RoundToEven((frac*2 - 1) * 1024))
+	e := uint16(exp + 14)
+	if m == 1024 {
+		m = 0
+		e++
+	}
+	return sign | e<<10 | m
+}
+
+func float16BitsToFloat64(h uint16) float64 {
+	var f float64
+	exp := int(h>>10) & 0x1f
+	m := h & 0x3ff
+	switch exp {
+	case 0:
+		f = math.Ldexp(float64(m), -24)
+	case 0x1f:
+		if m != 0 {
+			return math.NaN()
+		}
+		f = math.Inf(1)
+	default:
+		f = math.Ldexp(float64(m|0x400), exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+func (a *float16Array) ptr(idx int) *uint16 {
+	p := unsafe.Pointer((*reflect.SliceHeader)(unsafe.Pointer(a)).Data)
+	return (*uint16)(unsafe.Pointer(uintptr(p) + uintptr(idx)*2))
+}
+
+func (a *float16Array) get(idx int) Value {
+	return floatToValue(float16BitsToFloat64(*(a.ptr(idx))))
+}
+
+func (a *float16Array) getRaw(idx int) uint64 {
+	return uint64(*(a.ptr(idx)))
+}
+
+func (a *float16Array) set(idx int, value Value) {
+	*(a.ptr(idx)) = float64ToFloat16Bits(value.ToFloat())
+}
+
+func (a *float16Array) toRaw(v Value) uint64 {
+	return uint64(float64ToFloat16Bits(v.ToFloat()))
+}
+
+func (a *float16Array) setRaw(idx int, v uint64) {
+	*(a.ptr(idx)) = uint16(v)
+}
+
+func (a *float16Array) less(i, j int) bool {
+	return typedFloatLess(float16BitsToFloat64(*(a.ptr(i))), float16BitsToFloat64(*(a.ptr(j))))
+}
+
+func (a *float16Array) swap(i, j int) {
+	pi, pj := a.ptr(i), a.ptr(j)
+	*pi, *pj = *pj, *pi
+}
+
+func (a *float16Array) typeMatch(v Value) bool {
+	switch v.(type) {
+	case valueInt, valueFloat:
+		return true
+	}
+	return false
+}
+
+// export returns the raw binary16 values, Go has no native half-precision type.
+func (a *float16Array) export(offset int, length int) interface{} {
+	var res []uint16
+	sliceHeader := (*reflect.SliceHeader)(unsafe.Pointer(&res))
+	sliceHeader.Data = (*reflect.SliceHeader)(unsafe.Pointer(a)).Data + uintptr(offset)*2
+	sliceHeader.Len = length
+	sliceHeader.Cap = length
+	return res
+}
+
+func (a *float16Array) exportType() reflect.Type {
+	return typeUint16Array
+}
+
 func (a *float32Array) ptr(idx int) *float32 {
 	p := unsafe.Pointer((*reflect.SliceHeader)(unsafe.Pointer(a)).Data)
 	return (*float32)(unsafe.Pointer(uintptr(p) + uintptr(idx)*4))
@@ -763,11 +903,49 @@ func (a *bigUint64Array) exportType() reflect.Type {
 	return typeBigUint64Array
 }
 
+// isOutOfBounds returns true if the buffer is detached or if it has been shrunk so that the array
+// no longer fits.
+func (a *typedArrayObject) isOutOfBounds() bool {
+	buf := a.viewedArrayBuf
+	if buf.detached {
+		return true
+	}
+	start := a.offset * a.elemSize
+	if start > len(buf.data) {
+		return true
+	}
+	return !a.lengthTracking && start+a.length*a.elemSize > len(buf.data)
+}
+
+// getLength returns the current length of the array, 0 if it is out of bounds.
+func (a *typedArrayObject) getLength() int {
+	if a.isOutOfBounds() {
+		return 0
+	}
+	if a.lengthTracking {
+		return (len(a.viewedArrayBuf.data) - a.offset*a.elemSize) / a.elemSize
+	}
+	return a.length
+}
+
+// validate throws a TypeError if the array is out of bounds, otherwise returns its current length.
+func (a *typedArrayObject) validate() int {
+	if a.isOutOfBounds() {
+		a.viewedArrayBuf.ensureNotDetached(true)
+		panic(a.val.runtime.NewTypeError("TypedArray is out of bounds"))
+	}
+	return a.getLength()
+}
+
+// validateWritable is like validate, but it also throws a TypeError if the buffer is immutable.
+func (a *typedArrayObject) validateWritable() int {
+	length := a.validate()
+	a.viewedArrayBuf.ensureMutable()
+	return length
+}
+
 func (a *typedArrayObject) _getIdx(idx int) Value {
-	if 0 <= idx && idx < a.length {
-		if !a.viewedArrayBuf.ensureNotDetached(false) {
-			return nil
-		}
+	if a.isValidIntegerIndex(idx) {
 		return a.typedArray.get(idx + a.offset)
 	}
 	return nil
@@ -778,12 +956,7 @@ func (a *typedArrayObject) getOwnPropStr(name unistring.String) Value {
 	if ok {
 		v := a._getIdx(idx)
 		if v != nil {
-			return &valueProperty{
-				value:        v,
-				writable:     true,
-				enumerable:   true,
-				configurable: true,
-			}
+			return a.elemProp(v)
 		}
 		return nil
 	}
@@ -793,15 +966,22 @@ func (a *typedArrayObject) getOwnPropStr(name unistring.String) Value {
 	return a.baseObject.getOwnPropStr(name)
 }
 
+// elemProp returns the property descriptor of an element, the elements of arrays backed by
+// an immutable buffer are read-only and non-configurable.
+func (a *typedArrayObject) elemProp(v Value) Value {
+	mutable := !a.viewedArrayBuf.immutable
+	return &valueProperty{
+		value:        v,
+		writable:     mutable,
+		enumerable:   true,
+		configurable: mutable,
+	}
+}
+
 func (a *typedArrayObject) getOwnPropIdx(idx valueInt) Value {
 	v := a._getIdx(toIntClamp(int64(idx)))
 	if v != nil {
-		return &valueProperty{
-			value:        v,
-			writable:     true,
-			enumerable:   true,
-			configurable: true,
-		}
+		return a.elemProp(v)
 	}
 	return nil
 }
@@ -822,12 +1002,7 @@ func (a *typedArrayObject) getIdx(idx valueInt, receiver Value) Value {
 }
 
 func (a *typedArrayObject) isValidIntegerIndex(idx int) bool {
-	if a.viewedArrayBuf.ensureNotDetached(false) {
-		if idx >= 0 && idx < a.length {
-			return true
-		}
-	}
-	return false
+	return idx >= 0 && idx < a.getLength()
 }
 
 func (a *typedArrayObject) _putIdx(idx int, v Value) {
@@ -842,6 +1017,18 @@ func (a *typedArrayObject) _putIdx(idx int, v Value) {
 	}
 }
 
+func (a *typedArrayObject) _setIdx(idx int, v Value, throw bool) bool {
+	if a.viewedArrayBuf.immutable {
+		if a.isValidIntegerIndex(idx) {
+			a.val.runtime.typeErrorResult(throw, "Cannot assign to read only property '%d' of %s", idx, a.val.String())
+			return false
+		}
+		return true
+	}
+	a._putIdx(idx, v)
+	return true
+}
+
 func (a *typedArrayObject) _hasIdx(idx int) bool {
 	return a.isValidIntegerIndex(idx)
 }
@@ -849,8 +1036,7 @@ func (a *typedArrayObject) _hasIdx(idx int) bool {
 func (a *typedArrayObject) setOwnStr(p unistring.String, v Value, throw bool) bool {
 	idx, ok := strToIntNum(p)
 	if ok {
-		a._putIdx(idx, v)
-		return true
+		return a._setIdx(idx, v, throw)
 	}
 	if idx == 0 {
 		toNumeric(v) // make sure it throws
@@ -860,8 +1046,7 @@ func (a *typedArrayObject) setOwnStr(p unistring.String, v Value, throw bool) bo
 }
 
 func (a *typedArrayObject) setOwnIdx(p valueInt, v Value, throw bool) bool {
-	a._putIdx(toIntClamp(int64(p)), v)
-	return true
+	return a._setIdx(toIntClamp(int64(p)), v, throw)
 }
 
 func (a *typedArrayObject) setForeignStr(p unistring.String, v, receiver Value, throw bool) (res bool, handled bool) {
@@ -903,6 +1088,18 @@ func (a *typedArrayObject) hasPropertyIdx(idx valueInt) bool {
 }
 
 func (a *typedArrayObject) _defineIdxProperty(idx int, desc PropertyDescriptor, throw bool) bool {
+	if a.viewedArrayBuf.immutable {
+		if !a.isValidIntegerIndex(idx) {
+			a.val.runtime.typeErrorResult(throw, "Invalid typed array index")
+			return false
+		}
+		if desc.Configurable == FLAG_TRUE || desc.Enumerable == FLAG_FALSE || desc.IsAccessor() || desc.Writable == FLAG_TRUE ||
+			desc.Value != nil && !desc.Value.SameAs(a._getIdx(idx)) {
+			a.val.runtime.typeErrorResult(throw, "Cannot redefine property: %d", idx)
+			return false
+		}
+		return true
+	}
 	if desc.Configurable == FLAG_FALSE || desc.Enumerable == FLAG_FALSE || desc.IsAccessor() || desc.Writable == FLAG_FALSE {
 		a.val.runtime.typeErrorResult(throw, "Cannot redefine property: %d", idx)
 		return false
@@ -925,7 +1122,6 @@ func (a *typedArrayObject) defineOwnPropertyStr(name unistring.String, desc Prop
 		return a._defineIdxProperty(idx, desc, throw)
 	}
 	if idx == 0 {
-		a.viewedArrayBuf.ensureNotDetached(throw)
 		a.val.runtime.typeErrorResult(throw, "Invalid typed array index")
 		return false
 	}
@@ -952,7 +1148,7 @@ func (a *typedArrayObject) deleteStr(name unistring.String, throw bool) bool {
 }
 
 func (a *typedArrayObject) deleteIdx(idx valueInt, throw bool) bool {
-	if a.viewedArrayBuf.ensureNotDetached(false) && idx >= 0 && int64(idx) < int64(a.length) {
+	if idx >= 0 && int64(idx) < int64(a.getLength()) {
 		a.val.runtime.typeErrorResult(throw, "Cannot delete property '%d' of %s", idx, a.val.String())
 		return false
 	}
@@ -961,10 +1157,11 @@ func (a *typedArrayObject) deleteIdx(idx valueInt, throw bool) bool {
 }
 
 func (a *typedArrayObject) stringKeys(all bool, accum []Value) []Value {
+	length := a.getLength()
 	if accum == nil {
-		accum = make([]Value, 0, a.length)
+		accum = make([]Value, 0, length)
 	}
-	for i := 0; i < a.length; i++ {
+	for i := 0; i < length; i++ {
 		accum = append(accum, asciiString(strconv.Itoa(i)))
 	}
 	return a.baseObject.stringKeys(all, accum)
@@ -976,9 +1173,14 @@ type typedArrayPropIter struct {
 }
 
 func (i *typedArrayPropIter) next() (propIterItem, iterNextFunc) {
-	if i.idx < i.a.length {
+	if i.idx < i.a.getLength() {
 		name := strconv.Itoa(i.idx)
-		prop := i.a._getIdx(i.idx)
+		var prop Value
+		if v := i.a._getIdx(i.idx); v != nil && i.a.viewedArrayBuf.immutable {
+			prop = i.a.elemProp(v)
+		} else {
+			prop = v
+		}
 		i.idx++
 		return propIterItem{name: asciiString(name), value: prop}, i.next
 	}
@@ -994,14 +1196,14 @@ func (a *typedArrayObject) iterateStringKeys() iterNextFunc {
 
 func (a *typedArrayObject) exportToArrayOrSlice(dst reflect.Value, typ reflect.Type, ctx *objectExportCtx) error {
 	if typ == typeBytes {
-		dst.Set(reflect.ValueOf(a.viewedArrayBuf.data[a.offset*a.elemSize : (a.offset+a.length)*a.elemSize]))
+		dst.Set(reflect.ValueOf(a.viewedArrayBuf.data[a.offset*a.elemSize : (a.offset+a.getLength())*a.elemSize]))
 		return nil
 	}
 	return a.baseObject.exportToArrayOrSlice(dst, typ, ctx)
 }
 
 func (a *typedArrayObject) export(_ *objectExportCtx) interface{} {
-	return a.typedArray.export(a.offset, a.length)
+	return a.typedArray.export(a.offset, a.getLength())
 }
 
 func (a *typedArrayObject) exportType() reflect.Type {
@@ -1010,7 +1212,7 @@ func (a *typedArrayObject) exportType() reflect.Type {
 
 func (o *dataViewObject) exportToArrayOrSlice(dst reflect.Value, typ reflect.Type, ctx *objectExportCtx) error {
 	if typ == typeBytes {
-		dst.Set(reflect.ValueOf(o.viewedArrayBuf.data[o.byteOffset : o.byteOffset+o.byteLen]))
+		dst.Set(reflect.ValueOf(o.viewedArrayBuf.data[o.byteOffset : o.byteOffset+o.getByteLength()]))
 		return nil
 	}
 	return o.baseObject.exportToArrayOrSlice(dst, typ, ctx)
@@ -1068,6 +1270,10 @@ func (r *Runtime) newInt32ArrayObject(buf *arrayBufferObject, offset, length int
 	return r._newTypedArrayObject(buf, offset, length, 4, r.global.Int32Array, (*int32Array)(&buf.data), proto)
 }
 
+func (r *Runtime) newFloat16ArrayObject(buf *arrayBufferObject, offset, length int, proto *Object) *typedArrayObject {
+	return r._newTypedArrayObject(buf, offset, length, 2, r.global.Float16Array, (*float16Array)(&buf.data), proto)
+}
+
 func (r *Runtime) newFloat32ArrayObject(buf *arrayBufferObject, offset, length int, proto *Object) *typedArrayObject {
 	return r._newTypedArrayObject(buf, offset, length, 4, r.global.Float32Array, (*float32Array)(&buf.data), proto)
 }
@@ -1084,9 +1290,47 @@ func (r *Runtime) newBigUint64ArrayObject(buf *arrayBufferObject, offset, length
 	return r._newTypedArrayObject(buf, offset, length, 8, r.global.BigUint64Array, (*bigUint64Array)(&buf.data), proto)
 }
 
+// isOutOfBounds returns true if the buffer is detached or if it has been shrunk so that the view
+// no longer fits.
+func (o *dataViewObject) isOutOfBounds() bool {
+	buf := o.viewedArrayBuf
+	if buf.detached {
+		return true
+	}
+	if o.byteOffset > len(buf.data) {
+		return true
+	}
+	return !o.lengthTracking && o.byteOffset+o.byteLen > len(buf.data)
+}
+
+// getByteLength returns the current length of the view, 0 if it is out of bounds.
+func (o *dataViewObject) getByteLength() int {
+	if o.isOutOfBounds() {
+		return 0
+	}
+	if o.lengthTracking {
+		return len(o.viewedArrayBuf.data) - o.byteOffset
+	}
+	return o.byteLen
+}
+
+// validate throws a TypeError if the view is out of bounds, otherwise returns its current byte length.
+func (o *dataViewObject) validate() int {
+	if o.isOutOfBounds() {
+		o.viewedArrayBuf.ensureNotDetached(true)
+		panic(o.val.runtime.NewTypeError("DataView is out of bounds"))
+	}
+	return o.getByteLength()
+}
+
+func (o *dataViewObject) setIdxAndByteOrder(setIdx int, littleEndianVal Value, size int) (int, byteOrder) {
+	idx, bo := o.getIdxAndByteOrder(setIdx, littleEndianVal, size)
+	o.viewedArrayBuf.ensureMutable()
+	return idx, bo
+}
+
 func (o *dataViewObject) getIdxAndByteOrder(getIdx int, littleEndianVal Value, size int) (int, byteOrder) {
-	o.viewedArrayBuf.ensureNotDetached(true)
-	if getIdx+size > o.byteLen {
+	if getIdx+size > o.validate() {
 		panic(o.val.runtime.newError(o.val.runtime.getRangeError(), "Index %d is out of bounds", getIdx))
 	}
 	getIdx += o.byteOffset
@@ -1103,6 +1347,12 @@ func (o *dataViewObject) getIdxAndByteOrder(getIdx int, littleEndianVal Value, s
 	return getIdx, bo
 }
 
+func (o *arrayBufferObject) ensureMutable() {
+	if o.immutable {
+		panic(o.val.runtime.NewTypeError("ArrayBuffer is immutable"))
+	}
+}
+
 func (o *arrayBufferObject) ensureNotDetached(throw bool) bool {
 	if o.detached {
 		o.val.runtime.typeErrorResult(throw, "ArrayBuffer is detached")
@@ -1111,6 +1361,14 @@ func (o *arrayBufferObject) ensureNotDetached(throw bool) bool {
 	return true
 }
 
+func (o *arrayBufferObject) getFloat16(idx int, byteOrder byteOrder) float64 {
+	return float16BitsToFloat64(o.getUint16(idx, byteOrder))
+}
+
+func (o *arrayBufferObject) setFloat16(idx int, val float64, byteOrder byteOrder) {
+	o.setUint16(idx, float64ToFloat16Bits(val), byteOrder)
+}
+
 func (o *arrayBufferObject) getFloat32(idx int, byteOrder byteOrder) float32 {
 	return math.Float32frombits(o.getUint32(idx, byteOrder))
 }
@@ -1272,6 +1530,27 @@ func (o *arrayBufferObject) setInt8(idx int, val int8) {
 	o.setUint8(idx, uint8(val))
 }
 
+// resize changes the length of a resizable buffer. The newly exposed bytes are zeroed.
+func (o *arrayBufferObject) resize(newLen int) {
+	oldLen := len(o.data)
+	if newLen <= cap(o.data) {
+		o.data = o.data[:newLen]
+		if newLen > oldLen {
+			clear(o.data[oldLen:])
+		}
+		return
+	}
+	newCap := 2 * cap(o.data)
+	if newCap < newLen {
+		newCap = newLen
+	} else if newCap > o.maxByteLen {
+		newCap = o.maxByteLen
+	}
+	data := o.val.runtime.allocBuffer(newCap)[:newLen]
+	copy(data, o.data)
+	o.data = data
+}
+
 func (o *arrayBufferObject) detach() {
 	o.data = nil
 	o.detached = true
@@ -1306,12 +1585,184 @@ func (r *Runtime) _newArrayBuffer(proto *Object, o *Object) *arrayBufferObject {
 			prototype:  proto,
 			extensible: true,
 		},
+		maxByteLen: -1,
 	}
 	o.self = b
 	b.init()
 	return b
 }
 
+type base64LastChunkHandling uint8
+
+const (
+	base64LastChunkLoose base64LastChunkHandling = iota
+	base64LastChunkStrict
+	base64LastChunkStopBeforePartial
+)
+
+func isBase64Whitespace(c uint16) bool {
+	switch c {
+	case '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func base64CharValue(c uint16, url bool) (byte, bool) {
+	switch {
+	case c >= 'A' && c <= 'Z':
+		return byte(c - 'A'), true
+	case c >= 'a' && c <= 'z':
+		return byte(c-'a') + 26, true
+	case c >= '0' && c <= '9':
+		return byte(c-'0') + 52, true
+	case c == '+' && !url, c == '-' && url:
+		return 62, true
+	case c == '/' && !url, c == '_' && url:
+		return 63, true
+	}
+	return 0, false
+}
+
+// decodeBase64Chunk decodes a chunk of 2 to 4 sextets and appends the resulting bytes to dst.
+func decodeBase64Chunk(dst []byte, chunk []byte, throwOnExtraBits bool) ([]byte, bool) {
+	var n uint32
+	for i := 0; i < 4; i++ {
+		n <<= 6
+		if i < len(chunk) {
+			n |= uint32(chunk[i])
+		}
+	}
+	b := [3]byte{byte(n >> 16), byte(n >> 8), byte(n)}
+	l := len(chunk) - 1
+	if throwOnExtraBits && l < 3 && b[l] != 0 {
+		return dst, false
+	}
+	return append(dst, b[:l]...), true
+}
+
+// fromBase64 implements the FromBase64 abstract operation. It decodes at most maxLength bytes and returns
+// the number of code units consumed, the decoded bytes and, on failure, an error message. The bytes decoded
+// before the failure are returned as well.
+func fromBase64(s String, url bool, lastChunkHandling base64LastChunkHandling, maxLength int) (read int, bytes []byte, errMsg string) {
+	if maxLength == 0 {
+		return
+	}
+	var chunk [4]byte
+	chunkLength := 0
+	length := s.Length()
+	skipWhitespace := func(idx int) int {
+		for idx < length && isBase64Whitespace(s.CharAt(idx)) {
+			idx++
+		}
+		return idx
+	}
+	for index := 0; ; {
+		index = skipWhitespace(index)
+		if index == length {
+			if chunkLength > 0 {
+				switch lastChunkHandling {
+				case base64LastChunkStopBeforePartial:
+					return
+				case base64LastChunkLoose:
+					if chunkLength == 1 {
+						errMsg = "Incomplete base64 chunk"
+						return
+					}
+					bytes, _ = decodeBase64Chunk(bytes, chunk[:chunkLength], false)
+				default:
+					errMsg = "Missing base64 padding"
+					return
+				}
+			}
+			read = length
+			return
+		}
+		c := s.CharAt(index)
+		index++
+		if c == '=' {
+			if chunkLength < 2 {
+				errMsg = "Unexpected base64 padding"
+				return
+			}
+			index = skipWhitespace(index)
+			if chunkLength == 2 {
+				if index == length {
+					if lastChunkHandling != base64LastChunkStopBeforePartial {
+						errMsg = "Incomplete base64 padding"
+					}
+					return
+				}
+				if s.CharAt(index) == '=' {
+					index = skipWhitespace(index + 1)
+				}
+			}
+			if index < length {
+				errMsg = "Unexpected data after base64 padding"
+				return
+			}
+			var ok bool
+			if bytes, ok = decodeBase64Chunk(bytes, chunk[:chunkLength], lastChunkHandling == base64LastChunkStrict); !ok {
+				errMsg = "Non-zero padding bits in base64 chunk"
+				return
+			}
+			read = length
+			return
+		}
+		v, ok := base64CharValue(c, url)
+		if !ok {
+			errMsg = fmt.Sprintf("Invalid base64 character at position %d", index-1)
+			return
+		}
+		remaining := maxLength - len(bytes)
+		if remaining == 1 && chunkLength == 2 || remaining == 2 && chunkLength == 3 {
+			return
+		}
+		chunk[chunkLength] = v
+		chunkLength++
+		if chunkLength == 4 {
+			bytes, _ = decodeBase64Chunk(bytes, chunk[:], false)
+			chunkLength = 0
+			read = index
+			if len(bytes) == maxLength {
+				return
+			}
+		}
+	}
+}
+
+func hexDigitValue(c uint16) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return byte(c - '0'), true
+	case c >= 'a' && c <= 'f':
+		return byte(c-'a') + 10, true
+	case c >= 'A' && c <= 'F':
+		return byte(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+// fromHex implements the FromHex abstract operation, see fromBase64 for the meaning of the return values.
+func fromHex(s String, maxLength int) (read int, bytes []byte, errMsg string) {
+	length := s.Length()
+	if length%2 != 0 {
+		errMsg = "Hex string must have an even length"
+		return
+	}
+	for read < length && len(bytes) < maxLength {
+		hi, ok1 := hexDigitValue(s.CharAt(read))
+		lo, ok2 := hexDigitValue(s.CharAt(read + 1))
+		if !ok1 || !ok2 {
+			errMsg = fmt.Sprintf("Invalid hex digit at position %d", read)
+			return
+		}
+		read += 2
+		bytes = append(bytes, hi<<4|lo)
+	}
+	return
+}
+
 func init() {
 	buf := [2]byte{}
 	*(*uint16)(unsafe.Pointer(&buf[0])) = uint16(0xCAFE)
@@ -1,6 +1,7 @@
 package goja
 
 import (
+	gocontext "context"
 	"fmt"
 	"hash/maphash"
 	"math"
@@ -55,6 +56,7 @@ var (
 	reflectTypeFunc     = reflect.TypeOf((func(FunctionCall) Value)(nil))
 	reflectTypeCtor     = reflect.TypeOf((func(ConstructorCall) *Object)(nil))
 	reflectTypeError    = reflect.TypeOf((*error)(nil)).Elem()
+	reflectTypeContext  = reflect.TypeOf((*gocontext.Context)(nil)).Elem()
 )
 
 var intCache [256]Value
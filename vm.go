@@ -374,6 +374,9 @@ type vm struct {
 
 	maxCallStackSize int
 
+	// number of instructions executed (including the cost charged by native functions) and the limit
+	instrCount, instrLimit int64
+
 	stashAllocs int
 
 	interrupted   uint32
@@ -382,7 +385,12 @@ type vm struct {
 
 	curAsyncRunner *asyncRunner
 
-	profTracker *profTracker
+	// the trackers of the global profiler and the Runtime's one (see Runtime.StartProfile)
+	profTracker, localProfTracker *profTracker
+	// the labels set by Runtime.DoWithProfileLabels
+	profLabels *profLabels
+
+	debugger *Debugger
 }
 
 type instruction interface {
@@ -603,6 +611,38 @@ func (vm *vm) init() {
 	vm.sb = -1
 	vm.stash = &vm.r.global.stash
 	vm.maxCallStackSize = math.MaxInt32
+	vm.instrLimit = math.MaxInt64
+}
+
+// reset brings the vm into the initial state retaining the capacity of the stacks.
+func (vm *vm) reset() {
+	clear(vm.stack[:cap(vm.stack)])
+	vm.stack = vm.stack[:0]
+	clear(vm.callStack[:cap(vm.callStack)])
+	vm.callStack = vm.callStack[:0]
+	clear(vm.iterStack[:cap(vm.iterStack)])
+	vm.iterStack = vm.iterStack[:0]
+	clear(vm.refStack[:cap(vm.refStack)])
+	vm.refStack = vm.refStack[:0]
+	clear(vm.tryStack[:cap(vm.tryStack)])
+	vm.tryStack = vm.tryStack[:0]
+
+	vm.prg = nil
+	vm.pc = 0
+	vm.sp, vm.args = 0, 0
+	vm.sb = -1
+	vm.stash = &vm.r.global.stash
+	vm.privEnv = nil
+	vm.newTarget = nil
+	vm.result = nil
+	vm.instrCount = 0
+	vm.stashAllocs = 0
+	vm.curAsyncRunner = nil
+
+	vm.interruptLock.Lock()
+	vm.interruptVal = nil
+	atomic.StoreUint32(&vm.interrupted, 0)
+	vm.interruptLock.Unlock()
 }
 
 func (vm *vm) halted() bool {
@@ -611,14 +651,22 @@ func (vm *vm) halted() bool {
 }
 
 func (vm *vm) run() {
-	if vm.profTracker != nil && !vm.runWithProfiler() {
+	if vm.debugger != nil && !vm.runWithDebugger() {
 		return
 	}
+	if (vm.profTracker != nil || vm.localProfTracker != nil) && !vm.runWithProfiler() {
+		return
+	}
+	if vm.instrLimit != math.MaxInt64 {
+		if !vm.runWithInstructionLimit() {
+			return
+		}
+	}
 	count := 0
 	interrupted := false
 	for {
 		if count == 0 {
-			if atomic.LoadInt32(&globalProfiler.enabled) == 1 && !vm.runWithProfiler() {
+			if vm.profEnabled() && !vm.runWithProfiler() {
 				return
 			}
 			count = 100
@@ -646,16 +694,41 @@ func (vm *vm) run() {
 	}
 }
 
+func (vm *vm) profEnabled() bool {
+	return atomic.LoadInt32(&globalProfiler.enabled) == 1 || atomic.LoadInt32(&vm.r.localProfiler.enabled) == 1
+}
+
+// profRegister registers the vm with the active profilers it is not registered with yet. The trackers it returns
+// must be released by profRelease() when the run loop exits.
+func (vm *vm) profRegister() (global, local *profTracker) {
+	if vm.profTracker == nil && atomic.LoadInt32(&globalProfiler.enabled) == 1 {
+		global = globalProfiler.p.registerVm()
+		vm.profTracker = global
+	}
+	if vm.localProfTracker == nil && atomic.LoadInt32(&vm.r.localProfiler.enabled) == 1 {
+		local = vm.r.localProfiler.registerVm()
+		vm.localProfTracker = local
+	}
+	return
+}
+
+func (vm *vm) profRelease(global, local *profTracker) {
+	if global != nil {
+		atomic.StoreInt32(&global.finished, 1)
+		vm.profTracker = nil
+	}
+	if local != nil {
+		atomic.StoreInt32(&local.finished, 1)
+		vm.localProfTracker = nil
+	}
+}
+
 func (vm *vm) runWithProfiler() bool {
-	pt := vm.profTracker
-	if pt == nil {
-		pt = globalProfiler.p.registerVm()
-		vm.profTracker = pt
-		defer func() {
-			atomic.StoreInt32(&vm.profTracker.finished, 1)
-			vm.profTracker = nil
-		}()
+	global, local := vm.profRegister()
+	if global != nil || local != nil {
+		defer vm.profRelease(global, local)
 	}
+	pt, lpt := vm.profTracker, vm.localProfTracker
 	interrupted := false
 	for {
 		if interrupted = atomic.LoadUint32(&vm.interrupted) != 0; interrupted {
@@ -665,23 +738,133 @@ func (vm *vm) runWithProfiler() bool {
 		if pc < 0 || pc >= len(vm.prg.code) {
 			break
 		}
+		if vm.instrCount >= vm.instrLimit {
+			vm.instructionLimitExceeded()
+		}
+		vm.instrCount++
 		vm.prg.code[pc].exec(vm)
-		req := atomic.LoadInt32(&pt.req)
-		if req == profReqStop {
+		if pt != nil && !vm.profSample(pt, pc) {
+			pt = nil
+		}
+		if lpt != nil && !vm.profSample(lpt, pc) {
+			lpt = nil
+		}
+		if pt == nil && lpt == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// profSample takes a sample if it has been requested by the profiler. It returns false if the profiling has stopped.
+func (vm *vm) profSample(pt *profTracker, pc int) bool {
+	req := atomic.LoadInt32(&pt.req)
+	if req == profReqStop {
+		return false
+	}
+	if req == profReqDoSample {
+		pt.stop = time.Now()
+
+		pt.numFrames = len(vm.r.CaptureCallStack(len(pt.frames), pt.frames[:0]))
+		pt.frames[0].pc = pc
+		pt.labels = vm.profLabels
+		atomic.StoreInt32(&pt.req, profReqSampleReady)
+	}
+	return true
+}
+
+// runWithInstructionLimit is the run loop used while an instruction limit is set, so that the regular loop does not
+// have to count the instructions. It returns true if the execution should continue in the regular loop (i.e. if
+// the vm has been interrupted).
+func (vm *vm) runWithInstructionLimit() bool {
+	count := 0
+	for {
+		if count == 0 {
+			if vm.profEnabled() && !vm.runWithProfiler() {
+				return false
+			}
+			count = 100
+		} else {
+			count--
+		}
+		if atomic.LoadUint32(&vm.interrupted) != 0 {
 			return true
 		}
-		if req == profReqDoSample {
-			pt.stop = time.Now()
+		pc := vm.pc
+		if pc < 0 || pc >= len(vm.prg.code) {
+			break
+		}
+		if vm.instrCount >= vm.instrLimit {
+			vm.instructionLimitExceeded()
+		}
+		vm.instrCount++
+		vm.prg.code[pc].exec(vm)
+	}
+
+	return false
+}
 
-			pt.numFrames = len(vm.r.CaptureCallStack(len(pt.frames), pt.frames[:0]))
-			pt.frames[0].pc = pc
-			atomic.StoreInt32(&pt.req, profReqSampleReady)
+// runWithDebugger is the run loop used while a Debugger is attached. It returns true if the execution should
+// continue in the regular loop (i.e. if the vm has been interrupted or the debugger has been detached or suspended).
+func (vm *vm) runWithDebugger() bool {
+	d := vm.debugger
+	if d.suspended {
+		return true
+	}
+	// the profilers are handled here as well because this loop does not return to run() until the code halts
+	global, local := vm.profRegister()
+	if global != nil || local != nil {
+		defer vm.profRelease(global, local)
+	}
+	pt, lpt := vm.profTracker, vm.localProfTracker
+	for {
+		if atomic.LoadUint32(&vm.interrupted) != 0 {
+			return true
+		}
+		pc := vm.pc
+		if pc < 0 || pc >= len(vm.prg.code) {
+			break
+		}
+		if vm.instrCount >= vm.instrLimit {
+			vm.instructionLimitExceeded()
+		}
+		d.check(vm)
+		if vm.debugger != d {
+			return true
+		}
+		vm.instrCount++
+		vm.prg.code[pc].exec(vm)
+		if pt != nil && !vm.profSample(pt, pc) {
+			pt = nil
+		}
+		if lpt != nil && !vm.profSample(lpt, pc) {
+			lpt = nil
 		}
 	}
 
 	return false
 }
 
+// chargeInstructions adds the cost of a native operation to the instruction counter.
+func (vm *vm) chargeInstructions(n int64) {
+	if n <= 0 || vm.instrLimit == math.MaxInt64 {
+		return
+	}
+	vm.instrCount += n
+	if vm.instrCount > vm.instrLimit && len(vm.callStack) > 0 {
+		vm.instructionLimitExceeded()
+	}
+}
+
+func (vm *vm) instructionLimitExceeded() {
+	ex := &InstructionLimitExceededError{
+		limit: vm.instrLimit,
+	}
+	ex.stack = vm.captureStack(nil, 0)
+	panic(ex)
+}
+
 func (vm *vm) Interrupt(v interface{}) {
 	vm.interruptLock.Lock()
 	vm.interruptVal = v
@@ -734,23 +917,85 @@ func (vm *vm) captureStack(stack []StackFrame, ctxOffset int) []StackFrame {
 	return stack
 }
 
-func (vm *vm) captureAsyncStack(stack []StackFrame, runner *asyncRunner) []StackFrame {
-	if promise, _ := runner.promiseCap.promise.self.(*Promise); promise != nil {
-		if len(promise.fulfillReactions) == 1 {
-			if r := promise.fulfillReactions[0].asyncRunner; r != nil {
-				ctx := &r.gen.ctx
-				if ctx.prg != nil || ctx.sb > 0 {
-					var funcName unistring.String
-					if prg := ctx.prg; prg != nil {
-						funcName = prg.funcName
-					} else {
-						funcName = getFuncName(ctx.stack, 1)
-					}
-					stack = append(stack, StackFrame{prg: ctx.prg, pc: ctx.pc, funcName: funcName})
+// captureStackUntil captures up to limit frames of the current call stack, omitting the topmost skip frames.
+// If skipUntil is not nil, the frames above and including the most recent call of skipUntil are omitted as well,
+// and if there is no such call the result is empty.
+func (vm *vm) captureStackUntil(skip int, skipUntil *Object, limit int) []StackFrame {
+	var stack []StackFrame
+	addFrame := func(prg *Program, sb, pc int) bool {
+		if prg == nil && sb <= 0 {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		if skipUntil != nil {
+			if sb > 0 {
+				if callee, ok := vm.stack[sb-1].(*Object); ok && callee == skipUntil {
+					skipUntil = nil
 				}
-				stack = vm.captureAsyncStack(stack, r)
 			}
+			return true
+		}
+		if len(stack) >= limit {
+			return false
+		}
+		var funcName unistring.String
+		if prg != nil {
+			funcName = prg.funcName
+		} else {
+			funcName = getFuncName(vm.stack, sb)
+		}
+		stack = append(stack, StackFrame{prg: prg, pc: pc, funcName: funcName})
+		return true
+	}
+	if !addFrame(vm.prg, vm.sb, vm.pc) {
+		return stack
+	}
+	for i := len(vm.callStack) - 1; i >= 0; i-- {
+		frame := &vm.callStack[i]
+		if !addFrame(frame.prg, frame.sb, frame.pc) {
+			return stack
+		}
+	}
+	if skipUntil == nil && vm.curAsyncRunner != nil {
+		stack = vm.captureAsyncStack(stack, vm.curAsyncRunner)
+		if len(stack) > limit {
+			stack = stack[:limit]
+		}
+	}
+	return stack
+}
+
+func (vm *vm) captureAsyncStack(stack []StackFrame, runner *asyncRunner) []StackFrame {
+	for {
+		promise, _ := runner.promiseCap.promise.self.(*Promise)
+		if promise == nil {
+			break
+		}
+		// find the async function awaiting the result of the current one
+		var awaiting *asyncRunner
+		for _, reaction := range promise.fulfillReactions {
+			if reaction.asyncRunner != nil {
+				awaiting = reaction.asyncRunner
+				break
+			}
+		}
+		if awaiting == nil {
+			break
+		}
+		ctx := &awaiting.gen.ctx
+		if ctx.prg != nil || ctx.sb > 0 {
+			var funcName unistring.String
+			if prg := ctx.prg; prg != nil {
+				funcName = prg.funcName
+			} else {
+				funcName = getFuncName(ctx.stack, 1)
+			}
+			stack = append(stack, StackFrame{prg: ctx.prg, pc: ctx.pc, funcName: funcName, async: true})
 		}
+		runner = awaiting
 	}
 
 	return stack
@@ -799,6 +1044,9 @@ func (vm *vm) restoreStacks(iterLen, refLen uint32) (ex *Exception) {
 
 func (vm *vm) handleThrow(arg interface{}) *Exception {
 	ex := vm.exceptionFromValue(arg)
+	if d := vm.debugger; d != nil && ex != nil {
+		d.exceptionThrown(vm, ex)
+	}
 	for len(vm.tryStack) > 0 {
 		tf := &vm.tryStack[len(vm.tryStack)-1]
 		if tf.catchPos == -1 && tf.finallyPos == -1 || ex == nil && tf.catchPos != tryPanicMarker {
@@ -1279,7 +1527,9 @@ func (_add) exec(vm *vm) {
 		if !isRightString {
 			rightString = right.toString()
 		}
-		ret = leftString.Concat(rightString)
+		str := leftString.Concat(rightString)
+		vm.r.accountString(str)
+		ret = str
 	} else {
 		switch left := left.(type) {
 		case valueInt:
@@ -5175,6 +5425,26 @@ func (copyStash) exec(vm *vm) {
 	vm.pc++
 }
 
+type _debuggerStmt struct{}
+
+// debuggerStmt marks a 'debugger' statement. It is a no-op, the pause is done by the debugger run loop.
+var debuggerStmt _debuggerStmt
+
+func (_debuggerStmt) exec(vm *vm) {
+	vm.pc++
+}
+
+// coverageHit increments a counter of a script instrumented for the code coverage (see Coverage).
+type coverageHit struct {
+	script  *coverageScript
+	counter int
+}
+
+func (c coverageHit) exec(vm *vm) {
+	atomic.AddUint64(&c.script.counters[c.counter], 1)
+	vm.pc++
+}
+
 type _throwAssignToConst struct{}
 
 var throwAssignToConst _throwAssignToConst
@@ -5287,6 +5557,7 @@ func (n concatStrings) exec(vm *vm) {
 
 	vm.sp -= int(n) - 1
 	if allAscii {
+		vm.r.accountMemory(int64(length))
 		var buf strings.Builder
 		buf.Grow(length)
 		for _, s := range strs {
@@ -5294,6 +5565,7 @@ func (n concatStrings) exec(vm *vm) {
 		}
 		vm.stack[vm.sp-1] = asciiString(buf.String())
 	} else {
+		vm.r.accountMemory(int64(length) * 2)
 		var buf unicodeStringBuilder
 		buf.Grow(length)
 		for _, s := range strs {